@@ -4,24 +4,52 @@
 //
 //	airspace pipeline             # Full pipeline: sync → tile → manifest
 //	airspace sync                 # Smart sync (only download if changed)
+//	airspace validate             # Check (and -repair) GeoJSON before tiling
+//	airspace tfr                  # Refresh the TFR layer from its AIXM feed
 //	airspace tile                 # Convert GeoJSON to PMTiles
-//	airspace manifest             # Generate manifest files
+//	airspace manifest             # Generate manifests, MapLibre style and schema
 //	airspace status               # Show data file status
+//	airspace query                # What airspace applies at a point/path/area
+//	airspace plan check <file>    # Validate a flight plan (GeoJSON, GPX, KML, .plan)
+//	airspace elevation            # Ground elevation at a point or along a path
+//	airspace inspect <file>       # Look inside a PMTiles archive
+//	airspace diff <a> <b>         # Compare two PMTiles archives
+//	airspace serve                # Local tile server (range, z/x/y, TileJSON)
+//	airspace daemon               # Pipeline on the AIRAC schedule, upload, webhooks
+//	airspace bundle -bbox ...     # Offline bundle of a sub-region for field kits
 //	airspace download             # Download FAA data (use sync instead)
 //
 // See also: layouts/fleet/airspace-demo.html
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/paulmach/orb"
+
 	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/bundle"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/daemon"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/elevation"
 	"github.com/joeblew999/ubuntu-website/internal/airspace/gotiler"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/inspect"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/notam"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/plan"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/query"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/r2"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/serve"
 	"github.com/joeblew999/ubuntu-website/internal/airspace/tiler"
 )
 
@@ -39,6 +67,10 @@ func main() {
 		runPipeline()
 	case "sync":
 		runSync()
+	case "validate":
+		runValidate()
+	case "tfr":
+		runTFR()
 	case "tile":
 		runTile()
 	case "manifest":
@@ -55,6 +87,22 @@ func main() {
 		runSummary()
 	case "check":
 		runCheck()
+	case "query":
+		runQuery()
+	case "plan":
+		runPlan()
+	case "elevation":
+		runElevation()
+	case "inspect":
+		runInspect()
+	case "diff":
+		runDiff()
+	case "serve":
+		runServe()
+	case "daemon":
+		runDaemon()
+	case "bundle":
+		runBundle()
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -68,29 +116,131 @@ func printUsage() {
 	fmt.Println("Usage: airspace <command> [options]")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  pipeline    Full pipeline: sync → tile (if changed) → manifest")
+	fmt.Println("  pipeline    Full pipeline: sync → validate → tile (if changed) → manifest")
 	fmt.Println("  sync        Smart sync FAA data (only download if source changed)")
+	fmt.Println("  validate    Check GeoJSON geometry, winding, coordinates and required properties")
+	fmt.Println("  tfr         Refresh TFRs from an AIXM feed: drop expired, tile, update manifest")
 	fmt.Println("  tile        Convert GeoJSON to PMTiles")
-	fmt.Println("  manifest    Generate manifest files with file sizes and metadata")
-	fmt.Println("  upload      Upload PMTiles to Cloudflare R2 (manifest-driven)")
+	fmt.Println("  manifest    Generate manifests, MapLibre style and schema (-check validates a manifest)")
+	fmt.Println("  upload      Publish PMTiles to Cloudflare R2 as a new version (or -rollback)")
 	fmt.Println("  status      Show data file status and age")
 	fmt.Println("  history     Show sync history and change patterns")
 	fmt.Println("  check       Output sync result for GitHub Actions")
 	fmt.Println("  summary     Generate GitHub Actions step summary")
+	fmt.Println("  query       Query airspace at a point, path or area (or serve as HTTP API)")
+	fmt.Println("  plan check  Validate a flight plan against airspace, UAS ceilings and obstacles")
+	fmt.Println("  elevation   Ground elevation at a point or profile along a path (from -dem)")
+	fmt.Println("  inspect     Show header, zoom histogram and layers of a PMTiles file")
+	fmt.Println("  diff        Compare two PMTiles files tile by tile")
+	fmt.Println("  serve       Serve local PMTiles (range requests, z/x/y tiles, TileJSON)")
+	fmt.Println("  daemon      Run the pipeline on the AIRAC cycle, upload, notify webhooks, /status")
+	fmt.Println("  bundle      Export a bbox as an offline zip: tiles, clipped GeoJSON, manifest, style")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -force              Force all steps even if no changes")
 	fmt.Println("  -tiler <name>       Tiler: auto, tippecanoe, gotiler (default: auto)")
 	fmt.Println("  -dataset <name>     Process single dataset (uas, boundary, sua, airports, navaids)")
+	fmt.Println("  -workers <n>        gotiler: tiles encoded in parallel (default: all CPUs)")
+	fmt.Println("  -combined           Build one multi-layer PMTiles from all datasets")
+	fmt.Println("  -repair             pipeline/validate: fix rings, winding and duplicates in place")
+	fmt.Println("  -max-error-rate <f> pipeline/validate: invalid feature fraction that fails (default: 0.01, 0 = any)")
+	fmt.Println("  -region <key>       Region from the regions config (default: usa)")
+	fmt.Println("  -regions <file>     Regions config file (default: embedded regions.yaml)")
+	fmt.Println("  -dem <path>         query/plan/elevation: DEM file or dir (default: data/airspace/dem)")
+	fmt.Println("  -at <time>          query/plan/tfr: TFRs in force at this time (RFC 3339, 'now', 'any')")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  airspace pipeline                  # Full idempotent pipeline")
 	fmt.Println("  airspace pipeline -tiler gotiler   # Pipeline with pure Go tiler")
+	fmt.Println("  airspace pipeline -regions internal/airspace/testdata/eu/regions.yaml -region eu")
+	fmt.Println("  airspace pipeline -repair          # Repair fixable GeoJSON before tiling")
 	fmt.Println("  airspace sync                      # Sync only changed datasets")
+	fmt.Println("  airspace validate -dataset sua     # Validate one dataset")
 	fmt.Println("  airspace sync -force               # Force re-download all")
 	fmt.Println("  airspace tile -dataset uas         # Convert single dataset")
+	fmt.Println("  airspace tile -combined            # All layers in one PMTiles")
+	fmt.Println("  airspace manifest -check manifest_usa.json")
 	fmt.Println("  airspace upload                    # Upload to R2")
 	fmt.Println("  airspace upload -test              # Test R2 endpoints")
+	fmt.Println("  airspace upload -versions          # List published versions")
+	fmt.Println("  airspace upload -rollback          # Back to the previous version")
+	fmt.Println("  airspace query -lat 37.62 -lon -122.38 -alt 400")
+	fmt.Println("  airspace query -geojson path.geojson -alt 200")
+	fmt.Println("  airspace query -serve :8090        # JSON API at /query")
+	fmt.Println("  airspace query -lat 37.62 -lon -122.38 -at 2026-10-20T18:00:00Z")
+	fmt.Println("  airspace tfr -feed internal/airspace/testdata/tfr/tfr_aixm.xml")
+	fmt.Println("  airspace plan check -alt 400 mission.plan")
+	fmt.Println("  airspace elevation -lat 37.62 -lon -122.38")
+	fmt.Println("  airspace elevation -profile route.gpx -step 100")
+	fmt.Println("  airspace inspect static/airspace/tiles/faa_airports.pmtiles")
+	fmt.Println("  airspace diff -exit-code tippecanoe.pmtiles gotiler.pmtiles")
+	fmt.Println("  airspace serve -addr :8091         # http://localhost:8091/boundary.json")
+	fmt.Println("  airspace daemon -upload -webhook https://hooks.slack.com/services/...")
+	fmt.Println("  airspace daemon -cycle 56 -interval 0   # Chart dates only")
+	fmt.Println("  airspace bundle -bbox -122.6,37.4,-121.8,38.0 -zoom 12")
+	fmt.Println("  airspace bundle -verify airspace-usa-20261017T120000Z.zip")
+}
+
+// regionFlags registers -region and -regions on a command's flag set.
+// Call the returned function after parsing to resolve the region.
+func regionFlags(fs *flag.FlagSet) func() airspace.Region {
+	regionFlag := fs.String("region", "", "Region key (default: registry default, usa)")
+	regionsFile := fs.String("regions", "", "Regions config file (default: embedded regions.yaml)")
+
+	return func() airspace.Region {
+		if *regionsFile != "" {
+			if err := airspace.UseRegionsFile(*regionsFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		r, err := airspace.GetRegion(*regionFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return r
+	}
+}
+
+// demFlag registers -dem on a command's flag set. Call the returned function
+// after parsing to open the elevation source; nil means no terrain data.
+// The default directory may be absent, an explicit path must open.
+func demFlag(fs *flag.FlagSet) func() elevation.Source {
+	path := fs.String("dem", airspace.DirDEM, "DEM file or directory (GeoTIFF, terrarium PMTiles) for AGL/MSL; 'none' = ground at sea level")
+
+	return func() elevation.Source {
+		if *path == "none" {
+			return nil
+		}
+		src, err := elevation.Open(*path)
+		if os.IsNotExist(err) && *path == airspace.DirDEM {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return src
+	}
+}
+
+// atFlag registers -at on a command's flag set. Call the returned function
+// after parsing; nil ('any') means time-bounded features match at any time.
+func atFlag(fs *flag.FlagSet) func() *time.Time {
+	at := fs.String("at", "now", "Match TFRs in force at this time (RFC 3339, 'now' or 'any')")
+
+	return func() *time.Time {
+		if *at == "any" {
+			return nil
+		}
+		t, err := notam.ParseTime(*at, time.Now().UTC())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return &t
+	}
 }
 
 // ============================================================================
@@ -101,7 +251,12 @@ func runPipeline() {
 	fs := flag.NewFlagSet("pipeline", flag.ExitOnError)
 	force := fs.Bool("force", false, "Force all steps even if no changes")
 	tilerFlag := fs.String("tiler", "auto", "Tiler: auto, tippecanoe, gotiler")
+	combined := fs.Bool("combined", false, "Also build the combined multi-layer PMTiles")
+	repair := fs.Bool("repair", false, "Repair fixable GeoJSON issues before tiling")
+	maxErrorRate := fs.Float64("max-error-rate", airspace.DefaultMaxErrorRate, "Invalid feature fraction per dataset that fails the pipeline (0 = any, <0 = never)")
+	region := regionFlags(fs)
 	fs.Parse(os.Args[1:])
+	r := region()
 
 	fmt.Println("╔════════════════════════════════════════╗")
 	fmt.Println("║   FAA Airspace Pipeline (Idempotent)   ║")
@@ -114,15 +269,25 @@ func runPipeline() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Region: %s (%s)\n", r.Name, r.Key)
 	fmt.Printf("Tiler: %s\n\n", activeTiler.Name())
 
 	// Run pipeline
 	opts := airspace.PipelineOptions{
 		Force:     *force,
 		TilerName: *tilerFlag,
+		Region:    r.Key,
+		Combined:  *combined,
+
+		Repair:       *repair,
+		MaxErrorRate: *maxErrorRate,
 	}
 
 	result, err := airspace.Pipeline(opts, activeTiler)
+	if result != nil && result.Validation != nil {
+		fmt.Println()
+		printValidationReport(*result.Validation, 3)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Pipeline error: %v\n", err)
 		os.Exit(1)
@@ -151,7 +316,9 @@ func runSync() {
 	force := fs.Bool("force", false, "Force re-download even if unchanged")
 	skipLarge := fs.Bool("skip-large", true, "Skip datasets >100MB (obstacles)")
 	timeout := fs.Duration("timeout", 5*time.Minute, "HTTP request timeout")
+	region := regionFlags(fs)
 	fs.Parse(os.Args[1:])
+	r := region()
 
 	fmt.Println("Syncing FAA Airspace Data")
 	fmt.Println("=========================")
@@ -162,13 +329,13 @@ func runSync() {
 	}
 	fmt.Println()
 
-	opts := airspace.DefaultSyncOptions()
+	opts := airspace.RegionSyncOptions(r)
 	opts.Force = *force
 	opts.SkipLarge = *skipLarge
 	opts.Timeout = *timeout
 
 	if !*skipLarge {
-		opts.Datasets = airspace.AllDatasets
+		opts.Datasets = r.AllDatasets()
 	}
 
 	result, err := airspace.Sync(opts)
@@ -181,7 +348,11 @@ func runSync() {
 	for key, ds := range result.Datasets {
 		switch ds.Status {
 		case "updated":
-			fmt.Printf("[%s] ✓ updated (%.1f MB)\n", key, ds.SizeMB)
+			fmt.Printf("[%s] ✓ updated (%.1f MB)", key, ds.SizeMB)
+			if c := ds.Changes; c != nil {
+				fmt.Printf(" +%d -%d ~%d features", c.Added, c.Removed, c.Modified)
+			}
+			fmt.Println()
 		case "unchanged":
 			fmt.Printf("[%s] unchanged\n", key)
 		case "missing":
@@ -208,10 +379,16 @@ func runTile() {
 	datasetFlag := fs.String("dataset", "", "Specific dataset to tile (empty = all)")
 	force := fs.Bool("force", false, "Force regenerate even if up-to-date")
 	tilerFlag := fs.String("tiler", "auto", "Tiler: auto, tippecanoe, gotiler")
+	workers := fs.Int("workers", 0, "Tiles encoded in parallel by gotiler (0 = all CPUs)")
+	combined := fs.Bool("combined", false, "Build one multi-layer PMTiles from all datasets")
+	region := regionFlags(fs)
 	fs.Parse(os.Args[1:])
+	r := region()
 
 	// Select tiler
-	activeTiler, err := airspace.SelectTiler(*tilerFlag, tiler.New(), gotiler.New())
+	goTiler := gotiler.New()
+	goTiler.Workers = *workers
+	activeTiler, err := airspace.SelectTiler(*tilerFlag, tiler.New(), goTiler)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -221,16 +398,23 @@ func runTile() {
 	fmt.Println("=========================================")
 	fmt.Println()
 
-	if *datasetFlag != "" {
+	if *combined {
+		// All datasets as layers of one archive
+		if err := airspace.TileCombined(activeTiler, r, *force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("[%s] ✓ done\n", airspace.PMTilesCombined)
+	} else if *datasetFlag != "" {
 		// Single dataset
-		if err := airspace.TileOne(activeTiler, *datasetFlag, *force); err != nil {
+		if err := airspace.TileOne(activeTiler, r, *datasetFlag, *force); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("[%s] ✓ done\n", *datasetFlag)
 	} else {
 		// All datasets
-		count, err := airspace.TileAll(activeTiler, *force)
+		count, err := airspace.TileAll(activeTiler, r, *force)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -244,17 +428,39 @@ func runTile() {
 // ============================================================================
 
 func runManifest() {
+	fs := flag.NewFlagSet("manifest", flag.ExitOnError)
+	check := fs.String("check", "", "Validate this regional manifest against the schema instead of generating")
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+
+	if *check != "" {
+		data, err := os.ReadFile(*check)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := airspace.ValidateManifestJSON(data); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s does not match %s:\n%v\n", *check, airspace.FileManifestSchema, err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ %s is valid\n", *check)
+		return
+	}
+	r := region()
+
 	fmt.Println("Generating Airspace Manifests")
 	fmt.Println("=============================")
 	fmt.Println()
 
-	if err := airspace.GenerateManifests(airspace.DirData, airspace.DirPMTiles, airspace.DirGeoJSON); err != nil {
+	if err := airspace.GenerateManifests(r); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Printf("✓ %s\n", filepath.Join(airspace.DirData, airspace.FileManifest))
-	fmt.Printf("✓ %s\n", filepath.Join(airspace.DirData, airspace.FileUSAManifest))
+	fmt.Printf("✓ %s (validated)\n", filepath.Join(airspace.DirData, r.ManifestFile()))
+	fmt.Printf("✓ %s\n", filepath.Join(airspace.DirData, r.StyleFile()))
+	fmt.Printf("✓ %s\n", filepath.Join(airspace.DirData, airspace.FileManifestSchema))
 	fmt.Println("\nManifests updated.")
 }
 
@@ -264,10 +470,15 @@ func runManifest() {
 
 func runDownload() {
 	fs := flag.NewFlagSet("download", flag.ExitOnError)
-	outputDir := fs.String("output", airspace.DirGeoJSON, "Output directory")
+	outputDir := fs.String("output", "", "Output directory (default: region GeoJSON dir)")
 	datasetFlag := fs.String("dataset", "", "Specific dataset (empty = all)")
 	timeout := fs.Duration("timeout", 5*time.Minute, "HTTP timeout")
+	region := regionFlags(fs)
 	fs.Parse(os.Args[1:])
+	r := region()
+	if *outputDir == "" {
+		*outputDir = r.GeoJSONDir()
+	}
 
 	fmt.Println("Downloading FAA Data")
 	fmt.Println("====================")
@@ -280,10 +491,10 @@ func runDownload() {
 	if *datasetFlag != "" {
 		datasets = []string{*datasetFlag}
 	} else {
-		datasets = airspace.AllDatasets
+		datasets = r.AllDatasets()
 	}
 
-	if err := airspace.Download(client, *outputDir, datasets); err != nil {
+	if err := airspace.Download(client, r, *outputDir, datasets); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -291,19 +502,218 @@ func runDownload() {
 	fmt.Println("\nDone.")
 }
 
+// ============================================================================
+// Validate Command
+// ============================================================================
+
+func runValidate() {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	datasetFlag := fs.String("dataset", "", "Specific dataset (empty = region dataset order)")
+	dir := fs.String("dir", "", "Directory containing GeoJSON (default: region GeoJSON dir)")
+	repair := fs.Bool("repair", false, "Repair fixable issues and rewrite the GeoJSON")
+	maxErrorRate := fs.Float64("max-error-rate", airspace.DefaultMaxErrorRate, "Invalid feature fraction per dataset that fails (0 = any, <0 = never)")
+	issues := fs.Int("issues", 10, "Issues to list per dataset")
+	jsonOut := fs.Bool("json", false, "Output the report as JSON")
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+
+	opts := airspace.DefaultValidateOptions(r)
+	opts.Dir, opts.Repair, opts.MaxErrorRate = *dir, *repair, *maxErrorRate
+	if *datasetFlag != "" {
+		opts.Datasets = []string{*datasetFlag}
+	}
+
+	report, err := airspace.Validate(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(report.Datasets) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no GeoJSON found in %s (run 'airspace sync' first)\n", r.GeoJSONDir())
+		os.Exit(1)
+	}
+
+	// The report is what 'airspace status' shows, so keep it with the sync state
+	if *dir == "" && *datasetFlag == "" {
+		if err := airspace.SaveValidationReport(filepath.Join(r.DataDir(), airspace.FileValidation), *report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: saving report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printValidationReport(*report, *issues)
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
+
+// printValidationReport prints per-dataset counts and up to issues issues each.
+func printValidationReport(report airspace.ValidationReport, issues int) {
+	status := "PASSED"
+	if !report.Passed {
+		status = "FAILED"
+	}
+	fmt.Printf("Validation %s: %d features, %d invalid, %d warnings, %d repaired, %d dropped (max error rate %.2f%%)\n",
+		status, report.Features, report.Invalid, report.Warnings, report.Repaired, report.Dropped, report.MaxErrorRate*100)
+
+	for _, key := range sortedKeys(report.Datasets) {
+		v := report.Datasets[key]
+		mark := "✓"
+		if !v.Passed {
+			mark = "✗"
+		}
+		fmt.Printf("  %s [%s] %d features, %d invalid (%.2f%%), %d warnings, %d repaired, %d dropped\n",
+			mark, key, v.Features, v.Invalid, v.ErrorRate*100, v.Warnings, v.Repaired, v.Dropped)
+		if len(v.Counts) > 0 {
+			var counts []string
+			for _, code := range sortedKeys(v.Counts) {
+				counts = append(counts, fmt.Sprintf("%s=%d", code, v.Counts[code]))
+			}
+			fmt.Printf("      %s\n", strings.Join(counts, " "))
+		}
+		for i, issue := range v.Issues {
+			if i >= issues {
+				fmt.Printf("      ... and %d more\n", len(v.Issues)-issues)
+				break
+			}
+			label := issue.ID
+			if label == "" {
+				label = fmt.Sprintf("#%d", issue.Feature)
+			}
+			fixed := ""
+			if issue.Repaired {
+				fixed = " (repaired)"
+			}
+			fmt.Printf("      %-7s %s: %s%s\n", issue.Severity, label, issue.Message, fixed)
+		}
+	}
+}
+
+// ============================================================================
+// TFR Command
+// ============================================================================
+
+func runTFR() {
+	fs := flag.NewFlagSet("tfr", flag.ExitOnError)
+	feed := fs.String("feed", "", "AIXM 5.1 feed URL or file (default: dataset base_url)")
+	datasetFlag := fs.String("dataset", "", "Dynamic dataset (default: the region's only dynamic dataset)")
+	tilerFlag := fs.String("tiler", "auto", "Tiler: auto, tippecanoe, gotiler")
+	noTile := fs.Bool("no-tile", false, "Only write the GeoJSON (skip tiles and manifest)")
+	jsonOut := fs.Bool("json", false, "Output the refresh result as JSON")
+	region := regionFlags(fs)
+	at := atFlag(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+
+	key := *datasetFlag
+	if key == "" {
+		dynamic := r.DynamicDatasets()
+		if len(dynamic) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: region %s has %d dynamic datasets; pick one with -dataset\n", r.Key, len(dynamic))
+			os.Exit(1)
+		}
+		key = dynamic[0]
+	}
+
+	// Expiry is always judged against the real clock; -at only marks what is in force
+	now := time.Now().UTC()
+	var result *airspace.DynamicResult
+	var err error
+	if *noTile {
+		var ds airspace.Dataset
+		if ds, err = r.Dataset(key); err == nil {
+			client := &http.Client{Timeout: time.Minute}
+			result, err = airspace.FetchDynamic(client, ds, *feed, filepath.Join(r.GeoJSONDir(), ds.GeoJSON), now)
+		}
+	} else {
+		activeTiler, terr := airspace.SelectTiler(*tilerFlag, tiler.New(), gotiler.New())
+		if terr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", terr)
+			os.Exit(1)
+		}
+		result, err = airspace.RefreshDynamic(activeTiler, r, key, *feed, now)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+		return
+	}
+
+	fmt.Printf("TFR refresh [%s] from %s\n", key, result.Feed)
+	fmt.Printf("  %d in feed, %d expired, %d beyond lookahead, %d written\n",
+		result.Parsed, result.Expired, result.Deferred, result.Written)
+	if !result.Window.NextChange.IsZero() {
+		fmt.Printf("  Next change: %s\n", result.Window.NextChange.Format(time.RFC3339))
+	}
+
+	when := at()
+	fmt.Println()
+	for _, tfr := range result.Restrictions {
+		status := "      "
+		switch {
+		case when == nil:
+		case tfr.Active(*when):
+			status = "ACTIVE"
+		case when.Before(tfr.From):
+			status = "LATER "
+		default:
+			status = "ENDED "
+		}
+		fmt.Printf("  %s %-8s %s  %s\n", status, tfr.NOTAM, tfr.Name, formatWindow(tfr.From, timePtr(tfr.To)))
+	}
+}
+
+// formatWindow formats an effective window; a nil end is until further notice.
+func formatWindow(from time.Time, to *time.Time) string {
+	end := "UFN"
+	if to != nil {
+		end = to.UTC().Format("2006-01-02 15:04Z")
+	}
+	return from.UTC().Format("2006-01-02 15:04Z") + " – " + end
+}
+
+// timePtr returns nil for the zero time.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 // ============================================================================
 // Status Command
 // ============================================================================
 
 func runStatus() {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+
 	fmt.Println("Airspace Data Status")
 	fmt.Println("====================")
+	fmt.Printf("Region: %s (%s)\n", r.Name, r.Key)
 	fmt.Println()
 
 	found := 0
-	for _, key := range airspace.AllDatasets {
-		ds := airspace.Datasets[key]
-		path := filepath.Join(airspace.DirGeoJSON, ds.GeoJSON)
+	all := r.AllDatasets()
+	for _, key := range all {
+		ds := r.Datasets[key]
+		path := filepath.Join(r.GeoJSONDir(), ds.GeoJSON)
 		info, err := os.Stat(path)
 		if err != nil {
 			fmt.Printf("  [%s] %s: NOT FOUND\n", key, ds.Name)
@@ -317,7 +727,16 @@ func runStatus() {
 	}
 
 	fmt.Println()
-	fmt.Printf("Found %d/%d datasets.\n", found, len(airspace.AllDatasets))
+	fmt.Printf("Found %d/%d datasets.\n", found, len(all))
+
+	report := airspace.LoadValidationReport(filepath.Join(r.DataDir(), airspace.FileValidation))
+	fmt.Println()
+	if report.Timestamp.IsZero() {
+		fmt.Println("Validation: never run (airspace validate)")
+		return
+	}
+	fmt.Printf("Last validation: %s\n", airspace.FormatTimeSince(report.Timestamp))
+	printValidationReport(report, 0)
 }
 
 // ============================================================================
@@ -325,7 +744,13 @@ func runStatus() {
 // ============================================================================
 
 func runHistory() {
-	historyFile := filepath.Join(airspace.DirData, airspace.FileSyncHistory)
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	details := fs.Int("changes", 5, "Changed features to list per dataset")
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+
+	historyFile := filepath.Join(r.DataDir(), airspace.FileSyncHistory)
 	history := airspace.LoadSyncHistory(historyFile)
 
 	fmt.Println("FAA Airspace Sync History")
@@ -360,7 +785,36 @@ func runHistory() {
 			run.Timestamp.Format("2006-01-02 15:04"),
 			status,
 			run.Duration)
+		printFeatureChanges(run, *details)
+	}
+}
+
+// printFeatureChanges lists a run's feature-level changes, up to limit per dataset.
+func printFeatureChanges(run airspace.SyncResult, limit int) {
+	for _, key := range sortedKeys(run.Datasets) {
+		c := run.Datasets[key].Changes
+		if c == nil || !c.HasChanges() {
+			continue
+		}
+		fmt.Printf("      %-10s +%d added, -%d removed, ~%d modified\n", key, c.Added, c.Removed, c.Modified)
+		for i, ch := range c.Changes {
+			if i >= limit {
+				fmt.Printf("        ... and %d more\n", c.Added+c.Removed+c.Modified-limit)
+				break
+			}
+			fmt.Printf("        %-20s %s %s\n", ch.Change, ch.ID, ch.Name)
+		}
+	}
+}
+
+// sortedKeys returns a map's keys in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
 }
 
 // ============================================================================
@@ -370,61 +824,916 @@ func runHistory() {
 func runUpload() {
 	fs := flag.NewFlagSet("upload", flag.ExitOnError)
 	testOnly := fs.Bool("test", false, "Test endpoints only (don't upload)")
+	versions := fs.Bool("versions", false, "List published versions")
+	rollback := fs.Bool("rollback", false, "Point manifest.json back at an earlier version")
+	version := fs.String("version", "", "Version to publish (default: UTC timestamp) or roll back to (default: previous)")
+	region := regionFlags(fs)
 	fs.Parse(os.Args[1:])
+	r := region()
 
 	if *testOnly {
 		// Test mode - check if endpoints are accessible
-		if err := airspace.TestR2Endpoints(); err != nil {
+		if err := airspace.TestR2Endpoints(r); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 		return
 	}
 
-	// Upload mode
-	if err := airspace.UploadToR2(); err != nil {
+	client, err := r2.FromEnv(airspace.R2Bucket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case *versions:
+		printR2Versions(client, r)
+
+	case *rollback:
+		v, err := airspace.RollbackR2(client, r, *version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ %s now points at version %s\n", airspace.FileManifest, v)
+
+	default:
+		opts := airspace.RegionPublishOptions(r)
+		opts.Version = *version
+		fmt.Printf("Publishing %s to r2://%s/%s/%s/\n\n", r.Key, airspace.R2Bucket, airspace.R2Prefix, r.TilesPath)
+
+		result, err := airspace.PublishToR2(client, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, f := range result.Files {
+			switch f.Status {
+			case "missing":
+				fmt.Printf("  SKIP: %s (file not found)\n", f.File)
+			default:
+				fmt.Printf("  %-9s %s (%.1f MB)\n", f.Status, f.File, float64(f.Size)/(1024*1024))
+			}
+		}
+		fmt.Printf("\n✓ Version %s is live (%s/%s/%s)\n", result.Version, airspace.R2PublicURL, airspace.R2Prefix, airspace.FileManifest)
+	}
+}
+
+func printR2Versions(client *r2.Client, r airspace.Region) {
+	log, err := airspace.R2Versions(client, r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	current, err := airspace.CurrentR2Version(client, r)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	fmt.Printf("Published versions (%s)\n", r.Key)
+	fmt.Println("=========================================")
+	if len(log.Versions) == 0 {
+		fmt.Println("No versions published yet.")
+		return
+	}
+	for _, v := range log.Versions {
+		marker := " "
+		if v.Version == current {
+			marker = "*"
+		}
+		var size int64
+		for _, f := range v.Files {
+			size += f.Size
+		}
+		fmt.Printf("%s %s  %s  %d files, %.1f MB\n", marker, v.Version, v.Published.Format("2006-01-02 15:04"), len(v.Files), float64(size)/(1024*1024))
+	}
 }
 
 // ============================================================================
-// CI Helpers
+// Query Command
 // ============================================================================
 
-func runCheck() {
-	resultPath := filepath.Join(airspace.DirData, airspace.FileSyncResult)
-	result := airspace.LoadSyncResult(resultPath)
+func runQuery() {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Latitude of point query")
+	lon := fs.Float64("lon", 0, "Longitude of point query")
+	altFlag := fs.String("alt", "", "Altitude in feet (e.g. 400, 400AGL, 5500MSL; bare = AGL)")
+	geoJSONFlag := fs.String("geojson", "", "GeoJSON file with a LineString (flight path) or Polygon (area)")
+	datasetsFlag := fs.String("datasets", "", "Comma-separated datasets to load (default: region dataset order)")
+	dir := fs.String("dir", "", "Directory containing synced GeoJSON (default: region GeoJSON dir)")
+	serve := fs.String("serve", "", "Serve the JSON HTTP API on this address (e.g. :8090)")
+	jsonOut := fs.Bool("json", false, "Output JSON instead of a human report")
+	region := regionFlags(fs)
+	dem := demFlag(fs)
+	at := atFlag(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+	if *dir == "" {
+		*dir = r.GeoJSONDir()
+	}
 
-	fmt.Printf("has_changes=%t\n", result.HasChanges)
-	fmt.Printf("updated_count=%d\n", result.Updated)
-}
+	var keys []string
+	if *datasetsFlag != "" {
+		keys = strings.Split(*datasetsFlag, ",")
+	}
 
-func runSummary() {
-	var out *os.File
-	if summaryPath := os.Getenv("GITHUB_STEP_SUMMARY"); summaryPath != "" {
-		f, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	idx, err := query.Load(r, *dir, keys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if idx.Len() == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no GeoJSON found in %s (run 'airspace sync' first)\n", *dir)
+		os.Exit(1)
+	}
+	if src := dem(); src != nil {
+		defer src.Close()
+		idx.SetElevation(src)
+	}
+
+	if *serve != "" {
+		fmt.Printf("Airspace query API on %s (%d features)\n", *serve, idx.Len())
+		fmt.Printf("  GET  http://localhost%s/query?lat=37.62&lon=-122.38&alt=400\n", *serve)
+		fmt.Printf("  POST http://localhost%s/query?alt=400   (GeoJSON body)\n", *serve)
+		fmt.Printf("  GET  http://localhost%s/query?lat=37.62&lon=-122.38&at=now\n", *serve)
+		if err := http.ListenAndServe(*serve, query.Handler(idx)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	req := query.Request{At: at()}
+	if *altFlag != "" {
+		alt, err := query.ParseAltitude(*altFlag)
 		if err != nil {
-			out = os.Stdout
-		} else {
-			defer f.Close()
-			out = f
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		req.Altitude = &alt
+	}
+
+	if *geoJSONFlag != "" {
+		data, err := os.ReadFile(*geoJSONFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		req.Geometry, err = query.ParseGeometry(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 	} else {
-		out = os.Stdout
+		req.Geometry = orb.Point{*lon, *lat}
 	}
 
-	fmt.Fprintln(out, "## Airspace Sync Complete")
-	fmt.Fprintln(out)
+	result := idx.Query(req)
 
-	// Sync result
-	resultPath := filepath.Join(airspace.DirData, airspace.FileSyncResult)
-	if data, err := os.ReadFile(resultPath); err == nil {
-		fmt.Fprintln(out, "### Sync Result")
-		fmt.Fprintln(out, "```json")
-		fmt.Fprintln(out, string(data))
-		fmt.Fprintln(out, "```")
-		fmt.Fprintln(out)
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+		return
+	}
+
+	printQueryResult(result)
+}
+
+func printQueryResult(result query.Result) {
+	fmt.Println("Airspace Query")
+	fmt.Println("==============")
+	fmt.Printf("Geometry: %s\n", result.Geometry)
+	if result.Altitude != nil {
+		fmt.Printf("Altitude: %.0f ft %s", result.Altitude.Feet, result.Altitude.Ref)
+		if result.GroundFt != nil {
+			fmt.Printf("  (ground %.0f ft MSL: %.0f ft MSL, %.0f ft AGL)", *result.GroundFt,
+				result.Altitude.MSL(*result.GroundFt), result.Altitude.AGL(*result.GroundFt))
+		}
+		fmt.Println()
+	}
+	if result.At != nil {
+		fmt.Printf("At: %s\n", result.At.Format(time.RFC3339))
+	}
+	fmt.Println()
+
+	s := result.Summary
+	if len(s.Classes) > 0 {
+		fmt.Printf("Controlled airspace: Class %s\n", strings.Join(s.Classes, ", "))
+	} else {
+		fmt.Println("Controlled airspace: none (Class G)")
+	}
+	if len(s.SUA) > 0 {
+		fmt.Printf("Special use airspace: %s\n", strings.Join(s.SUA, ", "))
+	}
+	if len(s.TFRs) > 0 {
+		fmt.Printf("⚠ Temporary flight restrictions: %s\n", strings.Join(s.TFRs, ", "))
+	}
+	if s.UASCeilingFt != nil {
+		fmt.Printf("UAS facility map ceiling: %.0f ft AGL", *s.UASCeilingFt)
+		if s.AboveUASCeiling {
+			fmt.Print("  ⚠ planned altitude is above ceiling")
+		}
+		fmt.Println()
+	}
+	if s.Airports > 0 || s.Navaids > 0 {
+		fmt.Printf("Airports: %d, Navaids: %d\n", s.Airports, s.Navaids)
+	}
+
+	if len(result.Matches) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("Matches (%d):\n", len(result.Matches))
+	for _, m := range result.Matches {
+		line := fmt.Sprintf("  [%s] %s", m.Dataset, m.Name)
+		if m.Class != "" {
+			line += fmt.Sprintf(" (%s)", m.Class)
+		}
+		if m.Floor != nil && m.Ceiling != nil {
+			line += fmt.Sprintf("  %s – %s", m.Floor, m.Ceiling)
+		}
+		if m.From != nil {
+			line += "  " + formatWindow(*m.From, m.To)
+		}
+		fmt.Println(line)
+	}
+}
+
+// ============================================================================
+// Plan Command
+// ============================================================================
+
+func runPlan() {
+	if len(os.Args) < 2 || os.Args[1] != "check" {
+		fmt.Fprintln(os.Stderr, "Usage: airspace plan check [-alt 400] [-at now] [-buffer 1852] [-json] [-exit-code] <plan.geojson|.gpx|.kml|.plan>")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("plan check", flag.ExitOnError)
+	altFlag := fs.String("alt", "", "Planned altitude for every waypoint (e.g. 400, 400AGL, 5500MSL; default: from the plan)")
+	buffer := fs.Float64("buffer", plan.DefaultBufferMeters, "Report airports and obstacles within this many meters of the path")
+	dir := fs.String("dir", "", "Directory containing synced GeoJSON (default: region GeoJSON dir)")
+	jsonOut := fs.Bool("json", false, "Output JSON instead of a human report")
+	exitCode := fs.Bool("exit-code", false, "Exit with status 2 if any segment is flagged")
+	region := regionFlags(fs)
+	dem := demFlag(fs)
+	at := atFlag(fs)
+	fs.Parse(os.Args[2:])
+	r := region()
+	if *dir == "" {
+		*dir = r.GeoJSONDir()
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Error: expected one flight plan file")
+		os.Exit(1)
+	}
+
+	p, err := plan.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := plan.Options{BufferMeters: *buffer, At: at()}
+	if *altFlag != "" {
+		alt, err := query.ParseAltitude(*altFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Altitude = &alt
+	}
+
+	idx, err := query.Load(r, *dir, plan.Datasets(r))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if idx.Len() == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no GeoJSON found in %s (run 'airspace sync' first)\n", *dir)
+		os.Exit(1)
+	}
+	if src := dem(); src != nil {
+		defer src.Close()
+		idx.SetElevation(src)
+	}
+
+	report, err := plan.Check(idx, p, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printPlanReport(report)
+	}
+
+	if *exitCode && !report.Summary.OK {
+		os.Exit(2)
+	}
+}
+
+func printPlanReport(r *plan.Report) {
+	fmt.Printf("Flight Plan Check: %s (%s)\n", r.Plan, r.Format)
+	fmt.Println("=========================================")
+	fmt.Printf("Waypoints: %d  Length: %.2f km (%.2f NM)  Buffer: %.0f m\n",
+		r.Waypoints, r.LengthMeters/1000, r.LengthMeters/1852, r.BufferMeters)
+	fmt.Println()
+
+	for _, seg := range r.Segments {
+		status := "✓"
+		if len(seg.Findings) > 0 {
+			status = "✗"
+		}
+		fmt.Printf("%s Segment %d  (%.5f,%.5f) → (%.5f,%.5f)  %.0f m at %.0f ft %s",
+			status, seg.Index, seg.From[1], seg.From[0], seg.To[1], seg.To[0], seg.LengthMeters, seg.Altitude.Feet, seg.Altitude.Ref)
+		if seg.GroundFt != nil {
+			fmt.Printf(", ground up to %.0f ft MSL", *seg.GroundFt)
+		}
+		fmt.Println()
+		for _, f := range seg.Findings {
+			label := f.Name
+			if label == "" {
+				label = f.ID
+			}
+			switch f.Kind {
+			case plan.KindControlled:
+				fmt.Printf("    Class %s airspace: %s (%s – %s)\n", f.Class, label, f.Floor, f.Ceiling)
+			case plan.KindSUA:
+				fmt.Printf("    Special use airspace %s: %s (%s – %s)\n", f.Class, label, f.Floor, f.Ceiling)
+			case plan.KindUASCeiling:
+				fmt.Printf("    UAS facility map ceiling %.0f ft AGL is below planned altitude\n", f.Ceiling.Feet)
+			case plan.KindTFR:
+				fmt.Printf("    Temporary flight restriction: %s (%s – %s)\n", label, f.Floor, f.Ceiling)
+			}
+		}
+	}
+
+	if len(r.Nearby) > 0 {
+		fmt.Println()
+		fmt.Printf("Nearby (within %.0f m):\n", r.BufferMeters)
+		for _, n := range r.Nearby {
+			line := fmt.Sprintf("  [%s] %s", n.Dataset, n.Name)
+			if n.Name == "" {
+				line = fmt.Sprintf("  [%s] %s", n.Dataset, n.ID)
+			}
+			line += fmt.Sprintf("  %.0f m from segment %d", n.DistanceMeters, n.Segment)
+			if n.HeightFt != nil {
+				line += fmt.Sprintf(", %.0f ft AGL", *n.HeightFt)
+			}
+			fmt.Println(line)
+		}
+	}
+
+	fmt.Println()
+	s := r.Summary
+	if s.OK {
+		fmt.Println("✓ No airspace conflicts")
+		return
+	}
+	fmt.Printf("✗ %d of %d segments flagged\n", s.FlaggedLegs, len(r.Segments))
+	if len(s.Controlled) > 0 {
+		fmt.Printf("  Controlled airspace: Class %s (authorization required)\n", strings.Join(s.Controlled, ", "))
+	}
+	if len(s.SUA) > 0 {
+		fmt.Printf("  Special use airspace: %s\n", strings.Join(s.SUA, ", "))
+	}
+	if len(s.TFRs) > 0 {
+		fmt.Printf("  Temporary flight restrictions: %s\n", strings.Join(s.TFRs, ", "))
+	}
+	if s.UASCeilingFt != nil {
+		fmt.Printf("  Lowest UAS facility map ceiling exceeded: %.0f ft AGL\n", *s.UASCeilingFt)
+	}
+}
+
+// ============================================================================
+// Elevation Command
+// ============================================================================
+
+func runElevation() {
+	fs := flag.NewFlagSet("elevation", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Latitude of point lookup")
+	lon := fs.Float64("lon", 0, "Longitude of point lookup")
+	profileFlag := fs.String("profile", "", "Path to profile: flight plan or GeoJSON LineString (.geojson, .gpx, .kml, .plan)")
+	step := fs.Float64("step", 30, "Profile sample spacing in meters")
+	jsonOut := fs.Bool("json", false, "Output JSON instead of text")
+	dem := demFlag(fs)
+	fs.Parse(os.Args[1:])
+
+	src := dem()
+	if src == nil {
+		fmt.Fprintf(os.Stderr, "Error: no DEM (put GeoTIFF or terrarium PMTiles in %s, or pass -dem)\n", airspace.DirDEM)
+		os.Exit(1)
+	}
+	defer src.Close()
+
+	if *profileFlag == "" {
+		meters, err := src.Elevation(*lon, *lat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %.5f,%.5f: %v\n", *lat, *lon, err)
+			os.Exit(1)
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(map[string]any{"point": orb.Point{*lon, *lat}, "elevation_m": meters, "elevation_ft": meters * elevation.FeetPerMeter})
+			return
+		}
+		fmt.Printf("%.5f,%.5f: %.1f m (%.0f ft) MSL\n", *lat, *lon, meters, meters*elevation.FeetPerMeter)
+		return
+	}
+
+	p, err := plan.Load(*profileFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	profile, err := elevation.ExtractProfile(src, p.Path(), *step)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(profile)
+		return
+	}
+
+	fmt.Printf("Elevation Profile: %s\n", p.Name)
+	fmt.Println("=========================================")
+	fmt.Printf("Length: %.2f km  Samples: %d every %.0f m", profile.LengthMeters/1000, len(profile.Points), profile.StepMeters)
+	if profile.Missing > 0 {
+		fmt.Printf("  (%d without data)", profile.Missing)
+	}
+	fmt.Println()
+	if profile.MinMeters == nil {
+		fmt.Println("No elevation data along the path")
+		return
+	}
+	fmt.Printf("Ground: %.0f – %.0f m (%.0f – %.0f ft) MSL\n", *profile.MinMeters, *profile.MaxMeters,
+		*profile.MinMeters*elevation.FeetPerMeter, *profile.MaxMeters*elevation.FeetPerMeter)
+}
+
+// ============================================================================
+// Inspect / Diff Commands
+// ============================================================================
+
+func runInspect() {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	largest := fs.Int("largest", 10, "Number of largest tiles to list")
+	jsonOut := fs.Bool("json", false, "Output JSON instead of text")
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: airspace inspect [-json] [-largest n] <file.pmtiles>")
+		os.Exit(1)
+	}
+	if *largest < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -largest must be 0 or more")
+		os.Exit(1)
+	}
+
+	report, err := inspect.Inspect(fs.Arg(0), *largest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	printInspectReport(report)
+}
+
+func printInspectReport(r *inspect.Report) {
+	h := r.Header
+	fmt.Printf("PMTiles: %s (%.2f MB)\n", r.File, float64(r.SizeBytes)/(1024*1024))
+	fmt.Println("=========================================")
+	fmt.Printf("Spec version: %d  Type: %s  Compression: %s (internal %s)  Clustered: %t\n",
+		h.SpecVersion, h.TileType, h.TileCompression, h.InternalCompression, h.Clustered)
+	fmt.Printf("Zoom: %d-%d  Bounds: %.4f,%.4f,%.4f,%.4f  Center: %.4f,%.4f z%.0f\n",
+		h.MinZoom, h.MaxZoom, h.Bounds[0], h.Bounds[1], h.Bounds[2], h.Bounds[3], h.Center[0], h.Center[1], h.Center[2])
+	fmt.Printf("Tiles: %d addressed, %d entries, %d unique contents\n", h.AddressedTiles, h.TileEntries, h.TileContents)
+	fmt.Printf("Sections: root dir %d B, leaf dirs %d B, metadata %d B, tile data %d B\n",
+		h.RootDirBytes, h.LeafDirBytes, h.MetadataBytes, h.TileDataBytes)
+	fmt.Printf("Verify: %s\n", r.Verify)
+
+	if name, ok := r.Metadata["name"]; ok {
+		fmt.Printf("Metadata name: %v\n", name)
+	}
+
+	fmt.Println()
+	fmt.Println("Zoom  Tiles     Bytes        Features")
+	for _, z := range r.Zooms {
+		fmt.Printf("%4d  %-8d  %-11d  %d\n", z.Zoom, z.Tiles, z.Bytes, z.Features)
+	}
+
+	fmt.Println()
+	fmt.Printf("Tile sizes: p50 %d B, p90 %d B, p99 %d B, max %d B\n",
+		r.TileSizes.P50, r.TileSizes.P90, r.TileSizes.P99, r.TileSizes.Max)
+
+	if len(r.Largest) > 0 {
+		fmt.Println()
+		fmt.Println("Largest tiles:")
+		for _, t := range r.Largest {
+			fmt.Printf("  %d/%d/%d  %d B\n", t.Z, t.X, t.Y, t.Bytes)
+		}
+	}
+
+	if len(r.Layers) > 0 {
+		fmt.Println()
+		fmt.Println("Layers:")
+		for _, l := range r.Layers {
+			fmt.Printf("  %-12s %d features in %d tiles\n", l.Name, l.Features, l.Tiles)
+		}
+	}
+}
+
+func runDiff() {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonOut := fs.Bool("json", false, "Output JSON instead of text")
+	exitCode := fs.Bool("exit-code", false, "Exit with status 1 if the archives differ")
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: airspace diff [-json] [-exit-code] <a.pmtiles> <b.pmtiles>")
+		os.Exit(1)
+	}
+
+	d, err := inspect.Diff(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(d)
+	} else {
+		printDiffReport(d)
+	}
+
+	if *exitCode && !d.Identical() {
+		os.Exit(1)
+	}
+}
+
+func printDiffReport(d *inspect.DiffReport) {
+	fmt.Printf("a: %s\n", d.A)
+	fmt.Printf("b: %s\n", d.B)
+	fmt.Println()
+	fmt.Printf("Tiles: %d added, %d removed, %d changed, %d unchanged\n", d.Added, d.Removed, d.Changed, d.Unchanged)
+	fmt.Println()
+	fmt.Println("Zoom  Tiles a/b      +added  -removed  ~changed  Features a/b      Delta")
+	for _, z := range d.Zooms {
+		fmt.Printf("%4d  %-13s  %-6d  %-8d  %-8d  %-16s  %+d\n",
+			z.Zoom, fmt.Sprintf("%d/%d", z.TilesA, z.TilesB), z.Added, z.Removed, z.Changed,
+			fmt.Sprintf("%d/%d", z.FeaturesA, z.FeaturesB), z.FeatureDelta)
+	}
+
+	fmt.Println()
+	if d.Identical() {
+		fmt.Println("✓ Archives are identical")
+	} else {
+		fmt.Println("✗ Archives differ")
+	}
+}
+
+// ============================================================================
+// Serve Command
+// ============================================================================
+
+func runServe() {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8091", "Listen address")
+	dir := fs.String("dir", "", "Directory containing PMTiles (default: region tiles dir)")
+	manifestFlag := fs.String("manifest", "", "Regional manifest (default: data/airspace/manifest_<region>.json; 'none' serves every .pmtiles in -dir)")
+	cors := fs.String("cors", "*", "Access-Control-Allow-Origin value")
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+	if *dir == "" {
+		*dir = r.PMTilesDir()
+	}
+
+	var manifest *airspace.RegionManifest
+	if *manifestFlag != "none" {
+		path := *manifestFlag
+		if path == "" {
+			path = filepath.Join(airspace.DirData, r.ManifestFile())
+		}
+		m, err := airspace.LoadRegionManifest(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: loading manifest: %v (run 'airspace manifest' or use -manifest none)\n", err)
+			os.Exit(1)
+		}
+		manifest = m
+	}
+
+	srv, err := serve.New(*dir, manifest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer srv.Close()
+	srv.CORSOrigin = *cors
+
+	fmt.Printf("Serving %s on %s\n", *dir, *addr)
+	for _, name := range srv.Layers() {
+		fmt.Printf("  %-10s http://localhost%s/%s.json\n", name, *addr, name)
+	}
+	fmt.Printf("  raw PMTiles: http://localhost%s/pmtiles/<file>\n", *addr)
+	if manifest != nil {
+		fmt.Printf("  style:       http://localhost%s/style.json\n", *addr)
+	}
+	if err := http.ListenAndServe(*addr, srv.Handler()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// ============================================================================
+// Daemon Command
+// ============================================================================
+
+// webhookFlags collects repeated -webhook values.
+type webhookFlags []daemon.Webhook
+
+func (w *webhookFlags) String() string { return fmt.Sprint(len(*w)) }
+
+func (w *webhookFlags) Set(s string) error {
+	hook, err := daemon.ParseWebhook(s)
+	if err != nil {
+		return err
+	}
+	*w = append(*w, hook)
+	return nil
+}
+
+func runDaemon() {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8092", "Listen address for /health, /status and POST /run")
+	token := fs.String("token", os.Getenv("AIRSPACE_DAEMON_TOKEN"), "Bearer token POST /run requires (default: AIRSPACE_DAEMON_TOKEN; set it before listening beyond localhost)")
+	cycle := fs.Int("cycle", airspace.AIRACCycleDays, "Run on every AIRAC date (28) or chart dates only (56)")
+	offset := fs.Duration("offset", time.Hour, "Delay after the 0901Z effective time")
+	interval := fs.Duration("interval", 24*time.Hour, "Also run this long after each run (0 = cycle dates only)")
+	runNow := fs.Bool("run-now", true, "Run once at startup")
+	upload := fs.Bool("upload", false, "Publish to R2 when datasets change (R2 credentials from env)")
+	notifyUnchanged := fs.Bool("notify-unchanged", false, "Notify webhooks after runs without changes too")
+	tilerFlag := fs.String("tiler", "auto", "Tiler: auto, tippecanoe, gotiler")
+	combined := fs.Bool("combined", false, "Also build the combined multi-layer PMTiles")
+	repair := fs.Bool("repair", false, "Repair fixable GeoJSON issues before tiling")
+	maxErrorRate := fs.Float64("max-error-rate", airspace.DefaultMaxErrorRate, "Invalid feature fraction per dataset that fails a run (0 = any, <0 = never)")
+	var webhooks webhookFlags
+	fs.Var(&webhooks, "webhook", "Webhook `[slack=|discord=|json=]url`, repeatable (also AIRSPACE_WEBHOOKS, comma-separated)")
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+
+	if *cycle != airspace.AIRACCycleDays && *cycle != airspace.ChartCycleDays {
+		fmt.Fprintf(os.Stderr, "Error: -cycle must be %d or %d\n", airspace.AIRACCycleDays, airspace.ChartCycleDays)
+		os.Exit(1)
+	}
+	for _, s := range strings.Split(os.Getenv("AIRSPACE_WEBHOOKS"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if err := webhooks.Set(s); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: AIRSPACE_WEBHOOKS: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	activeTiler, err := airspace.SelectTiler(*tilerFlag, tiler.New(), gotiler.New())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg := daemon.Config{
+		Region:   r,
+		Tiler:    activeTiler,
+		Schedule: daemon.Schedule{CycleDays: *cycle, Offset: *offset, Interval: *interval},
+		Pipeline: airspace.PipelineOptions{
+			TilerName:    *tilerFlag,
+			Combined:     *combined,
+			Repair:       *repair,
+			MaxErrorRate: *maxErrorRate,
+		},
+		Webhooks:        webhooks,
+		NotifyUnchanged: *notifyUnchanged,
+		RunToken:        *token,
+	}
+	if *upload {
+		client, err := r2.FromEnv(airspace.R2Bucket)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Publisher = daemon.R2Publisher{Client: client}
+	}
+	d := daemon.New(cfg)
+
+	cur := airspace.AIRACAt(time.Now().UTC())
+	fmt.Printf("Region: %s (%s)\n", r.Name, r.Key)
+	fmt.Printf("Tiler: %s\n", activeTiler.Name())
+	fmt.Printf("AIRAC: %s (effective %s), next %s\n", cur.Ident, cur.Effective.Format("2006-01-02"), cur.Next().Effective.Format("2006-01-02"))
+	fmt.Printf("Upload: %t, webhooks: %d\n", *upload, len(webhooks))
+	fmt.Printf("Status: http://%s/status (POST /run token: %t)\n\n", *addr, *token != "")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: d.Handler()}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	d.Run(ctx, *runNow)
+	srv.Shutdown(context.Background())
+}
+
+// ============================================================================
+// Bundle Command
+// ============================================================================
+
+func runBundle() {
+	fs := flag.NewFlagSet("bundle", flag.ExitOnError)
+	bboxFlag := fs.String("bbox", "", "Area as west,south,east,north in degrees (required)")
+	zoom := fs.Int("zoom", 12, "Deepest zoom level to include")
+	layersFlag := fs.String("layers", "", "Comma-separated manifest layers (default: all)")
+	version := fs.String("version", "", "Bundle version (default: UTC timestamp)")
+	out := fs.String("o", "", "Output archive (default: airspace-<region>-<version>.zip)")
+	verify := fs.String("verify", "", "Check a bundle's checksums instead of building one")
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+
+	if *verify != "" {
+		index, err := bundle.Verify(*verify)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", *verify, err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ %s: %s %s, %d files, AIRAC %s, bbox %v\n", *verify, index.Region, index.Version, len(index.Files), index.AIRACCycle, index.BBox)
+		return
+	}
+
+	bbox, err := parseBBox(*bboxFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	r := region()
+	opts := bundle.DefaultOptions(r, bbox, *zoom)
+	opts.Version = *version
+	if *layersFlag != "" {
+		opts.Layers = strings.Split(*layersFlag, ",")
+	}
+	if opts.Version == "" {
+		opts.Version = time.Now().UTC().Format("20060102T150405Z")
+	}
+	if *out == "" {
+		*out = bundle.FileName(r.Key, opts.Version)
+	}
+
+	fmt.Println("Building Offline Airspace Bundle")
+	fmt.Println("================================")
+	fmt.Printf("Region: %s (%s), bbox %s, zoom 0-%d\n\n", r.Name, r.Key, *bboxFlag, *zoom)
+
+	index, err := bundle.Build(*out, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, key := range sortedKeys(index.Layers) {
+		l := index.Layers[key]
+		if l.Skipped != "" {
+			fmt.Printf("  - %-10s skipped: %s\n", key, l.Skipped)
+			continue
+		}
+		fmt.Printf("  ✓ %-10s %6d tiles %8d features\n", key, l.Tiles, l.Features)
+	}
+	info, _ := os.Stat(*out)
+	fmt.Printf("\n✓ %s (%.1f MB, %d files, AIRAC %s)\n", *out, float64(info.Size())/(1024*1024), len(index.Files), index.AIRACCycle)
+}
+
+// parseBBox parses "west,south,east,north".
+func parseBBox(s string) (orb.Bound, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return orb.Bound{}, fmt.Errorf("-bbox must be west,south,east,north (got %q)", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return orb.Bound{}, fmt.Errorf("-bbox: %w", err)
+		}
+		v[i] = f
+	}
+	return orb.Bound{Min: orb.Point{v[0], v[1]}, Max: orb.Point{v[2], v[3]}}, nil
+}
+
+// ============================================================================
+// CI Helpers
+// ============================================================================
+
+func runCheck() {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+
+	resultPath := filepath.Join(r.DataDir(), airspace.FileSyncResult)
+	result := airspace.LoadSyncResult(resultPath)
+
+	fmt.Printf("has_changes=%t\n", result.HasChanges)
+	fmt.Printf("updated_count=%d\n", result.Updated)
+}
+
+func runSummary() {
+	fs := flag.NewFlagSet("summary", flag.ExitOnError)
+	region := regionFlags(fs)
+	fs.Parse(os.Args[1:])
+	r := region()
+
+	var out *os.File
+	if summaryPath := os.Getenv("GITHUB_STEP_SUMMARY"); summaryPath != "" {
+		f, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			out = os.Stdout
+		} else {
+			defer f.Close()
+			out = f
+		}
+	} else {
+		out = os.Stdout
+	}
+
+	fmt.Fprintln(out, "## Airspace Sync Complete")
+	fmt.Fprintln(out)
+
+	// Sync result
+	resultPath := filepath.Join(r.DataDir(), airspace.FileSyncResult)
+	if data, err := os.ReadFile(resultPath); err == nil {
+		fmt.Fprintln(out, "### Sync Result")
+		fmt.Fprintln(out, "```json")
+		fmt.Fprintln(out, string(data))
+		fmt.Fprintln(out, "```")
+		fmt.Fprintln(out)
+	}
+
+	// Feature changes
+	result := airspace.LoadSyncResult(resultPath)
+	var changed []string
+	for _, key := range sortedKeys(result.Datasets) {
+		if c := result.Datasets[key].Changes; c != nil && c.HasChanges() {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) > 0 {
+		fmt.Fprintln(out, "### Feature Changes")
+		fmt.Fprintln(out, "| Dataset | Added | Removed | Modified |")
+		fmt.Fprintln(out, "|---------|-------|---------|----------|")
+		for _, key := range changed {
+			c := result.Datasets[key].Changes
+			fmt.Fprintf(out, "| %s | %d | %d | %d |\n", key, c.Added, c.Removed, c.Modified)
+		}
+		fmt.Fprintln(out)
+
+		for _, key := range changed {
+			c := result.Datasets[key].Changes
+			fmt.Fprintf(out, "<details><summary>%s changes</summary>\n\n", key)
+			fmt.Fprintln(out, "| Change | ID | Name |")
+			fmt.Fprintln(out, "|--------|----|------|")
+			for _, ch := range c.Changes {
+				fmt.Fprintf(out, "| %s | %s | %s |\n", ch.Change, ch.ID, ch.Name)
+			}
+			if c.Truncated {
+				fmt.Fprintf(out, "\n_Only the first %d changes are listed; %s not all shown._\n", len(c.Changes), strings.Join(c.TruncatedKinds, ", "))
+			}
+			fmt.Fprintln(out, "\n</details>")
+			fmt.Fprintln(out)
+		}
 	}
 
 	// PMTiles files
@@ -432,7 +1741,7 @@ func runSummary() {
 	fmt.Fprintln(out, "| File | Size |")
 	fmt.Fprintln(out, "|------|------|")
 
-	files, _ := filepath.Glob(filepath.Join(airspace.DirPMTiles, "*.pmtiles"))
+	files, _ := filepath.Glob(filepath.Join(r.PMTilesDir(), "*.pmtiles"))
 	if len(files) == 0 {
 		fmt.Fprintln(out, "| _No PMTiles found_ | - |")
 	} else {
@@ -12,6 +12,27 @@
 //	batch    Translate multiple files
 //	status   Show translation quota/usage
 //
+// Translated paragraphs are kept in a translation memory
+// (translations/memory.json, see -memory) shared with "translate tm";
+// only new or changed paragraphs are sent to the provider.
+//
+// Bodies are parsed as CommonMark (goldmark, as Hugo does): only text,
+// alt text and link titles are translated. Code, HTML, link destinations
+// and shortcodes are kept byte for byte, and a file whose translation
+// loses any of them fails.
+//
+// Front matter may be YAML (---), TOML (+++) or JSON. Values at the
+// -front-matter key paths (e.g. title, banner.button.label,
+// features.bulletpoints) are translated where they are written; comments,
+// key order and everything else are left as they are.
+//
+// -provider may list several providers, tried in order (e.g. deepl,claude).
+// Each is rate limited (-rate); overloaded or rate-limited requests are
+// retried with backoff, and once a provider's quota or credit runs out the
+// next one takes over. Characters, tokens and estimated cost of every run
+// are recorded in translations/ledger.json (see -ledger) and shown by
+// "status"; -budget stops a run before it would cost more.
+//
 // Examples:
 //
 //	# Translate a single file to Vietnamese using DeepL
@@ -20,6 +41,9 @@
 //	# Translate using Claude (no per-character cost if you have subscription)
 //	autotranslate --provider=claude missing vi
 //
+//	# DeepL first, Claude once the DeepL quota is used up, at most $5
+//	autotranslate --provider=deepl,claude --budget=5 missing vi
+//
 //	# Dry-run to see what would be translated
 //	autotranslate missing vi --dry-run
 package main
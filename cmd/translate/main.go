@@ -10,6 +10,7 @@
 //	translate content missing         Show files missing in target languages
 //	translate content orphans         Show target files with no English source
 //	translate content stale           Show potentially outdated translations
+//	translate content validate        Check translations against the glossary
 //	translate content clean           Delete orphaned files (prompts unless -force)
 //
 //	translate menu check              Validate menu files for broken links and sync issues
@@ -21,11 +22,19 @@
 //	translate lang remove <code>      Remove a language (prompts unless -force)
 //	translate lang init <code>        Initialize content directory for configured language
 //
+//	translate tm status               Show translation memory coverage per language
+//	translate tm approve <code> [file...]   Approve existing translations in the memory
+//	translate tm prune                Remove unapproved segments no longer in English
+//
+//	translate export -lang <code> [-format xliff|po] [-o file] [-all]   Write segments for human translators
+//	translate import <file>           Apply reviewed translations from an XLIFF or PO file
+//
 // Flags:
 //
 //	-github-issue    Output markdown for GitHub Issue (exit 1 if action needed)
 //	-force           Skip confirmation prompts (for CI)
 //	-version         Print version and exit
+//	-front-matter    Front matter key paths to translate, comma-separated
 package main
 
 import (
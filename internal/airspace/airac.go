@@ -0,0 +1,57 @@
+package airspace
+
+import (
+	"fmt"
+	"time"
+)
+
+// AIRAC cycles are 28 days long. The FAA publishes its NASR data and the
+// ADDS layers on AIRAC effective dates at 0901Z; its 56-day chart cycle
+// falls on every other AIRAC date.
+const (
+	AIRACCycleDays = 28
+	ChartCycleDays = 56
+)
+
+// airacEpoch is AIRAC 2001, which is also a 56-day chart date.
+var airacEpoch = time.Date(2020, 1, 2, 9, 1, 0, 0, time.UTC)
+
+const airacPeriod = AIRACCycleDays * 24 * time.Hour
+
+// AIRACCycle is one AIRAC cycle.
+type AIRACCycle struct {
+	Ident     string    `json:"ident"`     // YYNN, e.g. 2601 for the first cycle of 2026
+	Effective time.Time `json:"effective"` // 0901Z on the effective date
+	Chart     bool      `json:"chart"`     // Also a 56-day chart cycle date
+}
+
+// AIRACAt returns the cycle in effect at t.
+func AIRACAt(t time.Time) AIRACCycle {
+	n := t.Sub(airacEpoch) / airacPeriod
+	if t.Before(airacEpoch.Add(n * airacPeriod)) {
+		n-- // Before the epoch, division truncates towards zero
+	}
+	return airacCycle(int(n))
+}
+
+// Next returns the following cycle.
+func (c AIRACCycle) Next() AIRACCycle {
+	return AIRACAt(c.Effective.Add(airacPeriod))
+}
+
+func airacCycle(n int) AIRACCycle {
+	effective := airacEpoch.Add(time.Duration(n) * airacPeriod)
+
+	// NN counts the cycles that became effective this year
+	jan1 := time.Date(effective.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	first := jan1.Sub(airacEpoch) / airacPeriod
+	if airacEpoch.Add(first * airacPeriod).Before(jan1) {
+		first++
+	}
+
+	return AIRACCycle{
+		Ident:     fmt.Sprintf("%02d%02d", effective.Year()%100, n-int(first)+1),
+		Effective: effective,
+		Chart:     n%2 == 0,
+	}
+}
@@ -0,0 +1,347 @@
+// Package bundle exports a sub-region of a region's airspace as one
+// offline archive for field kits without connectivity.
+//
+// A bundle is a zip holding a single directory, airspace-<region>-<version>:
+//
+//	bundle.json                 index: area, zoom, AIRAC cycle, layers, file checksums
+//	SHA256SUMS                  checksums of every other file (sha256sum -c)
+//	manifest_<region>.json      regional manifest for the bundle's area
+//	style_<region>.json         MapLibre style; sources are pmtiles://tiles/<file>
+//	tiles/<file>.pmtiles        tile subset by bbox and max zoom (gotiler.Extract)
+//	geojson/<file>.geojson      features clipped to the bbox, for "airspace query -dir"
+//
+// Archives are stored uncompressed in the zip, so a reader can range-read
+// them in place; the JSON and GeoJSON files are deflated. Layers without
+// tiles in the area are left out and listed in bundle.json with the reason.
+package bundle
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/gotiler"
+)
+
+// Format is the bundle layout version, recorded in bundle.json.
+const Format = 1
+
+// Files at the root of a bundle.
+const (
+	FileIndex     = "bundle.json"
+	FileChecksums = "SHA256SUMS"
+)
+
+// Options configures Build.
+type Options struct {
+	Region  airspace.Region
+	BBox    orb.Bound
+	MaxZoom int      // Deepest zoom copied (capped at each archive's max zoom)
+	Layers  []string // Manifest layer keys (nil = every layer)
+	Version string   // Bundle version (default UTC timestamp)
+
+	TilesDir     string // Local PMTiles (default region tiles dir)
+	GeoJSONDir   string // Local GeoJSON (default region GeoJSON dir)
+	ManifestPath string // Local regional manifest (default data/airspace/manifest_<region>.json)
+}
+
+// Index is a bundle's bundle.json.
+type Index struct {
+	Format        int              `json:"format"`
+	Region        string           `json:"region"`
+	Name          string           `json:"name"`
+	Version       string           `json:"version"`
+	Created       time.Time        `json:"created"`
+	AIRACCycle    string           `json:"airac_cycle"`
+	BBox          []float64        `json:"bbox"` // [west, south, east, north]
+	MaxZoom       int              `json:"max_zoom"`
+	SourceUpdated string           `json:"source_updated"` // The regional manifest's updated time
+	ManifestFile  string           `json:"manifest_file"`
+	StyleFile     string           `json:"style_file"`
+	Layers        map[string]Layer `json:"layers"`
+	Files         []File           `json:"files"` // Every file but bundle.json and SHA256SUMS
+}
+
+// Layer is one manifest layer's contents in the bundle.
+type Layer struct {
+	Tiles    int    `json:"tiles"`
+	Features int    `json:"features"`
+	Skipped  string `json:"skipped,omitempty"` // Why the layer is not in the bundle
+}
+
+// File is a checksummed file, by path relative to the bundle root.
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DefaultOptions returns the bundle options for a region's local data.
+func DefaultOptions(region airspace.Region, bbox orb.Bound, maxZoom int) Options {
+	return Options{
+		Region:       region,
+		BBox:         bbox,
+		MaxZoom:      maxZoom,
+		TilesDir:     region.PMTilesDir(),
+		GeoJSONDir:   region.GeoJSONDir(),
+		ManifestPath: filepath.Join(airspace.DirData, region.ManifestFile()),
+	}
+}
+
+// FileName is the default archive name of a bundle version.
+func FileName(region, version string) string {
+	return rootDir(region, version) + ".zip"
+}
+
+func rootDir(region, version string) string {
+	return fmt.Sprintf("airspace-%s-%s", region, version)
+}
+
+// Build writes the bundle archive to outPath and returns its index.
+func Build(outPath string, opts Options) (*Index, error) {
+	b := opts.BBox
+	if b.Min[0] >= b.Max[0] || b.Min[1] >= b.Max[1] || b.Min[0] < -180 || b.Max[0] > 180 || b.Min[1] < -90 || b.Max[1] > 90 {
+		return nil, fmt.Errorf("invalid bbox %v (want west,south,east,north in degrees)", []float64{b.Min[0], b.Min[1], b.Max[0], b.Max[1]})
+	}
+	if opts.MaxZoom < 0 {
+		return nil, fmt.Errorf("invalid max zoom %d", opts.MaxZoom)
+	}
+
+	region := opts.Region
+	manifest, err := airspace.LoadRegionManifest(opts.ManifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("manifest not found: %s (run 'airspace manifest' first): %w", opts.ManifestPath, err)
+	}
+
+	keys := opts.Layers
+	if keys == nil {
+		for key := range manifest.Layers {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := manifest.Layers[key]; !ok {
+			return nil, fmt.Errorf("layer %q is not in %s", key, opts.ManifestPath)
+		}
+	}
+
+	// Manifest layer keys to the datasets holding their GeoJSON
+	geoJSON := make(map[string]string)
+	for _, key := range region.ManifestDatasets() {
+		if ds := region.Datasets[key]; ds.Manifest != nil {
+			geoJSON[ds.Manifest.Key] = ds.GeoJSON
+		}
+	}
+
+	version := opts.Version
+	if version == "" {
+		version = time.Now().UTC().Format("20060102T150405Z")
+	}
+	created := time.Now().UTC()
+
+	stage, err := os.MkdirTemp("", "airspace-bundle-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stage)
+	for _, dir := range []string{"tiles", "geojson"} {
+		if err := os.Mkdir(filepath.Join(stage, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	index := &Index{
+		Format:        Format,
+		Region:        region.Key,
+		Name:          region.Name,
+		Version:       version,
+		Created:       created,
+		AIRACCycle:    airspace.AIRACAt(created).Ident,
+		BBox:          []float64{b.Min[0], b.Min[1], b.Max[0], b.Max[1]},
+		MaxZoom:       opts.MaxZoom,
+		SourceUpdated: manifest.Updated,
+		Layers:        make(map[string]Layer),
+		ManifestFile:  region.ManifestFile(),
+		StyleFile:     region.StyleFile(),
+	}
+
+	clipped := *manifest
+	clipped.BBox = index.BBox
+	clipped.Updated = created.Format(time.RFC3339)
+	clipped.Combined = ""
+	clipped.Layers = make(map[string]airspace.ManifestLayer)
+
+	for _, key := range keys {
+		layer := manifest.Layers[key]
+		tilesOut := filepath.Join(stage, "tiles", layer.File)
+		extracted, err := gotiler.Extract(filepath.Join(opts.TilesDir, layer.File), tilesOut, b, opts.MaxZoom)
+		switch {
+		case errors.Is(err, gotiler.ErrNoTiles):
+			index.Layers[key] = Layer{Skipped: "no tiles in bbox"}
+			continue
+		case errors.Is(err, os.ErrNotExist):
+			index.Layers[key] = Layer{Skipped: "tiles not built"}
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("extracting %s: %w", key, err)
+		}
+
+		entry := Layer{Tiles: extracted.Tiles}
+		if name := geoJSON[key]; name != "" {
+			n, err := clipGeoJSON(filepath.Join(opts.GeoJSONDir, name), filepath.Join(stage, "geojson", name), b)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("clipping %s: %w", name, err)
+			}
+			entry.Features = n
+		}
+		index.Layers[key] = entry
+
+		if info, err := os.Stat(tilesOut); err == nil {
+			layer.SizeMB = float64(info.Size()) / (1024 * 1024)
+		}
+		layer.Features = entry.Features
+		if len(layer.ZoomRange) == 2 {
+			layer.ZoomRange = []int{min(layer.ZoomRange[0], extracted.MaxZoom), min(layer.ZoomRange[1], extracted.MaxZoom)}
+		}
+		clipped.Layers[key] = layer
+	}
+	if len(clipped.Layers) == 0 {
+		var reasons []string
+		for _, key := range keys {
+			reasons = append(reasons, key+": "+index.Layers[key].Skipped)
+		}
+		return nil, fmt.Errorf("no layer has tiles in bbox %v (%s)", index.BBox, strings.Join(reasons, ", "))
+	}
+
+	if err := airspace.ValidateManifest(clipped); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest:\n%w", err)
+	}
+	style := airspace.MapLibreStyle(clipped, airspace.StyleOptions{TilesURL: "tiles"})
+	if err := writeJSON(filepath.Join(stage, index.ManifestFile), clipped); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(filepath.Join(stage, index.StyleFile), style); err != nil {
+		return nil, err
+	}
+
+	files, err := checksumTree(stage)
+	if err != nil {
+		return nil, err
+	}
+	index.Files = files
+	if err := writeJSON(filepath.Join(stage, FileIndex), index); err != nil {
+		return nil, err
+	}
+	indexFile, err := checksumFile(stage, FileIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	var sums strings.Builder
+	for _, f := range append(files, indexFile) {
+		fmt.Fprintf(&sums, "%s  %s\n", f.SHA256, f.Path)
+	}
+	if err := os.WriteFile(filepath.Join(stage, FileChecksums), []byte(sums.String()), 0644); err != nil {
+		return nil, err
+	}
+
+	paths := []string{FileIndex, FileChecksums}
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	if err := writeZip(outPath, stage, rootDir(region.Key, version), paths, created); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// checksumTree checksums every file under dir, sorted by path.
+func checksumTree(dir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f, err := checksumFile(dir, filepath.ToSlash(rel))
+		files = append(files, f)
+		return err
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
+
+func checksumFile(dir, rel string) (File, error) {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(rel)))
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	return File{Path: rel, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, err
+}
+
+// writeZip packs the staged files under root in the archive.
+func writeZip(outPath, stage, root string, paths []string, modified time.Time) error {
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	bw := bufio.NewWriterSize(out, 1<<20)
+	zw := zip.NewWriter(bw)
+	for _, rel := range paths {
+		method := zip.Deflate
+		if strings.HasSuffix(rel, ".pmtiles") {
+			method = zip.Store // Tiles are already gzipped
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: path.Join(root, rel), Method: method, Modified: modified})
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(filepath.Join(stage, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("adding %s: %w", rel, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,66 @@
+package bundle
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/clip"
+	"github.com/paulmach/orb/geojson"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+// clipGeoJSON streams the features of inPath that intersect bound to
+// outPath, with geometries clipped to bound, and returns how many were
+// written. Properties are kept as they are, so the clipped file answers
+// queries inside bound like the original.
+func clipGeoJSON(inPath, outPath string, bound orb.Bound) (int, error) {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriterSize(out, 1<<20)
+	w.WriteString(`{"type":"FeatureCollection","features":[`)
+	n := 0
+	err = airspace.DecodeFeatures(in, func(raw json.RawMessage) error {
+		f, err := geojson.UnmarshalFeature(raw)
+		if err != nil {
+			return fmt.Errorf("parsing feature: %w", err)
+		}
+		if f.Geometry == nil || !f.Geometry.Bound().Intersects(bound) {
+			return nil
+		}
+		if f.Geometry = clip.Geometry(bound, f.Geometry); f.Geometry == nil {
+			return nil
+		}
+		data, err := f.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			w.WriteByte(',')
+		}
+		n++
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	w.WriteString("]}\n")
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return n, out.Close()
+}
@@ -0,0 +1,111 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Verify checks a bundle archive against its SHA256SUMS: every listed file
+// must be present with its checksum, and nothing else may be in the bundle.
+// It returns the bundle's index.
+func Verify(archivePath string) (*Index, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File)
+	root := ""
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		dir, rel, ok := strings.Cut(f.Name, "/")
+		if !ok || (root != "" && dir != root) {
+			return nil, fmt.Errorf("%s: not in a single bundle directory", f.Name)
+		}
+		root = dir
+		files[rel] = f
+	}
+
+	sums, ok := files[FileChecksums]
+	if !ok {
+		return nil, fmt.Errorf("no %s in bundle", FileChecksums)
+	}
+	data, err := readZipFile(sums)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := map[string]bool{FileChecksums: true}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		want, rel, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			return nil, fmt.Errorf("%s: malformed line %q", FileChecksums, scanner.Text())
+		}
+		f, ok := files[rel]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing", rel)
+		}
+		got, err := zipFileSHA256(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+		if got != want {
+			return nil, fmt.Errorf("%s: checksum mismatch", rel)
+		}
+		checked[rel] = true
+	}
+	for rel := range files {
+		if !checked[rel] {
+			return nil, fmt.Errorf("%s: not in %s", path.Join(root, rel), FileChecksums)
+		}
+	}
+
+	f, ok := files[FileIndex]
+	if !ok {
+		return nil, fmt.Errorf("no %s in bundle", FileIndex)
+	}
+	if data, err = readZipFile(f); err != nil {
+		return nil, err
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", FileIndex, err)
+	}
+	if index.Format > Format {
+		return nil, fmt.Errorf("bundle format %d is newer than this tool (%d)", index.Format, Format)
+	}
+	return &index, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func zipFileSHA256(f *zip.File) (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
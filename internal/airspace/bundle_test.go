@@ -0,0 +1,124 @@
+package airspace_test
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/bundle"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/gotiler"
+)
+
+func TestBundle(t *testing.T) {
+	region, err := airspace.GetRegion("usa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	geoDir := filepath.Join("testdata", "query")
+	tilesDir := t.TempDir()
+
+	manifest := airspace.RegionManifest{
+		Region: region.Key, Name: region.Name, Version: 1, Updated: "2026-10-17T00:00:00Z",
+		BBox: region.BBox, Layers: map[string]airspace.ManifestLayer{},
+		Source: airspace.ManifestSource{Authority: "FAA", UpdateCycle: "56 days"},
+	}
+	for _, key := range []string{"boundary", "airports", "sua"} {
+		ds := region.Datasets[key]
+		d := ds.Manifest
+		manifest.Layers[key] = airspace.ManifestLayer{
+			Name: d.Name, File: ds.PMTiles, PMTilesLayer: ds.Layer, GeomType: d.GeomType,
+			ZoomRange: d.ZoomRange, DefaultVisible: d.DefaultVisible, RenderRules: d.RenderRules,
+		}
+		if key == "sua" {
+			continue // Not built: skipped
+		}
+		cfg := airspace.TileConfig{MinZoom: 0, MaxZoom: 8, Layer: ds.Layer}
+		if err := gotiler.New().Tile(filepath.Join(geoDir, ds.GeoJSON), filepath.Join(tilesDir, ds.PMTiles), cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifestPath := filepath.Join(tilesDir, "manifest_usa.json")
+	data, _ := json.Marshal(manifest)
+	os.WriteFile(manifestPath, data, 0644)
+
+	opts := bundle.Options{
+		Region:       region,
+		BBox:         orb.Bound{Min: orb.Point{-122.6, 37.5}, Max: orb.Point{-122.2, 37.8}},
+		MaxZoom:      6,
+		Version:      "v1",
+		TilesDir:     tilesDir,
+		GeoJSONDir:   geoDir,
+		ManifestPath: manifestPath,
+	}
+	out := filepath.Join(t.TempDir(), bundle.FileName("usa", "v1"))
+	index, err := bundle.Build(out, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := index.Layers["boundary"]; l.Tiles != 7 || l.Features == 0 {
+		t.Errorf("boundary = %+v, want one tile per zoom 0-6", l)
+	}
+	if l := index.Layers["airports"]; l.Features != 1 {
+		t.Errorf("airports = %+v", l)
+	}
+	if l := index.Layers["sua"]; l.Skipped == "" {
+		t.Errorf("sua = %+v, want skipped", l)
+	}
+
+	verified, err := bundle.Verify(out)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if verified.Version != "v1" || len(verified.Files) != len(index.Files) {
+		t.Errorf("verified index = %+v", verified)
+	}
+
+	// A modified file fails verification
+	tampered := filepath.Join(t.TempDir(), "tampered.zip")
+	rewriteZip(t, out, tampered, "geojson/faa_airports.geojson")
+	if _, err := bundle.Verify(tampered); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("tampered bundle: %v", err)
+	}
+
+	// No layer in the area
+	opts.BBox = orb.Bound{Min: orb.Point{10, 10}, Max: orb.Point{11, 11}}
+	if _, err := bundle.Build(out, opts); err == nil {
+		t.Error("bundle of an empty area should fail")
+	}
+}
+
+// rewriteZip copies a zip, appending a byte to the entry ending in name.
+func rewriteZip(t *testing.T, src, dst, name string) {
+	t.Helper()
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	f, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, zf := range zr.File {
+		r, _ := zf.Open()
+		data, _ := io.ReadAll(r)
+		r.Close()
+		if strings.HasSuffix(zf.Name, name) {
+			data = append(data, ' ')
+		}
+		w, _ := zw.Create(zf.Name)
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
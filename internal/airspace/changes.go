@@ -0,0 +1,191 @@
+package airspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Feature change kinds.
+const (
+	ChangeAdded      = "added"
+	ChangeRemoved    = "removed"
+	ChangeGeometry   = "geometry"
+	ChangeAttributes = "attributes"
+	ChangeBoth       = "geometry+attributes"
+)
+
+// FeatureChanges is the feature-level diff of one dataset between two syncs.
+// Counts are complete; Changes lists at most MaxFeatureChanges entries,
+// removed features first, then modified, then added, so what disappeared
+// is the last to be cut.
+type FeatureChanges struct {
+	Added          int             `json:"added"`
+	Removed        int             `json:"removed"`
+	Modified       int             `json:"modified"`
+	Unchanged      int             `json:"unchanged"`
+	Changes        []FeatureChange `json:"changes,omitempty"`
+	Truncated      bool            `json:"truncated,omitempty"`       // More changes than listed
+	TruncatedKinds []string        `json:"truncated_kinds,omitempty"` // Counts not fully listed: removed, modified, added
+}
+
+// FeatureChange identifies one added, removed or modified feature.
+type FeatureChange struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Change string `json:"change"` // added, removed, geometry, attributes, geometry+attributes
+}
+
+// HasChanges reports whether any feature was added, removed or modified.
+func (c *FeatureChanges) HasChanges() bool {
+	return c.Added+c.Removed+c.Modified > 0
+}
+
+// featureFingerprint is what we keep of a feature to detect changes
+// without holding the whole dataset in memory.
+type featureFingerprint struct {
+	name     string
+	geometry string // hash of the canonical geometry JSON
+	props    string // hash of the canonical properties JSON
+}
+
+// fingerprintFeatures reads a GeoJSON file and returns a fingerprint per
+// feature, keyed on its FAA identifier (see FeatureIDKeys). Features without
+// one are keyed on their content, not their row number; repeated
+// identifiers get a "#n" suffix so each feature keeps its own key.
+func fingerprintFeatures(path string) (map[string]featureFingerprint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prints := make(map[string]featureFingerprint)
+	seen := make(map[string]int)
+
+	err = DecodeFeatures(f, func(raw json.RawMessage) error {
+		var feature struct {
+			Geometry   any            `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		}
+		if err := json.Unmarshal(raw, &feature); err != nil {
+			return fmt.Errorf("parsing feature %d: %w", len(prints), err)
+		}
+
+		id := firstProp(feature.Properties, FeatureIDKeys)
+		name := FeatureName(feature.Properties)
+
+		// OBJECTID is a row number in the FAA's ArcGIS layer and is
+		// renumbered on re-export, so it is not an attribute change
+		for _, k := range rowIDKeys {
+			delete(feature.Properties, k)
+		}
+
+		fp := featureFingerprint{
+			name:     name,
+			geometry: canonicalHash(feature.Geometry),
+			props:    canonicalHash(feature.Properties),
+		}
+
+		if id == "" {
+			id = "sha:" + fp.geometry + fp.props
+		}
+		if n := seen[id]; n > 0 {
+			seen[id]++
+			id = fmt.Sprintf("%s#%d", id, n+1)
+		} else {
+			seen[id] = 1
+		}
+
+		prints[id] = fp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prints, nil
+}
+
+// diffFeatures compares fingerprints from the previous and current sync.
+func diffFeatures(prev, curr map[string]featureFingerprint) *FeatureChanges {
+	c := &FeatureChanges{}
+
+	for id, fp := range curr {
+		old, ok := prev[id]
+		switch {
+		case !ok:
+			c.Added++
+			c.Changes = append(c.Changes, FeatureChange{ID: id, Name: fp.name, Change: ChangeAdded})
+		case old.geometry != fp.geometry && old.props != fp.props:
+			c.Modified++
+			c.Changes = append(c.Changes, FeatureChange{ID: id, Name: fp.name, Change: ChangeBoth})
+		case old.geometry != fp.geometry:
+			c.Modified++
+			c.Changes = append(c.Changes, FeatureChange{ID: id, Name: fp.name, Change: ChangeGeometry})
+		case old.props != fp.props:
+			c.Modified++
+			c.Changes = append(c.Changes, FeatureChange{ID: id, Name: fp.name, Change: ChangeAttributes})
+		default:
+			c.Unchanged++
+		}
+	}
+
+	for id, fp := range prev {
+		if _, ok := curr[id]; !ok {
+			c.Removed++
+			c.Changes = append(c.Changes, FeatureChange{ID: id, Name: fp.name, Change: ChangeRemoved})
+		}
+	}
+
+	sort.Slice(c.Changes, func(i, j int) bool {
+		a, b := c.Changes[i], c.Changes[j]
+		if ka, kb := changeRank(a.Change), changeRank(b.Change); ka != kb {
+			return ka < kb
+		}
+		if a.Change != b.Change {
+			return a.Change < b.Change
+		}
+		return a.ID < b.ID
+	})
+	if len(c.Changes) > MaxFeatureChanges {
+		c.Changes = c.Changes[:MaxFeatureChanges]
+		c.Truncated = true
+		listed := make(map[string]int)
+		for _, ch := range c.Changes {
+			listed[changeKinds[changeRank(ch.Change)]]++
+		}
+		for i, n := range []int{c.Removed, c.Modified, c.Added} {
+			if listed[changeKinds[i]] < n {
+				c.TruncatedKinds = append(c.TruncatedKinds, changeKinds[i])
+			}
+		}
+	}
+
+	return c
+}
+
+// changeKinds are the change counts in listing order.
+var changeKinds = []string{"removed", "modified", "added"}
+
+// changeRank is a change's index in changeKinds.
+func changeRank(change string) int {
+	switch change {
+	case ChangeRemoved:
+		return 0
+	case ChangeAdded:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// canonicalHash hashes a decoded JSON value. Re-encoding sorts object keys
+// and normalises number formatting, so only content changes alter the hash.
+func canonicalHash(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
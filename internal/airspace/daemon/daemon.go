@@ -0,0 +1,418 @@
+// Package daemon keeps a region's airspace tiles current without a CI job.
+//
+// A Daemon runs the pipeline (sync → validate → tile → manifest) on a
+// schedule aligned to the FAA AIRAC cycle, publishes to R2 when anything
+// changed and posts a change notification to webhooks:
+//
+//	GET  /health   200 when the last run succeeded, 503 when it failed
+//	GET  /status   schedule, last run and the region's last SyncResult
+//	POST /run      start a run now
+//
+// POST /run starts a pipeline run and an R2 publish, so the daemon listens
+// on localhost by default. Set Config.RunToken before exposing it: /run
+// then requires "Authorization: Bearer <token>". /health and /status are
+// read-only and stay open.
+//
+// The pipeline only retiles datasets whose GeoJSON changed, so a run on a
+// day without new FAA data costs one conditional request per dataset.
+//
+// The sync records new ETags before anything is built, so once a run has
+// synced changes that it failed to validate, tile or publish, the daemon
+// keeps them pending: every later run resumes the pipeline and publishes
+// until one succeeds. Pending changes are kept in memory; after a restart,
+// run "airspace pipeline -force" if the last run failed.
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/r2"
+)
+
+// Schedule decides when the daemon runs.
+type Schedule struct {
+	CycleDays int           // 28 (every AIRAC date) or 56 (chart dates only)
+	Offset    time.Duration // Wait after the 0901Z effective time for the FAA to publish
+	Interval  time.Duration // Also run this long after the last run (0 = cycle dates only)
+}
+
+// Next returns the time of the next run after now, given when the last
+// run finished (zero polls now).
+func (s Schedule) Next(last, now time.Time) time.Time {
+	c := airspace.AIRACAt(now.Add(-s.Offset))
+	for {
+		c = c.Next()
+		if s.CycleDays != airspace.ChartCycleDays || c.Chart {
+			break
+		}
+	}
+	next := c.Effective.Add(s.Offset)
+
+	if s.Interval > 0 {
+		poll := now
+		if !last.IsZero() {
+			poll = last.Add(s.Interval)
+		}
+		if poll.Before(next) {
+			next = poll
+		}
+	}
+	if next.Before(now) {
+		return now
+	}
+	return next
+}
+
+// Publisher uploads a region's tiles and manifest, returning the version.
+type Publisher interface {
+	Publish(region airspace.Region) (string, error)
+}
+
+// R2Publisher publishes to Cloudflare R2 as a new version.
+type R2Publisher struct {
+	Client *r2.Client
+}
+
+// Publish implements Publisher.
+func (p R2Publisher) Publish(region airspace.Region) (string, error) {
+	result, err := airspace.PublishToR2(p.Client, airspace.RegionPublishOptions(region))
+	if err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// PipelineFunc runs the pipeline; airspace.Pipeline unless replaced.
+type PipelineFunc func(opts airspace.PipelineOptions, tiler airspace.Tiler) (*airspace.PipelineResult, error)
+
+// Config configures a Daemon.
+type Config struct {
+	Region   airspace.Region
+	Tiler    airspace.Tiler
+	Schedule Schedule
+	Pipeline airspace.PipelineOptions // Region is set from Region.Key
+
+	Publisher       Publisher // nil = don't upload
+	Webhooks        []Webhook
+	NotifyUnchanged bool   // Also notify after runs that found nothing new
+	RunToken        string // Bearer token POST /run requires ("" = none)
+
+	RunPipeline PipelineFunc // nil = airspace.Pipeline
+	Logger      *log.Logger  // nil = log.Default()
+	Client      *http.Client // Webhook client (nil = 30s timeout)
+}
+
+// Run records one daemon run.
+type Run struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Cycle    string    `json:"airac_cycle"`
+	Changed  []string  `json:"changed,omitempty"` // Datasets updated by the sync
+	Tiled    int       `json:"tiled"`
+	Version  string    `json:"version,omitempty"` // Published version
+	Error    string    `json:"error,omitempty"`
+
+	NotifyErrors []string `json:"notify_errors,omitempty"`
+}
+
+// Status is the /status response.
+type Status struct {
+	Region    string               `json:"region"`
+	Started   time.Time            `json:"started"`
+	Running   bool                 `json:"running"`
+	Runs      int                  `json:"runs"`
+	Failures  int                  `json:"failures"`
+	LastRun   *Run                 `json:"last_run,omitempty"`
+	NextRun   time.Time            `json:"next_run"`
+	Cycle     airspace.AIRACCycle  `json:"cycle"`
+	NextCycle airspace.AIRACCycle  `json:"next_cycle"`
+	Publish   bool                 `json:"publish"`
+	Pending   bool                 `json:"pending"` // Synced changes not yet published
+	Webhooks  int                  `json:"webhooks"`
+	LastSync  *airspace.SyncResult `json:"last_sync,omitempty"`
+}
+
+// Daemon runs the pipeline on a schedule.
+type Daemon struct {
+	cfg     Config
+	log     *log.Logger
+	client  *http.Client
+	started time.Time
+	trigger chan struct{}
+
+	mu       sync.Mutex
+	running  bool
+	runs     int
+	failures int
+	last     *Run
+	next     time.Time
+	pending  bool // A run synced changes but failed before publishing them
+}
+
+// New returns a daemon for the configured region.
+func New(cfg Config) *Daemon {
+	if cfg.Schedule.CycleDays == 0 {
+		cfg.Schedule.CycleDays = airspace.AIRACCycleDays
+	}
+	if cfg.RunPipeline == nil {
+		cfg.RunPipeline = airspace.Pipeline
+	}
+	cfg.Pipeline.Region = cfg.Region.Key
+
+	d := &Daemon{
+		cfg:     cfg,
+		log:     cfg.Logger,
+		client:  cfg.Client,
+		started: time.Now().UTC(),
+		trigger: make(chan struct{}, 1),
+	}
+	if d.log == nil {
+		d.log = log.Default()
+	}
+	if d.client == nil {
+		d.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return d
+}
+
+// maxSleep bounds each wait so a suspended host or clock change is noticed.
+const maxSleep = time.Hour
+
+// Run runs the pipeline on schedule until ctx is cancelled. With runNow the
+// first run starts immediately.
+func (d *Daemon) Run(ctx context.Context, runNow bool) error {
+	if runNow {
+		d.RunOnce()
+	}
+	for {
+		last := d.started
+		d.mu.Lock()
+		if d.last != nil {
+			last = d.last.Finished
+		}
+		next := d.cfg.Schedule.Next(last, time.Now().UTC())
+		d.next = next
+		d.mu.Unlock()
+		d.log.Printf("next run %s (AIRAC %s)", next.Format(time.RFC3339), airspace.AIRACAt(next).Ident)
+
+		if !d.wait(ctx, next) {
+			return ctx.Err()
+		}
+		d.RunOnce()
+	}
+}
+
+// wait sleeps until next or a trigger; false when ctx is cancelled.
+func (d *Daemon) wait(ctx context.Context, next time.Time) bool {
+	for {
+		sleep := time.Until(next)
+		if sleep <= 0 {
+			return true
+		}
+		timer := time.NewTimer(min(sleep, maxSleep))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-d.trigger:
+			timer.Stop()
+			return true
+		case <-timer.C:
+		}
+	}
+}
+
+// Trigger starts a run as soon as the current one (if any) finishes.
+func (d *Daemon) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	default: // Already pending
+	}
+}
+
+// RunOnce runs the pipeline, publishes on changes and notifies webhooks.
+func (d *Daemon) RunOnce() Run {
+	d.mu.Lock()
+	d.running = true
+
+	pending := d.pending
+	d.mu.Unlock()
+
+	run := Run{Started: time.Now().UTC()}
+	run.Cycle = airspace.AIRACAt(run.Started).Ident
+	d.log.Printf("run started (AIRAC %s)", run.Cycle)
+	if pending {
+		d.log.Printf("resuming: changes from a failed run are not published yet")
+	}
+
+	opts := d.cfg.Pipeline
+	opts.Resume = pending
+	result, err := d.cfg.RunPipeline(opts, d.cfg.Tiler)
+	var synced *airspace.SyncResult
+	if result != nil {
+		synced = result.SyncResult
+		run.Tiled = result.TileCount
+	}
+	if synced != nil {
+		for _, key := range sortedKeys(synced.Datasets) {
+			if synced.Datasets[key].Status == "updated" {
+				run.Changed = append(run.Changed, key)
+			}
+		}
+	}
+
+	hasChanges := pending || (synced != nil && synced.HasChanges)
+	changed := err == nil && result != nil && !result.Skipped && hasChanges
+	if changed && d.cfg.Publisher != nil {
+		run.Version, err = d.cfg.Publisher.Publish(d.cfg.Region)
+		if err != nil {
+			err = fmt.Errorf("publish: %w", err)
+		}
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	run.Finished = time.Now().UTC()
+
+	if changed || err != nil || d.cfg.NotifyUnchanged {
+		n := NewNotification(d.cfg.Region, run, synced)
+		for _, w := range d.cfg.Webhooks {
+			if err := w.Send(d.client, n); err != nil {
+				d.log.Printf("webhook %s: %v", w.Format, err)
+				run.NotifyErrors = append(run.NotifyErrors, err.Error())
+			}
+		}
+	}
+
+	switch {
+	case err != nil:
+		d.log.Printf("run failed: %v", err)
+	case changed:
+		d.log.Printf("run complete: %d changed, %d tiled, version %q", len(run.Changed), run.Tiled, run.Version)
+	default:
+		d.log.Printf("run complete: no changes")
+	}
+
+	d.mu.Lock()
+	d.running = false
+	d.runs++
+	if err != nil {
+		d.failures++
+	}
+	d.last = &run
+	d.pending = hasChanges && err != nil
+	d.mu.Unlock()
+	return run
+}
+
+// Status reports the daemon's state. The sync result is read from the
+// region's data dir, so it also reflects runs made outside the daemon.
+func (d *Daemon) Status() Status {
+	now := time.Now().UTC()
+	cycle := airspace.AIRACAt(now)
+
+	d.mu.Lock()
+	s := Status{
+		Region:    d.cfg.Region.Key,
+		Started:   d.started,
+		Running:   d.running,
+		Runs:      d.runs,
+		Failures:  d.failures,
+		LastRun:   d.last,
+		NextRun:   d.next,
+		Cycle:     cycle,
+		NextCycle: cycle.Next(),
+		Publish:   d.cfg.Publisher != nil,
+		Webhooks:  len(d.cfg.Webhooks),
+		Pending:   d.pending,
+	}
+	d.mu.Unlock()
+
+	if synced := airspace.LoadSyncResult(filepath.Join(d.cfg.Region.DataDir(), airspace.FileSyncResult)); !synced.Timestamp.IsZero() {
+		s.LastSync = &synced
+	}
+	return s
+}
+
+// Handler returns the HTTP handler for /health, /status and /run.
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /health", d.handleHealth)
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Status())
+	})
+	mux.HandleFunc("POST /run", func(w http.ResponseWriter, r *http.Request) {
+		if !d.authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "POST /run needs the daemon's bearer token"})
+			return
+		}
+		d.Trigger()
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered"})
+	})
+
+	return mux
+}
+
+// authorized reports whether a request carries the RunToken, if one is set.
+func (d *Daemon) authorized(r *http.Request) bool {
+	if d.cfg.RunToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(d.cfg.RunToken)) == 1
+}
+
+func (d *Daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	last := d.last
+	d.mu.Unlock()
+
+	health := struct {
+		Status  string    `json:"status"`
+		Uptime  string    `json:"uptime"`
+		LastRun time.Time `json:"last_run,omitzero"`
+		Error   string    `json:"error,omitempty"`
+	}{
+		Status: "ok",
+		Uptime: time.Since(d.started).Round(time.Second).String(),
+	}
+	status := http.StatusOK
+	if last != nil {
+		health.LastRun = last.Finished
+		if last.Error != "" {
+			health.Status = "failing"
+			health.Error = last.Error
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, status, health)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+// Webhook formats.
+const (
+	FormatSlack   = "slack"   // {"text": ...} incoming webhook
+	FormatDiscord = "discord" // {"content": ...} channel webhook
+	FormatJSON    = "json"    // The Notification as JSON
+)
+
+// discordLimit is the maximum length of a Discord message.
+const discordLimit = 2000
+
+// Webhook is one notification target.
+type Webhook struct {
+	Format string
+	URL    string
+}
+
+// ParseWebhook parses "[format=]url". Without a format, Slack and Discord
+// webhook URLs are recognised by host and anything else gets JSON.
+func ParseWebhook(s string) (Webhook, error) {
+	w := Webhook{URL: s}
+	if format, rest, ok := strings.Cut(s, "="); ok && !strings.Contains(format, "/") {
+		w.Format, w.URL = strings.ToLower(format), rest
+	}
+
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return w, fmt.Errorf("invalid webhook URL %q", w.URL)
+	}
+
+	switch w.Format {
+	case "":
+		switch {
+		case u.Host == "hooks.slack.com":
+			w.Format = FormatSlack
+		case strings.HasSuffix(u.Host, "discord.com") || strings.HasSuffix(u.Host, "discordapp.com"):
+			w.Format = FormatDiscord
+		default:
+			w.Format = FormatJSON
+		}
+	case FormatSlack, FormatDiscord, FormatJSON:
+	default:
+		return w, fmt.Errorf("unknown webhook format %q (valid: slack, discord, json)", w.Format)
+	}
+	return w, nil
+}
+
+// Notification is the structured change notification sent after a run.
+type Notification struct {
+	Event      string          `json:"event"` // changes, unchanged or error
+	Region     string          `json:"region"`
+	RegionName string          `json:"region_name"`
+	Cycle      string          `json:"airac_cycle"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Datasets   []DatasetChange `json:"datasets,omitempty"`
+	Tiled      int             `json:"tiled"`
+	Version    string          `json:"version,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// DatasetChange summarises one dataset's sync.
+type DatasetChange struct {
+	Key      string `json:"key"`
+	Status   string `json:"status"`
+	Features int    `json:"features"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Modified int    `json:"modified"`
+	Error    string `json:"error,omitempty"`
+}
+
+// NewNotification builds the notification for a run. synced may be nil
+// when the sync itself failed.
+func NewNotification(region airspace.Region, run Run, synced *airspace.SyncResult) Notification {
+	n := Notification{
+		Event:      "unchanged",
+		Region:     region.Key,
+		RegionName: region.Name,
+		Cycle:      run.Cycle,
+		Timestamp:  run.Finished,
+		Tiled:      run.Tiled,
+		Version:    run.Version,
+		Error:      run.Error,
+	}
+	if synced != nil {
+		for _, key := range sortedKeys(synced.Datasets) {
+			ds := synced.Datasets[key]
+			if ds.Status == "unchanged" {
+				continue
+			}
+			c := DatasetChange{Key: key, Status: ds.Status, Features: ds.Features, Error: ds.Error}
+			if ds.Changes != nil {
+				c.Added, c.Removed, c.Modified = ds.Changes.Added, ds.Changes.Removed, ds.Changes.Modified
+			}
+			n.Datasets = append(n.Datasets, c)
+		}
+		if synced.HasChanges {
+			n.Event = "changes"
+		}
+	}
+	if run.Error != "" {
+		n.Event = "error"
+	}
+	return n
+}
+
+// Title is a one-line summary of the notification.
+func (n Notification) Title() string {
+	switch n.Event {
+	case "error":
+		return fmt.Sprintf("Airspace %s: run failed (AIRAC %s)", n.Region, n.Cycle)
+	case "changes":
+		return fmt.Sprintf("Airspace %s: %d datasets changed (AIRAC %s)", n.Region, len(n.Datasets), n.Cycle)
+	}
+	return fmt.Sprintf("Airspace %s: no changes (AIRAC %s)", n.Region, n.Cycle)
+}
+
+// Lines are the notification's detail lines, one per dataset.
+func (n Notification) Lines() []string {
+	var lines []string
+	for _, d := range n.Datasets {
+		switch d.Status {
+		case "error":
+			lines = append(lines, fmt.Sprintf("%s: error: %s", d.Key, d.Error))
+		case "updated":
+			lines = append(lines, fmt.Sprintf("%s: +%d −%d ~%d (%d features)", d.Key, d.Added, d.Removed, d.Modified, d.Features))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s", d.Key, d.Status))
+		}
+	}
+	if n.Version != "" {
+		lines = append(lines, fmt.Sprintf("Published version %s (%d tiled)", n.Version, n.Tiled))
+	}
+	if n.Error != "" {
+		lines = append(lines, "Error: "+n.Error)
+	}
+	return lines
+}
+
+// Payload returns the request body for the webhook's format.
+func (w Webhook) Payload(n Notification) ([]byte, error) {
+	switch w.Format {
+	case FormatSlack:
+		text := "*" + n.Title() + "*\n" + strings.Join(n.Lines(), "\n")
+		return json.Marshal(map[string]string{"text": strings.TrimSpace(text)})
+	case FormatDiscord:
+		text := strings.TrimSpace("**" + n.Title() + "**\n" + strings.Join(n.Lines(), "\n"))
+		if r := []rune(text); len(r) > discordLimit {
+			text = string(r[:discordLimit-1]) + "…"
+		}
+		return json.Marshal(map[string]string{"content": text})
+	}
+	return json.Marshal(n)
+}
+
+// Send posts the notification.
+func (w Webhook) Send(client *http.Client, n Notification) error {
+	body, err := w.Payload(n)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err // The URL holds the webhook's secret
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package airspace_test
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/daemon"
+)
+
+func TestAIRACCycle(t *testing.T) {
+	tests := []struct {
+		at        string
+		ident     string
+		effective string
+	}{
+		{"2025-01-23T10:00:00Z", "2501", "2025-01-23"},
+		{"2025-01-23T09:00:00Z", "2413", "2024-12-26"}, // Before 0901Z
+		{"2025-12-31T00:00:00Z", "2513", "2025-12-25"},
+		{"2026-01-22T12:00:00Z", "2601", "2026-01-22"},
+		{"2019-12-30T00:00:00Z", "1913", "2019-12-05"},
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		c := airspace.AIRACAt(at)
+		if c.Ident != tt.ident || c.Effective.Format("2006-01-02") != tt.effective {
+			t.Errorf("AIRACAt(%s) = %s %s, want %s %s", tt.at, c.Ident, c.Effective.Format("2006-01-02"), tt.ident, tt.effective)
+		}
+	}
+
+	// 56-day chart dates: 2025-01-23, 2025-03-20, ...
+	now := time.Date(2025, 1, 24, 0, 0, 0, 0, time.UTC)
+	s := daemon.Schedule{CycleDays: airspace.AIRACCycleDays, Offset: time.Hour}
+	if got := s.Next(time.Time{}, now); !got.Equal(time.Date(2025, 2, 20, 10, 1, 0, 0, time.UTC)) {
+		t.Errorf("28-day next = %s", got)
+	}
+	s.CycleDays = airspace.ChartCycleDays
+	if got := s.Next(time.Time{}, now); !got.Equal(time.Date(2025, 3, 20, 10, 1, 0, 0, time.UTC)) {
+		t.Errorf("56-day next = %s", got)
+	}
+	s.Interval = 24 * time.Hour
+	if got := s.Next(now.Add(-time.Hour), now); !got.Equal(now.Add(23 * time.Hour)) {
+		t.Errorf("polling next = %s", got)
+	}
+}
+
+type fakePublisher struct{ calls, fail int }
+
+// Publish fails the first fail calls.
+func (p *fakePublisher) Publish(airspace.Region) (string, error) {
+	p.calls++
+	if p.calls <= p.fail {
+		return "", io.ErrUnexpectedEOF
+	}
+	return "20260101T000000Z", nil
+}
+
+func TestDaemonRun(t *testing.T) {
+	var mu sync.Mutex
+	bodies := map[string]string{}
+	hooks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = string(data)
+		mu.Unlock()
+	}))
+	defer hooks.Close()
+
+	var webhooks []daemon.Webhook
+	for _, s := range []string{"slack=" + hooks.URL + "/slack", hooks.URL + "/json"} {
+		w, err := daemon.ParseWebhook(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	if w, _ := daemon.ParseWebhook("https://discord.com/api/webhooks/1/x"); w.Format != daemon.FormatDiscord {
+		t.Errorf("discord URL format = %q", w.Format)
+	}
+
+	synced := &airspace.SyncResult{
+		HasChanges: true,
+		Updated:    1,
+		Datasets: map[string]airspace.DatasetSync{
+			"sua": {Status: "updated", Features: 8, Changes: &airspace.FeatureChanges{Added: 1, Modified: 2}},
+			"uas": {Status: "unchanged"},
+		},
+	}
+	pub := &fakePublisher{}
+	d := daemon.New(daemon.Config{
+		Region:    airspace.Region{Key: "test", Name: "Test"},
+		Publisher: pub,
+		Webhooks:  webhooks,
+		RunPipeline: func(airspace.PipelineOptions, airspace.Tiler) (*airspace.PipelineResult, error) {
+			return &airspace.PipelineResult{SyncResult: synced, TileCount: 1}, nil
+		},
+		Logger: log.New(io.Discard, "", 0),
+	})
+
+	run := d.RunOnce()
+	if run.Error != "" || pub.calls != 1 || run.Version == "" || strings.Join(run.Changed, ",") != "sua" {
+		t.Fatalf("run = %+v, publishes = %d", run, pub.calls)
+	}
+
+	if !strings.Contains(bodies["/slack"], `"text":"*Airspace test: 1 datasets changed`) || !strings.Contains(bodies["/slack"], "sua: +1 −0 ~2") {
+		t.Errorf("slack payload = %s", bodies["/slack"])
+	}
+	var n daemon.Notification
+	if err := json.Unmarshal([]byte(bodies["/json"]), &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Event != "changes" || len(n.Datasets) != 1 || n.Datasets[0].Modified != 2 || n.Version != run.Version {
+		t.Errorf("json notification = %+v", n)
+	}
+
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status daemon.Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Runs != 1 || status.LastRun == nil || status.LastRun.Version != run.Version || !status.Publish {
+		t.Errorf("status = %+v", status)
+	}
+
+	// A failed run makes /health unhealthy
+	d = daemon.New(daemon.Config{
+		Region: airspace.Region{Key: "test"},
+		RunPipeline: func(airspace.PipelineOptions, airspace.Tiler) (*airspace.PipelineResult, error) {
+			return nil, io.ErrUnexpectedEOF
+		},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	d.RunOnce()
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/health after a failure = %d, want 503", rec.Code)
+	}
+}
+
+func TestDaemonRetriesFailedPublish(t *testing.T) {
+	// The first sync finds changes; later ones find nothing new
+	syncs := []*airspace.SyncResult{
+		{HasChanges: true, Updated: 1, Datasets: map[string]airspace.DatasetSync{"sua": {Status: "updated"}}},
+		{Datasets: map[string]airspace.DatasetSync{"sua": {Status: "unchanged"}}},
+		{Datasets: map[string]airspace.DatasetSync{"sua": {Status: "unchanged"}}},
+	}
+	var resumed []bool
+	pub := &fakePublisher{fail: 1}
+	d := daemon.New(daemon.Config{
+		Region:    airspace.Region{Key: "test"},
+		Publisher: pub,
+		RunPipeline: func(opts airspace.PipelineOptions, _ airspace.Tiler) (*airspace.PipelineResult, error) {
+			synced := syncs[len(resumed)]
+			resumed = append(resumed, opts.Resume)
+			return &airspace.PipelineResult{SyncResult: synced, Skipped: !synced.HasChanges && !opts.Resume}, nil
+		},
+		Logger: log.New(io.Discard, "", 0),
+	})
+
+	if run := d.RunOnce(); run.Error == "" || !d.Status().Pending {
+		t.Fatalf("first run = %+v, pending = %v", run, d.Status().Pending)
+	}
+	if run := d.RunOnce(); run.Error != "" || run.Version == "" || pub.calls != 2 {
+		t.Fatalf("retry run = %+v, publishes = %d", run, pub.calls)
+	}
+	if d.Status().Pending {
+		t.Error("still pending after a successful publish")
+	}
+	if run := d.RunOnce(); run.Version != "" || pub.calls != 2 {
+		t.Errorf("unchanged run = %+v, publishes = %d", run, pub.calls)
+	}
+	if !slices.Equal(resumed, []bool{false, true, false}) {
+		t.Errorf("resumed = %v", resumed)
+	}
+}
+
+func TestDaemonRunToken(t *testing.T) {
+	d := daemon.New(daemon.Config{
+		Region: airspace.Region{Key: "test"},
+		RunPipeline: func(airspace.PipelineOptions, airspace.Tiler) (*airspace.PipelineResult, error) {
+			return &airspace.PipelineResult{Skipped: true}, nil
+		},
+		RunToken: "s3cret",
+		Logger:   log.New(io.Discard, "", 0),
+	})
+
+	tests := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"Bearer s3cret", http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/run", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		d.Handler().ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("POST /run with %q = %d, want %d", tt.auth, rec.Code, tt.want)
+		}
+	}
+
+	// /status stays open
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /status = %d, want 200", rec.Code)
+	}
+}
@@ -1,79 +1,51 @@
 package airspace
 
-// Dataset describes a FAA data source.
+// Dataset describes an airspace data source within a region.
 type Dataset struct {
-	Name        string // Human-readable name
-	Key         string // Dataset key (uas, boundary, etc.)
-	GeoJSON     string // GeoJSON filename
-	PMTiles     string // PMTiles filename
-	Layer       string // PMTiles layer name
-	BaseURL     string // Download URL
-	IsPaginated bool   // For FeatureServer APIs that require pagination
-	PageSize    int    // Page size for paginated APIs
-	ETagURL     string // URL to check for ETag/Last-Modified (for paginated APIs)
+	Name        string          `yaml:"name"`      // Human-readable name
+	Key         string          `yaml:"-"`         // Dataset key (uas, boundary, etc.), set from the registry map key
+	GeoJSON     string          `yaml:"geojson"`   // GeoJSON filename
+	PMTiles     string          `yaml:"pmtiles"`   // PMTiles filename
+	Layer       string          `yaml:"layer"`     // PMTiles layer name
+	BaseURL     string          `yaml:"base_url"`  // Download URL
+	IsPaginated bool            `yaml:"paginated"` // For FeatureServer APIs that require pagination
+	PageSize    int             `yaml:"page_size"` // Page size for paginated APIs
+	ETagURL     string          `yaml:"etag_url"`  // URL to check for ETag/Last-Modified (for paginated APIs)
+	Tile        TileConfig      `yaml:"tile"`      // Tile generation settings
+	Validate    ValidationRules `yaml:"validate"`  // Per-feature checks before tiling
+	Dynamic     *DynamicSource  `yaml:"dynamic"`   // Time-bounded feed (nil = static AIRAC layer)
+	Manifest    *LayerDisplay   `yaml:"manifest"`  // Map display settings (nil = not in manifest)
 }
 
-// Datasets is the registry of all FAA data sources.
-var Datasets = map[string]Dataset{
-	"uas": {
-		Name:        "UAS Facility Map",
-		Key:         "uas",
-		GeoJSON:     GeoJSONUAS,
-		PMTiles:     PMTilesUAS,
-		Layer:       LayerUAS,
-		BaseURL:     "https://services6.arcgis.com/ssFJjBXIUyZDrSYZ/arcgis/rest/services/FAA_UAS_FacilityMap_Data/FeatureServer/0/query",
-		IsPaginated: true,
-		PageSize:    2000,
-		ETagURL:     "https://services6.arcgis.com/ssFJjBXIUyZDrSYZ/arcgis/rest/services/FAA_UAS_FacilityMap_Data/FeatureServer/0",
-	},
-	"boundary": {
-		Name:    "Airspace Boundary",
-		Key:     "boundary",
-		GeoJSON: GeoJSONBoundary,
-		PMTiles: PMTilesBoundary,
-		Layer:   LayerBoundary,
-		BaseURL: "https://adds-faa.opendata.arcgis.com/api/download/v1/items/67885972e4e940b2aa6d74024901c561/geojson?layers=0",
-	},
-	"sua": {
-		Name:    "Special Use Airspace",
-		Key:     "sua",
-		GeoJSON: GeoJSONSUA,
-		PMTiles: PMTilesSUA,
-		Layer:   LayerSUA,
-		BaseURL: "https://adds-faa.opendata.arcgis.com/api/download/v1/items/dd0d1b726e504137ab3c41b21835d05b/geojson?layers=0",
-	},
-	"airports": {
-		Name:    "Airports",
-		Key:     "airports",
-		GeoJSON: GeoJSONAirports,
-		PMTiles: PMTilesAirports,
-		Layer:   LayerAirports,
-		BaseURL: "https://adds-faa.opendata.arcgis.com/api/download/v1/items/e747ab91a11045e8b3f8a3efd093d3b5/geojson?layers=0",
-	},
-	"navaids": {
-		Name:    "Navigation Aids",
-		Key:     "navaids",
-		GeoJSON: GeoJSONNavaids,
-		PMTiles: PMTilesNavaids,
-		Layer:   LayerNavaids,
-		BaseURL: "https://adds-faa.opendata.arcgis.com/api/download/v1/items/990e238991b44dd08af27d7b43e70b92/geojson?layers=0",
-	},
-	"obstacles": {
-		Name:    "Obstacles",
-		Key:     "obstacles",
-		GeoJSON: GeoJSONObstacles,
-		PMTiles: PMTilesObstacles,
-		Layer:   LayerObstacles,
-		BaseURL: "https://adds-faa.opendata.arcgis.com/api/download/v1/items/c6a62360338e408cb1512366ad61559e/geojson?layers=0",
-	},
+// ValidationRules are the dataset-specific checks of the validation stage.
+// Geometry validity, winding and coordinate ranges are checked for every dataset.
+type ValidationRules struct {
+	Required []string `yaml:"required"` // Properties every feature must have (non-empty)
+	Geometry []string `yaml:"geometry"` // Allowed GeoJSON geometry types (empty = any)
 }
 
-// TileConfigs holds tile generation settings per dataset.
-var TileConfigs = map[string]TileConfig{
-	"boundary":  {MinZoom: -1, MaxZoom: -1, DropDensest: false},                                        // -zg (auto zoom)
-	"sua":       {MinZoom: -1, MaxZoom: -1, DropDensest: false},                                        // -zg
-	"uas":       {MinZoom: 0, MaxZoom: 10, ReduceRate: 1, NoFeatureLimit: true, NoTileSizeLimit: true}, // explicit zoom, no reduction
-	"airports":  {MinZoom: 0, MaxZoom: 10, ReduceRate: 1, NoFeatureLimit: true, NoTileSizeLimit: true},
-	"navaids":   {MinZoom: 0, MaxZoom: 10, ReduceRate: 1, NoFeatureLimit: true, NoTileSizeLimit: true},
-	"obstacles": {MinZoom: -1, MaxZoom: -1, DropDensest: true},                                         // -zg --drop-densest-as-needed
+// LayerDisplay holds the manifest entry for a dataset: how the map renders it.
+type LayerDisplay struct {
+	Key            string        `yaml:"key"` // Manifest layer key (defaults to the dataset key)
+	Name           string        `yaml:"name"`
+	GeomType       string        `yaml:"geom_type"` // polygon, point, line
+	ZoomRange      []int         `yaml:"zoom_range"`
+	DefaultVisible bool          `yaml:"default_visible"`
+	RenderRules    []RenderRule  `yaml:"render_rules"`
+	Legend         []LegendEntry `yaml:"legend"`
+}
+
+// TileConfigFor returns the tile settings for a dataset, with the layer name filled in.
+// The Go tiler cannot auto-detect zoom, so -1 zooms become 0..10 for it.
+func TileConfigFor(ds Dataset, tilerName string) TileConfig {
+	cfg := ds.Tile
+	cfg.Layer = ds.Layer
+
+	if tilerName == "go" && cfg.MinZoom < 0 {
+		cfg.MinZoom = 0
+	}
+	if tilerName == "go" && cfg.MaxZoom < 0 {
+		cfg.MaxZoom = 10
+	}
+	return cfg
 }
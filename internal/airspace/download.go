@@ -1,25 +1,25 @@
 package airspace
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 )
 
-// DownloadDirect downloads a file directly (no pagination).
+// DownloadDirect downloads a file directly (no pagination). downloadURL may
+// also be a local file, which is copied.
 func DownloadDirect(client *http.Client, downloadURL, outPath string) error {
-	resp, err := client.Get(downloadURL)
+	in, err := openFeed(client, downloadURL)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
-	}
+	defer in.Close()
 
 	f, err := os.Create(outPath)
 	if err != nil {
@@ -27,83 +27,304 @@ func DownloadDirect(client *http.Client, downloadURL, outPath string) error {
 	}
 	defer f.Close()
 
-	_, err = io.Copy(f, resp.Body)
+	_, err = io.Copy(f, in)
 	return err
 }
 
-// DownloadPaginated handles ArcGIS FeatureServer pagination.
-func DownloadPaginated(client *http.Client, ds Dataset, outPath string) error {
-	type FeatureCollection struct {
-		Type     string `json:"type"`
-		Features []any  `json:"features"`
+// PaginatedOptions controls retries for paginated downloads.
+type PaginatedOptions struct {
+	Retries    int           // Attempts per page (and for the count check) before giving up
+	Backoff    time.Duration // Delay before the first retry, doubled each time
+	MaxBackoff time.Duration
+}
+
+// DefaultPaginatedOptions returns the retry policy used by sync.
+func DefaultPaginatedOptions() PaginatedOptions {
+	return PaginatedOptions{
+		Retries:    5,
+		Backoff:    time.Second,
+		MaxBackoff: 30 * time.Second,
 	}
+}
+
+// downloadCheckpoint records progress of a paginated download so an
+// interrupted run can resume at the last good page.
+type downloadCheckpoint struct {
+	BaseURL   string `json:"base_url"`
+	PageSize  int    `json:"page_size"`
+	Offset    int    `json:"offset"`     // Next resultOffset to fetch
+	Features  int    `json:"features"`   // Features written to the partial file
+	PartBytes int64  `json:"part_bytes"` // Valid length of the partial file
+	Done      bool   `json:"done"`       // All pages fetched, count not yet verified
+}
+
+// arcgisPage is one page of an ArcGIS FeatureServer GeoJSON query. ArcGIS
+// reports some failures as HTTP 200 with an error object.
+type arcgisPage struct {
+	Features []json.RawMessage `json:"features"`
+	Error    *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// DownloadPaginated handles ArcGIS FeatureServer pagination with the default retry policy.
+func DownloadPaginated(client *http.Client, ds Dataset, outPath string) error {
+	return DownloadPaginatedWith(client, ds, outPath, DefaultPaginatedOptions())
+}
 
-	collection := FeatureCollection{
-		Type:     "FeatureCollection",
-		Features: make([]any, 0),
+// DownloadPaginatedWith handles ArcGIS FeatureServer pagination.
+//
+// Pages are streamed to <outPath>.part and progress is saved to
+// <outPath>.checkpoint after every page, so a failed or interrupted download
+// resumes where it stopped. Each page is retried with exponential backoff.
+// Once all pages are in, the feature count is checked against the service's
+// returnCountOnly query and the partial file is renamed over outPath.
+func DownloadPaginatedWith(client *http.Client, ds Dataset, outPath string, opts PaginatedOptions) error {
+	pageSize := ds.PageSize
+	if pageSize <= 0 {
+		pageSize = 2000 // ArcGIS default maxRecordCount
 	}
 
-	offset := 0
-	for {
-		params := url.Values{}
-		params.Set("where", "1=1")
-		params.Set("outFields", "*")
-		params.Set("f", "geojson")
-		params.Set("resultRecordCount", fmt.Sprintf("%d", ds.PageSize))
-		params.Set("resultOffset", fmt.Sprintf("%d", offset))
+	partPath := outPath + ".part"
+	checkpointPath := outPath + ".checkpoint"
 
-		queryURL := ds.BaseURL + "?" + params.Encode()
+	cp := loadCheckpoint(checkpointPath)
+	if cp.BaseURL != ds.BaseURL || cp.PageSize != pageSize {
+		cp = downloadCheckpoint{BaseURL: ds.BaseURL, PageSize: pageSize}
+	}
 
-		resp, err := client.Get(queryURL)
+	part, err := openPart(partPath, &cp)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+
+	for !cp.Done {
+		var page arcgisPage
+		err := withRetry(opts, func() error {
+			return fetchPage(client, ds.BaseURL, pageSize, cp.Offset, &page)
+		})
 		if err != nil {
-			return fmt.Errorf("fetch page at offset %d: %w", offset, err)
+			return fmt.Errorf("page at offset %d: %w", cp.Offset, err)
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			return fmt.Errorf("HTTP %d at offset %d", resp.StatusCode, offset)
+		if err := appendFeatures(part, page.Features, cp.Features); err != nil {
+			return err
+		}
+		cp.Features += len(page.Features)
+		cp.Offset += pageSize
+		cp.Done = len(page.Features) < pageSize
+		if cp.PartBytes, err = part.Seek(0, io.SeekCurrent); err != nil {
+			return err
+		}
+		if err := saveCheckpoint(checkpointPath, cp); err != nil {
+			return err
 		}
+	}
+
+	// Verify against the server's count before replacing the previous download
+	var expected int
+	err = withRetry(opts, func() error {
+		var err error
+		expected, err = fetchCount(client, ds.BaseURL)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("feature count check: %w", err)
+	}
+	if expected != cp.Features {
+		// The layer changed mid-download or pages went missing; start over next time
+		part.Close()
+		os.Remove(partPath)
+		os.Remove(checkpointPath)
+		return fmt.Errorf("downloaded %d features, service reports %d", cp.Features, expected)
+	}
+
+	if _, err := part.WriteString("]}\n"); err != nil {
+		return err
+	}
+	if err := part.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(partPath, outPath); err != nil {
+		return fmt.Errorf("replacing %s: %w", outPath, err)
+	}
+	os.Remove(checkpointPath)
+	return nil
+}
 
-		var page FeatureCollection
-		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
-			resp.Body.Close()
-			return fmt.Errorf("decode page at offset %d: %w", offset, err)
+// openPart opens the partial file positioned to continue from cp, starting a
+// new one (and resetting cp) if it is missing or shorter than recorded.
+func openPart(path string, cp *downloadCheckpoint) (*os.File, error) {
+	if cp.PartBytes > 0 {
+		if info, err := os.Stat(path); err == nil && info.Size() >= cp.PartBytes {
+			f, err := os.OpenFile(path, os.O_RDWR, 0644)
+			if err != nil {
+				return nil, err
+			}
+			if err := f.Truncate(cp.PartBytes); err != nil {
+				f.Close()
+				return nil, err
+			}
+			if _, err := f.Seek(cp.PartBytes, io.SeekStart); err != nil {
+				f.Close()
+				return nil, err
+			}
+			return f, nil
 		}
-		resp.Body.Close()
+	}
 
-		collection.Features = append(collection.Features, page.Features...)
+	*cp = downloadCheckpoint{BaseURL: cp.BaseURL, PageSize: cp.PageSize}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	header := `{"type":"FeatureCollection","features":[`
+	if _, err := f.WriteString(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	cp.PartBytes = int64(len(header))
+	return f, nil
+}
 
-		if len(page.Features) < ds.PageSize {
-			break
+// appendFeatures writes features to the partial file and syncs it to disk,
+// so the checkpoint saved afterwards never points past durable data.
+func appendFeatures(f *os.File, features []json.RawMessage, written int) error {
+	var buf bytes.Buffer
+	for i, feature := range features {
+		if written+i > 0 {
+			buf.WriteByte(',')
 		}
-		offset += ds.PageSize
+		buf.Write(feature)
 	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing features: %w", err)
+	}
+	return f.Sync()
+}
 
-	f, err := os.Create(outPath)
+// fetchPage gets one page of features.
+func fetchPage(client *http.Client, baseURL string, pageSize, offset int, page *arcgisPage) error {
+	params := url.Values{}
+	params.Set("where", "1=1")
+	params.Set("outFields", "*")
+	params.Set("f", "geojson")
+	params.Set("resultRecordCount", fmt.Sprintf("%d", pageSize))
+	params.Set("resultOffset", fmt.Sprintf("%d", offset))
+
+	*page = arcgisPage{}
+	if err := getJSON(client, baseURL+"?"+params.Encode(), page); err != nil {
+		return err
+	}
+	if page.Error != nil {
+		return fmt.Errorf("arcgis error %d: %s", page.Error.Code, page.Error.Message)
+	}
+	return nil
+}
+
+// fetchCount asks the service how many features the layer has.
+func fetchCount(client *http.Client, baseURL string) (int, error) {
+	params := url.Values{}
+	params.Set("where", "1=1")
+	params.Set("returnCountOnly", "true")
+	params.Set("f", "json")
+
+	var result struct {
+		Count *int `json:"count"`
+	}
+	if err := getJSON(client, baseURL+"?"+params.Encode(), &result); err != nil {
+		return 0, err
+	}
+	if result.Count == nil {
+		return 0, fmt.Errorf("no count in response")
+	}
+	return *result.Count, nil
+}
+
+// getJSON fetches and decodes a JSON response. Client errors other than 429
+// are wrapped in permanentError so they are not retried.
+func getJSON(client *http.Client, u string, v any) error {
+	resp, err := client.Get(u)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer resp.Body.Close()
 
-	encoder := json.NewEncoder(f)
-	return encoder.Encode(collection)
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("HTTP %d", resp.StatusCode)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return permanentError{err}
+		}
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
 }
 
-// Download downloads all specified datasets.
-func Download(client *http.Client, outputDir string, datasetKeys []string) error {
+// permanentError marks an error that retrying will not fix.
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// withRetry calls fn until it succeeds, fails permanently or runs out of attempts.
+func withRetry(opts PaginatedOptions, fn func() error) error {
+	delay := opts.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var perm permanentError
+		if errors.As(err, &perm) || attempt >= opts.Retries {
+			return err
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, opts.MaxBackoff)
+	}
+}
+
+func loadCheckpoint(path string) downloadCheckpoint {
+	var cp downloadCheckpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp
+	}
+	json.Unmarshal(data, &cp)
+	return cp
+}
+
+// saveCheckpoint writes the checkpoint atomically.
+func saveCheckpoint(path string, cp downloadCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Download downloads the specified datasets of a region.
+func Download(client *http.Client, region Region, outputDir string, datasetKeys []string) error {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("creating output dir: %w", err)
 	}
 
 	for _, key := range datasetKeys {
-		ds, ok := Datasets[key]
-		if !ok {
-			return fmt.Errorf("unknown dataset: %s", key)
+		ds, err := region.Dataset(key)
+		if err != nil {
+			return err
 		}
 
 		outPath := outputDir + "/" + ds.GeoJSON
 
-		var err error
 		if ds.IsPaginated {
 			err = DownloadPaginated(client, ds, outPath)
 		} else {
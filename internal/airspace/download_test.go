@@ -0,0 +1,164 @@
+package airspace_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+// fakeFeatureServer is a stand-in for an ArcGIS FeatureServer query endpoint.
+type fakeFeatureServer struct {
+	mu       sync.Mutex
+	total    int         // Features in the layer
+	count    int         // Reported by returnCountOnly (defaults to total)
+	down     map[int]int // Offset -> remaining 503 responses (-1 = always)
+	requests []int       // Offsets requested, in order
+}
+
+func (s *fakeFeatureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := r.URL.Query()
+	if q.Get("returnCountOnly") == "true" {
+		count := s.total
+		if s.count != 0 {
+			count = s.count
+		}
+		fmt.Fprintf(w, `{"count":%d}`, count)
+		return
+	}
+
+	offset, _ := strconv.Atoi(q.Get("resultOffset"))
+	size, _ := strconv.Atoi(q.Get("resultRecordCount"))
+	s.requests = append(s.requests, offset)
+
+	if n := s.down[offset]; n != 0 {
+		if n > 0 {
+			s.down[offset]--
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	features := []map[string]any{}
+	for i := offset; i < min(offset+size, s.total); i++ {
+		features = append(features, map[string]any{
+			"type":       "Feature",
+			"properties": map[string]any{"GLOBAL_ID": fmt.Sprintf("F%d", i)},
+			"geometry":   map[string]any{"type": "Point", "coordinates": []float64{float64(i), 0}},
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"type": "FeatureCollection", "features": features})
+}
+
+func readIDs(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fc struct {
+		Features []struct {
+			Properties struct {
+				GlobalID string `json:"GLOBAL_ID"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("output is not valid GeoJSON: %v", err)
+	}
+	var ids []string
+	for _, f := range fc.Features {
+		ids = append(ids, f.Properties.GlobalID)
+	}
+	return ids
+}
+
+func TestDownloadPaginatedResume(t *testing.T) {
+	fs := &fakeFeatureServer{total: 25, down: map[int]int{0: 2, 20: -1}}
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+
+	ds := airspace.Dataset{Name: "Test", BaseURL: srv.URL, IsPaginated: true, PageSize: 10}
+	opts := airspace.PaginatedOptions{Retries: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	outPath := filepath.Join(t.TempDir(), "test.geojson")
+	os.WriteFile(outPath, []byte("previous"), 0644)
+
+	// Transient failures at offset 0 are retried; offset 20 stays down
+	err := airspace.DownloadPaginatedWith(http.DefaultClient, ds, outPath, opts)
+	if err == nil {
+		t.Fatal("expected error while offset 20 is down")
+	}
+	if data, _ := os.ReadFile(outPath); string(data) != "previous" {
+		t.Errorf("failed download replaced the output: %q", data)
+	}
+	if _, err := os.Stat(outPath + ".checkpoint"); err != nil {
+		t.Fatalf("no checkpoint after failure: %v", err)
+	}
+
+	// Resume picks up at offset 20
+	fs.down[20] = 0
+	fs.requests = nil
+	if err := airspace.DownloadPaginatedWith(http.DefaultClient, ds, outPath, opts); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(fs.requests) != 1 || fs.requests[0] != 20 {
+		t.Errorf("resume requested offsets %v, want [20]", fs.requests)
+	}
+
+	ids := readIDs(t, outPath)
+	if len(ids) != 25 {
+		t.Fatalf("got %d features, want 25", len(ids))
+	}
+	for i, id := range ids {
+		if want := fmt.Sprintf("F%d", i); id != want {
+			t.Fatalf("feature %d = %s, want %s", i, id, want)
+		}
+	}
+	for _, leftover := range []string{".part", ".checkpoint"} {
+		if _, err := os.Stat(outPath + leftover); !os.IsNotExist(err) {
+			t.Errorf("%s left behind after success", leftover)
+		}
+	}
+}
+
+func TestDownloadPaginatedCountMismatch(t *testing.T) {
+	fs := &fakeFeatureServer{total: 15, count: 16}
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+
+	ds := airspace.Dataset{Name: "Test", BaseURL: srv.URL, IsPaginated: true, PageSize: 10}
+	opts := airspace.PaginatedOptions{Retries: 1}
+	outPath := filepath.Join(t.TempDir(), "test.geojson")
+	os.WriteFile(outPath, []byte("previous"), 0644)
+
+	if err := airspace.DownloadPaginatedWith(http.DefaultClient, ds, outPath, opts); err == nil {
+		t.Fatal("expected count mismatch error")
+	}
+	if data, _ := os.ReadFile(outPath); string(data) != "previous" {
+		t.Errorf("mismatched download replaced the output: %q", data)
+	}
+
+	// A mismatch discards progress so the next run starts over
+	fs.count = 0
+	fs.requests = nil
+	if err := airspace.DownloadPaginatedWith(http.DefaultClient, ds, outPath, opts); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if len(fs.requests) != 2 || fs.requests[0] != 0 {
+		t.Errorf("retry requested offsets %v, want [0 10]", fs.requests)
+	}
+	if ids := readIDs(t, outPath); len(ids) != 15 {
+		t.Errorf("got %d features, want 15", len(ids))
+	}
+}
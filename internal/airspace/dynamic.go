@@ -0,0 +1,234 @@
+package airspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/notam"
+)
+
+// DynamicSource marks a dataset as a time-bounded feed (TFRs, NOTAM areas)
+// rather than a 28-day AIRAC layer. Its GeoJSON is rebuilt from the feed on
+// every sync, without expired entries, and its manifest entry carries a
+// validity window.
+type DynamicSource struct {
+	Format    string        `yaml:"format"`    // Feed format: aixm
+	Refresh   time.Duration `yaml:"refresh"`   // How long a build stays current (0 = DefaultDynamicRefresh)
+	Lookahead time.Duration `yaml:"lookahead"` // Keep entries starting within this window (0 = every unexpired entry)
+}
+
+// RefreshInterval returns the configured refresh, or DefaultDynamicRefresh.
+func (d DynamicSource) RefreshInterval() time.Duration {
+	if d.Refresh > 0 {
+		return d.Refresh
+	}
+	return DefaultDynamicRefresh
+}
+
+// DynamicResult records one refresh of a dynamic dataset.
+type DynamicResult struct {
+	Feed     string       `json:"feed"`
+	Parsed   int          `json:"parsed"`   // Restrictions in the feed
+	Expired  int          `json:"expired"`  // Dropped: ended before the refresh
+	Deferred int          `json:"deferred"` // Dropped: start after the lookahead window
+	Written  int          `json:"written"`
+	Window   notam.Window `json:"window"`
+
+	Restrictions []notam.Restriction `json:"-"` // As written, for reports
+}
+
+// FetchDynamic reads a dynamic dataset's feed and writes the restrictions
+// that have not expired by now (and start within the lookahead) to outPath
+// as GeoJSON. feed is a URL or local file; "" means the dataset's base_url.
+func FetchDynamic(client *http.Client, ds Dataset, feed, outPath string, now time.Time) (*DynamicResult, error) {
+	if ds.Dynamic == nil {
+		return nil, fmt.Errorf("dataset %s is not dynamic", ds.Key)
+	}
+	if feed == "" {
+		feed = ds.BaseURL
+	}
+	if feed == "" {
+		return nil, fmt.Errorf("dataset %s has no feed (set base_url or pass -feed)", ds.Key)
+	}
+
+	var parse func(io.Reader) ([]notam.Restriction, error)
+	switch ds.Dynamic.Format {
+	case "aixm", "":
+		parse = notam.Parse
+	default:
+		return nil, fmt.Errorf("dataset %s: unknown feed format %q (valid: aixm)", ds.Key, ds.Dynamic.Format)
+	}
+
+	body, err := openFeed(client, feed)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", feed, err)
+	}
+	defer body.Close()
+
+	all, err := parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	current := notam.Current(all, now, ds.Dynamic.Lookahead)
+	result := &DynamicResult{
+		Feed:         feed,
+		Parsed:       len(all),
+		Written:      len(current),
+		Window:       notam.Validity(current, now),
+		Restrictions: current,
+	}
+	for _, r := range all {
+		if r.Expired(now) {
+			result.Expired++
+		}
+	}
+	result.Deferred = result.Parsed - result.Expired - result.Written
+
+	data, err := notam.FeatureCollection(current).MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return nil, err
+	}
+	return result, os.WriteFile(outPath, data, 0644)
+}
+
+// openFeed opens an http(s) URL or a local file.
+func openFeed(client *http.Client, feed string) (io.ReadCloser, error) {
+	if path, ok := localPath(feed); ok {
+		return os.Open(path)
+	}
+	resp, err := client.Get(feed)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// localPath returns the file a source that is not an http(s) URL names.
+func localPath(source string) (string, bool) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return "", false
+	}
+	return strings.TrimPrefix(source, "file://"), true
+}
+
+// syncDynamic refreshes a dynamic dataset during Sync. There is no ETag
+// check: expiry changes the output even when the feed does not, so the
+// feed is always read and the feature diff decides whether it changed.
+func syncDynamic(client *http.Client, ds Dataset, outPath string, now time.Time) DatasetSync {
+	dsResult := DatasetSync{Status: "unchanged"}
+
+	prevFeatures, prevErr := fingerprintFeatures(outPath)
+	dyn, err := FetchDynamic(client, ds, "", outPath, now)
+	if err != nil {
+		dsResult.Status = "error"
+		dsResult.Error = err.Error()
+		return dsResult
+	}
+	dsResult.Dynamic = dyn
+
+	if info, err := os.Stat(outPath); err == nil {
+		dsResult.SizeBytes = info.Size()
+		dsResult.SizeMB = float64(info.Size()) / (1024 * 1024)
+	}
+	if currFeatures, err := fingerprintFeatures(outPath); err == nil {
+		dsResult.Features = len(currFeatures)
+		dsResult.Changes = diffFeatures(prevFeatures, currFeatures)
+		if prevErr != nil || dsResult.Changes.HasChanges() {
+			dsResult.Status = "updated"
+		}
+	}
+	return dsResult
+}
+
+// RefreshDynamic fetches a region's dynamic dataset, retiles it and
+// regenerates the manifests, independent of the AIRAC sync. feed overrides
+// the dataset's base_url.
+func RefreshDynamic(tiler Tiler, region Region, key, feed string, now time.Time) (*DynamicResult, error) {
+	ds, err := region.Dataset(key)
+	if err != nil {
+		return nil, err
+	}
+
+	outPath := filepath.Join(region.GeoJSONDir(), ds.GeoJSON)
+	client := &http.Client{Timeout: time.Minute}
+	result, err := FetchDynamic(client, ds, feed, outPath, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Always retile: the GeoJSON was just rewritten
+	if err := TileOne(tiler, region, key, true); err != nil {
+		return result, fmt.Errorf("tile: %w", err)
+	}
+	if err := GenerateManifests(region); err != nil {
+		return result, fmt.Errorf("manifest: %w", err)
+	}
+	return result, nil
+}
+
+// DynamicValidity reads the effective windows of a dynamic dataset's
+// GeoJSON. The layer was generated when the file was written and expires
+// after the refresh interval or at the next start or end, whichever is
+// sooner, since the set of restrictions in force changes then.
+func DynamicValidity(path string, refresh time.Duration, now time.Time) (*LayerValidity, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rs []notam.Restriction
+	err = DecodeFeatures(f, func(raw json.RawMessage) error {
+		var feature struct {
+			Properties map[string]any `json:"properties"`
+		}
+		if err := json.Unmarshal(raw, &feature); err != nil {
+			return err
+		}
+		if from, to, ok := notam.FeatureWindow(feature.Properties); ok {
+			rs = append(rs, notam.Restriction{From: from, To: to})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	generated := info.ModTime().UTC()
+	w := notam.Validity(rs, generated)
+	expires := generated.Add(refresh)
+	if !w.NextChange.IsZero() && w.NextChange.Before(expires) {
+		expires = w.NextChange
+	}
+
+	v := &LayerValidity{
+		Generated: generated.Format(time.RFC3339),
+		Expires:   expires.Format(time.RFC3339),
+		Active:    notam.Validity(rs, now).Active,
+	}
+	if len(rs) > 0 {
+		v.From = w.From.Format(time.RFC3339)
+		if !w.To.IsZero() {
+			v.To = w.To.Format(time.RFC3339)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,212 @@
+// Package elevation reads ground elevation from local DEM tiles.
+//
+// FAA vertical limits mix references: Class E floors and UAS facility-map
+// ceilings are AGL, most other floors and ceilings are MSL. Comparing them
+// needs the terrain height under the aircraft, which a Source provides.
+//
+// Two formats are supported:
+//
+//   - GeoTIFF (including BigTIFF/COG such as GEDTM30) in geographic
+//     coordinates, uncompressed, Deflate or LZW, with any TIFF predictor
+//   - PMTiles raster archives of terrarium-encoded PNG tiles
+//
+// A directory of such files opens as a Multi source, consulted in name order.
+package elevation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+// FeetPerMeter converts DEM meters to the feet used by FAA limits.
+const FeetPerMeter = 3.28084
+
+// ErrNoData is returned for positions outside a source or on a nodata cell.
+var ErrNoData = errors.New("no elevation data")
+
+// Source is a pluggable elevation provider.
+type Source interface {
+	// Elevation returns the ground elevation in meters above mean sea level,
+	// or ErrNoData if the source does not cover the position.
+	Elevation(lon, lat float64) (float64, error)
+	// Bounds is the area the source covers.
+	Bounds() orb.Bound
+	Close() error
+}
+
+// Open opens a DEM file (.tif, .tiff, .pmtiles) or a directory of them.
+func Open(path string) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return OpenDir(path)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".tif", ".tiff":
+		return OpenGeoTIFF(path)
+	case ".pmtiles":
+		return OpenTerrarium(path)
+	default:
+		return nil, fmt.Errorf("unsupported DEM format %q (want .tif or .pmtiles)", ext)
+	}
+}
+
+// OpenDir opens every DEM file in dir as one Multi source.
+func OpenDir(dir string) (Multi, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".tif", ".tiff", ".pmtiles":
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no DEM files (.tif, .pmtiles) in %s", dir)
+	}
+
+	var m Multi
+	for _, name := range names {
+		src, err := Open(filepath.Join(dir, name))
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m = append(m, src)
+	}
+	return m, nil
+}
+
+// Multi consults several sources in order, returning the first with data.
+type Multi []Source
+
+// Elevation implements Source.
+func (m Multi) Elevation(lon, lat float64) (float64, error) {
+	pt := orb.Point{lon, lat}
+	for _, src := range m {
+		if !src.Bounds().Contains(pt) {
+			continue
+		}
+		elev, err := src.Elevation(lon, lat)
+		if errors.Is(err, ErrNoData) {
+			continue
+		}
+		return elev, err
+	}
+	return 0, ErrNoData
+}
+
+// Bounds implements Source.
+func (m Multi) Bounds() orb.Bound {
+	if len(m) == 0 {
+		return orb.Bound{}
+	}
+	b := m[0].Bounds()
+	for _, src := range m[1:] {
+		b = b.Union(src.Bounds())
+	}
+	return b
+}
+
+// Close implements Source.
+func (m Multi) Close() error {
+	var errs []error
+	for _, src := range m {
+		errs = append(errs, src.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Profile is the terrain along a path.
+type Profile struct {
+	LengthMeters float64        `json:"length_m"`
+	StepMeters   float64        `json:"step_m"`
+	MinMeters    *float64       `json:"min_m,omitempty"`
+	MaxMeters    *float64       `json:"max_m,omitempty"`
+	Missing      int            `json:"missing,omitempty"` // Samples without data
+	Points       []ProfilePoint `json:"points"`
+}
+
+// ProfilePoint is one sample of a profile.
+type ProfilePoint struct {
+	Point          orb.Point `json:"point"`
+	DistanceMeters float64   `json:"distance_m"`
+	Meters         *float64  `json:"elevation_m"` // nil = no data
+}
+
+// maxProfilePoints caps the samples of one profile; longer paths get a wider step.
+const maxProfilePoints = 10000
+
+// ExtractProfile samples the ground along path every step meters, always
+// including each vertex. Points between vertices are interpolated linearly in
+// longitude and latitude, which is accurate over the lengths of a flight leg.
+func ExtractProfile(src Source, path orb.LineString, step float64) (*Profile, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("profile step must be positive")
+	}
+
+	p := &Profile{}
+	for i := 1; i < len(path); i++ {
+		p.LengthMeters += geo.Distance(path[i-1], path[i])
+	}
+	p.StepMeters = math.Max(step, p.LengthMeters/maxProfilePoints)
+
+	add := func(pt orb.Point, dist float64) error {
+		pp := ProfilePoint{Point: pt, DistanceMeters: dist}
+		elev, err := src.Elevation(pt[0], pt[1])
+		switch {
+		case errors.Is(err, ErrNoData):
+			p.Missing++
+		case err != nil:
+			return err
+		default:
+			pp.Meters = &elev
+			if p.MinMeters == nil || elev < *p.MinMeters {
+				p.MinMeters = &elev
+			}
+			if p.MaxMeters == nil || elev > *p.MaxMeters {
+				p.MaxMeters = &elev
+			}
+		}
+		p.Points = append(p.Points, pp)
+		return nil
+	}
+
+	if err := add(path[0], 0); err != nil {
+		return nil, err
+	}
+	dist := 0.0
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		leg := geo.Distance(a, b)
+		n := max(int(math.Ceil(leg/p.StepMeters)), 1)
+		for k := 1; k <= n; k++ {
+			t := float64(k) / float64(n)
+			pt := orb.Point{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+			if err := add(pt, dist+t*leg); err != nil {
+				return nil, err
+			}
+		}
+		dist += leg
+	}
+	return p, nil
+}
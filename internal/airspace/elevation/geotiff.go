@@ -0,0 +1,518 @@
+package elevation
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"golang.org/x/image/tiff/lzw"
+)
+
+// TIFF tags read by the GeoTIFF source.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSampleFormat    = 339
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagGeoKeyDirectory = 34735
+	tagGDALMetadata    = 42112
+	tagGDALNoData      = 42113
+)
+
+// TIFF field types.
+const (
+	typeByte   = 1
+	typeASCII  = 2
+	typeShort  = 3
+	typeLong   = 4
+	typeSShort = 8
+	typeSLong  = 9
+	typeFloat  = 11
+	typeDouble = 12
+	typeLong8  = 16
+	typeSLong8 = 17
+)
+
+var typeSizes = map[uint16]int{
+	typeByte: 1, typeASCII: 1, typeShort: 2, typeLong: 4, typeSShort: 2, typeSLong: 4,
+	typeFloat: 4, typeDouble: 8, typeLong8: 8, typeSLong8: 8,
+}
+
+// Compression and predictor codes.
+const (
+	compressionNone    = 1
+	compressionLZW     = 5
+	compressionDeflate = 8
+	compressionZlib    = 32946 // Old-style Deflate code
+	predictorNone      = 1
+	predictorDiff      = 2 // Horizontal differencing
+	predictorFloat     = 3 // Floating point byte shuffling
+)
+
+// GeoKeys (GeoTIFF 1.1).
+const (
+	keyModelType      = 1024
+	keyRasterType     = 1025
+	modelGeographic   = 2
+	rasterPixelIsArea = 1
+)
+
+// maxCachedBlocks bounds the decoded strips/tiles kept in memory.
+const maxCachedBlocks = 64
+
+// GeoTIFF is a single-band elevation GeoTIFF in geographic coordinates.
+// Only the first image (full resolution) is read; blocks are decoded on
+// demand and cached. Values are sampled bilinearly between cell centres.
+type GeoTIFF struct {
+	Path  string
+	Scale float64 // Multiplier from stored values to meters (GEDTM30 stores decimeters: 0.1)
+
+	file  *os.File
+	order binary.ByteOrder
+
+	width, height   int
+	blockW, blockH  int
+	blocksAcross    int
+	offsets, counts []uint64
+	bits, format    int // Bits per sample and SampleFormat (1 uint, 2 int, 3 float)
+	compression     int
+	predictor       int
+
+	originX, originY float64 // Outer corner of cell (0, 0)
+	scaleX, scaleY   float64 // Degrees per cell
+	noData           *float64
+
+	mu    sync.Mutex
+	cache map[int][]float64
+}
+
+// gdalScale finds the band scale in GDAL_METADATA XML.
+var gdalScale = regexp.MustCompile(`<Item[^>]*role="scale"[^>]*>\s*([-+0-9.eE]+)\s*</Item>`)
+
+// OpenGeoTIFF opens a DEM GeoTIFF. Scale is read from the GDAL band
+// metadata if present, else 1.
+func OpenGeoTIFF(path string) (*GeoTIFF, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	g := &GeoTIFF{Path: path, Scale: 1, file: f, cache: make(map[int][]float64)}
+	if err := g.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// Close releases the underlying file.
+func (g *GeoTIFF) Close() error {
+	return g.file.Close()
+}
+
+// Bounds implements Source.
+func (g *GeoTIFF) Bounds() orb.Bound {
+	return orb.Bound{
+		Min: orb.Point{g.originX, g.originY - float64(g.height)*g.scaleY},
+		Max: orb.Point{g.originX + float64(g.width)*g.scaleX, g.originY},
+	}
+}
+
+// Elevation implements Source.
+func (g *GeoTIFF) Elevation(lon, lat float64) (float64, error) {
+	// Continuous cell coordinates, with cell centres at integers
+	x := (lon-g.originX)/g.scaleX - 0.5
+	y := (g.originY-lat)/g.scaleY - 0.5
+	if x < -0.5 || y < -0.5 || x > float64(g.width)-0.5 || y > float64(g.height)-0.5 {
+		return 0, ErrNoData
+	}
+
+	x0 := min(max(int(math.Floor(x)), 0), g.width-1)
+	y0 := min(max(int(math.Floor(y)), 0), g.height-1)
+	x1, y1 := min(x0+1, g.width-1), min(y0+1, g.height-1)
+	tx := min(max(x-float64(x0), 0), 1)
+	ty := min(max(y-float64(y0), 0), 1)
+
+	var v [4]float64
+	for i, c := range [4][2]int{{x0, y0}, {x1, y0}, {x0, y1}, {x1, y1}} {
+		val, ok, err := g.cell(c[0], c[1])
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			// Next to nodata, fall back to the nearest cell
+			val, ok, err = g.cell(int(math.Round(x)), int(math.Round(y)))
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				return 0, ErrNoData
+			}
+			return val * g.Scale, nil
+		}
+		v[i] = val
+	}
+
+	top := v[0] + tx*(v[1]-v[0])
+	bottom := v[2] + tx*(v[3]-v[2])
+	return (top + ty*(bottom-top)) * g.Scale, nil
+}
+
+// cell returns the stored value of one cell and whether it holds data.
+func (g *GeoTIFF) cell(col, row int) (float64, bool, error) {
+	col = min(max(col, 0), g.width-1)
+	row = min(max(row, 0), g.height-1)
+
+	block := (row/g.blockH)*g.blocksAcross + col/g.blockW
+	values, err := g.block(block)
+	if err != nil {
+		return 0, false, err
+	}
+	i := (row%g.blockH)*g.blockW + col%g.blockW
+	if i >= len(values) {
+		return 0, false, fmt.Errorf("block %d is truncated", block)
+	}
+	v := values[i]
+	if math.IsNaN(v) || (g.noData != nil && v == *g.noData) {
+		return 0, false, nil
+	}
+	return v, true, nil
+}
+
+// block returns the decoded values of one strip or tile.
+func (g *GeoTIFF) block(i int) ([]float64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if values, ok := g.cache[i]; ok {
+		return values, nil
+	}
+	if i >= len(g.offsets) || i >= len(g.counts) {
+		return nil, fmt.Errorf("block %d out of range", i)
+	}
+
+	raw := make([]byte, g.counts[i])
+	if _, err := g.file.ReadAt(raw, int64(g.offsets[i])); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading block %d: %w", i, err)
+	}
+	data, err := g.decompress(raw)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", i, err)
+	}
+
+	bytesPerSample := g.bits / 8
+	rows := len(data) / (g.blockW * bytesPerSample)
+	values := make([]float64, 0, rows*g.blockW)
+	for r := range rows {
+		row := data[r*g.blockW*bytesPerSample : (r+1)*g.blockW*bytesPerSample]
+		values = g.decodeRow(values, row)
+	}
+
+	if len(g.cache) >= maxCachedBlocks {
+		clear(g.cache)
+	}
+	g.cache[i] = values
+	return values, nil
+}
+
+func (g *GeoTIFF) decompress(raw []byte) ([]byte, error) {
+	switch g.compression {
+	case compressionNone:
+		return raw, nil
+	case compressionDeflate, compressionZlib:
+		r, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case compressionLZW:
+		r := lzw.NewReader(bytes.NewReader(raw), lzw.MSB, 8)
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return nil, fmt.Errorf("unsupported compression %d", g.compression)
+}
+
+// decodeRow undoes the predictor on one block row and appends its values.
+func (g *GeoTIFF) decodeRow(values []float64, row []byte) []float64 {
+	n := len(row) / (g.bits / 8)
+
+	if g.predictor == predictorFloat {
+		// Bytes are differenced, then grouped most significant byte first
+		for i := 1; i < len(row); i++ {
+			row[i] += row[i-1]
+		}
+		sample := make([]byte, g.bits/8)
+		for i := range n {
+			for b := range sample {
+				sample[b] = row[b*n+i]
+			}
+			values = append(values, decodeSample(binary.BigEndian, sample, g.bits, g.format))
+		}
+		return values
+	}
+
+	start := len(values)
+	for i := range n {
+		values = append(values, decodeSample(g.order, row[i*g.bits/8:], g.bits, g.format))
+	}
+	if g.predictor == predictorDiff {
+		for i := start + 1; i < len(values); i++ {
+			values[i] = wrapSample(values[i]+values[i-1], g.bits, g.format)
+		}
+	}
+	return values
+}
+
+func decodeSample(order binary.ByteOrder, b []byte, bits, format int) float64 {
+	switch {
+	case format == 3 && bits == 32:
+		return float64(math.Float32frombits(order.Uint32(b)))
+	case format == 3 && bits == 64:
+		return math.Float64frombits(order.Uint64(b))
+	case bits == 8 && format == 2:
+		return float64(int8(b[0]))
+	case bits == 8:
+		return float64(b[0])
+	case bits == 16 && format == 2:
+		return float64(int16(order.Uint16(b)))
+	case bits == 16:
+		return float64(order.Uint16(b))
+	case bits == 32 && format == 2:
+		return float64(int32(order.Uint32(b)))
+	default:
+		return float64(order.Uint32(b))
+	}
+}
+
+// wrapSample applies integer overflow after horizontal differencing.
+func wrapSample(v float64, bits, format int) float64 {
+	u := uint64(int64(v)) & (1<<bits - 1)
+	if format == 2 && u >= 1<<(bits-1) {
+		return float64(int64(u) - 1<<bits)
+	}
+	return float64(u)
+}
+
+// field is one decoded IFD entry.
+type field struct {
+	ints   []uint64
+	floats []float64
+	text   string
+}
+
+func (g *GeoTIFF) load() error {
+	head := make([]byte, 16)
+	if _, err := g.file.ReadAt(head, 0); err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	switch string(head[:2]) {
+	case "II":
+		g.order = binary.LittleEndian
+	case "MM":
+		g.order = binary.BigEndian
+	default:
+		return fmt.Errorf("not a TIFF file")
+	}
+
+	var fields map[uint16]field
+	var err error
+	switch g.order.Uint16(head[2:]) {
+	case 42:
+		fields, err = g.readIFD(uint64(g.order.Uint32(head[4:])), false)
+	case 43:
+		fields, err = g.readIFD(g.order.Uint64(head[8:]), true)
+	default:
+		return fmt.Errorf("not a TIFF file")
+	}
+	if err != nil {
+		return err
+	}
+
+	first := func(tag uint16, def int) int {
+		if f, ok := fields[tag]; ok && len(f.ints) > 0 {
+			return int(f.ints[0])
+		}
+		return def
+	}
+
+	g.width = first(tagImageWidth, 0)
+	g.height = first(tagImageLength, 0)
+	g.bits = first(tagBitsPerSample, 1)
+	g.format = first(tagSampleFormat, 1)
+	g.compression = first(tagCompression, compressionNone)
+	g.predictor = first(tagPredictor, predictorNone)
+	if g.width == 0 || g.height == 0 {
+		return fmt.Errorf("missing image dimensions")
+	}
+	if spp := first(tagSamplesPerPixel, 1); spp != 1 {
+		return fmt.Errorf("DEM must have one band, got %d", spp)
+	}
+	switch g.bits {
+	case 8, 16, 32:
+	case 64:
+		if g.format != 3 {
+			return fmt.Errorf("unsupported 64-bit integer samples")
+		}
+	default:
+		return fmt.Errorf("unsupported %d-bit samples", g.bits)
+	}
+
+	if _, tiled := fields[tagTileOffsets]; tiled {
+		g.blockW = first(tagTileWidth, 0)
+		g.blockH = first(tagTileLength, 0)
+		g.offsets = fields[tagTileOffsets].ints
+		g.counts = fields[tagTileByteCounts].ints
+	} else {
+		g.blockW = g.width
+		g.blockH = min(first(tagRowsPerStrip, g.height), g.height)
+		g.offsets = fields[tagStripOffsets].ints
+		g.counts = fields[tagStripByteCounts].ints
+	}
+	if g.blockW == 0 || g.blockH == 0 || len(g.offsets) == 0 {
+		return fmt.Errorf("missing strip or tile layout")
+	}
+	g.blocksAcross = (g.width + g.blockW - 1) / g.blockW
+
+	// Georeferencing: one tiepoint plus pixel scale
+	scale, tie := fields[tagModelPixelScale].floats, fields[tagModelTiepoint].floats
+	if len(scale) < 2 || len(tie) < 6 || scale[0] <= 0 || scale[1] <= 0 {
+		return fmt.Errorf("missing GeoTIFF tiepoint and pixel scale")
+	}
+	g.scaleX, g.scaleY = scale[0], scale[1]
+	g.originX = tie[3] - tie[0]*g.scaleX
+	g.originY = tie[4] + tie[1]*g.scaleY
+
+	if keys := fields[tagGeoKeyDirectory].ints; len(keys) >= 4 {
+		for i := 4; i+3 < len(keys); i += 4 {
+			switch keys[i] {
+			case keyModelType:
+				if keys[i+3] != modelGeographic {
+					return fmt.Errorf("DEM must be in geographic coordinates (EPSG:4326), model type is %d", keys[i+3])
+				}
+			case keyRasterType:
+				if keys[i+3] != rasterPixelIsArea {
+					// Tiepoint refers to the centre of cell (0, 0)
+					g.originX -= g.scaleX / 2
+					g.originY += g.scaleY / 2
+				}
+			}
+		}
+	}
+
+	if f, ok := fields[tagGDALNoData]; ok {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(f.text, "\x00")), 64); err == nil {
+			g.noData = &v
+		}
+	}
+	if m := gdalScale.FindStringSubmatch(fields[tagGDALMetadata].text); m != nil {
+		if v, err := strconv.ParseFloat(m[1], 64); err == nil && v != 0 {
+			g.Scale = v
+		}
+	}
+	return nil
+}
+
+// readIFD decodes the entries of the IFD at offset.
+func (g *GeoTIFF) readIFD(offset uint64, big bool) (map[uint16]field, error) {
+	countSize, entrySize, inline := 2, 12, 4
+	if big {
+		countSize, entrySize, inline = 8, 20, 8
+	}
+
+	buf := make([]byte, countSize)
+	if _, err := g.file.ReadAt(buf, int64(offset)); err != nil {
+		return nil, fmt.Errorf("reading IFD: %w", err)
+	}
+	var n uint64
+	if big {
+		n = g.order.Uint64(buf)
+	} else {
+		n = uint64(g.order.Uint16(buf))
+	}
+
+	entries := make([]byte, n*uint64(entrySize))
+	if _, err := g.file.ReadAt(entries, int64(offset)+int64(countSize)); err != nil {
+		return nil, fmt.Errorf("reading IFD: %w", err)
+	}
+
+	fields := make(map[uint16]field, n)
+	for i := range int(n) {
+		e := entries[i*entrySize : (i+1)*entrySize]
+		tag, typ := g.order.Uint16(e), g.order.Uint16(e[2:])
+		size, known := typeSizes[typ]
+		if !known {
+			continue // Rationals and other types no DEM tag uses
+		}
+
+		var count uint64
+		var value []byte
+		if big {
+			count, value = g.order.Uint64(e[4:]), e[12:20]
+		} else {
+			count, value = uint64(g.order.Uint32(e[4:])), e[8:12]
+		}
+
+		data := value
+		if total := count * uint64(size); total > uint64(inline) {
+			var at uint64
+			if big {
+				at = g.order.Uint64(value)
+			} else {
+				at = uint64(g.order.Uint32(value))
+			}
+			data = make([]byte, total)
+			if _, err := g.file.ReadAt(data, int64(at)); err != nil {
+				return nil, fmt.Errorf("reading tag %d: %w", tag, err)
+			}
+		}
+		fields[tag] = g.decodeField(typ, data, int(count), size)
+	}
+	return fields, nil
+}
+
+func (g *GeoTIFF) decodeField(typ uint16, data []byte, count, size int) field {
+	var f field
+	for i := range count {
+		b := data[i*size:]
+		switch typ {
+		case typeASCII:
+			f.text = string(data[:count])
+			return f
+		case typeByte:
+			f.ints = append(f.ints, uint64(b[0]))
+		case typeShort, typeSShort:
+			f.ints = append(f.ints, uint64(g.order.Uint16(b)))
+		case typeLong, typeSLong:
+			f.ints = append(f.ints, uint64(g.order.Uint32(b)))
+		case typeLong8, typeSLong8:
+			f.ints = append(f.ints, g.order.Uint64(b))
+		case typeFloat:
+			f.floats = append(f.floats, float64(math.Float32frombits(g.order.Uint32(b))))
+		case typeDouble:
+			f.floats = append(f.floats, math.Float64frombits(g.order.Uint64(b)))
+		}
+	}
+	return f
+}
@@ -0,0 +1,134 @@
+package elevation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/protomaps/go-pmtiles/pmtiles"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/inspect"
+)
+
+// Terrarium is a PMTiles raster archive of terrarium-encoded PNG tiles, as
+// published by Mapzen/AWS Terrain Tiles:
+//
+//	meters = R*256 + G + B/256 - 32768
+//
+// Lookups use the archive's maximum zoom and the nearest pixel.
+type Terrarium struct {
+	Path string
+
+	archive *inspect.Archive
+	zoom    uint8
+
+	mu    sync.Mutex
+	cache map[uint64]image.Image // Decoded tiles by tile ID; nil image = missing tile
+}
+
+// OpenTerrarium opens a terrarium PMTiles archive.
+func OpenTerrarium(path string) (*Terrarium, error) {
+	a, err := inspect.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if a.Header.TileType != pmtiles.Png {
+		a.Close()
+		return nil, fmt.Errorf("%s: terrarium tiles must be PNG", path)
+	}
+	return &Terrarium{Path: path, archive: a, zoom: a.Header.MaxZoom, cache: make(map[uint64]image.Image)}, nil
+}
+
+// Close releases the underlying file.
+func (t *Terrarium) Close() error {
+	return t.archive.Close()
+}
+
+// Bounds implements Source.
+func (t *Terrarium) Bounds() orb.Bound {
+	h := t.archive.Header
+	return orb.Bound{
+		Min: orb.Point{float64(h.MinLonE7) / 1e7, float64(h.MinLatE7) / 1e7},
+		Max: orb.Point{float64(h.MaxLonE7) / 1e7, float64(h.MaxLatE7) / 1e7},
+	}
+}
+
+// Elevation implements Source.
+func (t *Terrarium) Elevation(lon, lat float64) (float64, error) {
+	if math.Abs(lat) > 85.0511 {
+		return 0, ErrNoData
+	}
+
+	// Web Mercator tile coordinates at the archive's maximum zoom
+	n := math.Exp2(float64(t.zoom))
+	x := (lon + 180) / 360 * n
+	latRad := lat * math.Pi / 180
+	y := (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n
+	tx, ty := math.Floor(x), math.Floor(y)
+	if tx < 0 || ty < 0 || tx >= n || ty >= n {
+		return 0, ErrNoData
+	}
+
+	img, err := t.tile(uint32(tx), uint32(ty))
+	if err != nil {
+		return 0, err
+	}
+	if img == nil {
+		return 0, ErrNoData
+	}
+
+	b := img.Bounds()
+	px := b.Min.X + min(int((x-tx)*float64(b.Dx())), b.Dx()-1)
+	py := b.Min.Y + min(int((y-ty)*float64(b.Dy())), b.Dy()-1)
+	c := color.NRGBAModel.Convert(img.At(px, py)).(color.NRGBA)
+	if c.A == 0 {
+		return 0, ErrNoData
+	}
+	return float64(c.R)*256 + float64(c.G) + float64(c.B)/256 - 32768, nil
+}
+
+// tile returns the decoded tile, or nil if the archive does not have it.
+func (t *Terrarium) tile(x, y uint32) (image.Image, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := pmtiles.ZxyToID(t.zoom, x, y)
+	if img, ok := t.cache[id]; ok {
+		return img, nil
+	}
+
+	data, err := t.archive.Tile(t.zoom, x, y)
+	if err != nil {
+		return nil, fmt.Errorf("reading tile %d/%d/%d: %w", t.zoom, x, y, err)
+	}
+
+	var img image.Image
+	if data != nil {
+		if t.archive.Header.TileCompression == pmtiles.Gzip {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("tile %d/%d/%d: %w", t.zoom, x, y, err)
+			}
+			data, err = io.ReadAll(zr)
+			if err != nil {
+				return nil, fmt.Errorf("tile %d/%d/%d: %w", t.zoom, x, y, err)
+			}
+		}
+		if img, err = png.Decode(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("decoding tile %d/%d/%d: %w", t.zoom, x, y, err)
+		}
+	}
+
+	if len(t.cache) >= maxCachedBlocks {
+		clear(t.cache)
+	}
+	t.cache[id] = img
+	return img, nil
+}
@@ -0,0 +1,180 @@
+package airspace_test
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/elevation"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/plan"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/query"
+)
+
+// The test DEM covers [-123,37,-121,38.5] in 0.125° cells holding
+// 20*col + 2*row meters, with nodata in the north-west corner cell. The
+// terrarium archive is one z9 tile of the same grid around San Francisco.
+const (
+	testDEMTIFF      = "testdata/dem/bay_area.tif"
+	testDEMTerrarium = "testdata/dem/bay_area_terrarium.pmtiles"
+)
+
+// cellCentre returns the position of the test DEM cell's centre.
+func cellCentre(col, row int) (lon, lat float64) {
+	return -123 + 0.125*(float64(col)+0.5), 38.5 - 0.125*(float64(row)+0.5)
+}
+
+func TestElevationSources(t *testing.T) {
+	tiff, err := elevation.OpenGeoTIFF(testDEMTIFF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tiff.Close()
+	terrarium, err := elevation.OpenTerrarium(testDEMTerrarium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer terrarium.Close()
+
+	lon, lat := cellCentre(5, 7)
+	lon2, _ := cellCentre(6, 7)
+	tests := []struct {
+		name     string
+		src      elevation.Source
+		lon, lat float64
+		want     float64 // NaN = ErrNoData
+	}{
+		{"tiff cell centre", tiff, lon, lat, 114},
+		{"tiff bilinear", tiff, (lon + lon2) / 2, lat, 124},
+		{"tiff last cell", tiff, -121.01, 37.01, 322},
+		{"tiff nodata", tiff, -122.99, 38.49, math.NaN()},
+		{"tiff outside", tiff, -120, 37.5, math.NaN()},
+		{"terrarium", terrarium, -122.4, 37.7, 92},
+		{"terrarium outside tile", terrarium, lon2, lat, math.NaN()},
+		{"multi falls back", elevation.Multi{terrarium, tiff}, lon2, lat, 134},
+	}
+	for _, tt := range tests {
+		got, err := tt.src.Elevation(tt.lon, tt.lat)
+		if math.IsNaN(tt.want) {
+			if !errors.Is(err, elevation.ErrNoData) {
+				t.Errorf("%s: got %v, %v; want ErrNoData", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: got %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	profile, err := elevation.ExtractProfile(tiff, orb.LineString{{-122.9375, 37.6875}, {-121.0625, 37.6875}}, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *profile.MinMeters != 12 || *profile.MaxMeters != 312 || profile.Missing != 0 {
+		t.Errorf("profile min/max = %v/%v, missing %d; want 12/312", *profile.MinMeters, *profile.MaxMeters, profile.Missing)
+	}
+	if n := len(profile.Points); n < 150 || profile.Points[n-1].DistanceMeters != profile.LengthMeters {
+		t.Errorf("profile has %d points ending at %v m of %v", n, profile.Points[n-1].DistanceMeters, profile.LengthMeters)
+	}
+}
+
+func TestQueryWithTerrain(t *testing.T) {
+	dem, err := elevation.Open("testdata/dem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dem.Close()
+
+	// 500 ft MSL in the 200 ft UAS grid, over ~362 ft of ground
+	alt := query.Altitude{Feet: 500, Ref: query.RefMSL}
+	idx := loadPlanIndex(t)
+	if res := idx.Point(-122.33, 37.62, &alt); !res.Summary.AboveUASCeiling || res.GroundFt != nil {
+		t.Errorf("without terrain: above ceiling = %v, ground = %v", res.Summary.AboveUASCeiling, res.GroundFt)
+	}
+
+	idx.SetElevation(dem)
+	res := idx.Point(-122.33, 37.62, &alt)
+	if res.Summary.AboveUASCeiling || res.GroundFt == nil || math.Abs(*res.GroundFt-362) > 1 {
+		t.Errorf("with terrain: above ceiling = %v, ground = %v; want 138 ft AGL", res.Summary.AboveUASCeiling, res.GroundFt)
+	}
+
+	// 1500 ft MSL across R-2531 is under the 700 AGL Class E floor over ~1050 ft of ground
+	p, err := plan.Load(writePlan(t, "survey.gpx", planR2531GPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt = query.Altitude{Feet: 1500, Ref: query.RefMSL}
+	report, err := plan.Check(idx, p, plan.Options{Altitude: &alt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := report.Summary; len(s.Controlled) != 0 || len(s.SUA) != 1 || report.Segments[0].GroundFt == nil {
+		t.Errorf("controlled = %v, sua = %v, ground = %v; want no Class E", s.Controlled, s.SUA, report.Segments[0].GroundFt)
+	}
+}
+
+// ridge is terrain at sea level but for 3000 m blocks.
+type ridge []orb.Bound
+
+func (r ridge) Elevation(lon, lat float64) (float64, error) {
+	for _, b := range r {
+		if b.Contains(orb.Point{lon, lat}) {
+			return 3000, nil
+		}
+	}
+	return 0, nil
+}
+
+func (r ridge) Bounds() orb.Bound { return orb.Bound{Min: orb.Point{-1, -1}, Max: orb.Point{2, 2}} }
+func (r ridge) Close() error      { return nil }
+
+func TestQueryGroundPerFeature(t *testing.T) {
+	box := func(minLon, minLat, maxLon, maxLat float64) orb.Polygon {
+		return orb.Bound{Min: orb.Point{minLon, minLat}, Max: orb.Point{maxLon, maxLat}}.ToPolygon()
+	}
+	classE := func(name string, p orb.Polygon) *geojson.Feature {
+		f := geojson.NewFeature(p)
+		f.Properties = geojson.Properties{"NAME": name, "CLASS": "E", "LOWER_VAL": 700.0, "LOWER_CODE": "AGL", "UPPER_VAL": 17999.0, "UPPER_CODE": "MSL"}
+		return f
+	}
+	boundary := geojson.NewFeatureCollection()
+	boundary.Append(classE("valley", box(0, 0, 0.4, 1)))
+	boundary.Append(classE("mountains", box(0.6, 0, 1, 1)))
+	uas := geojson.NewFeatureCollection()
+	grid := geojson.NewFeature(box(0.1, 0.4, 0.3, 0.6))
+	grid.Properties = geojson.Properties{"CEILING": 400.0}
+	uas.Append(grid)
+
+	idx := query.New(query.Source{Dataset: "boundary", Features: boundary}, query.Source{Dataset: "uas", Features: uas})
+	idx.SetElevation(ridge{{Min: orb.Point{0.5, 0}, Max: orb.Point{1, 1}}})
+
+	// 1000 ft MSL from the valley over the mountains: the ridge sets the
+	// highest ground, but the valley's limits stay at sea level
+	alt := query.Altitude{Feet: 1000, Ref: query.RefMSL}
+	res := idx.Query(query.Request{Geometry: orb.LineString{{0.05, 0.5}, {0.95, 0.5}}, Altitude: &alt})
+	if res.GroundFt == nil || math.Abs(*res.GroundFt-3000*elevation.FeetPerMeter) > 1 {
+		t.Fatalf("ground = %v, want the ridge", res.GroundFt)
+	}
+	var names []string
+	for _, m := range res.Matches {
+		names = append(names, m.Name)
+		if m.Name == "valley" && (m.GroundFt == nil || *m.GroundFt != 0) {
+			t.Errorf("valley checked at %v ft, want 0", m.GroundFt)
+		}
+	}
+	if !slices.Contains(names, "valley") || slices.Contains(names, "mountains") {
+		t.Errorf("matches = %v, want the valley Class E only", names)
+	}
+	if !res.Summary.AboveUASCeiling {
+		t.Error("1000 ft over the valley grid is not above its 400 ft AGL ceiling")
+	}
+
+	// A peak inside an area, away from its rings and centre, is sampled
+	idx.SetElevation(ridge{{Min: orb.Point{0.27, 0.27}, Max: orb.Point{0.33, 0.33}}})
+	if ft, ok := idx.Ground(box(0.1, 0.1, 0.9, 0.9)); !ok || ft < 3000 {
+		t.Errorf("area ground = %v, %v; want the peak", ft, ok)
+	}
+}
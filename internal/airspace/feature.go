@@ -0,0 +1,97 @@
+package airspace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FeatureIDKeys lists the FAA property names that carry a stable feature identifier,
+// in order of preference.
+var FeatureIDKeys = []string{"GLOBAL_ID", "GLOBALID", "GlobalID", "IDENT"}
+
+// rowIDKeys are ArcGIS database row numbers. They are renumbered on
+// re-export, so they only name a feature that has no stable identifier.
+var rowIDKeys = []string{"OBJECTID", "OBJECT_ID"}
+
+// FeatureNameKeys lists the FAA property names that carry a human-readable name.
+var FeatureNameKeys = []string{"NAME", "Name", "name", "APT1_NAME", "IDENT"}
+
+// FeatureID returns the stable FAA identifier for a feature, failing that its
+// row number, or "" if neither is present.
+func FeatureID(props map[string]any) string {
+	if id := firstProp(props, FeatureIDKeys); id != "" {
+		return id
+	}
+	return firstProp(props, rowIDKeys)
+}
+
+// FeatureName returns a human-readable name for a feature, or "" if none is present.
+func FeatureName(props map[string]any) string {
+	return firstProp(props, FeatureNameKeys)
+}
+
+func firstProp(props map[string]any, keys []string) string {
+	for _, k := range keys {
+		v, ok := props[k]
+		if !ok || v == nil {
+			continue
+		}
+		switch t := v.(type) {
+		case string:
+			if t != "" {
+				return t
+			}
+		case float64:
+			return fmt.Sprintf("%.0f", t)
+		default:
+			return fmt.Sprint(t)
+		}
+	}
+	return ""
+}
+
+// DecodeFeatures walks a GeoJSON FeatureCollection token by token and calls fn
+// with the raw JSON of each feature, without holding the whole document in memory.
+func DecodeFeatures(r io.Reader, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(bufio.NewReaderSize(r, 1<<20))
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("parsing geojson: expected object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("parsing geojson: %w", err)
+		}
+		key, _ := tok.(string)
+
+		if key != "features" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("parsing geojson %q: %w", key, err)
+			}
+			continue
+		}
+
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return fmt.Errorf("parsing geojson: features is not an array")
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return fmt.Errorf("parsing geojson feature: %w", err)
+			}
+			if err := fn(raw); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("parsing geojson: %w", err)
+		}
+	}
+
+	return nil
+}
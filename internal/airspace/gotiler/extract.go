@@ -0,0 +1,104 @@
+package gotiler
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/maptile"
+	"github.com/protomaps/go-pmtiles/pmtiles"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/inspect"
+)
+
+// ErrNoTiles is returned by Extract when no tile of the source falls in
+// the requested area and zoom range.
+var ErrNoTiles = errors.New("no tiles in extract area")
+
+// ExtractResult describes an extracted archive.
+type ExtractResult struct {
+	Tiles   int // Addressed tiles copied
+	MinZoom int
+	MaxZoom int
+}
+
+// Extract writes the tiles of the PMTiles archive at src that intersect
+// bound, up to maxZoom, to a new archive at dst. Tiles are copied as
+// stored, without re-encoding, so features run to the edge of the border
+// tiles; the metadata (vector_layers, name) is kept, with zooms capped.
+// The source must hold gzip-compressed MVT tiles, as both tilers write.
+func Extract(src, dst string, bound orb.Bound, maxZoom int) (*ExtractResult, error) {
+	a, err := inspect.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	h := a.Header
+	if h.TileType != pmtiles.Mvt || h.TileCompression != pmtiles.Gzip {
+		return nil, fmt.Errorf("%s: only gzip-compressed MVT archives can be extracted", src)
+	}
+
+	minZoom := int(h.MinZoom)
+	maxZoom = min(maxZoom, int(h.MaxZoom))
+	srcBound := orb.Bound{
+		Min: orb.Point{float64(h.MinLonE7) / 1e7, float64(h.MinLatE7) / 1e7},
+		Max: orb.Point{float64(h.MaxLonE7) / 1e7, float64(h.MaxLatE7) / 1e7},
+	}
+	if !srcBound.Intersects(bound) {
+		return nil, ErrNoTiles
+	}
+	bound = clipBound(bound, srcBound)
+
+	store := newMemStore()
+	defer store.Close()
+	for z := minZoom; z <= maxZoom; z++ {
+		zoom := maptile.Zoom(z)
+		nw := maptile.At(orb.Point{bound.Min[0], bound.Max[1]}, zoom)
+		se := maptile.At(orb.Point{bound.Max[0], bound.Min[1]}, zoom)
+		for x := nw.X; x <= se.X; x++ {
+			for y := nw.Y; y <= se.Y; y++ {
+				data, err := a.Tile(uint8(z), x, y)
+				if err != nil {
+					return nil, fmt.Errorf("reading tile %d/%d/%d: %w", z, x, y, err)
+				}
+				if data == nil {
+					continue
+				}
+				if err := store.Put(pmtiles.ZxyToID(uint8(z), x, y), data); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if store.Len() == 0 {
+		return nil, ErrNoTiles
+	}
+
+	ts := &tileset{name: fmt.Sprint(a.Metadata["name"]), minZoom: minZoom, maxZoom: maxZoom, bounds: bound}
+	if layers, ok := a.Metadata["vector_layers"].([]any); ok {
+		for _, l := range layers {
+			if m, ok := l.(map[string]any); ok {
+				m = maps.Clone(m)
+				if z, ok := m["maxzoom"].(float64); ok && int(z) > maxZoom {
+					m["maxzoom"] = maxZoom
+				}
+				l = m
+			}
+			ts.vectorLayers = append(ts.vectorLayers, l)
+		}
+	}
+	if err := writePMTiles(dst, store, ts); err != nil {
+		return nil, err
+	}
+	return &ExtractResult{Tiles: store.Len(), MinZoom: minZoom, MaxZoom: maxZoom}, nil
+}
+
+// clipBound returns the intersection of two overlapping bounds.
+func clipBound(a, b orb.Bound) orb.Bound {
+	return orb.Bound{
+		Min: orb.Point{max(a.Min[0], b.Min[0]), max(a.Min[1], b.Min[1])},
+		Max: orb.Point{min(a.Max[0], b.Max[0]), min(a.Max[1], b.Max[1])},
+	}
+}
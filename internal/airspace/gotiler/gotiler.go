@@ -25,26 +25,60 @@
 // The current architecture (GitHub Actions → PMTiles → R2 → CDN → Browser)
 // is optimal because FAA data updates weekly (AIRAC cycle) and PMTiles
 // enables efficient CDN caching with Range request support.
+//
+// # Memory
+//
+// By default the whole GeoJSON and every generated tile are held in memory.
+// Setting TileConfig.MemoryBudgetMB switches to streaming mode (stream.go):
+// features are decoded incrementally and spilled to disk, each zoom level is
+// processed in spatial chunks that fit the budget, and encoded tiles are
+// spilled to disk until the PMTiles file is written. This is what lets the
+// obstacles and UAS layers tile on small CI runners.
+//
+// # Concurrency
+//
+// Tiles are encoded on a pool of GoTiler.Workers goroutines (workers.go).
+// Results are collected and written in tile ID order, so the archive is
+// byte-identical to a sequential run.
+//
+// # Feature Dropping
+//
+// Every TileConfig field is honoured, as tippecanoe would (limits.go).
+// Below a layer's max zoom, points are thinned by ReduceRate per zoom level.
+// Tiles over MaxTileFeatures or MaxTileBytes (tippecanoe's defaults unless
+// NoFeatureLimit or NoTileSizeLimit) drop features until they fit: evenly
+// across the tile, or with DropDensest where features are closest together.
+// Include, Exclude and Rename filter attributes, and Simplification sets the
+// tolerance in tile units.
+//
+// # Combined Archives
+//
+// TileCombined writes several datasets into one archive, each tile holding
+// one MVT layer per dataset, so the web map needs a single PMTiles source.
 package gotiler
 
 import (
-	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/encoding/mvt"
 	"github.com/paulmach/orb/geojson"
 	"github.com/paulmach/orb/maptile"
 	"github.com/paulmach/orb/simplify"
-	"github.com/protomaps/go-pmtiles/pmtiles"
 
 	"github.com/joeblew999/ubuntu-website/internal/airspace"
 )
 
 // GoTiler implements airspace.Tiler using pure Go libraries.
-type GoTiler struct{}
+type GoTiler struct {
+	// Workers is the number of tiles encoded concurrently
+	// (0 = GOMAXPROCS, 1 = sequential). Output does not depend on it.
+	Workers int
+}
 
 // New creates a new GoTiler.
 func New() *GoTiler {
@@ -61,101 +95,216 @@ func (g *GoTiler) Available() bool {
 	return true
 }
 
+// layerSpec is one MVT layer in an archive with its clamped zoom range.
+type layerSpec struct {
+	name    string
+	minZoom int
+	maxZoom int
+	config  airspace.TileConfig // Limits, dropping, simplification and attributes
+}
+
+// covers reports whether the layer is generated at zoom z.
+func (l layerSpec) covers(z uint32) bool {
+	return int(z) >= l.minZoom && int(z) <= l.maxZoom
+}
+
 // Tile converts GeoJSON to PMTiles using pure Go.
 func (g *GoTiler) Tile(inputPath, outputPath string, config airspace.TileConfig) error {
-	// Read GeoJSON
-	data, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("reading geojson: %w", err)
-	}
+	return g.tile([]airspace.LayerInput{{InputPath: inputPath, Config: config}}, outputPath, config.Layer)
+}
 
-	fc, err := geojson.UnmarshalFeatureCollection(data)
-	if err != nil {
-		return fmt.Errorf("parsing geojson: %w", err)
+// TileCombined converts several GeoJSON files into one PMTiles archive with
+// one MVT layer per input. Each layer keeps its own zoom range; the archive
+// covers their union.
+func (g *GoTiler) TileCombined(inputs []airspace.LayerInput, outputPath string) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no inputs to tile")
 	}
+	name := strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath))
+	return g.tile(inputs, outputPath, name)
+}
 
-	// Determine zoom range
-	minZoom := config.MinZoom
-	maxZoom := config.MaxZoom
-	if minZoom < 0 {
-		minZoom = 0
+// tile generates an archive from one or more layers. name is the archive
+// name written to the PMTiles metadata.
+func (g *GoTiler) tile(inputs []airspace.LayerInput, outputPath, name string) error {
+	specs := make([]layerSpec, len(inputs))
+	var budgetMB int
+	for i, in := range inputs {
+		// Determine zoom range
+		minZoom := in.Config.MinZoom
+		maxZoom := in.Config.MaxZoom
+		if minZoom < 0 {
+			minZoom = 0
+		}
+		if maxZoom < 0 || maxZoom > 14 {
+			maxZoom = 14
+		}
+		specs[i] = layerSpec{name: in.Config.Layer, minZoom: minZoom, maxZoom: maxZoom, config: in.Config}
+
+		// A combined archive streams if any layer asks for it
+		budgetMB = max(budgetMB, in.Config.MemoryBudgetMB)
 	}
-	if maxZoom < 0 || maxZoom > 14 {
-		maxZoom = 14
+
+	ts := newTileset(name, specs)
+
+	if budgetMB > 0 {
+		return g.tileStreaming(inputs, outputPath, ts, budgetMB)
 	}
 
-	// Generate tiles for each zoom level
-	tiles := make(map[maptile.Tile][]byte)
+	// Read GeoJSON
+	layers := make([][]*geojson.Feature, len(inputs))
+	for i, in := range inputs {
+		data, err := os.ReadFile(in.InputPath)
+		if err != nil {
+			return fmt.Errorf("reading geojson: %w", err)
+		}
+
+		fc, err := geojson.UnmarshalFeatureCollection(data)
+		if err != nil {
+			return fmt.Errorf("parsing geojson: %w", err)
+		}
 
-	for z := minZoom; z <= maxZoom; z++ {
-		zoomTiles := g.generateZoomLevel(fc, uint32(z), config.Layer)
-		for tile, data := range zoomTiles {
-			tiles[tile] = data
+		for _, f := range fc.Features {
+			if f.Geometry != nil {
+				ts.add(i, f.Geometry.Bound(), f.Properties)
+				layers[i] = append(layers[i], f)
+			}
 		}
 	}
 
+	// Generate tiles for all zoom levels on one worker pool, so the few
+	// heavy low-zoom tiles run alongside the many light high-zoom ones
+	var jobs []tileJob
+	for z := ts.minZoom; z <= ts.maxZoom; z++ {
+		jobs = append(jobs, g.generateZoomLevel(specs, layers, uint32(z))...)
+	}
+
+	store := newMemStore()
+	err := g.encodeTiles(jobs, func(tile maptile.Tile, data []byte) error {
+		return store.Put(tileID(tile), data)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Write PMTiles
-	return writePMTiles(outputPath, tiles, config)
+	return writePMTiles(outputPath, store, ts)
 }
 
-// generateZoomLevel creates MVT tiles for a specific zoom level.
-func (g *GoTiler) generateZoomLevel(fc *geojson.FeatureCollection, zoom uint32, layerName string) map[maptile.Tile][]byte {
-	result := make(map[maptile.Tile][]byte)
-
+// generateZoomLevel groups each layer's features by the tiles they touch at a
+// zoom level, after point dropping. Jobs are returned in tile ID order, with
+// layers in input order.
+func (g *GoTiler) generateZoomLevel(specs []layerSpec, layers [][]*geojson.Feature, zoom uint32) []tileJob {
 	// Group features by tile
-	tileFeatures := make(map[maptile.Tile][]*geojson.Feature)
+	tileFeatures := make(map[maptile.Tile][]layerFeatures)
 
-	for _, f := range fc.Features {
-		// Get all tiles that intersect this feature's bounds
-		bounds := f.Geometry.Bound()
-		tiles := tilesInBounds(bounds, zoom)
+	for i, features := range layers {
+		if !specs[i].covers(zoom) {
+			continue
+		}
+		var seq [2]int // Points and other geometries are ranked separately
+		for _, f := range features {
+			point := isPoint(f.Geometry)
+			rank := featureRank(nextSeq(&seq, point))
+			if !specs[i].keeps(point, rank, zoom) {
+				continue
+			}
+
+			// Get all tiles that intersect this feature's bounds
+			bounds := f.Geometry.Bound()
+			tiles := tilesInBounds(bounds, zoom)
+
+			for _, tile := range tiles {
+				byLayer, ok := tileFeatures[tile]
+				if !ok {
+					byLayer = make([]layerFeatures, len(layers))
+					tileFeatures[tile] = byLayer
+				}
+				byLayer[i].features = append(byLayer[i].features, f)
+				byLayer[i].ranks = append(byLayer[i].ranks, rank)
+			}
+		}
+	}
 
-		for _, tile := range tiles {
-			tileFeatures[tile] = append(tileFeatures[tile], f)
+	jobs := make([]tileJob, 0, len(tileFeatures))
+	for tile, byLayer := range tileFeatures {
+		job := tileJob{tile: tile}
+		for i, l := range byLayer {
+			if len(l.features) > 0 {
+				l.spec = &specs[i]
+				job.layers = append(job.layers, l)
+			}
 		}
+		jobs = append(jobs, job)
 	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return tileID(jobs[i].tile) < tileID(jobs[j].tile)
+	})
+
+	return jobs
+}
+
+// createMVT creates an MVT tile with one layer per non-empty job layer,
+// within the layers' tile limits.
+func (g *GoTiler) createMVT(job tileJob) []byte {
+	return g.limit(job)
+}
 
-	// Generate MVT for each tile
-	for tile, features := range tileFeatures {
-		mvtData := g.createMVT(tile, features, layerName)
-		if len(mvtData) > 0 {
-			result[tile] = mvtData
+// encode encodes a tile's layers as gzipped MVT (nil if all are empty).
+func (g *GoTiler) encode(job tileJob) []byte {
+	var layers mvt.Layers
+	for _, l := range job.layers {
+		if layer := createLayer(job.tile, l); layer != nil {
+			layers = append(layers, layer)
 		}
 	}
 
-	return result
+	// Skip empty tiles
+	if len(layers) == 0 {
+		return nil
+	}
+
+	// Encode to protobuf
+	data, err := mvt.MarshalGzipped(layers)
+	if err != nil {
+		return nil
+	}
+
+	return data
 }
 
-// createMVT creates an MVT tile from features.
-func (g *GoTiler) createMVT(tile maptile.Tile, features []*geojson.Feature, layerName string) []byte {
+// createLayer filters attributes, clips, simplifies and projects features
+// into an MVT layer. Returns nil if no feature survives.
+func createLayer(tile maptile.Tile, l layerFeatures) *mvt.Layer {
 	// Create a FeatureCollection for this tile
 	fc := geojson.NewFeatureCollection()
 	tileBound := tile.Bound()
 
-	for _, f := range features {
+	for _, f := range l.features {
 		// Skip if geometry doesn't intersect tile
 		if !f.Geometry.Bound().Intersects(tileBound) {
 			continue
 		}
 
-		// Clone the feature to avoid modifying the original
-		clone := geojson.NewFeature(f.Geometry)
-		for k, v := range f.Properties {
+		// Deep-clone the feature: Simplify, Clip and ProjectToTile modify
+		// geometry in place, and the same feature is reused across tiles and zooms.
+		clone := geojson.NewFeature(orb.Clone(f.Geometry))
+		for k, v := range l.spec.config.FilterProperties(f.Properties) {
 			clone.Properties[k] = v
 		}
 		fc.Append(clone)
 	}
 
-	// Skip empty tiles
+	// Skip empty layers
 	if len(fc.Features) == 0 {
 		return nil
 	}
 
 	// Create layer from FeatureCollection
-	layer := mvt.NewLayer(layerName, fc)
+	layer := mvt.NewLayer(l.spec.name, fc)
 
 	// Simplify based on zoom level - less detail at lower zooms
-	epsilon := simplifyEpsilon(tile.Z)
+	epsilon := l.spec.simplifyEpsilon(tile.Z)
 	if epsilon > 0 {
 		layer.Simplify(simplify.DouglasPeucker(epsilon))
 	}
@@ -174,14 +323,7 @@ func (g *GoTiler) createMVT(tile maptile.Tile, features []*geojson.Feature, laye
 		return nil
 	}
 
-	// Encode to protobuf
-	layers := mvt.Layers{layer}
-	data, err := mvt.MarshalGzipped(layers)
-	if err != nil {
-		return nil
-	}
-
-	return data
+	return layer
 }
 
 // tilesInBounds returns all tiles at a zoom level that intersect a bounding box.
@@ -209,8 +351,9 @@ func tilesInBounds(bounds orb.Bound, zoom uint32) []maptile.Tile {
 	return tiles
 }
 
-// simplifyEpsilon returns the simplification tolerance for a zoom level.
-func simplifyEpsilon(zoom maptile.Zoom) float64 {
+// defaultSimplifyEpsilon returns the simplification tolerance in degrees
+// for a zoom level when the layer does not configure one.
+func defaultSimplifyEpsilon(zoom maptile.Zoom) float64 {
 	// Higher zoom = less simplification
 	switch {
 	case zoom >= 14:
@@ -228,124 +371,3 @@ func simplifyEpsilon(zoom maptile.Zoom) float64 {
 
 // Ensure GoTiler implements Tiler.
 var _ airspace.Tiler = (*GoTiler)(nil)
-
-// writePMTiles writes tiles to a PMTiles file using the official go-pmtiles library.
-// PMTiles v3 format: https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md
-func writePMTiles(path string, tiles map[maptile.Tile][]byte, config airspace.TileConfig) error {
-	if len(tiles) == 0 {
-		return fmt.Errorf("no tiles to write")
-	}
-
-	// Convert tiles to PMTiles entries, sorted by tile ID
-	type tileEntry struct {
-		id   uint64
-		data []byte
-	}
-	var tileEntries []tileEntry
-
-	for t, data := range tiles {
-		// Use pmtiles ZxyToID for proper tile ID encoding
-		id := pmtiles.ZxyToID(uint8(t.Z), uint32(t.X), uint32(t.Y))
-		tileEntries = append(tileEntries, tileEntry{id: id, data: data})
-	}
-
-	// Sort by tile ID for clustered output
-	sort.Slice(tileEntries, func(i, j int) bool {
-		return tileEntries[i].id < tileEntries[j].id
-	})
-
-	// Build directory entries and collect tile data
-	var entries []pmtiles.EntryV3
-	var tileData bytes.Buffer
-	currentOffset := uint64(0)
-
-	for _, te := range tileEntries {
-		entries = append(entries, pmtiles.EntryV3{
-			TileID:    te.id,
-			Offset:    currentOffset,
-			Length:    uint32(len(te.data)),
-			RunLength: 1,
-		})
-		tileData.Write(te.data)
-		currentOffset += uint64(len(te.data))
-	}
-
-	// Build metadata JSON
-	metadata := map[string]any{
-		"name":        config.Layer,
-		"format":      "pbf",
-		"compression": "gzip",
-		"minzoom":     config.MinZoom,
-		"maxzoom":     config.MaxZoom,
-	}
-	metadataBytes, err := pmtiles.SerializeMetadata(metadata, pmtiles.Gzip)
-	if err != nil {
-		return fmt.Errorf("serializing metadata: %w", err)
-	}
-
-	// Serialize the root directory with gzip compression
-	rootDirBytes := pmtiles.SerializeEntries(entries, pmtiles.Gzip)
-
-	// Calculate offsets
-	headerSize := uint64(pmtiles.HeaderV3LenBytes)
-	rootDirOffset := headerSize
-	rootDirLen := uint64(len(rootDirBytes))
-	metadataOffset := rootDirOffset + rootDirLen
-	metadataLen := uint64(len(metadataBytes))
-	tileDataOffset := metadataOffset + metadataLen
-	tileDataLen := uint64(tileData.Len())
-
-	// Build header
-	header := pmtiles.HeaderV3{
-		SpecVersion:         3,
-		RootOffset:          rootDirOffset,
-		RootLength:          rootDirLen,
-		MetadataOffset:      metadataOffset,
-		MetadataLength:      metadataLen,
-		LeafDirectoryOffset: 0, // No leaf directories for small files
-		LeafDirectoryLength: 0,
-		TileDataOffset:      tileDataOffset,
-		TileDataLength:      tileDataLen,
-		AddressedTilesCount: uint64(len(entries)),
-		TileEntriesCount:    uint64(len(entries)),
-		TileContentsCount:   uint64(len(entries)), // No deduplication
-		Clustered:           true,
-		InternalCompression: pmtiles.Gzip,
-		TileCompression:     pmtiles.Gzip,
-		TileType:            pmtiles.Mvt,
-		MinZoom:             uint8(config.MinZoom),
-		MaxZoom:             uint8(config.MaxZoom),
-	}
-
-	// Serialize header
-	headerBytes := pmtiles.SerializeHeader(header)
-
-	// Write file
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	// Write header
-	if _, err := f.Write(headerBytes); err != nil {
-		return err
-	}
-
-	// Write root directory
-	if _, err := f.Write(rootDirBytes); err != nil {
-		return err
-	}
-
-	// Write metadata
-	if _, err := f.Write(metadataBytes); err != nil {
-		return err
-	}
-
-	// Write tile data
-	if _, err := f.Write(tileData.Bytes()); err != nil {
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,190 @@
+package gotiler
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/maptile"
+	"github.com/protomaps/go-pmtiles/pmtiles"
+)
+
+// maxShrinks bounds the re-encodes of a tile over its byte limit.
+const maxShrinks = 8
+
+// densityZoom is how many zooms below a tile feature positions are compared
+// when dropping the densest features (256 cells across the tile).
+const densityZoom = 8
+
+// goldenFrac spreads feature ranks evenly over [0, 1) in input order.
+const goldenFrac = 0.6180339887498949
+
+// featureRank returns the rank of a layer's n-th point (or n-th other
+// feature). Lower ranks are kept longer: point dropping keeps ranks below a
+// per-zoom fraction, so a point shown at one zoom is shown at every higher
+// zoom, and the first point is never dropped.
+func featureRank(n int) float64 {
+	return math.Mod(float64(n)*goldenFrac, 1)
+}
+
+// nextSeq returns the feature's position among the layer's points or other
+// features, and advances the count.
+func nextSeq(seq *[2]int, point bool) int {
+	i := 0
+	if point {
+		i = 1
+	}
+	n := seq[i]
+	seq[i]++
+	return n
+}
+
+// isPoint reports whether a geometry is subject to the point drop rate.
+func isPoint(g orb.Geometry) bool {
+	switch g.(type) {
+	case orb.Point, orb.MultiPoint:
+		return true
+	}
+	return false
+}
+
+// keeps reports whether the layer shows a feature at zoom z. Below the
+// layer's max zoom, points are thinned by the drop rate per zoom level
+// (tippecanoe -r); lines and polygons are always kept.
+func (l layerSpec) keeps(point bool, rank float64, z uint32) bool {
+	rate := l.config.DropRate()
+	if !point || int(z) >= l.maxZoom || rate <= 1 {
+		return true
+	}
+	return rank < math.Pow(rate, -float64(l.maxZoom-int(z)))
+}
+
+// simplifyEpsilon returns the layer's simplification tolerance in degrees.
+// A configured tolerance is in tile units (4096 per tile, tippecanoe -S).
+func (l layerSpec) simplifyEpsilon(zoom maptile.Zoom) float64 {
+	switch s := l.config.Simplification; {
+	case s < 0:
+		return 0
+	case s > 0:
+		return s * 360 / (mvt.DefaultExtent * math.Exp2(float64(zoom)))
+	}
+	return defaultSimplifyEpsilon(zoom)
+}
+
+// limit applies the layers' per-tile feature and byte limits, dropping
+// features until the tile fits, and returns the encoded tile. Features are
+// counted by bounds, before clipping. A tile whose single remaining feature
+// is still too large is kept rather than lost.
+func (g *GoTiler) limit(job tileJob) []byte {
+	job.layers = slices.Clone(job.layers)
+	for i, l := range job.layers {
+		if n := l.spec.config.TileFeaturesLimit(); n > 0 && len(l.features) > n {
+			job.layers[i] = l.thin(n, job.tile)
+		}
+	}
+
+	data := g.encode(job)
+	limit := job.bytesLimit()
+	for range maxShrinks {
+		if limit == 0 || len(data) <= limit || job.features() <= 1 {
+			break
+		}
+		keep := 0.9 * float64(limit) / float64(len(data))
+		for i, l := range job.layers {
+			job.layers[i] = l.thin(max(int(float64(len(l.features))*keep), 1), job.tile)
+		}
+		data = g.encode(job)
+	}
+	return data
+}
+
+// bytesLimit is the tightest byte limit of the tile's layers (0 = none).
+func (j tileJob) bytesLimit() int {
+	limit := 0
+	for _, l := range j.layers {
+		if n := l.spec.config.TileBytesLimit(); n > 0 && (limit == 0 || n < limit) {
+			limit = n
+		}
+	}
+	return limit
+}
+
+func (j tileJob) features() int {
+	n := 0
+	for _, l := range j.layers {
+		n += len(l.features)
+	}
+	return n
+}
+
+// thin keeps n features, in their original order. The highest ranks are
+// dropped first or, with DropDensest, the features closest to a neighbour.
+func (l layerFeatures) thin(n int, tile maptile.Tile) layerFeatures {
+	if n >= len(l.features) {
+		return l
+	}
+
+	order := make([]int, len(l.features))
+	for i := range order {
+		order[i] = i
+	}
+	if l.spec.config.DropDensest {
+		gaps := l.gaps(tile)
+		sort.SliceStable(order, func(a, b int) bool {
+			ia, ib := order[a], order[b]
+			if gaps[ia] != gaps[ib] {
+				return gaps[ia] < gaps[ib]
+			}
+			return l.ranks[ia] > l.ranks[ib]
+		})
+	} else {
+		sort.SliceStable(order, func(a, b int) bool {
+			return l.ranks[order[a]] > l.ranks[order[b]]
+		})
+	}
+
+	drop := make([]bool, len(l.features))
+	for _, i := range order[:len(l.features)-n] {
+		drop[i] = true
+	}
+	out := layerFeatures{spec: l.spec}
+	for i, f := range l.features {
+		if !drop[i] {
+			out.features = append(out.features, f)
+			out.ranks = append(out.ranks, l.ranks[i])
+		}
+	}
+	return out
+}
+
+// gaps returns, per feature, the distance from the centre of its bounds to
+// the nearest neighbouring centre along the Hilbert curve, in cells of
+// densityZoom zooms below the tile. Small gaps mark dense areas.
+func (l layerFeatures) gaps(tile maptile.Tile) []float64 {
+	type cell struct {
+		id   uint64
+		x, y float64
+		idx  int
+	}
+	z := tile.Z + densityZoom
+	cells := make([]cell, len(l.features))
+	for i, f := range l.features {
+		t := maptile.At(f.Geometry.Bound().Center(), z)
+		cells[i] = cell{id: pmtiles.ZxyToID(uint8(z), t.X, t.Y), x: float64(t.X), y: float64(t.Y), idx: i}
+	}
+	sort.SliceStable(cells, func(a, b int) bool { return cells[a].id < cells[b].id })
+
+	gaps := make([]float64, len(cells))
+	for i := range gaps {
+		gaps[i] = math.Inf(1)
+	}
+	for i := 1; i < len(cells); i++ {
+		a, b := cells[i-1], cells[i]
+		d := math.Max(math.Abs(a.x-b.x), math.Abs(a.y-b.y))
+		gaps[a.idx] = math.Min(gaps[a.idx], d)
+		gaps[b.idx] = math.Min(gaps[b.idx], d)
+	}
+	return gaps
+}
@@ -0,0 +1,269 @@
+package gotiler
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/protomaps/go-pmtiles/pmtiles"
+)
+
+// rootDirTarget is the maximum serialized root directory size. The spec
+// recommends the header and root directory fit in the first 16 KiB so
+// clients can fetch both with a single range request.
+const rootDirTarget = 16384 - pmtiles.HeaderV3LenBytes
+
+// tileset describes the archive contents for the header and metadata.
+type tileset struct {
+	name    string
+	minZoom int
+	maxZoom int
+	layers  []*layerStats
+	bounds  orb.Bound
+	empty   bool
+
+	vectorLayers []any // Copied from a source archive instead of layers (see Extract)
+}
+
+// layerStats describes one MVT layer for the vector_layers metadata.
+type layerStats struct {
+	spec   layerSpec
+	fields map[string]string // attribute name -> MVT field type (String, Number, Boolean)
+}
+
+// newTileset returns an empty tileset covering the zoom range of all layers.
+func newTileset(name string, specs []layerSpec) *tileset {
+	ts := &tileset{name: name, empty: true, minZoom: specs[0].minZoom, maxZoom: specs[0].maxZoom}
+	for _, spec := range specs {
+		ts.minZoom = min(ts.minZoom, spec.minZoom)
+		ts.maxZoom = max(ts.maxZoom, spec.maxZoom)
+		ts.layers = append(ts.layers, &layerStats{spec: spec, fields: make(map[string]string)})
+	}
+	return ts
+}
+
+// add extends the tileset bounds and a layer's attribute list with a
+// feature, after the layer's attribute rules.
+func (ts *tileset) add(layer int, bound orb.Bound, props geojson.Properties) {
+	if ts.empty {
+		ts.bounds = bound
+		ts.empty = false
+	} else {
+		ts.bounds = ts.bounds.Union(bound)
+	}
+
+	l := ts.layers[layer]
+	fields := l.fields
+	for k, v := range l.spec.config.FilterProperties(props) {
+		typ := "String"
+		switch v.(type) {
+		case float64, int, int64:
+			typ = "Number"
+		case bool:
+			typ = "Boolean"
+		}
+		if existing, ok := fields[k]; ok && existing != typ {
+			typ = "Mixed"
+		}
+		fields[k] = typ
+	}
+}
+
+// writePMTiles writes tiles to a PMTiles file using the official go-pmtiles library.
+// PMTiles v3 format: https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md
+//
+// Identical tiles (e.g. open ocean or fully-covered interior tiles) are
+// stored once: repeated contents reuse the first offset, and consecutive
+// tile IDs with the same contents collapse into one entry with a RunLength.
+// Directories larger than rootDirTarget are split into leaf directories.
+func writePMTiles(path string, store *tileStore, ts *tileset) error {
+	if store.Len() == 0 {
+		return fmt.Errorf("no tiles to write")
+	}
+
+	// Sort by tile ID for clustered output
+	refs, err := store.Sorted()
+	if err != nil {
+		return err
+	}
+
+	// Build directory entries with deduplication; offsets are relative to the tile data section
+	var entries []pmtiles.EntryV3
+	var unique []tileRef // tiles whose contents are written, in output order
+	seen := make(map[[sha256.Size]byte]uint64)
+	var tileDataLen uint64
+
+	for _, ref := range refs {
+		data, err := store.Read(ref)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+
+		if offset, ok := seen[sum]; ok {
+			last := &entries[len(entries)-1]
+			if last.Offset == offset && last.TileID+uint64(last.RunLength) == ref.id && last.RunLength < math.MaxUint32 {
+				last.RunLength++
+			} else {
+				entries = append(entries, pmtiles.EntryV3{TileID: ref.id, Offset: offset, Length: ref.length, RunLength: 1})
+			}
+			continue
+		}
+
+		seen[sum] = tileDataLen
+		entries = append(entries, pmtiles.EntryV3{TileID: ref.id, Offset: tileDataLen, Length: ref.length, RunLength: 1})
+		unique = append(unique, ref)
+		tileDataLen += uint64(ref.length)
+	}
+
+	// Build metadata JSON
+	vectorLayers := ts.vectorLayers
+	for _, l := range ts.layers {
+		vectorLayers = append(vectorLayers, map[string]any{
+			"id":      l.spec.name,
+			"minzoom": l.spec.minZoom,
+			"maxzoom": l.spec.maxZoom,
+			"fields":  l.fields,
+		})
+	}
+	metadata := map[string]any{
+		"name":          ts.name,
+		"format":        "pbf",
+		"compression":   "gzip",
+		"minzoom":       ts.minZoom,
+		"maxzoom":       ts.maxZoom,
+		"vector_layers": vectorLayers,
+	}
+	if !ts.empty {
+		metadata["bounds"] = fmt.Sprintf("%f,%f,%f,%f", ts.bounds.Min[0], ts.bounds.Min[1], ts.bounds.Max[0], ts.bounds.Max[1])
+	}
+	metadataBytes, err := pmtiles.SerializeMetadata(metadata, pmtiles.Gzip)
+	if err != nil {
+		return fmt.Errorf("serializing metadata: %w", err)
+	}
+
+	// Serialize the root directory (and leaf directories if needed) with gzip compression
+	rootDirBytes, leafDirBytes := optimizeDirectories(entries, rootDirTarget)
+
+	// Calculate offsets: header, root dir, metadata, leaf dirs, tile data
+	headerSize := uint64(pmtiles.HeaderV3LenBytes)
+	rootDirOffset := headerSize
+	rootDirLen := uint64(len(rootDirBytes))
+	metadataOffset := rootDirOffset + rootDirLen
+	metadataLen := uint64(len(metadataBytes))
+	leafDirOffset := metadataOffset + metadataLen
+	leafDirLen := uint64(len(leafDirBytes))
+	tileDataOffset := leafDirOffset + leafDirLen
+
+	// Build header
+	header := pmtiles.HeaderV3{
+		SpecVersion:         3,
+		RootOffset:          rootDirOffset,
+		RootLength:          rootDirLen,
+		MetadataOffset:      metadataOffset,
+		MetadataLength:      metadataLen,
+		LeafDirectoryOffset: leafDirOffset,
+		LeafDirectoryLength: leafDirLen,
+		TileDataOffset:      tileDataOffset,
+		TileDataLength:      tileDataLen,
+		AddressedTilesCount: uint64(len(refs)),
+		TileEntriesCount:    uint64(len(entries)),
+		TileContentsCount:   uint64(len(unique)),
+		Clustered:           true,
+		InternalCompression: pmtiles.Gzip,
+		TileCompression:     pmtiles.Gzip,
+		TileType:            pmtiles.Mvt,
+		MinZoom:             uint8(ts.minZoom),
+		MaxZoom:             uint8(ts.maxZoom),
+		CenterZoom:          uint8(ts.minZoom),
+	}
+	if !ts.empty {
+		center := ts.bounds.Center()
+		header.MinLonE7 = toE7(ts.bounds.Min[0])
+		header.MinLatE7 = toE7(ts.bounds.Min[1])
+		header.MaxLonE7 = toE7(ts.bounds.Max[0])
+		header.MaxLatE7 = toE7(ts.bounds.Max[1])
+		header.CenterLonE7 = toE7(center[0])
+		header.CenterLatE7 = toE7(center[1])
+	}
+
+	// Serialize header
+	headerBytes := pmtiles.SerializeHeader(header)
+
+	// Write file
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriterSize(f, 1<<20)
+
+	for _, section := range [][]byte{headerBytes, rootDirBytes, metadataBytes, leafDirBytes} {
+		if _, err := w.Write(section); err != nil {
+			return err
+		}
+	}
+
+	// Write unique tile data in tile ID order
+	for _, ref := range unique {
+		data, err := store.Read(ref)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// optimizeDirectories serializes entries into a root directory no larger than
+// target bytes, moving entries into leaf directories when they do not fit.
+// Mirrors the strategy used by the pmtiles CLI: leaves start at 4096 entries
+// and grow by 20% until the root (leaf pointers only) fits.
+func optimizeDirectories(entries []pmtiles.EntryV3, target int) (root, leaves []byte) {
+	if len(entries) < 16384 {
+		root = pmtiles.SerializeEntries(entries, pmtiles.Gzip)
+		if len(root) <= target {
+			return root, nil
+		}
+	}
+
+	leafSize := max(float64(len(entries))/3500, 4096)
+	for {
+		root, leaves = buildRootLeaves(entries, int(leafSize))
+		if len(root) <= target {
+			return root, leaves
+		}
+		leafSize *= 1.2
+	}
+}
+
+// buildRootLeaves splits entries into leaf directories of leafSize entries.
+// Root entries with RunLength 0 point at leaf directories (offsets relative
+// to the leaf directory section).
+func buildRootLeaves(entries []pmtiles.EntryV3, leafSize int) (root, leaves []byte) {
+	var rootEntries []pmtiles.EntryV3
+
+	for i := 0; i < len(entries); i += leafSize {
+		leaf := pmtiles.SerializeEntries(entries[i:min(i+leafSize, len(entries))], pmtiles.Gzip)
+		rootEntries = append(rootEntries, pmtiles.EntryV3{
+			TileID:    entries[i].TileID,
+			Offset:    uint64(len(leaves)),
+			Length:    uint32(len(leaf)),
+			RunLength: 0,
+		})
+		leaves = append(leaves, leaf...)
+	}
+
+	return pmtiles.SerializeEntries(rootEntries, pmtiles.Gzip), leaves
+}
+
+func toE7(deg float64) int32 {
+	return int32(math.Round(deg * 1e7))
+}
@@ -0,0 +1,97 @@
+package gotiler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// tileRef locates one encoded tile inside a tileStore.
+type tileRef struct {
+	id     uint64 // PMTiles tile ID (Hilbert order)
+	offset uint64 // Offset in the store, not the output file
+	length uint32
+}
+
+// tileStore accumulates encoded tiles until the PMTiles file is written.
+// In-memory stores keep tile bytes in a buffer; spill stores append them to a
+// temp file so only the small tileRef index stays in RAM.
+type tileStore struct {
+	refs []tileRef
+	size uint64
+
+	mem bytes.Buffer // used when file == nil
+
+	file *os.File // spill file (streaming mode)
+	w    *bufio.Writer
+}
+
+// newMemStore returns a store that keeps all tile data in memory.
+func newMemStore() *tileStore {
+	return &tileStore{}
+}
+
+// newSpillStore returns a store that appends tile data to a temp file in dir.
+func newSpillStore(dir string) (*tileStore, error) {
+	f, err := os.CreateTemp(dir, "tiles-*.bin")
+	if err != nil {
+		return nil, fmt.Errorf("creating tile spill file: %w", err)
+	}
+	return &tileStore{file: f, w: bufio.NewWriterSize(f, 1<<20)}, nil
+}
+
+// Put appends an encoded tile.
+func (s *tileStore) Put(id uint64, data []byte) error {
+	if s.file != nil {
+		if _, err := s.w.Write(data); err != nil {
+			return fmt.Errorf("spilling tile: %w", err)
+		}
+	} else {
+		s.mem.Write(data)
+	}
+	s.refs = append(s.refs, tileRef{id: id, offset: s.size, length: uint32(len(data))})
+	s.size += uint64(len(data))
+	return nil
+}
+
+// Len returns the number of stored tiles.
+func (s *tileStore) Len() int {
+	return len(s.refs)
+}
+
+// Sorted returns the tile refs ordered by tile ID.
+func (s *tileStore) Sorted() ([]tileRef, error) {
+	if s.w != nil {
+		if err := s.w.Flush(); err != nil {
+			return nil, fmt.Errorf("flushing tile spill file: %w", err)
+		}
+	}
+	refs := append([]tileRef(nil), s.refs...)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].id < refs[j].id })
+	return refs, nil
+}
+
+// Read returns the bytes of one tile. Sorted must be called first for spill stores.
+func (s *tileStore) Read(ref tileRef) ([]byte, error) {
+	if s.file == nil {
+		return s.mem.Bytes()[ref.offset : ref.offset+uint64(ref.length)], nil
+	}
+	buf := make([]byte, ref.length)
+	if _, err := s.file.ReadAt(buf, int64(ref.offset)); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading spilled tile: %w", err)
+	}
+	return buf, nil
+}
+
+// Close releases the spill file, if any.
+func (s *tileStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	s.file.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,251 @@
+package gotiler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/protomaps/go-pmtiles/pmtiles"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+// decodedFeatureFactor estimates decoded orb feature size relative to raw GeoJSON.
+// Coordinates go from ~10 bytes of text to 8-byte floats inside slices, and
+// property maps add per-entry overhead; 4x is a conservative rule of thumb.
+const decodedFeatureFactor = 4
+
+// featureRef locates one raw GeoJSON feature in the feature spill file.
+type featureRef struct {
+	bound  orb.Bound
+	offset int64
+	length int32
+	layer  int32 // index into the archive's layers
+	point  bool  // Subject to the point drop rate
+	seq    int32 // Position among the layer's points or other features
+}
+
+// featureSpill holds every input feature as raw JSON on disk, with only
+// bounds and offsets kept in memory.
+type featureSpill struct {
+	file *os.File
+	size int64
+	refs []featureRef
+}
+
+// tileStreaming generates tiles with bounded memory.
+//
+// Features are decoded one at a time and spilled to disk as raw JSON. Each
+// zoom level is then processed in chunks of tiles (in Hilbert order, so chunks
+// are spatially compact) whose features fit within the memory budget. Encoded
+// tiles are spilled to a second file and copied into the PMTiles archive at
+// the end. Output is byte-identical to the in-memory path.
+func (g *GoTiler) tileStreaming(inputs []airspace.LayerInput, outputPath string, ts *tileset, budgetMB int) error {
+	spillDir, err := os.MkdirTemp(filepath.Dir(outputPath), ".gotiler-*")
+	if err != nil {
+		return fmt.Errorf("creating spill dir: %w", err)
+	}
+	defer os.RemoveAll(spillDir)
+
+	file, err := os.CreateTemp(spillDir, "features-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("creating feature spill file: %w", err)
+	}
+	features := &featureSpill{file: file}
+	defer features.file.Close()
+
+	// Layers are spilled one after another, so feature indexes are ordered
+	// by layer and then by input order
+	for i, in := range inputs {
+		if err := features.spill(in.InputPath, int32(i), ts); err != nil {
+			return err
+		}
+	}
+
+	store, err := newSpillStore(spillDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	budget := int64(budgetMB) << 20
+	specs := make([]layerSpec, len(ts.layers))
+	for i, l := range ts.layers {
+		specs[i] = l.spec
+	}
+
+	for z := ts.minZoom; z <= ts.maxZoom; z++ {
+		if err := g.streamZoomLevel(features, store, uint32(z), specs, budget); err != nil {
+			return fmt.Errorf("zoom %d: %w", z, err)
+		}
+	}
+
+	return writePMTiles(outputPath, store, ts)
+}
+
+// streamZoomLevel generates one zoom level in memory-bounded chunks.
+func (g *GoTiler) streamZoomLevel(features *featureSpill, store *tileStore, zoom uint32, specs []layerSpec, budget int64) error {
+	rank := func(idx int32) float64 {
+		return featureRank(int(features.refs[idx].seq))
+	}
+
+	// Group feature indexes by tile (indexes stay in layer and input order)
+	tileFeatures := make(map[maptile.Tile][]int32)
+	for i, ref := range features.refs {
+		if !specs[ref.layer].covers(zoom) || !specs[ref.layer].keeps(ref.point, rank(int32(i)), zoom) {
+			continue
+		}
+		for _, tile := range tilesInBounds(ref.bound, zoom) {
+			tileFeatures[tile] = append(tileFeatures[tile], int32(i))
+		}
+	}
+
+	tiles := make([]maptile.Tile, 0, len(tileFeatures))
+	for t := range tileFeatures {
+		tiles = append(tiles, t)
+	}
+	sort.Slice(tiles, func(i, j int) bool {
+		return tileID(tiles[i]) < tileID(tiles[j])
+	})
+
+	var chunk []maptile.Tile
+	inChunk := make(map[int32]bool)
+	var chunkBytes int64
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		decoded, err := features.load(inChunk)
+		if err != nil {
+			return err
+		}
+		jobs := make([]tileJob, len(chunk))
+		for i, tile := range chunk {
+			job := tileJob{tile: tile}
+			last := int32(-1)
+			for _, idx := range tileFeatures[tile] {
+				if layer := features.refs[idx].layer; layer != last {
+					job.layers = append(job.layers, layerFeatures{spec: &specs[layer]})
+					last = layer
+				}
+				l := &job.layers[len(job.layers)-1]
+				l.features = append(l.features, decoded[idx])
+				l.ranks = append(l.ranks, rank(idx))
+			}
+			jobs[i] = job
+		}
+		err = g.encodeTiles(jobs, func(tile maptile.Tile, data []byte) error {
+			return store.Put(tileID(tile), data)
+		})
+		if err != nil {
+			return err
+		}
+		chunk = chunk[:0]
+		clear(inChunk)
+		chunkBytes = 0
+		return nil
+	}
+
+	for _, tile := range tiles {
+		var added int64
+		for _, idx := range tileFeatures[tile] {
+			if !inChunk[idx] {
+				added += int64(features.refs[idx].length) * decodedFeatureFactor
+			}
+		}
+
+		// A single oversized tile is processed on its own
+		if len(chunk) > 0 && chunkBytes+added > budget {
+			if err := flush(); err != nil {
+				return err
+			}
+			added = 0
+			for _, idx := range tileFeatures[tile] {
+				added += int64(features.refs[idx].length) * decodedFeatureFactor
+			}
+		}
+
+		for _, idx := range tileFeatures[tile] {
+			inChunk[idx] = true
+		}
+		chunk = append(chunk, tile)
+		chunkBytes += added
+	}
+
+	return flush()
+}
+
+// spill streams a GeoJSON FeatureCollection, appending each feature's raw
+// JSON to the spill file and recording its bounds in the spill and tileset.
+func (s *featureSpill) spill(inputPath string, layer int32, ts *tileset) error {
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("reading geojson: %w", err)
+	}
+	defer in.Close()
+
+	w := bufio.NewWriterSize(io.NewOffsetWriter(s.file, s.size), 1<<20)
+
+	first := len(s.refs)
+	var seq [2]int
+	err = airspace.DecodeFeatures(in, func(raw json.RawMessage) error {
+		f, err := geojson.UnmarshalFeature(raw)
+		if err != nil {
+			return fmt.Errorf("parsing feature %d: %w", len(s.refs)-first, err)
+		}
+		if f.Geometry == nil {
+			return nil
+		}
+		if _, err := w.Write(raw); err != nil {
+			return fmt.Errorf("spilling feature: %w", err)
+		}
+		bound := f.Geometry.Bound()
+		point := isPoint(f.Geometry)
+		ts.add(int(layer), bound, f.Properties)
+		s.refs = append(s.refs, featureRef{
+			bound:  bound,
+			offset: s.size,
+			length: int32(len(raw)),
+			layer:  layer,
+			point:  point,
+			seq:    int32(nextSeq(&seq, point)),
+		})
+		s.size += int64(len(raw))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// load decodes the given features from the spill file.
+func (s *featureSpill) load(idxs map[int32]bool) (map[int32]*geojson.Feature, error) {
+	out := make(map[int32]*geojson.Feature, len(idxs))
+	for idx := range idxs {
+		ref := s.refs[idx]
+		buf := make([]byte, ref.length)
+		if _, err := s.file.ReadAt(buf, ref.offset); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("reading spilled feature: %w", err)
+		}
+		f, err := geojson.UnmarshalFeature(buf)
+		if err != nil {
+			return nil, fmt.Errorf("decoding spilled feature: %w", err)
+		}
+		out[idx] = f
+	}
+	return out, nil
+}
+
+// tileID returns the PMTiles Hilbert tile ID.
+func tileID(t maptile.Tile) uint64 {
+	return pmtiles.ZxyToID(uint8(t.Z), t.X, t.Y)
+}
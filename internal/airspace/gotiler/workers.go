@@ -0,0 +1,72 @@
+package gotiler
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+)
+
+// tileJob is one tile to encode and, per layer, the features whose bounds touch it.
+type tileJob struct {
+	tile   maptile.Tile
+	layers []layerFeatures
+}
+
+// layerFeatures is the input for one MVT layer of a tile.
+type layerFeatures struct {
+	spec     *layerSpec
+	features []*geojson.Feature
+	ranks    []float64 // featureRank of each feature, for dropping
+}
+
+// workers returns the effective concurrency level.
+func (g *GoTiler) workers() int {
+	if g.Workers > 0 {
+		return g.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// encodeTiles runs createMVT for every job on a pool of workers, then calls
+// put with the non-empty tiles in job order. createMVT only reads the shared
+// features (it clones geometry before modifying it), so the output is the
+// same whatever the concurrency level.
+func (g *GoTiler) encodeTiles(jobs []tileJob, put func(maptile.Tile, []byte) error) error {
+	results := make([][]byte, len(jobs))
+
+	n := min(g.workers(), len(jobs))
+	if n <= 1 {
+		for i, job := range jobs {
+			results[i] = g.createMVT(job)
+		}
+	} else {
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					results[i] = g.createMVT(jobs[i])
+				}
+			}()
+		}
+		for i := range jobs {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	for i, data := range results {
+		if len(data) == 0 {
+			continue
+		}
+		if err := put(jobs[i].tile, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
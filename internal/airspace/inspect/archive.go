@@ -0,0 +1,195 @@
+// Package inspect reads PMTiles archives to report on and compare their contents.
+//
+// Inspect summarises one archive (header, metadata, per-zoom tile counts and
+// sizes, largest tiles, per-layer feature counts) and Diff compares two
+// archives tile by tile. Both work on any PMTiles v3 file, so tippecanoe and
+// gotiler output can be checked against each other.
+//
+// Tile contents are decoded once per unique content: deduplicated tiles that
+// share an offset are counted for every tile ID they address.
+package inspect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/protomaps/go-pmtiles/pmtiles"
+)
+
+// Archive is an open PMTiles file with its directories loaded.
+type Archive struct {
+	Path     string
+	Size     int64
+	Header   pmtiles.HeaderV3
+	Metadata map[string]any
+	Entries  []pmtiles.EntryV3 // Tile entries in tile ID order, leaf directories resolved
+
+	file     *os.File
+	contents map[uint64]*content // Decoded tile contents by data offset
+}
+
+// content is what we know about one stored tile blob.
+type content struct {
+	size     uint32
+	hash     [sha256.Size]byte // Of the decompressed tile
+	features map[string]int    // Feature count per MVT layer
+}
+
+// Open reads the header, metadata and directories of a PMTiles archive.
+func Open(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	a := &Archive{Path: path, Size: info.Size(), file: f, contents: make(map[uint64]*content)}
+	if err := a.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, nil
+}
+
+// Close releases the underlying file.
+func (a *Archive) Close() error {
+	return a.file.Close()
+}
+
+func (a *Archive) load() error {
+	buf := make([]byte, pmtiles.HeaderV3LenBytes)
+	if _, err := a.file.ReadAt(buf, 0); err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	header, err := pmtiles.DeserializeHeader(buf)
+	if err != nil {
+		return fmt.Errorf("parsing header: %w", err)
+	}
+	a.Header = header
+
+	metadata, err := a.read(header.MetadataOffset, header.MetadataLength)
+	if err != nil {
+		return fmt.Errorf("reading metadata: %w", err)
+	}
+	if len(metadata) > 0 {
+		a.Metadata, err = pmtiles.DeserializeMetadata(bytes.NewReader(metadata), header.InternalCompression)
+		if err != nil {
+			return fmt.Errorf("parsing metadata: %w", err)
+		}
+	}
+
+	return pmtiles.IterateEntries(header, a.read, func(e pmtiles.EntryV3) {
+		a.Entries = append(a.Entries, e)
+	})
+}
+
+// read returns length bytes at offset, bounds-checked against the file size.
+func (a *Archive) read(offset, length uint64) ([]byte, error) {
+	if offset+length > uint64(a.Size) {
+		return nil, fmt.Errorf("range %d+%d beyond end of file (%d bytes)", offset, length, a.Size)
+	}
+	buf := make([]byte, length)
+	if _, err := a.file.ReadAt(buf, int64(offset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ReadTile returns the stored (possibly compressed) bytes of an entry's tile.
+func (a *Archive) ReadTile(e pmtiles.EntryV3) ([]byte, error) {
+	return a.read(a.Header.TileDataOffset+e.Offset, uint64(e.Length))
+}
+
+// Tile returns the stored (possibly compressed) bytes of tile z/x/y, or nil
+// if the archive has no such tile.
+func (a *Archive) Tile(z uint8, x, y uint32) ([]byte, error) {
+	id := pmtiles.ZxyToID(z, x, y)
+	i := sort.Search(len(a.Entries), func(i int) bool { return a.Entries[i].TileID > id }) - 1
+	if i < 0 || id >= a.Entries[i].TileID+uint64(max(a.Entries[i].RunLength, 1)) {
+		return nil, nil
+	}
+	return a.ReadTile(a.Entries[i])
+}
+
+// content decodes and caches the tile stored for an entry.
+func (a *Archive) content(e pmtiles.EntryV3) (*content, error) {
+	if c, ok := a.contents[e.Offset]; ok {
+		return c, nil
+	}
+
+	data, err := a.ReadTile(e)
+	if err != nil {
+		return nil, fmt.Errorf("reading tile %d: %w", e.TileID, err)
+	}
+	raw, err := decompress(data, a.Header.TileCompression)
+	if err != nil {
+		return nil, fmt.Errorf("tile %d: %w", e.TileID, err)
+	}
+
+	c := &content{size: e.Length, hash: sha256.Sum256(raw), features: make(map[string]int)}
+	if a.Header.TileType == pmtiles.Mvt {
+		layers, err := mvt.Unmarshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decoding tile %d: %w", e.TileID, err)
+		}
+		for _, l := range layers {
+			c.features[l.Name] += len(l.Features)
+		}
+	}
+
+	a.contents[e.Offset] = c
+	return c, nil
+}
+
+// eachTile calls fn for every addressed tile, expanding run-length entries.
+func (a *Archive) eachTile(fn func(id uint64, c *content) error) error {
+	for _, e := range a.Entries {
+		c, err := a.content(e)
+		if err != nil {
+			return err
+		}
+		for i := range uint64(e.RunLength) {
+			if err := fn(e.TileID+i, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decompress undoes the archive's tile compression.
+func decompress(data []byte, compression pmtiles.Compression) ([]byte, error) {
+	switch compression {
+	case pmtiles.NoCompression, pmtiles.UnknownCompression:
+		return data, nil
+	case pmtiles.Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gunzip: %w", err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unsupported tile compression %d", compression)
+	}
+}
+
+// total returns the feature count across all layers.
+func (c *content) total() int {
+	n := 0
+	for _, count := range c.features {
+		n += count
+	}
+	return n
+}
@@ -0,0 +1,122 @@
+package inspect
+
+import (
+	"sort"
+
+	"github.com/protomaps/go-pmtiles/pmtiles"
+)
+
+// DiffReport compares two archives tile by tile. A tile is changed when its
+// decompressed contents differ, so recompressing alone is not a change.
+type DiffReport struct {
+	A         string     `json:"a"`
+	B         string     `json:"b"`
+	Added     int        `json:"added"`   // In B only
+	Removed   int        `json:"removed"` // In A only
+	Changed   int        `json:"changed"`
+	Unchanged int        `json:"unchanged"`
+	Zooms     []ZoomDiff `json:"zooms"`
+}
+
+// ZoomDiff compares one zoom level.
+type ZoomDiff struct {
+	Zoom         int `json:"zoom"`
+	TilesA       int `json:"tiles_a"`
+	TilesB       int `json:"tiles_b"`
+	Added        int `json:"added"`
+	Removed      int `json:"removed"`
+	Changed      int `json:"changed"`
+	FeaturesA    int `json:"features_a"`
+	FeaturesB    int `json:"features_b"`
+	FeatureDelta int `json:"feature_delta"` // FeaturesB - FeaturesA
+}
+
+// Identical reports whether every tile matches.
+func (d *DiffReport) Identical() bool {
+	return d.Added == 0 && d.Removed == 0 && d.Changed == 0
+}
+
+// Diff opens two archives and compares them.
+func Diff(pathA, pathB string) (*DiffReport, error) {
+	a, err := Open(pathA)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	b, err := Open(pathB)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+
+	return Compare(a, b)
+}
+
+// Compare diffs two open archives.
+func Compare(a, b *Archive) (*DiffReport, error) {
+	d := &DiffReport{A: a.Path, B: b.Path}
+
+	tilesA := make(map[uint64]*content)
+	if err := a.eachTile(func(id uint64, c *content) error {
+		tilesA[id] = c
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	zooms := make(map[int]*ZoomDiff)
+	zoom := func(id uint64) *ZoomDiff {
+		z, _, _ := pmtiles.IDToZxy(id)
+		zd := zooms[int(z)]
+		if zd == nil {
+			zd = &ZoomDiff{Zoom: int(z)}
+			zooms[int(z)] = zd
+		}
+		return zd
+	}
+
+	err := b.eachTile(func(id uint64, cb *content) error {
+		zd := zoom(id)
+		zd.TilesB++
+		zd.FeaturesB += cb.total()
+
+		ca, ok := tilesA[id]
+		switch {
+		case !ok:
+			d.Added++
+			zd.Added++
+		case ca.hash != cb.hash:
+			d.Changed++
+			zd.Changed++
+		default:
+			d.Unchanged++
+		}
+		if ok {
+			zd.TilesA++
+			zd.FeaturesA += ca.total()
+			delete(tilesA, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Whatever is left in A is missing from B
+	for id, ca := range tilesA {
+		zd := zoom(id)
+		zd.TilesA++
+		zd.FeaturesA += ca.total()
+		zd.Removed++
+		d.Removed++
+	}
+
+	for _, zd := range zooms {
+		zd.FeatureDelta = zd.FeaturesB - zd.FeaturesA
+		d.Zooms = append(d.Zooms, *zd)
+	}
+	sort.Slice(d.Zooms, func(i, j int) bool { return d.Zooms[i].Zoom < d.Zooms[j].Zoom })
+
+	return d, nil
+}
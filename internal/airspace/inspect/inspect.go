@@ -0,0 +1,220 @@
+package inspect
+
+import (
+	"io"
+	"log"
+	"slices"
+	"sort"
+
+	"github.com/protomaps/go-pmtiles/pmtiles"
+)
+
+// Report summarises one archive.
+type Report struct {
+	File      string         `json:"file"`
+	SizeBytes int64          `json:"size_bytes"`
+	Header    HeaderInfo     `json:"header"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Verify    string         `json:"verify"` // "ok" or the pmtiles verify error
+	Zooms     []ZoomStats    `json:"zooms"`
+	TileSizes Percentiles    `json:"tile_sizes"`
+	Largest   []TileInfo     `json:"largest"`
+	Layers    []LayerStats   `json:"layers"`
+}
+
+// HeaderInfo is the readable part of a PMTiles v3 header.
+type HeaderInfo struct {
+	SpecVersion         int        `json:"spec_version"`
+	TileType            string     `json:"tile_type"`
+	TileCompression     string     `json:"tile_compression"`
+	InternalCompression string     `json:"internal_compression"`
+	Clustered           bool       `json:"clustered"`
+	MinZoom             int        `json:"min_zoom"`
+	MaxZoom             int        `json:"max_zoom"`
+	Bounds              [4]float64 `json:"bounds"` // [west, south, east, north]
+	Center              [3]float64 `json:"center"` // [lon, lat, zoom]
+	AddressedTiles      uint64     `json:"addressed_tiles"`
+	TileEntries         uint64     `json:"tile_entries"`
+	TileContents        uint64     `json:"tile_contents"`
+	RootDirBytes        uint64     `json:"root_dir_bytes"`
+	LeafDirBytes        uint64     `json:"leaf_dir_bytes"`
+	MetadataBytes       uint64     `json:"metadata_bytes"`
+	TileDataBytes       uint64     `json:"tile_data_bytes"`
+}
+
+// ZoomStats counts tiles at one zoom level.
+type ZoomStats struct {
+	Zoom     int   `json:"zoom"`
+	Tiles    int   `json:"tiles"`
+	Bytes    int64 `json:"bytes"` // Sum of addressed tile sizes (before deduplication)
+	Features int   `json:"features"`
+}
+
+// Percentiles of stored tile sizes in bytes.
+type Percentiles struct {
+	P50 uint32 `json:"p50"`
+	P90 uint32 `json:"p90"`
+	P99 uint32 `json:"p99"`
+	Max uint32 `json:"max"`
+}
+
+// TileInfo identifies one tile.
+type TileInfo struct {
+	Z     int    `json:"z"`
+	X     uint32 `json:"x"`
+	Y     uint32 `json:"y"`
+	Bytes uint32 `json:"bytes"`
+}
+
+// LayerStats counts one MVT layer's features over all addressed tiles.
+type LayerStats struct {
+	Name     string `json:"name"`
+	Tiles    int    `json:"tiles"`
+	Features int    `json:"features"`
+}
+
+// Inspect opens an archive and reports on it, listing the largest n tiles.
+func Inspect(path string, largest int) (*Report, error) {
+	a, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+	return a.Report(largest)
+}
+
+// Report summarises the archive, listing the largest n tiles (none if n <= 0).
+func (a *Archive) Report(largest int) (*Report, error) {
+	r := &Report{
+		File:      a.Path,
+		SizeBytes: a.Size,
+		Header:    headerInfo(a.Header),
+		Metadata:  a.Metadata,
+		Verify:    "ok",
+	}
+	if err := pmtiles.Verify(log.New(io.Discard, "", 0), a.Path); err != nil {
+		r.Verify = err.Error()
+	}
+
+	zooms := make(map[int]*ZoomStats)
+	layers := make(map[string]*LayerStats)
+	var sizes []uint32
+	var tiles []TileInfo
+
+	err := a.eachTile(func(id uint64, c *content) error {
+		z, x, y := pmtiles.IDToZxy(id)
+		zs := zooms[int(z)]
+		if zs == nil {
+			zs = &ZoomStats{Zoom: int(z)}
+			zooms[int(z)] = zs
+		}
+		zs.Tiles++
+		zs.Bytes += int64(c.size)
+		zs.Features += c.total()
+
+		for name, n := range c.features {
+			ls := layers[name]
+			if ls == nil {
+				ls = &LayerStats{Name: name}
+				layers[name] = ls
+			}
+			ls.Tiles++
+			ls.Features += n
+		}
+
+		sizes = append(sizes, c.size)
+		tiles = append(tiles, TileInfo{Z: int(z), X: x, Y: y, Bytes: c.size})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, zs := range zooms {
+		r.Zooms = append(r.Zooms, *zs)
+	}
+	sort.Slice(r.Zooms, func(i, j int) bool { return r.Zooms[i].Zoom < r.Zooms[j].Zoom })
+
+	for _, ls := range layers {
+		r.Layers = append(r.Layers, *ls)
+	}
+	sort.Slice(r.Layers, func(i, j int) bool { return r.Layers[i].Name < r.Layers[j].Name })
+
+	if len(sizes) > 0 {
+		slices.Sort(sizes)
+		r.TileSizes = Percentiles{
+			P50: percentile(sizes, 50),
+			P90: percentile(sizes, 90),
+			P99: percentile(sizes, 99),
+			Max: sizes[len(sizes)-1],
+		}
+	}
+
+	// Stable sort keeps tile ID order among equal sizes
+	sort.SliceStable(tiles, func(i, j int) bool { return tiles[i].Bytes > tiles[j].Bytes })
+	r.Largest = tiles[:max(min(largest, len(tiles)), 0)]
+
+	return r, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []uint32, p int) uint32 {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
+
+func headerInfo(h pmtiles.HeaderV3) HeaderInfo {
+	return HeaderInfo{
+		SpecVersion:         int(h.SpecVersion),
+		TileType:            tileTypeName(h.TileType),
+		TileCompression:     compressionName(h.TileCompression),
+		InternalCompression: compressionName(h.InternalCompression),
+		Clustered:           h.Clustered,
+		MinZoom:             int(h.MinZoom),
+		MaxZoom:             int(h.MaxZoom),
+		Bounds: [4]float64{
+			float64(h.MinLonE7) / 1e7, float64(h.MinLatE7) / 1e7,
+			float64(h.MaxLonE7) / 1e7, float64(h.MaxLatE7) / 1e7,
+		},
+		Center:         [3]float64{float64(h.CenterLonE7) / 1e7, float64(h.CenterLatE7) / 1e7, float64(h.CenterZoom)},
+		AddressedTiles: h.AddressedTilesCount,
+		TileEntries:    h.TileEntriesCount,
+		TileContents:   h.TileContentsCount,
+		RootDirBytes:   h.RootLength,
+		LeafDirBytes:   h.LeafDirectoryLength,
+		MetadataBytes:  h.MetadataLength,
+		TileDataBytes:  h.TileDataLength,
+	}
+}
+
+func tileTypeName(t pmtiles.TileType) string {
+	switch t {
+	case pmtiles.Mvt:
+		return "mvt"
+	case pmtiles.Png:
+		return "png"
+	case pmtiles.Jpeg:
+		return "jpg"
+	case pmtiles.Webp:
+		return "webp"
+	case pmtiles.Avif:
+		return "avif"
+	default:
+		return "unknown"
+	}
+}
+
+func compressionName(c pmtiles.Compression) string {
+	switch c {
+	case pmtiles.NoCompression:
+		return "none"
+	case pmtiles.Gzip:
+		return "gzip"
+	case pmtiles.Brotli:
+		return "br"
+	case pmtiles.Zstd:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,43 @@
+package airspace_test
+
+import (
+	"testing"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/inspect"
+)
+
+func TestInspectLargest(t *testing.T) {
+	all, err := inspect.Inspect(testReference, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(all.Largest)
+	if n == 0 || uint64(n) != all.Header.AddressedTiles {
+		t.Fatalf("largest = %d tiles, want all %d", n, all.Header.AddressedTiles)
+	}
+	for i := 1; i < n; i++ {
+		if all.Largest[i].Bytes > all.Largest[i-1].Bytes {
+			t.Fatalf("largest not sorted by size: %+v", all.Largest)
+		}
+	}
+	if all.Largest[0].Bytes != all.TileSizes.Max {
+		t.Errorf("largest tile = %d bytes, max = %d", all.Largest[0].Bytes, all.TileSizes.Max)
+	}
+
+	tests := []struct {
+		largest, want int
+	}{
+		{3, min(3, n)},
+		{0, 0},
+		{-1, 0}, // Clamped, not a panic
+	}
+	for _, tt := range tests {
+		r, err := inspect.Inspect(testReference, tt.largest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Largest) != tt.want {
+			t.Errorf("largest %d: got %d tiles, want %d", tt.largest, len(r.Largest), tt.want)
+		}
+	}
+}
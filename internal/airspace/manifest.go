@@ -2,6 +2,7 @@ package airspace
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,10 +24,12 @@ type ManifestRegion struct {
 	TilesPath     string    `json:"tiles_path"`
 	ManifestFile  string    `json:"manifest_file"`
 	DefaultLayers []string  `json:"default_layers"`
+	Version       string    `json:"version,omitempty"` // Published version (R2 manifest.json only)
+	Style         string    `json:"style,omitempty"`   // Published MapLibre style (R2 manifest.json only)
 }
 
-// ManifestUSA is the USA regional manifest structure.
-type ManifestUSA struct {
+// RegionManifest is the regional manifest structure (manifest_<region>.json).
+type RegionManifest struct {
 	Region  string                   `json:"region"`
 	Name    string                   `json:"name"`
 	Version int                      `json:"version"`
@@ -34,6 +37,10 @@ type ManifestUSA struct {
 	BBox    []float64                `json:"bbox"`
 	Layers  map[string]ManifestLayer `json:"layers"`
 	Source  ManifestSource           `json:"source"`
+
+	// Combined is the multi-layer archive holding every layer (each under
+	// its pmtiles_layer), if one has been built.
+	Combined string `json:"combined,omitempty"`
 }
 
 // ManifestLayer describes a data layer.
@@ -48,23 +55,37 @@ type ManifestLayer struct {
 	DefaultVisible bool          `json:"default_visible"`
 	RenderRules    []RenderRule  `json:"render_rules"`
 	Legend         []LegendEntry `json:"legend,omitempty"`
+
+	// Validity is set for dynamic (TFR/NOTAM) layers, whose features are
+	// only in force between their EFFECTIVE_FROM and EFFECTIVE_TO.
+	Validity *LayerValidity `json:"validity,omitempty"`
+}
+
+// LayerValidity is the time window of a dynamic layer. Clients should
+// refetch the manifest after Expires.
+type LayerValidity struct {
+	Generated string `json:"generated"`      // When the layer was built
+	Expires   string `json:"expires"`        // Rebuild due: refresh interval or next start/end
+	From      string `json:"from,omitempty"` // Earliest restriction start
+	To        string `json:"to,omitempty"`   // Latest restriction end ("" = open-ended)
+	Active    int    `json:"active"`         // Restrictions in force when the manifest was written
 }
 
 // RenderRule defines how to style features.
 type RenderRule struct {
-	FilterProp  string  `json:"filter_prop,omitempty"`
-	FilterValue string  `json:"filter_value,omitempty"`
-	Fill        string  `json:"fill"`
-	Stroke      string  `json:"stroke,omitempty"`
-	Opacity     float64 `json:"opacity,omitempty"`
-	Width       float64 `json:"width,omitempty"`
-	Radius      float64 `json:"radius,omitempty"` // For points
+	FilterProp  string  `json:"filter_prop,omitempty" yaml:"filter_prop"`
+	FilterValue string  `json:"filter_value,omitempty" yaml:"filter_value"`
+	Fill        string  `json:"fill" yaml:"fill"`
+	Stroke      string  `json:"stroke,omitempty" yaml:"stroke"`
+	Opacity     float64 `json:"opacity,omitempty" yaml:"opacity"`
+	Width       float64 `json:"width,omitempty" yaml:"width"`
+	Radius      float64 `json:"radius,omitempty" yaml:"radius"` // For points
 }
 
 // LegendEntry for UI layer toggles.
 type LegendEntry struct {
-	Label string `json:"label"`
-	Color string `json:"color"`
+	Label string `json:"label" yaml:"label"`
+	Color string `json:"color" yaml:"color"`
 }
 
 // ManifestSource describes the data source.
@@ -80,16 +101,18 @@ type LayerMetrics struct {
 	Features int
 }
 
-// GenerateManifests creates both global and USA manifests.
-func GenerateManifests(dataDir, tilesDir, geoJSONDir string) error {
+// GenerateManifests creates the global manifest and the region's manifest,
+// validated against ManifestSchema, along with the schema itself and the
+// region's MapLibre style (for the local tiles under /airspace/).
+func GenerateManifests(region Region) error {
 	timestamp := time.Now().UTC().Format(time.RFC3339)
 
 	// Collect metrics for each layer
 	layerMetrics := make(map[string]LayerMetrics)
-	for _, key := range DatasetOrder {
-		ds := Datasets[key]
-		pmTilesPath := filepath.Join(tilesDir, ds.PMTiles)
-		geoJSONPath := filepath.Join(geoJSONDir, ds.GeoJSON)
+	for _, key := range region.ManifestDatasets() {
+		ds := region.Datasets[key]
+		pmTilesPath := filepath.Join(region.PMTilesDir(), ds.PMTiles)
+		geoJSONPath := filepath.Join(region.GeoJSONDir(), ds.GeoJSON)
 
 		var sizeMB float64
 		var features int
@@ -102,175 +125,125 @@ func GenerateManifests(dataDir, tilesDir, geoJSONDir string) error {
 		layerMetrics[key] = LayerMetrics{sizeMB, features}
 	}
 
-	// Create global manifest
-	globalManifest := ManifestGlobal{
-		Version: 1,
-		Updated: timestamp,
-		Regions: map[string]ManifestRegion{
-			"usa": {
-				Name:          "United States",
-				BBox:          []float64{-125, 24, -66, 50},
-				TilesPath:     "tiles",
-				ManifestFile:  FileUSAManifest,
-				DefaultLayers: []string{"boundary", "sua"},
-			},
-		},
-		Notes: map[string]string{
-			"bbox_format":    "[west, south, east, north]",
-			"tiles_path":     "Relative to /airspace/ in R2",
-			"future_regions": "europe, canada, australia, japan",
-		},
-	}
+	// Create global manifest listing every configured region
+	globalManifest := createGlobalManifest(timestamp)
 
-	// Create USA manifest with all layers
-	usaManifest := createUSAManifest(timestamp, layerMetrics)
+	// Create regional manifest with all layers
+	regionManifest := createRegionManifest(region, timestamp, layerMetrics)
+	if err := ValidateManifest(regionManifest); err != nil {
+		return fmt.Errorf("invalid %s:\n%w", region.ManifestFile(), err)
+	}
+	style := MapLibreStyle(regionManifest, StyleOptions{TilesURL: "/airspace/" + region.TilesPath})
 
 	// Ensure data directory exists
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
+	if err := os.MkdirAll(DirData, 0755); err != nil {
 		return err
 	}
 
 	// Write manifests
-	globalPath := filepath.Join(dataDir, FileManifest)
-	usaPath := filepath.Join(dataDir, FileUSAManifest)
+	globalPath := filepath.Join(DirData, FileManifest)
+	regionPath := filepath.Join(DirData, region.ManifestFile())
 
 	if err := writeJSON(globalPath, globalManifest); err != nil {
 		return err
 	}
 
-	if err := writeJSON(usaPath, usaManifest); err != nil {
+	if err := writeJSON(regionPath, regionManifest); err != nil {
 		return err
 	}
 
-	// Copy to static directory for local dev
-	staticGlobal := filepath.Join(geoJSONDir, FileManifest)
-	staticUSA := filepath.Join(geoJSONDir, FileUSAManifest)
+	stylePath := filepath.Join(DirData, region.StyleFile())
+	if err := writeJSON(stylePath, style); err != nil {
+		return err
+	}
 
-	copyFile(globalPath, staticGlobal)
-	copyFile(usaPath, staticUSA)
+	schemaPath := filepath.Join(DirData, FileManifestSchema)
+	if err := os.WriteFile(schemaPath, manifestSchemaJSON, 0644); err != nil {
+		return err
+	}
+
+	// Copy to static directory for local dev
+	if err := os.MkdirAll(DirGeoJSON, 0755); err != nil {
+		return err
+	}
+	for _, path := range []string{globalPath, regionPath, stylePath, schemaPath} {
+		copyFile(path, filepath.Join(DirGeoJSON, filepath.Base(path)))
+	}
 
 	return nil
 }
 
-func createUSAManifest(timestamp string, metrics map[string]LayerMetrics) ManifestUSA {
-	usaManifest := ManifestUSA{
-		Region:  "usa",
-		Name:    "United States",
+func createGlobalManifest(timestamp string) ManifestGlobal {
+	manifest := ManifestGlobal{
 		Version: 1,
 		Updated: timestamp,
-		BBox:    []float64{-125, 24, -66, 50},
-		Layers:  make(map[string]ManifestLayer),
-		Source: ManifestSource{
-			Authority:   "FAA",
-			URLs:        map[string]string{"adds": "https://adds-faa.opendata.arcgis.com", "udds": "https://udds-faa.opendata.arcgis.com"},
-			UpdateCycle: "28-day AIRAC",
+		Regions: make(map[string]ManifestRegion),
+		Notes: map[string]string{
+			"bbox_format": "[west, south, east, north]",
+			"tiles_path":  "Relative to /airspace/ in R2",
 		},
 	}
 
-	// Add layer definitions with render rules
-	usaManifest.Layers["boundary"] = ManifestLayer{
-		Name:           "Airspace Boundary",
-		File:           PMTilesBoundary,
-		PMTilesLayer:   LayerBoundary,
-		GeomType:       "polygon",
-		SizeMB:         metrics["boundary"].SizeMB,
-		Features:       metrics["boundary"].Features,
-		ZoomRange:      []int{4, 14},
-		DefaultVisible: true,
-		RenderRules: []RenderRule{
-			{FilterProp: "CLASS", FilterValue: "A", Fill: "#0066cc", Stroke: "#0066cc", Opacity: 0.15, Width: 1},
-			{FilterProp: "CLASS", FilterValue: "C", Fill: "#cc00cc", Stroke: "#cc00cc", Opacity: 0.2, Width: 2},
-			{FilterProp: "CLASS", FilterValue: "D", Fill: "#0099cc", Stroke: "#0099cc", Opacity: 0.15, Width: 2},
-			{FilterProp: "CLASS", FilterValue: "E", Fill: "#00cc99", Stroke: "#00cc99", Opacity: 0.1, Width: 1},
-			{FilterProp: "CLASS", FilterValue: "G", Fill: "#999999", Stroke: "#999999", Opacity: 0.05, Width: 1},
-			{Fill: "#666666", Stroke: "#666666", Opacity: 0.1, Width: 1}, // fallback
-		},
-		Legend: []LegendEntry{
-			{Label: "Class A", Color: "#0066cc"},
-			{Label: "Class C", Color: "#cc00cc"},
-			{Label: "Class D", Color: "#0099cc"},
-			{Label: "Class E", Color: "#00cc99"},
-		},
+	for _, key := range RegionKeys() {
+		r := Regions.Regions[key]
+		manifest.Regions[key] = ManifestRegion{
+			Name:          r.Name,
+			BBox:          r.BBox,
+			TilesPath:     r.TilesPath,
+			ManifestFile:  r.ManifestFile(),
+			DefaultLayers: r.DefaultLayers,
+		}
 	}
 
-	usaManifest.Layers["sua"] = ManifestLayer{
-		Name:           "Special Use Airspace",
-		File:           PMTilesSUA,
-		PMTilesLayer:   LayerSUA,
-		GeomType:       "polygon",
-		SizeMB:         metrics["sua"].SizeMB,
-		Features:       metrics["sua"].Features,
-		ZoomRange:      []int{4, 14},
-		DefaultVisible: true,
-		RenderRules: []RenderRule{
-			{FilterProp: "TYPE_CODE", FilterValue: "R", Fill: "#cc0000", Stroke: "#cc0000", Opacity: 0.3, Width: 2},
-			{FilterProp: "TYPE_CODE", FilterValue: "P", Fill: "#ff0000", Stroke: "#ff0000", Opacity: 0.4, Width: 2},
-			{FilterProp: "TYPE_CODE", FilterValue: "MOA", Fill: "#ff9900", Stroke: "#ff9900", Opacity: 0.2, Width: 1},
-			{FilterProp: "TYPE_CODE", FilterValue: "A", Fill: "#ffcc00", Stroke: "#ffcc00", Opacity: 0.2, Width: 1},
-			{FilterProp: "TYPE_CODE", FilterValue: "W", Fill: "#996600", Stroke: "#996600", Opacity: 0.15, Width: 1},
-			{Fill: "#666666", Stroke: "#666666", Opacity: 0.1, Width: 1}, // fallback
-		},
-		Legend: []LegendEntry{
-			{Label: "Restricted", Color: "#cc0000"},
-			{Label: "Prohibited", Color: "#ff0000"},
-			{Label: "MOA", Color: "#ff9900"},
-			{Label: "Alert", Color: "#ffcc00"},
-			{Label: "Warning", Color: "#996600"},
-		},
-	}
+	return manifest
+}
 
-	usaManifest.Layers["laanc"] = ManifestLayer{
-		Name:           "LAANC/UAS Facility Map",
-		File:           PMTilesUAS,
-		PMTilesLayer:   LayerUAS,
-		GeomType:       "polygon",
-		SizeMB:         metrics["uas"].SizeMB,
-		Features:       metrics["uas"].Features,
-		ZoomRange:      []int{6, 14},
-		DefaultVisible: false,
-		RenderRules: []RenderRule{
-			{Fill: "#ffff00", Stroke: "#cc9900", Opacity: 0.5, Width: 1},
-		},
-		Legend: []LegendEntry{
-			{Label: "LAANC Grid", Color: "#ffff00"},
+func createRegionManifest(region Region, timestamp string, metrics map[string]LayerMetrics) RegionManifest {
+	manifest := RegionManifest{
+		Region:  region.Key,
+		Name:    region.Name,
+		Version: 1,
+		Updated: timestamp,
+		BBox:    region.BBox,
+		Layers:  make(map[string]ManifestLayer),
+		Source: ManifestSource{
+			Authority:   region.Authority,
+			URLs:        region.SourceURLs,
+			UpdateCycle: region.UpdateCycle,
 		},
 	}
 
-	usaManifest.Layers["airports"] = ManifestLayer{
-		Name:           "Airports",
-		File:           PMTilesAirports,
-		PMTilesLayer:   LayerAirports,
-		GeomType:       "point",
-		SizeMB:         metrics["airports"].SizeMB,
-		Features:       metrics["airports"].Features,
-		ZoomRange:      []int{0, 10},
-		DefaultVisible: false,
-		RenderRules: []RenderRule{
-			{Fill: "#00ff00", Stroke: "#006600", Width: 1, Radius: 5},
-		},
-		Legend: []LegendEntry{
-			{Label: "Airport", Color: "#00ff00"},
-		},
+	if _, err := os.Stat(filepath.Join(region.PMTilesDir(), PMTilesCombined)); err == nil {
+		manifest.Combined = PMTilesCombined
 	}
 
-	usaManifest.Layers["navaids"] = ManifestLayer{
-		Name:           "Navigation Aids",
-		File:           PMTilesNavaids,
-		PMTilesLayer:   LayerNavaids,
-		GeomType:       "point",
-		SizeMB:         metrics["navaids"].SizeMB,
-		Features:       metrics["navaids"].Features,
-		ZoomRange:      []int{0, 10},
-		DefaultVisible: false,
-		RenderRules: []RenderRule{
-			{Fill: "#ff00ff", Stroke: "#660066", Width: 1, Radius: 4},
-		},
-		Legend: []LegendEntry{
-			{Label: "VOR/NDB", Color: "#ff00ff"},
-		},
+	// Add layer definitions with render rules
+	for _, key := range region.ManifestDatasets() {
+		ds := region.Datasets[key]
+		if ds.Manifest == nil {
+			continue
+		}
+		d := ds.Manifest
+		layer := ManifestLayer{
+			Name:           d.Name,
+			File:           ds.PMTiles,
+			PMTilesLayer:   ds.Layer,
+			GeomType:       d.GeomType,
+			SizeMB:         metrics[key].SizeMB,
+			Features:       metrics[key].Features,
+			ZoomRange:      d.ZoomRange,
+			DefaultVisible: d.DefaultVisible,
+			RenderRules:    d.RenderRules,
+			Legend:         d.Legend,
+		}
+		if ds.Dynamic != nil {
+			geoJSONPath := filepath.Join(region.GeoJSONDir(), ds.GeoJSON)
+			layer.Validity, _ = DynamicValidity(geoJSONPath, ds.Dynamic.RefreshInterval(), time.Now().UTC())
+		}
+		manifest.Layers[d.Key] = layer
 	}
 
-	return usaManifest
+	return manifest
 }
 
 // CountGeoJSONFeatures counts features in a GeoJSON file.
@@ -0,0 +1,310 @@
+package notam
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+// circleSegments is the number of edges used to approximate a circular TFR.
+const circleSegments = 64
+
+// AIXM 5.1 message structure, matched by local element name so the
+// namespace prefixes used by a feed do not matter.
+type aixmMessage struct {
+	Members []struct {
+		Airspace *aixmAirspace `xml:"Airspace"`
+	} `xml:"hasMember"`
+}
+
+type aixmAirspace struct {
+	ID         string          `xml:"id,attr"`
+	TimeSlices []aixmTimeSlice `xml:"timeSlice>AirspaceTimeSlice"`
+}
+
+type aixmTimeSlice struct {
+	Begin          string       `xml:"validTime>TimePeriod>beginPosition"`
+	End            aixmPosition `xml:"validTime>TimePeriod>endPosition"`
+	Interpretation string       `xml:"interpretation"`
+	Type           string       `xml:"type"`
+	Designator     string       `xml:"designator"`
+	Name           string       `xml:"name"`
+	Volumes        []aixmVolume `xml:"geometryComponent>AirspaceGeometryComponent>theAirspaceVolume>AirspaceVolume"`
+}
+
+type aixmPosition struct {
+	Value         string `xml:",chardata"`
+	Indeterminate string `xml:"indeterminatePosition,attr"`
+}
+
+type aixmVolume struct {
+	Upper    aixmValue   `xml:"upperLimit"`
+	UpperRef string      `xml:"upperLimitReference"`
+	Lower    aixmValue   `xml:"lowerLimit"`
+	LowerRef string      `xml:"lowerLimitReference"`
+	Patches  []aixmPatch `xml:"horizontalProjection>Surface>patches>PolygonPatch"`
+}
+
+type aixmValue struct {
+	Value string `xml:",chardata"`
+	UOM   string `xml:"uom,attr"`
+}
+
+type aixmPatch struct {
+	Exterior aixmRing   `xml:"exterior"`
+	Interior []aixmRing `xml:"interior"`
+}
+
+// aixmRing is either a gml:LinearRing or a gml:Ring of curve segments;
+// TFRs use circles (CircleByCenterPoint) and straight segments.
+type aixmRing struct {
+	PosList  string `xml:"LinearRing>posList"`
+	Segments struct {
+		Items []aixmSegment `xml:",any"`
+	} `xml:"Ring>curveMember>Curve>segments"`
+}
+
+type aixmSegment struct {
+	XMLName xml.Name
+	PosList string    `xml:"posList"`
+	Pos     string    `xml:"pos"`
+	Radius  aixmValue `xml:"radius"`
+}
+
+// Parse reads the Airspace features of an AIXM 5.1 message. Each volume of
+// each BASELINE or PERMDELTA time slice becomes a Restriction. GML
+// positions are latitude first (EPSG:4326 axis order).
+func Parse(r io.Reader) ([]Restriction, error) {
+	var msg aixmMessage
+	if err := xml.NewDecoder(r).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("parsing AIXM: %w", err)
+	}
+
+	var out []Restriction
+	for _, m := range msg.Members {
+		if m.Airspace == nil {
+			continue
+		}
+		a := m.Airspace
+		for _, ts := range a.TimeSlices {
+			switch ts.Interpretation {
+			case "", "BASELINE", "PERMDELTA":
+			default:
+				continue // TEMPDELTA/SNAPSHOT repeat or amend a baseline
+			}
+
+			from, to, err := ts.window()
+			if err != nil {
+				return nil, fmt.Errorf("airspace %s: %w", a.ID, err)
+			}
+			for i, v := range ts.Volumes {
+				g, err := v.geometry()
+				if err != nil {
+					return nil, fmt.Errorf("airspace %s: %w", a.ID, err)
+				}
+				lower, err := parseLimit(v.Lower, v.LowerRef)
+				if err != nil {
+					return nil, fmt.Errorf("airspace %s lower limit: %w", a.ID, err)
+				}
+				upper, err := parseLimit(v.Upper, v.UpperRef)
+				if err != nil {
+					return nil, fmt.Errorf("airspace %s upper limit: %w", a.ID, err)
+				}
+
+				id := a.ID
+				if i > 0 {
+					id = fmt.Sprintf("%s-%d", a.ID, i+1)
+				}
+				out = append(out, Restriction{
+					ID:       id,
+					NOTAM:    strings.TrimSpace(ts.Designator),
+					Name:     strings.TrimSpace(ts.Name),
+					Type:     strings.TrimSpace(ts.Type),
+					From:     from,
+					To:       to,
+					Lower:    lower,
+					Upper:    upper,
+					Geometry: g,
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (ts aixmTimeSlice) window() (from, to time.Time, err error) {
+	from, err = time.Parse(time.RFC3339, strings.TrimSpace(ts.Begin))
+	if err != nil {
+		return from, to, fmt.Errorf("invalid beginPosition %q", ts.Begin)
+	}
+	end := strings.TrimSpace(ts.End.Value)
+	if end == "" || ts.End.Indeterminate != "" {
+		return from.UTC(), to, nil // Until further notice
+	}
+	if to, err = time.Parse(time.RFC3339, end); err != nil {
+		return from, to, fmt.Errorf("invalid endPosition %q", end)
+	}
+	return from.UTC(), to.UTC(), nil
+}
+
+// parseLimit converts an AIXM vertical limit and reference (SFC, MSL, W84,
+// STD) to the FAA attribute form.
+func parseLimit(v aixmValue, ref string) (Limit, error) {
+	value := strings.ToUpper(strings.TrimSpace(v.Value))
+	switch value {
+	case "GND", "SFC":
+		return Limit{Code: "SFC"}, nil
+	case "UNL":
+		return Limit{Code: "UNLTD"}, nil
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Limit{}, fmt.Errorf("invalid value %q", v.Value)
+	}
+
+	l := Limit{Value: n, UOM: "FT", Code: "MSL"}
+	switch strings.ToUpper(v.UOM) {
+	case "FL":
+		l.UOM = "FL"
+	case "M":
+		l.Value = math.Round(n * 3.28084)
+	}
+	switch strings.ToUpper(strings.TrimSpace(ref)) {
+	case "SFC":
+		l.Code = "AGL"
+	case "STD":
+		l.UOM = "FL"
+	}
+	return l, nil
+}
+
+func (v aixmVolume) geometry() (orb.Geometry, error) {
+	var mp orb.MultiPolygon
+	for _, p := range v.Patches {
+		outer, err := p.Exterior.ring()
+		if err != nil {
+			return nil, err
+		}
+		poly := orb.Polygon{outer}
+		for _, in := range p.Interior {
+			hole, err := in.ring()
+			if err != nil {
+				return nil, err
+			}
+			hole.Reverse() // Holes wind clockwise
+			poly = append(poly, hole)
+		}
+		mp = append(mp, poly)
+	}
+
+	switch len(mp) {
+	case 0:
+		return nil, fmt.Errorf("volume has no horizontal projection")
+	case 1:
+		return mp[0], nil
+	}
+	return mp, nil
+}
+
+func (r aixmRing) ring() (orb.Ring, error) {
+	if r.PosList != "" {
+		return closeRing(parsePosList(r.PosList))
+	}
+
+	var ring orb.Ring
+	for _, s := range r.Segments.Items {
+		switch s.XMLName.Local {
+		case "CircleByCenterPoint":
+			pts, err := parsePosList(s.Pos)
+			if err != nil || len(pts) != 1 {
+				return nil, fmt.Errorf("invalid circle centre %q", s.Pos)
+			}
+			radius, err := radiusMeters(s.Radius)
+			if err != nil {
+				return nil, err
+			}
+			return circle(pts[0], radius), nil
+		case "GeodesicString", "LineStringSegment":
+			pts, err := parsePosList(s.PosList)
+			if err != nil {
+				return nil, err
+			}
+			ring = append(ring, pts...)
+		default:
+			return nil, fmt.Errorf("unsupported curve segment %s", s.XMLName.Local)
+		}
+	}
+	return closeRing(ring, nil)
+}
+
+// parsePosList reads "lat lon lat lon ..." into lon/lat points.
+func parsePosList(s string) (orb.Ring, error) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("posList has an odd number of values")
+	}
+	ring := make(orb.Ring, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		lat, err1 := strconv.ParseFloat(fields[i], 64)
+		lon, err2 := strconv.ParseFloat(fields[i+1], 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid position %q %q", fields[i], fields[i+1])
+		}
+		ring = append(ring, orb.Point{lon, lat})
+	}
+	return ring, nil
+}
+
+// closeRing closes the ring and winds it counter-clockwise (RFC 7946).
+func closeRing(ring orb.Ring, err error) (orb.Ring, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(ring) > 0 && !ring.Closed() {
+		ring = append(ring, ring[0])
+	}
+	if len(ring) < 4 {
+		return nil, fmt.Errorf("ring has %d positions", len(ring))
+	}
+	if ring.Orientation() == orb.CW {
+		ring.Reverse()
+	}
+	return ring, nil
+}
+
+func radiusMeters(v aixmValue) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(v.Value), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid radius %q", v.Value)
+	}
+	switch v.UOM {
+	case "[nmi_i]", "NM":
+		return n * 1852, nil
+	case "km", "KM":
+		return n * 1000, nil
+	case "[mi_i]", "MI":
+		return n * 1609.344, nil
+	case "[ft_i]", "FT":
+		return n * 0.3048, nil
+	case "m", "M", "":
+		return n, nil
+	}
+	return 0, fmt.Errorf("unsupported radius unit %q", v.UOM)
+}
+
+// circle approximates a circle as a counter-clockwise ring.
+func circle(centre orb.Point, meters float64) orb.Ring {
+	ring := make(orb.Ring, 0, circleSegments+1)
+	for i := range circleSegments {
+		ring = append(ring, geo.PointAtBearingAndDistance(centre, -360*float64(i)/circleSegments, meters))
+	}
+	return append(ring, ring[0])
+}
@@ -0,0 +1,185 @@
+// Package notam reads time-bounded airspace restrictions (TFRs and other
+// NOTAM areas) and turns them into GeoJSON the airspace pipeline can tile.
+//
+// Unlike the 28-day AIRAC layers, a restriction is only in force between
+// its effective times. Features carry their window in the EFFECTIVE_FROM
+// and EFFECTIVE_TO properties (RFC 3339, UTC), so tiles and queries can
+// filter by time; expired restrictions are dropped before tiling.
+//
+// Feeds are AIXM 5.1 messages (see Parse). Vertical limits are written as
+// the FAA LOWER_*/UPPER_* attribute triplets used by the static layers.
+package notam
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Feature properties written for each restriction.
+const (
+	PropNOTAM         = "NOTAM_ID"       // NOTAM number, e.g. 6/4821
+	PropEffectiveFrom = "EFFECTIVE_FROM" // RFC 3339 start
+	PropEffectiveTo   = "EFFECTIVE_TO"   // RFC 3339 end (absent = until further notice)
+	PropTFRType       = "TFR_TYPE"       // AIXM airspace type (TRA, TSA, ...)
+
+	// TypeCode is the TYPE_CODE of every restriction, alongside the SUA codes.
+	TypeCode = "TFR"
+)
+
+// Limit is a vertical bound in FAA attribute form.
+type Limit struct {
+	Value float64 // Feet, or a flight level when UOM is FL
+	UOM   string  // FT or FL
+	Code  string  // MSL, AGL, SFC or UNLTD
+}
+
+// Restriction is one volume of a time-bounded restriction.
+type Restriction struct {
+	ID       string       // Stable feature ID (AIXM gml:id)
+	NOTAM    string       // NOTAM number
+	Name     string       // Description, e.g. "SAN FRANCISCO, CA VIP MOVEMENT"
+	Type     string       // AIXM airspace type
+	From     time.Time    // Effective from
+	To       time.Time    // Effective to (zero = until further notice)
+	Lower    Limit        // Floor
+	Upper    Limit        // Ceiling
+	Geometry orb.Geometry // Polygon or MultiPolygon
+}
+
+// Active reports whether the restriction is in force at t.
+func (r Restriction) Active(t time.Time) bool {
+	return !t.Before(r.From) && !r.Expired(t)
+}
+
+// Expired reports whether the restriction has ended by t.
+func (r Restriction) Expired(t time.Time) bool {
+	return !r.To.IsZero() && !t.Before(r.To)
+}
+
+// Current drops restrictions that have expired by now and, with a non-zero
+// lookahead, those that start after now+lookahead.
+func Current(rs []Restriction, now time.Time, lookahead time.Duration) []Restriction {
+	var out []Restriction
+	for _, r := range rs {
+		if r.Expired(now) {
+			continue
+		}
+		if lookahead > 0 && r.From.After(now.Add(lookahead)) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Window summarises when a set of restrictions applies.
+type Window struct {
+	From       time.Time `json:"from"`        // Earliest start
+	To         time.Time `json:"to"`          // Latest end (zero if any is open-ended)
+	NextChange time.Time `json:"next_change"` // First start or end after now (zero = none)
+	Active     int       `json:"active"`      // Restrictions in force now
+}
+
+// Validity returns the window covered by rs as seen at now.
+func Validity(rs []Restriction, now time.Time) Window {
+	var w Window
+	openEnded := false
+	for i, r := range rs {
+		if i == 0 || r.From.Before(w.From) {
+			w.From = r.From
+		}
+		if r.To.IsZero() {
+			openEnded = true
+		} else if r.To.After(w.To) {
+			w.To = r.To
+		}
+		if r.Active(now) {
+			w.Active++
+		}
+		for _, t := range []time.Time{r.From, r.To} {
+			if t.After(now) && (w.NextChange.IsZero() || t.Before(w.NextChange)) {
+				w.NextChange = t
+			}
+		}
+	}
+	if openEnded {
+		w.To = time.Time{}
+	}
+	return w
+}
+
+// Feature converts the restriction to a GeoJSON feature.
+func (r Restriction) Feature() *geojson.Feature {
+	f := geojson.NewFeature(r.Geometry)
+	f.Properties = geojson.Properties{
+		"GLOBAL_ID":       r.ID,
+		PropNOTAM:         r.NOTAM,
+		"NAME":            r.Name,
+		"TYPE_CODE":       TypeCode,
+		PropTFRType:       r.Type,
+		PropEffectiveFrom: r.From.UTC().Format(time.RFC3339),
+	}
+	if !r.To.IsZero() {
+		f.Properties[PropEffectiveTo] = r.To.UTC().Format(time.RFC3339)
+	}
+	setLimit(f.Properties, "LOWER", r.Lower)
+	setLimit(f.Properties, "UPPER", r.Upper)
+	return f
+}
+
+func setLimit(props geojson.Properties, prefix string, l Limit) {
+	props[prefix+"_CODE"] = l.Code
+	if l.Code == "SFC" || l.Code == "UNLTD" {
+		return
+	}
+	props[prefix+"_VAL"] = l.Value
+	props[prefix+"_UOM"] = l.UOM
+}
+
+// FeatureCollection converts restrictions to GeoJSON, ordered by start time.
+func FeatureCollection(rs []Restriction) *geojson.FeatureCollection {
+	sorted := slices.Clone(rs)
+	slices.SortStableFunc(sorted, func(a, b Restriction) int { return a.From.Compare(b.From) })
+
+	fc := geojson.NewFeatureCollection()
+	for _, r := range sorted {
+		fc.Append(r.Feature())
+	}
+	return fc
+}
+
+// FeatureWindow reads a feature's effective window. ok is false for
+// features without EFFECTIVE_FROM (static airspace, always in force).
+func FeatureWindow(props map[string]any) (from, to time.Time, ok bool) {
+	s, _ := props[PropEffectiveFrom].(string)
+	if s == "" {
+		return from, to, false
+	}
+	from, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return from, to, false
+	}
+	if s, _ := props[PropEffectiveTo].(string); s != "" {
+		if to, err = time.Parse(time.RFC3339, s); err != nil {
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
+// ParseTime parses a query time: RFC 3339, "2006-01-02T15:04" (UTC) or "now".
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if s == "now" {
+		return now, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want RFC 3339, e.g. 2026-10-17T14:00:00Z)", s)
+}
@@ -10,23 +10,40 @@ import (
 // PipelineOptions configures the pipeline.
 type PipelineOptions struct {
 	Force     bool
+	Resume    bool   // Validate and tile even if the sync found nothing new (a previous run failed after syncing)
 	TilerName string // "auto", "tippecanoe", "gotiler"
+	Region    string // Region key ("" = default region)
+	Combined  bool   // Also build the combined multi-layer archive
 	Verbose   bool
+
+	Repair       bool    // Repair fixable GeoJSON issues before tiling
+	MaxErrorRate float64 // Invalid feature fraction that fails the pipeline (0 = any invalid feature, <0 = never)
 }
 
 // PipelineResult contains the outcome of a pipeline run.
 type PipelineResult struct {
 	SyncResult *SyncResult
+	Validation *ValidationReport
 	TileCount  int
 	Skipped    bool // True if no changes and not forced
 }
 
-// Pipeline runs the full sync → tile → manifest pipeline.
+// Pipeline runs the full sync → validate → tile → manifest pipeline.
+// The validation report is saved in the region's data dir even when
+// validation fails the run. After a successful sync the result is returned
+// with any later error, so callers know whether new data is left unbuilt.
+// Unless forced, only datasets whose GeoJSON is newer than their tiles are
+// retiled, so a resumed run picks up where a failed one stopped.
 func Pipeline(opts PipelineOptions, tiler Tiler) (*PipelineResult, error) {
 	result := &PipelineResult{}
 
+	region, err := GetRegion(opts.Region)
+	if err != nil {
+		return nil, err
+	}
+
 	// Step 1: Sync
-	syncOpts := DefaultSyncOptions()
+	syncOpts := RegionSyncOptions(region)
 	syncOpts.Force = opts.Force
 
 	syncResult, err := Sync(syncOpts)
@@ -36,21 +53,43 @@ func Pipeline(opts PipelineOptions, tiler Tiler) (*PipelineResult, error) {
 	result.SyncResult = syncResult
 
 	// Check if we should continue
-	if !syncResult.HasChanges && !opts.Force {
+	if !syncResult.HasChanges && !opts.Force && !opts.Resume {
 		result.Skipped = true
 		return result, nil
 	}
 
-	// Step 2: Tile
-	tileCount, err := TileAll(tiler, opts.Force)
+	// Step 2: Validate (and repair) before anything reaches the tiler
+	validateOpts := DefaultValidateOptions(region)
+	validateOpts.Repair = opts.Repair
+	validateOpts.MaxErrorRate = opts.MaxErrorRate
+	validation, err := Validate(validateOpts)
+	if err != nil {
+		return result, fmt.Errorf("validate: %w", err)
+	}
+	result.Validation = validation
+	if err := SaveValidationReport(filepath.Join(region.DataDir(), FileValidation), *validation); err != nil {
+		return result, fmt.Errorf("saving validation report: %w", err)
+	}
+	if !validation.Passed {
+		return result, fmt.Errorf("validate: %s", validation.FailureSummary())
+	}
+
+	// Step 3: Tile
+	tileCount, err := TileAll(tiler, region, opts.Force)
 	if err != nil {
-		return nil, fmt.Errorf("tile: %w", err)
+		return result, fmt.Errorf("tile: %w", err)
 	}
 	result.TileCount = tileCount
 
-	// Step 3: Manifest
-	if err := GenerateManifests(DirData, DirPMTiles, DirGeoJSON); err != nil {
-		return nil, fmt.Errorf("manifest: %w", err)
+	if opts.Combined {
+		if err := TileCombined(tiler, region, opts.Force); err != nil {
+			return result, fmt.Errorf("tile combined: %w", err)
+		}
+	}
+
+	// Step 4: Manifest
+	if err := GenerateManifests(region); err != nil {
+		return result, fmt.Errorf("manifest: %w", err)
 	}
 
 	return result, nil
@@ -62,17 +101,17 @@ type TileOptions struct {
 	DatasetKeys []string // nil = all datasets
 }
 
-// TileAll generates PMTiles for all datasets.
-func TileAll(tiler Tiler, force bool) (int, error) {
-	if err := os.MkdirAll(DirPMTiles, 0755); err != nil {
+// TileAll generates PMTiles for all datasets in the region's processing order.
+func TileAll(tiler Tiler, region Region, force bool) (int, error) {
+	if err := os.MkdirAll(region.PMTilesDir(), 0755); err != nil {
 		return 0, fmt.Errorf("creating tiles dir: %w", err)
 	}
 
 	tiled := 0
-	for _, key := range DatasetOrder {
-		ds := Datasets[key]
-		geoJSONPath := filepath.Join(DirGeoJSON, ds.GeoJSON)
-		pmTilesPath := filepath.Join(DirPMTiles, ds.PMTiles)
+	for _, key := range region.DatasetOrder {
+		ds := region.Datasets[key]
+		geoJSONPath := filepath.Join(region.GeoJSONDir(), ds.GeoJSON)
+		pmTilesPath := filepath.Join(region.PMTilesDir(), ds.PMTiles)
 
 		// Check if GeoJSON exists
 		geoJSONInfo, err := os.Stat(geoJSONPath)
@@ -89,18 +128,7 @@ func TileAll(tiler Tiler, force bool) (int, error) {
 			}
 		}
 
-		// Build config
-		cfg := TileConfigs[key]
-		cfg.Layer = ds.Layer
-
-		// For gotiler, use sensible defaults when auto-zoom is requested
-		if tiler.Name() == "go" && cfg.MinZoom < 0 {
-			cfg.MinZoom = 0
-		}
-		if tiler.Name() == "go" && cfg.MaxZoom < 0 {
-			cfg.MaxZoom = 10
-		}
-
+		cfg := TileConfigFor(ds, tiler.Name())
 		if err := tiler.Tile(geoJSONPath, pmTilesPath, cfg); err != nil {
 			return tiled, fmt.Errorf("tiling %s: %w", key, err)
 		}
@@ -111,14 +139,14 @@ func TileAll(tiler Tiler, force bool) (int, error) {
 }
 
 // TileOne generates PMTiles for a single dataset.
-func TileOne(tiler Tiler, key string, force bool) error {
-	ds, ok := Datasets[key]
-	if !ok {
-		return fmt.Errorf("unknown dataset: %s", key)
+func TileOne(tiler Tiler, region Region, key string, force bool) error {
+	ds, err := region.Dataset(key)
+	if err != nil {
+		return err
 	}
 
-	geoJSONPath := filepath.Join(DirGeoJSON, ds.GeoJSON)
-	pmTilesPath := filepath.Join(DirPMTiles, ds.PMTiles)
+	geoJSONPath := filepath.Join(region.GeoJSONDir(), ds.GeoJSON)
+	pmTilesPath := filepath.Join(region.PMTilesDir(), ds.PMTiles)
 
 	// Check if GeoJSON exists
 	geoJSONInfo, err := os.Stat(geoJSONPath)
@@ -135,23 +163,52 @@ func TileOne(tiler Tiler, key string, force bool) error {
 		}
 	}
 
-	// Build config
-	cfg := TileConfigs[key]
-	cfg.Layer = ds.Layer
+	if err := os.MkdirAll(region.PMTilesDir(), 0755); err != nil {
+		return fmt.Errorf("creating tiles dir: %w", err)
+	}
+
+	return tiler.Tile(geoJSONPath, pmTilesPath, TileConfigFor(ds, tiler.Name()))
+}
+
+// TileCombined generates the region's combined archive (PMTilesCombined)
+// with one layer per dataset in processing order. Datasets without GeoJSON
+// are left out; the archive is skipped if it is newer than every input.
+func TileCombined(tiler Tiler, region Region, force bool) error {
+	outputPath := filepath.Join(region.PMTilesDir(), PMTilesCombined)
+	outputInfo, outputErr := os.Stat(outputPath)
+	upToDate := outputErr == nil
+
+	var inputs []LayerInput
+	for _, key := range region.DatasetOrder {
+		ds := region.Datasets[key]
+		geoJSONPath := filepath.Join(region.GeoJSONDir(), ds.GeoJSON)
+
+		geoJSONInfo, err := os.Stat(geoJSONPath)
+		if err != nil {
+			continue // Skip missing
+		}
+		if upToDate && !outputInfo.ModTime().After(geoJSONInfo.ModTime()) {
+			upToDate = false
+		}
+
+		inputs = append(inputs, LayerInput{
+			InputPath: geoJSONPath,
+			Config:    TileConfigFor(ds, tiler.Name()),
+		})
+	}
 
-	// For gotiler, use sensible defaults when auto-zoom is requested
-	if tiler.Name() == "go" && cfg.MinZoom < 0 {
-		cfg.MinZoom = 0
+	if len(inputs) == 0 {
+		return fmt.Errorf("no GeoJSON found in %s", region.GeoJSONDir())
 	}
-	if tiler.Name() == "go" && cfg.MaxZoom < 0 {
-		cfg.MaxZoom = 10
+	if upToDate && !force {
+		return nil
 	}
 
-	if err := os.MkdirAll(DirPMTiles, 0755); err != nil {
+	if err := os.MkdirAll(region.PMTilesDir(), 0755); err != nil {
 		return fmt.Errorf("creating tiles dir: %w", err)
 	}
 
-	return tiler.Tile(geoJSONPath, pmTilesPath, cfg)
+	return tiler.TileCombined(inputs, outputPath)
 }
 
 // SelectTiler returns the appropriate tiler based on name.
@@ -0,0 +1,349 @@
+// Package plan validates flight plans against the synced FAA airspace layers.
+//
+// A plan (GeoJSON LineString, GPX, KML or QGroundControl .plan) is split into
+// segments between consecutive waypoints. Each segment is queried against a
+// query.Index at its planned altitude and reported when it crosses:
+//
+//   - controlled airspace (Class B, C, D or E at that altitude)
+//   - special use airspace (restricted, prohibited, MOA, warning, alert)
+//   - a UAS facility-map grid whose ceiling is below the planned altitude
+//   - a temporary flight restriction in force at the planned time (Options.At)
+//
+// Airports and obstacles within a buffer of the path are listed with their
+// distance. The segment altitude is the higher of its two waypoints, so a
+// climb is checked at its top. When the index has an elevation source, AGL
+// and MSL are compared at the highest ground along the part of each segment
+// inside the airspace (see query.Index).
+package plan
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/query"
+)
+
+// Finding kinds.
+const (
+	KindControlled = "controlled_airspace"
+	KindSUA        = "sua"
+	KindUASCeiling = "uas_ceiling"
+	KindTFR        = "tfr"
+)
+
+// DefaultBufferMeters is the nearby-feature search distance (1 NM).
+const DefaultBufferMeters = 1852
+
+// controlledClasses are the airspace classes that need authorization.
+var controlledClasses = []string{"A", "B", "C", "D", "E"}
+
+// checkDatasets are the layers a plan is checked against.
+var checkDatasets = []string{"boundary", "sua", "uas", "tfr", "airports", "obstacles"}
+
+// Datasets returns the region's datasets that Check uses, for query.Load.
+func Datasets(region airspace.Region) []string {
+	var keys []string
+	for _, key := range region.AllDatasets() {
+		if slices.Contains(checkDatasets, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Options configures Check.
+type Options struct {
+	Altitude     *query.Altitude // Overrides waypoint altitudes (required if the plan has none)
+	BufferMeters float64         // Nearby airports/obstacles distance (0 = DefaultBufferMeters)
+	At           *time.Time      // Flight time; TFRs not in force then are ignored (nil = any TFR)
+}
+
+// Report is the result of checking a plan.
+type Report struct {
+	Plan         string          `json:"plan"`
+	Format       string          `json:"format"`
+	Waypoints    int             `json:"waypoints"`
+	LengthMeters float64         `json:"length_m"`
+	BufferMeters float64         `json:"buffer_m"`
+	At           *time.Time      `json:"at,omitempty"` // Flight time TFRs were checked at
+	Segments     []SegmentReport `json:"segments"`
+	Nearby       []Nearby        `json:"nearby"`
+	Summary      Summary         `json:"summary"`
+}
+
+// SegmentReport lists the findings for the leg between two waypoints.
+type SegmentReport struct {
+	Index        int            `json:"index"` // Segment i joins waypoints i and i+1
+	From         orb.Point      `json:"from"`
+	To           orb.Point      `json:"to"`
+	LengthMeters float64        `json:"length_m"`
+	Altitude     query.Altitude `json:"altitude"`
+	GroundFt     *float64       `json:"ground_ft,omitempty"` // Highest ground along the leg, ft MSL
+	Findings     []Finding      `json:"findings,omitempty"`
+}
+
+// Finding is one airspace feature a segment conflicts with.
+type Finding struct {
+	Kind    string       `json:"kind"` // controlled_airspace, sua, uas_ceiling, tfr
+	Dataset string       `json:"dataset"`
+	ID      string       `json:"id,omitempty"`
+	Name    string       `json:"name,omitempty"`
+	Class   string       `json:"class,omitempty"`
+	Floor   *query.Limit `json:"floor,omitempty"`
+	Ceiling *query.Limit `json:"ceiling,omitempty"`
+}
+
+// Nearby is an airport or obstacle within the buffer of the path.
+type Nearby struct {
+	Dataset        string    `json:"dataset"`
+	ID             string    `json:"id,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	Point          orb.Point `json:"point"`
+	DistanceMeters float64   `json:"distance_m"`
+	Segment        int       `json:"segment"`             // Closest segment
+	HeightFt       *float64  `json:"height_ft,omitempty"` // Obstacle height AGL
+}
+
+// Summary condenses a report.
+type Summary struct {
+	OK              bool     `json:"ok"` // No findings
+	Controlled      []string `json:"controlled,omitempty"`
+	SUA             []string `json:"sua,omitempty"`
+	TFRs            []string `json:"tfrs,omitempty"`
+	UASCeilingFt    *float64 `json:"uas_ceiling_ft,omitempty"` // Lowest ceiling exceeded
+	FlaggedLegs     int      `json:"flagged_segments"`
+	NearbyAirports  int      `json:"nearby_airports"`
+	NearbyObstacles int      `json:"nearby_obstacles"`
+}
+
+// Check validates a plan against the index. The plan needs at least 2
+// waypoints.
+func Check(idx *query.Index, p *Plan, opts Options) (*Report, error) {
+	if len(p.Waypoints) < 2 {
+		return nil, fmt.Errorf("flight plan needs at least 2 waypoints, got %d", len(p.Waypoints))
+	}
+	buffer := opts.BufferMeters
+	if buffer <= 0 {
+		buffer = DefaultBufferMeters
+	}
+
+	r := &Report{
+		Plan:         p.Name,
+		Format:       p.Format,
+		Waypoints:    len(p.Waypoints),
+		BufferMeters: buffer,
+		At:           opts.At,
+		Segments:     make([]SegmentReport, 0, len(p.Waypoints)-1),
+		Nearby:       make([]Nearby, 0),
+	}
+
+	nearby := make(map[string]Nearby)
+	for i := 0; i+1 < len(p.Waypoints); i++ {
+		from, to := p.Waypoints[i], p.Waypoints[i+1]
+		alt, err := segmentAltitude(idx, from, to, opts.Altitude)
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i, err)
+		}
+
+		seg := SegmentReport{
+			Index:        i,
+			From:         from.Point,
+			To:           to.Point,
+			LengthMeters: geo.Distance(from.Point, to.Point),
+			Altitude:     alt,
+		}
+		r.LengthMeters += seg.LengthMeters
+
+		path := orb.LineString{from.Point, to.Point}
+		res := idx.Query(query.Request{Geometry: path, Altitude: &alt, At: opts.At, Datasets: []string{"boundary", "sua", "uas", "tfr"}})
+		seg.GroundFt = res.GroundFt
+		for _, m := range res.Matches {
+			if f, ok := finding(m, alt); ok {
+				seg.Findings = append(seg.Findings, f)
+			}
+		}
+		r.Segments = append(r.Segments, seg)
+
+		// Airports and obstacles near this leg, keeping the closest leg per feature
+		res = idx.Query(query.Request{Geometry: bufferBound(path, buffer).ToPolygon(), Datasets: []string{"airports", "obstacles"}})
+		for _, m := range res.Matches {
+			pt, ok := m.Geometry.(orb.Point)
+			if !ok {
+				continue
+			}
+			d := distanceToSegment(pt, from.Point, to.Point)
+			if d > buffer {
+				continue
+			}
+			key := m.Dataset + ":" + m.ID + ":" + fmt.Sprint(pt)
+			if prev, ok := nearby[key]; ok && prev.DistanceMeters <= d {
+				continue
+			}
+			n := Nearby{Dataset: m.Dataset, ID: m.ID, Name: m.Name, Point: pt, DistanceMeters: d, Segment: i}
+			if m.Dataset == "obstacles" {
+				n.HeightFt = obstacleHeight(m.Properties)
+			}
+			nearby[key] = n
+		}
+	}
+
+	for _, n := range nearby {
+		r.Nearby = append(r.Nearby, n)
+	}
+	sort.Slice(r.Nearby, func(i, j int) bool {
+		if r.Nearby[i].DistanceMeters != r.Nearby[j].DistanceMeters {
+			return r.Nearby[i].DistanceMeters < r.Nearby[j].DistanceMeters
+		}
+		return r.Nearby[i].ID < r.Nearby[j].ID
+	})
+
+	r.Summary = summarize(r)
+	return r, nil
+}
+
+// segmentAltitude returns the altitude a leg is checked at.
+func segmentAltitude(idx *query.Index, from, to Waypoint, override *query.Altitude) (query.Altitude, error) {
+	if override != nil {
+		return *override, nil
+	}
+	switch {
+	case from.Altitude == nil && to.Altitude == nil:
+		return query.Altitude{}, fmt.Errorf("plan has no altitude (set one with -alt)")
+	case from.Altitude == nil:
+		return *to.Altitude, nil
+	case to.Altitude == nil:
+		return *from.Altitude, nil
+	}
+
+	// Without terrain data the ground is taken as sea level
+	fromGround, _ := idx.Ground(from.Point)
+	toGround, _ := idx.Ground(to.Point)
+	if to.Altitude.MSL(toGround) > from.Altitude.MSL(fromGround) {
+		return *to.Altitude, nil
+	}
+	return *from.Altitude, nil
+}
+
+// finding turns a query match into a conflict, if it is one. AGL limits
+// are referenced to the ground the match was checked at.
+func finding(m query.Match, alt query.Altitude) (Finding, bool) {
+	f := Finding{Dataset: m.Dataset, ID: m.ID, Name: m.Name, Class: m.Class, Floor: m.Floor, Ceiling: m.Ceiling}
+	switch m.Dataset {
+	case "boundary":
+		if !slices.Contains(controlledClasses, m.Class) {
+			return f, false
+		}
+		f.Kind = KindControlled
+	case "sua":
+		f.Kind = KindSUA
+	case "tfr":
+		f.Kind = KindTFR
+	case "uas":
+		// Facility-map ceilings are AGL
+		ground := 0.0
+		if m.GroundFt != nil {
+			ground = *m.GroundFt
+		}
+		if m.Ceiling == nil || alt.AGL(ground) <= m.Ceiling.Feet {
+			return f, false
+		}
+		f.Kind = KindUASCeiling
+	default:
+		return f, false
+	}
+	return f, true
+}
+
+func summarize(r *Report) Summary {
+	s := Summary{}
+	for _, seg := range r.Segments {
+		if len(seg.Findings) > 0 {
+			s.FlaggedLegs++
+		}
+		for _, f := range seg.Findings {
+			label := f.Name
+			if label == "" {
+				label = f.Class
+			}
+			switch f.Kind {
+			case KindControlled:
+				if !slices.Contains(s.Controlled, f.Class) {
+					s.Controlled = append(s.Controlled, f.Class)
+				}
+			case KindSUA:
+				if !slices.Contains(s.SUA, label) {
+					s.SUA = append(s.SUA, label)
+				}
+			case KindTFR:
+				if !slices.Contains(s.TFRs, label) {
+					s.TFRs = append(s.TFRs, label)
+				}
+			case KindUASCeiling:
+				if s.UASCeilingFt == nil || f.Ceiling.Feet < *s.UASCeilingFt {
+					ceiling := f.Ceiling.Feet
+					s.UASCeilingFt = &ceiling
+				}
+			}
+		}
+	}
+	for _, n := range r.Nearby {
+		switch n.Dataset {
+		case "airports":
+			s.NearbyAirports++
+		case "obstacles":
+			s.NearbyObstacles++
+		}
+	}
+	sort.Strings(s.Controlled)
+	sort.Strings(s.SUA)
+	sort.Strings(s.TFRs)
+	s.OK = s.FlaggedLegs == 0
+	return s
+}
+
+// bufferBound pads a geometry's bound by meters.
+func bufferBound(g orb.Geometry, meters float64) orb.Bound {
+	b := g.Bound()
+	lat := (b.Min[1] + b.Max[1]) / 2
+	dLat := meters / metersPerDegree
+	dLon := meters / (metersPerDegree * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	return orb.Bound{
+		Min: orb.Point{b.Min[0] - dLon, b.Min[1] - dLat},
+		Max: orb.Point{b.Max[0] + dLon, b.Max[1] + dLat},
+	}
+}
+
+const metersPerDegree = 111320
+
+// distanceToSegment returns the distance in meters from p to segment a-b,
+// on a local equirectangular projection (accurate over a few kilometres).
+func distanceToSegment(p, a, b orb.Point) float64 {
+	k := math.Cos(p[1] * math.Pi / 180)
+	px, py := p[0]*k, p[1]
+	ax, ay := a[0]*k, a[1]
+	bx, by := b[0]*k, b[1]
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/l))
+	}
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy)) * metersPerDegree
+}
+
+// obstacleHeight reads the FAA Digital Obstacle File height above ground.
+func obstacleHeight(props map[string]any) *float64 {
+	for _, key := range []string{"AGL", "Agl", "agl"} {
+		if v, ok := props[key].(float64); ok {
+			return &v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/query"
+)
+
+const feetPerMeter = 3.28084
+
+// Plan is a flight plan: an ordered list of waypoints.
+type Plan struct {
+	Name      string     `json:"name,omitempty"`
+	Format    string     `json:"format"` // geojson, gpx, kml, qgc
+	Waypoints []Waypoint `json:"waypoints"`
+}
+
+// Waypoint is one position of the plan, with its planned altitude if the
+// file carries one.
+type Waypoint struct {
+	Point    orb.Point       `json:"point"`
+	Altitude *query.Altitude `json:"altitude,omitempty"`
+}
+
+// Path returns the plan as a line string.
+func (p *Plan) Path() orb.LineString {
+	ls := make(orb.LineString, len(p.Waypoints))
+	for i, w := range p.Waypoints {
+		ls[i] = w.Point
+	}
+	return ls
+}
+
+// Load reads a flight plan, choosing the parser by file extension:
+// .geojson/.json (LineString), .gpx (track or route), .kml (LineString) or
+// .plan (QGroundControl mission).
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p *Plan
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".geojson", ".json":
+		p, err = ParseGeoJSON(data)
+	case ".gpx":
+		p, err = ParseGPX(data)
+	case ".kml":
+		p, err = ParseKML(data)
+	case ".plan":
+		p, err = ParseQGC(data)
+	default:
+		return nil, fmt.Errorf("unsupported flight plan format %q (want .geojson, .gpx, .kml or .plan)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(p.Waypoints) < 2 {
+		return nil, fmt.Errorf("%s: flight plan needs at least 2 waypoints, got %d", path, len(p.Waypoints))
+	}
+	return p, nil
+}
+
+// ParseGeoJSON reads the first LineString of a GeoJSON geometry, Feature or
+// FeatureCollection. A third coordinate is altitude in meters MSL, per RFC 7946.
+func ParseGeoJSON(data []byte) (*Plan, error) {
+	p := &Plan{Format: "geojson"}
+
+	// orb drops the third coordinate, so read positions directly
+	var doc struct {
+		Type        string            `json:"type"`
+		Coordinates [][]float64       `json:"coordinates"`
+		Geometry    json.RawMessage   `json:"geometry"`
+		Features    []json.RawMessage `json:"features"`
+		Properties  map[string]any    `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing geojson: %w", err)
+	}
+
+	switch doc.Type {
+	case "FeatureCollection":
+		for _, f := range doc.Features {
+			if fp, err := ParseGeoJSON(f); err == nil {
+				return fp, nil
+			}
+		}
+		return nil, fmt.Errorf("no LineString feature found")
+	case "Feature":
+		fp, err := ParseGeoJSON(doc.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := doc.Properties["name"].(string); ok {
+			fp.Name = name
+		}
+		return fp, nil
+	case "LineString":
+		for _, c := range doc.Coordinates {
+			if len(c) < 2 {
+				return nil, fmt.Errorf("invalid position %v", c)
+			}
+			w := Waypoint{Point: orb.Point{c[0], c[1]}}
+			if len(c) > 2 {
+				w.Altitude = &query.Altitude{Feet: c[2] * feetPerMeter, Ref: query.RefMSL}
+			}
+			p.Waypoints = append(p.Waypoints, w)
+		}
+		return p, nil
+	default:
+		// Validate other geometry types for a clearer error
+		if _, err := geojson.UnmarshalGeometry(data); err != nil {
+			return nil, fmt.Errorf("parsing geojson: %w", err)
+		}
+		return nil, fmt.Errorf("geometry is %s, want LineString", doc.Type)
+	}
+}
+
+// ParseGPX reads the first track (or, failing that, route) of a GPX file.
+// Elevations are meters MSL.
+func ParseGPX(data []byte) (*Plan, error) {
+	type gpxPoint struct {
+		Lat float64  `xml:"lat,attr"`
+		Lon float64  `xml:"lon,attr"`
+		Ele *float64 `xml:"ele"`
+	}
+	var doc struct {
+		Tracks []struct {
+			Name     string `xml:"name"`
+			Segments []struct {
+				Points []gpxPoint `xml:"trkpt"`
+			} `xml:"trkseg"`
+		} `xml:"trk"`
+		Routes []struct {
+			Name   string     `xml:"name"`
+			Points []gpxPoint `xml:"rtept"`
+		} `xml:"rte"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing gpx: %w", err)
+	}
+
+	p := &Plan{Format: "gpx"}
+	add := func(pts []gpxPoint) {
+		for _, pt := range pts {
+			w := Waypoint{Point: orb.Point{pt.Lon, pt.Lat}}
+			if pt.Ele != nil {
+				w.Altitude = &query.Altitude{Feet: *pt.Ele * feetPerMeter, Ref: query.RefMSL}
+			}
+			p.Waypoints = append(p.Waypoints, w)
+		}
+	}
+
+	switch {
+	case len(doc.Tracks) > 0:
+		p.Name = doc.Tracks[0].Name
+		for _, seg := range doc.Tracks[0].Segments {
+			add(seg.Points)
+		}
+	case len(doc.Routes) > 0:
+		p.Name = doc.Routes[0].Name
+		add(doc.Routes[0].Points)
+	default:
+		return nil, fmt.Errorf("no track or route found")
+	}
+	return p, nil
+}
+
+// ParseKML reads the first LineString placemark of a KML file. Altitudes are
+// meters, relative to ground or absolute per altitudeMode; clampToGround
+// (the KML default) carries no altitude.
+func ParseKML(data []byte) (*Plan, error) {
+	type placemark struct {
+		Name       string `xml:"name"`
+		LineString *struct {
+			Coordinates  string `xml:"coordinates"`
+			AltitudeMode string `xml:"altitudeMode"`
+		} `xml:"LineString"`
+	}
+
+	// Placemarks may sit at any depth under Document and Folder elements
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no LineString placemark found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing kml: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+		var pm placemark
+		if err := dec.DecodeElement(&pm, &start); err != nil {
+			return nil, fmt.Errorf("parsing kml: %w", err)
+		}
+		if pm.LineString == nil {
+			continue
+		}
+
+		ref := ""
+		switch strings.TrimSpace(pm.LineString.AltitudeMode) {
+		case "relativeToGround":
+			ref = query.RefAGL
+		case "absolute":
+			ref = query.RefMSL
+		}
+
+		p := &Plan{Format: "kml", Name: pm.Name}
+		for _, tuple := range strings.Fields(pm.LineString.Coordinates) {
+			parts := strings.Split(tuple, ",")
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("invalid coordinate %q", tuple)
+			}
+			lon, err1 := strconv.ParseFloat(parts[0], 64)
+			lat, err2 := strconv.ParseFloat(parts[1], 64)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid coordinate %q", tuple)
+			}
+			w := Waypoint{Point: orb.Point{lon, lat}}
+			if len(parts) > 2 && ref != "" {
+				if alt, err := strconv.ParseFloat(parts[2], 64); err == nil {
+					w.Altitude = &query.Altitude{Feet: alt * feetPerMeter, Ref: ref}
+				}
+			}
+			p.Waypoints = append(p.Waypoints, w)
+		}
+		return p, nil
+	}
+}
+
+// MAVLink frames used by QGroundControl mission items.
+const (
+	mavFrameGlobal         = 0  // Altitude MSL
+	mavFrameGlobalRelative = 3  // Altitude above home
+	mavFrameGlobalTerrain  = 10 // Altitude above terrain
+)
+
+// ParseQGC reads a QGroundControl .plan mission: the planned home position
+// followed by every simple mission item with a position. Complex items
+// (surveys, corridor scans) contribute their visual transect points.
+func ParseQGC(data []byte) (*Plan, error) {
+	type item struct {
+		Type   string     `json:"type"`
+		Frame  int        `json:"frame"`
+		Params []*float64 `json:"params"`
+		TSItem *struct {
+			VisualTransectPoints [][]float64 `json:"VisualTransectPoints"`
+		} `json:"TransectStyleComplexItem"`
+	}
+	var doc struct {
+		FileType string `json:"fileType"`
+		Mission  struct {
+			Items       []item    `json:"items"`
+			PlannedHome []float64 `json:"plannedHomePosition"`
+		} `json:"mission"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+	if doc.FileType != "Plan" {
+		return nil, fmt.Errorf("not a QGroundControl plan (fileType %q)", doc.FileType)
+	}
+
+	p := &Plan{Format: "qgc"}
+	if h := doc.Mission.PlannedHome; len(h) >= 2 {
+		p.Waypoints = append(p.Waypoints, Waypoint{Point: orb.Point{h[1], h[0]}})
+	}
+
+	var lastAlt *query.Altitude
+	for _, it := range doc.Mission.Items {
+		if it.Type == "ComplexItem" && it.TSItem != nil {
+			for _, pt := range it.TSItem.VisualTransectPoints {
+				if len(pt) >= 2 {
+					p.Waypoints = append(p.Waypoints, Waypoint{Point: orb.Point{pt[1], pt[0]}, Altitude: lastAlt})
+				}
+			}
+			continue
+		}
+		if it.Type != "SimpleItem" || len(it.Params) < 7 || it.Params[4] == nil || it.Params[5] == nil {
+			continue
+		}
+		lat, lon := *it.Params[4], *it.Params[5]
+		if lat == 0 && lon == 0 {
+			continue // Commands without a position (speed changes, camera triggers)
+		}
+
+		w := Waypoint{Point: orb.Point{lon, lat}}
+		if it.Params[6] != nil {
+			alt := *it.Params[6] * feetPerMeter
+			switch it.Frame {
+			case mavFrameGlobal:
+				w.Altitude = &query.Altitude{Feet: alt, Ref: query.RefMSL}
+			case mavFrameGlobalRelative, mavFrameGlobalTerrain:
+				// Relative to home; treated as above ground
+				w.Altitude = &query.Altitude{Feet: alt, Ref: query.RefAGL}
+			}
+		}
+		if w.Altitude != nil {
+			lastAlt = w.Altitude
+		}
+		p.Waypoints = append(p.Waypoints, w)
+	}
+	return p, nil
+}
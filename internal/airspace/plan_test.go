@@ -0,0 +1,153 @@
+package airspace_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/plan"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/query"
+)
+
+const (
+	// Inside the SFO Class C: 200 ft grid, then the 0 ft grid next to the airport
+	planSFO = `{"type":"Feature","properties":{"name":"SFO hop"},"geometry":{"type":"LineString",
+"coordinates":[[-122.32,37.62],[-122.38,37.62]]}}`
+
+	// Crosses R-2531 between two points outside it, at 1000 ft MSL (304.8 m)
+	planR2531GPX = `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><name>Survey</name><trkseg>
+    <trkpt lat="37.1" lon="-121.6"><ele>304.8</ele></trkpt>
+    <trkpt lat="37.1" lon="-121.0"><ele>304.8</ele></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+	planKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2"><Document><Folder>
+  <Placemark><name>Pin</name><Point><coordinates>-122,37</coordinates></Point></Placemark>
+  <Placemark><name>Route</name><LineString>
+    <altitudeMode>relativeToGround</altitudeMode>
+    <coordinates>-122.32,37.62,30 -122.38,37.62,60</coordinates>
+  </LineString></Placemark>
+</Folder></Document></kml>`
+
+	planQGC = `{"fileType":"Plan","version":1,"mission":{
+"plannedHomePosition":[37.62,-122.32,5],
+"items":[
+ {"type":"SimpleItem","command":22,"frame":3,"params":[0,0,0,null,37.62,-122.32,30]},
+ {"type":"SimpleItem","command":178,"frame":2,"params":[1,5,-1,0,0,0,0]},
+ {"type":"SimpleItem","command":16,"frame":3,"params":[0,0,0,null,37.62,-122.38,60]}
+]}}`
+)
+
+func writePlan(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadPlanIndex(t *testing.T) *query.Index {
+	t.Helper()
+	region := airspace.DefaultRegion()
+	idx, err := query.Load(region, testQueryDir, plan.Datasets(region))
+	if err != nil {
+		t.Fatalf("loading index: %v", err)
+	}
+	return idx
+}
+
+func TestPlanCheck(t *testing.T) {
+	idx := loadPlanIndex(t)
+
+	p, err := plan.Load(writePlan(t, "hop.geojson", planSFO))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plan.Check(idx, p, plan.Options{}); err == nil {
+		t.Error("plan without altitude should need -alt")
+	}
+
+	alt := query.Altitude{Feet: 100, Ref: query.RefAGL}
+	report, err := plan.Check(idx, p, plan.Options{Altitude: &alt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := report.Summary
+	if s.OK || !slices.Equal(s.Controlled, []string{"C"}) {
+		t.Errorf("controlled = %v, want [C] (Class E floor is 700 AGL)", s.Controlled)
+	}
+	if s.UASCeilingFt == nil || *s.UASCeilingFt != 0 {
+		t.Errorf("uas ceiling exceeded = %v, want 0 (the 200 ft grid is not exceeded)", s.UASCeilingFt)
+	}
+	if len(report.Nearby) != 1 || report.Nearby[0].ID != "A-SFO" || report.Nearby[0].DistanceMeters > 200 {
+		t.Errorf("nearby = %+v, want SFO within 200 m", report.Nearby)
+	}
+
+	// A tighter buffer leaves the airport out
+	report, _ = plan.Check(idx, p, plan.Options{Altitude: &alt, BufferMeters: 50})
+	if len(report.Nearby) != 0 {
+		t.Errorf("nearby with 50 m buffer = %+v", report.Nearby)
+	}
+
+	// Plans built without Load still need a segment
+	for _, waypoints := range [][]plan.Waypoint{nil, p.Waypoints[:1]} {
+		if _, err := plan.Check(idx, &plan.Plan{Waypoints: waypoints}, plan.Options{Altitude: &alt}); err == nil {
+			t.Errorf("plan with %d waypoints: no error", len(waypoints))
+		}
+	}
+}
+
+func TestPlanCheckGPXCrossesSUA(t *testing.T) {
+	idx := loadPlanIndex(t)
+
+	p, err := plan.Load(writePlan(t, "survey.gpx", planR2531GPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Survey" || p.Waypoints[0].Altitude == nil || math.Abs(p.Waypoints[0].Altitude.Feet-1000) > 0.1 {
+		t.Fatalf("gpx plan = %+v", p)
+	}
+
+	report, err := plan.Check(idx, p, plan.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := report.Summary
+	if !slices.Equal(s.SUA, []string{"R-2531"}) || !slices.Equal(s.Controlled, []string{"E"}) {
+		t.Errorf("sua = %v, controlled = %v; want [R-2531], [E]", s.SUA, s.Controlled)
+	}
+}
+
+func TestPlanFormats(t *testing.T) {
+	for name, content := range map[string]string{"route.kml": planKML, "mission.plan": planQGC} {
+		p, err := plan.Load(writePlan(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// QGC plans start at the home position, which has no altitude
+		wps := p.Waypoints
+		if p.Format == "qgc" {
+			if len(wps) != 3 || wps[0].Altitude != nil {
+				t.Fatalf("%s: waypoints = %+v, want home + 2", name, wps)
+			}
+			wps = wps[1:]
+		}
+		if len(wps) != 2 {
+			t.Fatalf("%s: %d waypoints, want 2", name, len(wps))
+		}
+		last := wps[1]
+		if last.Point[0] != -122.38 || last.Altitude == nil || last.Altitude.Ref != query.RefAGL ||
+			math.Abs(last.Altitude.Feet-196.85) > 0.1 {
+			t.Errorf("%s: last waypoint = %+v %+v, want -122.38 at 60 m AGL", name, last.Point, last.Altitude)
+		}
+	}
+}
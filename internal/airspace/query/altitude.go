@@ -0,0 +1,159 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Altitude references.
+const (
+	RefMSL = "MSL" // Mean sea level
+	RefAGL = "AGL" // Above ground level (SFC is normalised to 0 AGL)
+)
+
+// FAA sentinel for "up to but not including" the next airspace above (usually 18,000 MSL).
+const faaUpperSentinel = -9998
+
+// Limit is one vertical bound of an airspace volume, in feet.
+type Limit struct {
+	Feet      float64 `json:"ft"`
+	Ref       string  `json:"ref"`                 // MSL or AGL
+	Unlimited bool    `json:"unlimited,omitempty"` // No upper bound
+}
+
+// String formats the limit like a sectional chart label.
+func (l Limit) String() string {
+	if l.Unlimited {
+		return "UNL"
+	}
+	if l.Ref == RefAGL && l.Feet == 0 {
+		return "SFC"
+	}
+	return fmt.Sprintf("%.0f ft %s", l.Feet, l.Ref)
+}
+
+// MSL converts the limit to feet MSL given the ground elevation in feet MSL.
+func (l Limit) MSL(groundFt float64) float64 {
+	if l.Ref == RefAGL {
+		return l.Feet + groundFt
+	}
+	return l.Feet
+}
+
+// Altitude is a queried altitude in feet.
+type Altitude struct {
+	Feet float64 `json:"ft"`
+	Ref  string  `json:"ref"` // MSL or AGL
+}
+
+// MSL converts the altitude to feet MSL given the ground elevation in feet MSL.
+func (a Altitude) MSL(groundFt float64) float64 {
+	if a.Ref == RefAGL {
+		return a.Feet + groundFt
+	}
+	return a.Feet
+}
+
+// AGL converts the altitude to feet above ground given the ground elevation in feet MSL.
+func (a Altitude) AGL(groundFt float64) float64 {
+	if a.Ref == RefMSL {
+		return a.Feet - groundFt
+	}
+	return a.Feet
+}
+
+// ParseAltitude parses "400", "400AGL", "5500 MSL" or "400ft agl".
+// Bare numbers are treated as AGL, which is how drone operators think.
+func ParseAltitude(s string) (Altitude, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	ref := RefAGL
+	switch {
+	case strings.HasSuffix(s, RefMSL):
+		ref = RefMSL
+		s = strings.TrimSuffix(s, RefMSL)
+	case strings.HasSuffix(s, RefAGL):
+		s = strings.TrimSuffix(s, RefAGL)
+	}
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "FT"))
+
+	ft, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Altitude{}, fmt.Errorf("invalid altitude %q", s)
+	}
+	return Altitude{Feet: ft, Ref: ref}, nil
+}
+
+// verticalLimits extracts the floor and ceiling of a feature from FAA attributes.
+// Returns ok=false when the feature carries no vertical information (points, unknown schemas).
+func verticalLimits(props map[string]any) (floor, ceiling Limit, ok bool) {
+	// UAS Facility Map grids: CEILING is the max LAANC altitude in feet AGL.
+	if v, found := numberProp(props, "CEILING"); found {
+		return Limit{Feet: 0, Ref: RefAGL}, Limit{Feet: v, Ref: RefAGL}, true
+	}
+
+	// Class airspace and SUA: LOWER_*/UPPER_* triplets.
+	lower, hasLower := limitProp(props, "LOWER")
+	upper, hasUpper := limitProp(props, "UPPER")
+	if !hasLower && !hasUpper {
+		return Limit{}, Limit{}, false
+	}
+	if !hasLower {
+		lower = Limit{Feet: 0, Ref: RefAGL}
+	}
+	if !hasUpper {
+		upper = Limit{Unlimited: true, Ref: RefMSL}
+	}
+	return lower, upper, true
+}
+
+// limitProp reads <prefix>_VAL, <prefix>_UOM and <prefix>_CODE.
+func limitProp(props map[string]any, prefix string) (Limit, bool) {
+	code := strings.ToUpper(stringProp(props, prefix+"_CODE"))
+	desc := strings.ToUpper(stringProp(props, prefix+"_DESC"))
+
+	if code == "SFC" || desc == "SFC" {
+		return Limit{Feet: 0, Ref: RefAGL}, true
+	}
+	if code == "UNLTD" || code == "UNL" || desc == "UNLTD" {
+		return Limit{Unlimited: true, Ref: RefMSL}, true
+	}
+
+	val, found := numberProp(props, prefix+"_VAL")
+	if !found {
+		return Limit{}, false
+	}
+	if val == faaUpperSentinel {
+		return Limit{Feet: 18000, Ref: RefMSL}, true
+	}
+
+	if strings.ToUpper(stringProp(props, prefix+"_UOM")) == "FL" {
+		val *= 100
+	}
+
+	ref := RefMSL
+	if code == "AGL" || code == "SFC" {
+		ref = RefAGL
+	}
+	return Limit{Feet: val, Ref: ref}, true
+}
+
+func stringProp(props map[string]any, key string) string {
+	if v, ok := props[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func numberProp(props map[string]any, key string) (float64, bool) {
+	switch v := props[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package query
+
+import (
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+// intersects reports whether two planar geometries share any point.
+// Works on lon/lat degrees, which is accurate enough for airspace volumes
+// that are small relative to the Earth's curvature.
+func intersects(a, b orb.Geometry) bool {
+	if !a.Bound().Intersects(b.Bound()) {
+		return false
+	}
+
+	// Any crossing edges
+	for _, la := range lines(a) {
+		for _, lb := range lines(b) {
+			if linesCross(la, lb) {
+				return true
+			}
+		}
+	}
+
+	// One geometry entirely inside the other
+	for _, p := range points(a) {
+		if containedBy(b, p) {
+			return true
+		}
+	}
+	for _, p := range points(b) {
+		if containedBy(a, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// containedBy reports whether point p lies inside the area of g.
+// Non-areal geometries contain only identical points.
+func containedBy(g orb.Geometry, p orb.Point) bool {
+	switch t := g.(type) {
+	case orb.Polygon:
+		return planar.PolygonContains(t, p)
+	case orb.MultiPolygon:
+		return planar.MultiPolygonContains(t, p)
+	case orb.Point:
+		return t.Equal(p)
+	case orb.Collection:
+		for _, c := range t {
+			if containedBy(c, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lines returns the edges of g as line strings (polygon rings included).
+func lines(g orb.Geometry) []orb.LineString {
+	switch t := g.(type) {
+	case orb.LineString:
+		return []orb.LineString{t}
+	case orb.MultiLineString:
+		return t
+	case orb.Ring:
+		return []orb.LineString{orb.LineString(t)}
+	case orb.Polygon:
+		out := make([]orb.LineString, len(t))
+		for i, r := range t {
+			out[i] = orb.LineString(r)
+		}
+		return out
+	case orb.MultiPolygon:
+		var out []orb.LineString
+		for _, p := range t {
+			out = append(out, lines(p)...)
+		}
+		return out
+	case orb.Collection:
+		var out []orb.LineString
+		for _, c := range t {
+			out = append(out, lines(c)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// points returns one representative vertex per component of g.
+func points(g orb.Geometry) []orb.Point {
+	switch t := g.(type) {
+	case orb.Point:
+		return []orb.Point{t}
+	case orb.MultiPoint:
+		return t
+	case orb.Collection:
+		var out []orb.Point
+		for _, c := range t {
+			out = append(out, points(c)...)
+		}
+		return out
+	}
+	var out []orb.Point
+	for _, l := range lines(g) {
+		if len(l) > 0 {
+			out = append(out, l[0])
+		}
+	}
+	return out
+}
+
+func linesCross(a, b orb.LineString) bool {
+	for i := 1; i < len(a); i++ {
+		for j := 1; j < len(b); j++ {
+			if airspace.SegmentsIntersect(a[i-1], a[i], b[j-1], b[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/notam"
+)
+
+// maxRequestBody limits POSTed GeoJSON (flight paths and areas are small).
+const maxRequestBody = 4 << 20
+
+// Handler serves the query index as a JSON HTTP API.
+//
+//	GET  /query?lat=37.6&lon=-122.4&alt=400AGL   point query
+//	POST /query?alt=400                          body: GeoJSON geometry, Feature or FeatureCollection
+//	GET  /health                                 index feature counts
+//
+// Both query forms accept datasets=boundary,sua to restrict the layers searched
+// and at=2026-10-20T18:00:00Z (or at=now) to match only TFRs in force then.
+func Handler(idx *Index) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status":   "ok",
+			"features": idx.Len(),
+			"datasets": idx.Counts(),
+		})
+	})
+
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseRequest(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, idx.Query(req))
+	})
+
+	return mux
+}
+
+// parseRequest builds a Request from query parameters and, for POST, a GeoJSON body.
+func parseRequest(r *http.Request) (Request, error) {
+	var req Request
+	q := r.URL.Query()
+
+	if s := q.Get("alt"); s != "" {
+		alt, err := ParseAltitude(s)
+		if err != nil {
+			return req, err
+		}
+		req.Altitude = &alt
+	}
+	if s := q.Get("datasets"); s != "" {
+		req.Datasets = strings.Split(s, ",")
+	}
+	if s := q.Get("at"); s != "" {
+		at, err := notam.ParseTime(s, time.Now().UTC())
+		if err != nil {
+			return req, err
+		}
+		req.At = &at
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		lat, err := strconv.ParseFloat(q.Get("lat"), 64)
+		if err != nil {
+			return req, fmt.Errorf("invalid or missing lat")
+		}
+		lon, err := strconv.ParseFloat(q.Get("lon"), 64)
+		if err != nil {
+			return req, fmt.Errorf("invalid or missing lon")
+		}
+		req.Geometry = orb.Point{lon, lat}
+	case http.MethodPost:
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+		if err != nil {
+			return req, fmt.Errorf("reading body: %w", err)
+		}
+		g, err := ParseGeometry(data)
+		if err != nil {
+			return req, err
+		}
+		req.Geometry = g
+	default:
+		return req, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	return req, nil
+}
+
+// ParseGeometry decodes a GeoJSON geometry, Feature or FeatureCollection.
+// For collections, the first feature's geometry is used. A null or empty
+// geometry is an error.
+func ParseGeometry(data []byte) (orb.Geometry, error) {
+	g, err := parseGeometry(data)
+	if err != nil {
+		return nil, err
+	}
+	if isEmpty(g) {
+		return nil, fmt.Errorf("geometry is null or empty")
+	}
+	return g, nil
+}
+
+func parseGeometry(data []byte) (orb.Geometry, error) {
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("parsing geojson: %w", err)
+	}
+
+	switch probe.Type {
+	case "FeatureCollection":
+		fc, err := geojson.UnmarshalFeatureCollection(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing geojson: %w", err)
+		}
+		if len(fc.Features) == 0 {
+			return nil, fmt.Errorf("feature collection is empty")
+		}
+		return fc.Features[0].Geometry, nil
+	case "Feature":
+		f, err := geojson.UnmarshalFeature(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing geojson: %w", err)
+		}
+		return f.Geometry, nil
+	default:
+		g, err := geojson.UnmarshalGeometry(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing geojson: %w", err)
+		}
+		return g.Geometry(), nil
+	}
+}
+
+// isEmpty reports whether g is nil or has no coordinates to query with.
+func isEmpty(g orb.Geometry) bool {
+	switch g := g.(type) {
+	case nil:
+		return true
+	case orb.MultiPoint:
+		return len(g) == 0
+	case orb.LineString:
+		return len(g) == 0
+	case orb.MultiLineString:
+		return len(g) == 0
+	case orb.Ring:
+		return len(g) == 0
+	case orb.Polygon:
+		return len(g) == 0 || len(g[0]) == 0
+	case orb.MultiPolygon:
+		return len(g) == 0
+	case orb.Collection:
+		return len(g) == 0
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
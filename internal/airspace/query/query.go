@@ -0,0 +1,465 @@
+// Package query answers "what airspace applies here?" against the synced FAA layers.
+//
+// The index is built in memory from the GeoJSON files written by airspace.Sync
+// (see airspace.Region.GeoJSONDir). Each feature's bounding box goes into a packed
+// R-tree; candidate features are then tested with exact planar geometry and,
+// when an altitude is given, against the feature's FAA floor and ceiling.
+//
+// Supported query geometries are points (a single position), line strings
+// (a flight path) and polygons (an operating area).
+//
+// AGL and MSL limits are compared at the ground elevation under the query
+// geometry when the index has an elevation source (see SetElevation);
+// without one the ground is taken as sea level. The ground is sampled every
+// 90 m along lines and polygon rings and on a grid over polygon interiors,
+// and each feature is checked against the highest sample inside it (for
+// features without an area, inside their bound). A ridge on the path so
+// only moves the AGL limits of the airspace over it; ground narrower than
+// the sample spacing can still be missed.
+//
+// Time-bounded features (TFRs, see package notam) carry an effective window;
+// a request with a time only matches those in force at that time.
+package query
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/elevation"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/notam"
+)
+
+// Source is one dataset's features to index.
+type Source struct {
+	Dataset  string
+	Features *geojson.FeatureCollection
+}
+
+// Index is an in-memory spatial index over airspace features.
+type Index struct {
+	features  []indexedFeature
+	tree      *rtree
+	counts    map[string]int
+	elevation elevation.Source
+}
+
+type indexedFeature struct {
+	dataset     string
+	geometry    orb.Geometry
+	properties  map[string]any
+	floor       Limit
+	ceiling     Limit
+	hasVertical bool
+	from, to    time.Time // Effective window (to zero = open-ended)
+	timed       bool
+}
+
+// Load builds an index from a region's GeoJSON files in dir.
+// Datasets whose file is missing are skipped; keys nil means the region's
+// DatasetOrder (obstacles excluded) plus its dynamic datasets.
+func Load(region airspace.Region, dir string, keys []string) (*Index, error) {
+	if keys == nil {
+		keys = region.ManifestDatasets()
+	}
+
+	var sources []Source
+	for _, key := range keys {
+		ds, err := region.Dataset(key)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, ds.GeoJSON))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", ds.GeoJSON, err)
+		}
+
+		fc, err := geojson.UnmarshalFeatureCollection(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", ds.GeoJSON, err)
+		}
+		sources = append(sources, Source{Dataset: key, Features: fc})
+	}
+
+	return New(sources...), nil
+}
+
+// New builds an index from in-memory feature collections.
+func New(sources ...Source) *Index {
+	idx := &Index{counts: make(map[string]int)}
+
+	var bounds []orb.Bound
+	for _, src := range sources {
+		for _, f := range src.Features.Features {
+			if f.Geometry == nil {
+				continue
+			}
+			floor, ceiling, ok := verticalLimits(f.Properties)
+			from, to, timed := notam.FeatureWindow(f.Properties)
+			idx.features = append(idx.features, indexedFeature{
+				dataset:     src.Dataset,
+				geometry:    f.Geometry,
+				properties:  f.Properties,
+				floor:       floor,
+				ceiling:     ceiling,
+				hasVertical: ok,
+				from:        from,
+				to:          to,
+				timed:       timed,
+			})
+			bounds = append(bounds, f.Geometry.Bound())
+			idx.counts[src.Dataset]++
+		}
+	}
+
+	idx.tree = newRTree(bounds)
+	return idx
+}
+
+// Len returns the number of indexed features.
+func (idx *Index) Len() int {
+	return len(idx.features)
+}
+
+// Counts returns the number of indexed features per dataset.
+func (idx *Index) Counts() map[string]int {
+	return idx.counts
+}
+
+// SetElevation sets the terrain used to convert between AGL and MSL.
+func (idx *Index) SetElevation(src elevation.Source) {
+	idx.elevation = src
+}
+
+// groundStepMeters is the profile spacing used to find the ground under a
+// path; polygon interiors are sampled on a grid of up to groundGrid² points.
+const (
+	groundStepMeters = 90
+	groundGrid       = 16
+)
+
+// Ground returns the highest ground elevation under g in feet MSL (see
+// groundSamples). Using the highest ground is conservative: an AGL altitude
+// is checked against MSL floors at its highest MSL value. ok is false
+// without an elevation source or data.
+func (idx *Index) Ground(g orb.Geometry) (ft float64, ok bool) {
+	return highest(idx.groundSamples(g), nil)
+}
+
+// groundSample is the ground elevation at a point, in feet MSL.
+type groundSample struct {
+	point orb.Point
+	ft    float64
+}
+
+// groundSamples samples the ground under g: at points, every
+// groundStepMeters along line strings and polygon rings, and on a grid over
+// polygon interiors. It returns nil without an elevation source.
+func (idx *Index) groundSamples(g orb.Geometry) []groundSample {
+	if idx.elevation == nil {
+		return nil
+	}
+
+	var paths []orb.LineString
+	var interior []orb.Polygon
+	switch g := g.(type) {
+	case orb.Point:
+		paths = []orb.LineString{{g}}
+	case orb.LineString:
+		paths = []orb.LineString{g}
+	case orb.Polygon:
+		paths = lines(g)
+		interior = []orb.Polygon{g}
+	case orb.MultiPolygon:
+		paths = lines(g)
+		interior = g
+	default:
+		paths = []orb.LineString{{g.Bound().Center()}}
+	}
+
+	var samples []groundSample
+	for _, path := range paths {
+		profile, err := elevation.ExtractProfile(idx.elevation, path, groundStepMeters)
+		if err != nil {
+			continue
+		}
+		for _, p := range profile.Points {
+			if p.Meters != nil {
+				samples = append(samples, groundSample{p.Point, *p.Meters * elevation.FeetPerMeter})
+			}
+		}
+	}
+	for _, poly := range interior {
+		b := poly.Bound()
+		dx, dy := (b.Max[0]-b.Min[0])/groundGrid, (b.Max[1]-b.Min[1])/groundGrid
+		for i := 0; i < groundGrid; i++ {
+			for j := 0; j < groundGrid; j++ {
+				p := orb.Point{b.Min[0] + (float64(i)+0.5)*dx, b.Min[1] + (float64(j)+0.5)*dy}
+				if !planar.PolygonContains(poly, p) {
+					continue
+				}
+				if m, err := idx.elevation.Elevation(p[0], p[1]); err == nil {
+					samples = append(samples, groundSample{p, m * elevation.FeetPerMeter})
+				}
+			}
+		}
+	}
+	return samples
+}
+
+// highest returns the highest sample inside area (nil = all samples).
+func highest(samples []groundSample, area orb.Geometry) (ft float64, ok bool) {
+	var bound orb.Bound
+	if area != nil {
+		bound = area.Bound()
+	}
+	for _, s := range samples {
+		if area != nil && (!bound.Contains(s.point) || !containedBy(area, s.point)) {
+			continue
+		}
+		if !ok || s.ft > ft {
+			ft, ok = s.ft, true
+		}
+	}
+	return ft, ok
+}
+
+// groundUnder returns the ground a feature's limits are checked at: the
+// highest sample inside its area, or inside its bound for features without
+// one, or failing both the highest sample of all.
+func groundUnder(samples []groundSample, f orb.Geometry, all float64) float64 {
+	switch f.(type) {
+	case orb.Polygon, orb.MultiPolygon:
+		if ft, ok := highest(samples, f); ok {
+			return ft
+		}
+	}
+	if ft, ok := highest(samples, f.Bound()); ok {
+		return ft
+	}
+	return all
+}
+
+// Request describes a query.
+type Request struct {
+	Geometry orb.Geometry // Point, LineString or Polygon
+	Altitude *Altitude    // nil = ignore vertical limits
+	At       *time.Time   // nil = ignore effective times
+	Datasets []string     // nil = all indexed datasets
+}
+
+// Result is the answer to a query.
+type Result struct {
+	Geometry string     `json:"geometry"` // GeoJSON type of the query geometry
+	Altitude *Altitude  `json:"altitude,omitempty"`
+	At       *time.Time `json:"at,omitempty"`
+	GroundFt *float64   `json:"ground_ft,omitempty"` // Highest ground under the geometry, ft MSL
+	Matches  []Match    `json:"matches"`
+	Summary  Summary    `json:"summary"`
+}
+
+// Match is a single feature that applies to the query.
+type Match struct {
+	Dataset    string         `json:"dataset"`
+	ID         string         `json:"id,omitempty"`
+	Name       string         `json:"name,omitempty"`
+	Class      string         `json:"class,omitempty"` // Airspace CLASS or SUA TYPE_CODE
+	Floor      *Limit         `json:"floor,omitempty"`
+	Ceiling    *Limit         `json:"ceiling,omitempty"`
+	From       *time.Time     `json:"effective_from,omitempty"` // Time-bounded features only
+	To         *time.Time     `json:"effective_to,omitempty"`   // nil = until further notice
+	GroundFt   *float64       `json:"ground_ft,omitempty"`      // Ground its limits were checked at, ft MSL
+	Properties map[string]any `json:"properties"`
+	Geometry   orb.Geometry   `json:"-"`
+}
+
+// Summary condenses matches into the answers a pre-flight check needs.
+type Summary struct {
+	Classes         []string `json:"classes,omitempty"`        // Controlled airspace classes hit
+	SUA             []string `json:"sua,omitempty"`            // SUA names hit
+	TFRs            []string `json:"tfrs,omitempty"`           // TFRs hit, as "NOTAM name"
+	UASCeilingFt    *float64 `json:"uas_ceiling_ft,omitempty"` // Lowest facility-map ceiling, ft AGL
+	AboveUASCeiling bool     `json:"above_uas_ceiling,omitempty"`
+	Airports        int      `json:"airports,omitempty"`
+	Navaids         int      `json:"navaids,omitempty"`
+}
+
+// Point queries a single position. alt may be nil.
+func (idx *Index) Point(lon, lat float64, alt *Altitude) Result {
+	return idx.Query(Request{Geometry: orb.Point{lon, lat}, Altitude: alt})
+}
+
+// Query returns every feature that intersects the request geometry and,
+// when an altitude is given, whose vertical extent contains it.
+func (idx *Index) Query(req Request) Result {
+	result := Result{
+		Geometry: req.Geometry.GeoJSONType(),
+		Altitude: req.Altitude,
+		At:       req.At,
+		Matches:  make([]Match, 0),
+	}
+
+	var samples []groundSample
+	highestGround := 0.0
+	if req.Altitude != nil {
+		samples = idx.groundSamples(req.Geometry)
+		if ft, ok := highest(samples, nil); ok {
+			highestGround = ft
+			result.GroundFt = &ft
+		}
+	}
+
+	var hits []int
+	idx.tree.Search(req.Geometry.Bound(), func(i int) bool {
+		hits = append(hits, i)
+		return true
+	})
+	sort.Ints(hits)
+
+	for _, i := range hits {
+		f := idx.features[i]
+		if req.Datasets != nil && !slices.Contains(req.Datasets, f.dataset) {
+			continue
+		}
+		if !intersects(f.geometry, req.Geometry) {
+			continue
+		}
+		ground := highestGround
+		if result.GroundFt != nil && (f.hasVertical || f.dataset == "uas") {
+			ground = groundUnder(samples, f.geometry, highestGround)
+		}
+		if !inVertical(f, req.Altitude, ground) {
+			continue
+		}
+		if !inEffect(f, req.At) {
+			continue
+		}
+		m := newMatch(f)
+		if result.GroundFt != nil {
+			m.GroundFt = &ground
+		}
+		result.Matches = append(result.Matches, m)
+	}
+
+	result.Summary = summarize(result.Matches, req.Altitude)
+	return result
+}
+
+// inVertical reports whether alt falls within the feature's floor and ceiling,
+// given the ground elevation in feet MSL. UAS facility-map grids always
+// apply: their ceiling is the maximum authorizable altitude, not the extent
+// of the volume.
+func inVertical(f indexedFeature, alt *Altitude, ground float64) bool {
+	if alt == nil || !f.hasVertical || f.dataset == "uas" {
+		return true
+	}
+
+	a := alt.MSL(ground)
+	if a < f.floor.MSL(ground) {
+		return false
+	}
+	return f.ceiling.Unlimited || a <= f.ceiling.MSL(ground)
+}
+
+// inEffect reports whether a time-bounded feature is in force at t.
+// Static features always are.
+func inEffect(f indexedFeature, t *time.Time) bool {
+	if t == nil || !f.timed {
+		return true
+	}
+	return !t.Before(f.from) && (f.to.IsZero() || t.Before(f.to))
+}
+
+func newMatch(f indexedFeature) Match {
+	m := Match{
+		Dataset:    f.dataset,
+		ID:         airspace.FeatureID(f.properties),
+		Name:       airspace.FeatureName(f.properties),
+		Class:      stringProp(f.properties, "CLASS"),
+		Properties: f.properties,
+		Geometry:   f.geometry,
+	}
+	if m.Class == "" {
+		m.Class = stringProp(f.properties, "TYPE_CODE")
+	}
+	if f.hasVertical {
+		floor, ceiling := f.floor, f.ceiling
+		m.Floor = &floor
+		m.Ceiling = &ceiling
+	}
+	if f.timed {
+		from := f.from
+		m.From = &from
+		if !f.to.IsZero() {
+			to := f.to
+			m.To = &to
+		}
+	}
+	return m
+}
+
+func summarize(matches []Match, alt *Altitude) Summary {
+	var s Summary
+	for _, m := range matches {
+		if m.Class == notam.TypeCode {
+			label := stringProp(m.Properties, notam.PropNOTAM) + " " + m.Name
+			if !slices.Contains(s.TFRs, label) {
+				s.TFRs = append(s.TFRs, label)
+			}
+			continue
+		}
+		switch m.Dataset {
+		case "boundary":
+			if m.Class != "" && !slices.Contains(s.Classes, m.Class) {
+				s.Classes = append(s.Classes, m.Class)
+			}
+		case "sua":
+			label := m.Name
+			if label == "" {
+				label = m.Class
+			}
+			if label != "" && !slices.Contains(s.SUA, label) {
+				s.SUA = append(s.SUA, label)
+			}
+		case "uas":
+			if m.Ceiling == nil {
+				break
+			}
+			if s.UASCeilingFt == nil || m.Ceiling.Feet < *s.UASCeilingFt {
+				ceiling := m.Ceiling.Feet
+				s.UASCeilingFt = &ceiling
+			}
+			// Facility-map ceilings are AGL, over the ground in that grid cell
+			if alt != nil && alt.AGL(m.ground()) > m.Ceiling.Feet {
+				s.AboveUASCeiling = true
+			}
+		case "airports":
+			s.Airports++
+		case "navaids":
+			s.Navaids++
+		}
+	}
+	sort.Strings(s.Classes)
+	return s
+}
+
+// ground returns the ground the match was checked at (sea level without
+// terrain data).
+func (m Match) ground() float64 {
+	if m.GroundFt == nil {
+		return 0
+	}
+	return *m.GroundFt
+}
@@ -0,0 +1,114 @@
+package query
+
+import (
+	"sort"
+
+	"github.com/paulmach/orb"
+)
+
+// rtreeNodeSize is the maximum number of children per R-tree node.
+const rtreeNodeSize = 16
+
+// rtree is a static R-tree bulk-loaded with Sort-Tile-Recursive (STR) packing.
+// Airspace layers are loaded once per process and never mutated, so a packed
+// tree gives good query performance without insert/split logic.
+type rtree struct {
+	root *rnode
+	size int
+}
+
+type rnode struct {
+	bound    orb.Bound
+	children []*rnode
+	item     int // index into the item slice (leaf entries only)
+	leaf     bool
+}
+
+// newRTree builds an R-tree over the given bounds. Search results report the
+// index of the bound in this slice.
+func newRTree(bounds []orb.Bound) *rtree {
+	if len(bounds) == 0 {
+		return &rtree{}
+	}
+
+	level := make([]*rnode, len(bounds))
+	for i, b := range bounds {
+		level[i] = &rnode{bound: b, item: i, leaf: true}
+	}
+
+	for len(level) > 1 {
+		level = packLevel(level)
+	}
+
+	return &rtree{root: level[0], size: len(bounds)}
+}
+
+// packLevel groups nodes into parents using STR: sort by X into vertical
+// slices, sort each slice by Y, then chunk into nodes of rtreeNodeSize.
+func packLevel(nodes []*rnode) []*rnode {
+	parentCount := (len(nodes) + rtreeNodeSize - 1) / rtreeNodeSize
+	sliceCount := ceilSqrt(parentCount)
+	sliceSize := sliceCount * rtreeNodeSize
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].bound.Center()[0] < nodes[j].bound.Center()[0]
+	})
+
+	parents := make([]*rnode, 0, parentCount)
+	for start := 0; start < len(nodes); start += sliceSize {
+		end := min(start+sliceSize, len(nodes))
+		slice := nodes[start:end]
+
+		sort.Slice(slice, func(i, j int) bool {
+			return slice[i].bound.Center()[1] < slice[j].bound.Center()[1]
+		})
+
+		for i := 0; i < len(slice); i += rtreeNodeSize {
+			children := slice[i:min(i+rtreeNodeSize, len(slice))]
+			parent := &rnode{children: append([]*rnode(nil), children...)}
+			parent.bound = children[0].bound
+			for _, c := range children[1:] {
+				parent.bound = parent.bound.Union(c.bound)
+			}
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+// Search calls fn for every item whose bound intersects b.
+// Iteration stops early if fn returns false.
+func (t *rtree) Search(b orb.Bound, fn func(item int) bool) {
+	if t.root == nil {
+		return
+	}
+	t.root.search(b, fn)
+}
+
+func (n *rnode) search(b orb.Bound, fn func(item int) bool) bool {
+	if !n.bound.Intersects(b) {
+		return true
+	}
+	if n.leaf {
+		return fn(n.item)
+	}
+	for _, c := range n.children {
+		if !c.search(b, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of indexed items.
+func (t *rtree) Len() int {
+	return t.size
+}
+
+func ceilSqrt(n int) int {
+	r := 1
+	for r*r < n {
+		r++
+	}
+	return r
+}
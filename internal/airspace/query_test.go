@@ -0,0 +1,135 @@
+package airspace_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/query"
+)
+
+const testQueryDir = "testdata/query"
+
+func loadTestIndex(t *testing.T) *query.Index {
+	t.Helper()
+	idx, err := query.Load(airspace.DefaultRegion(), testQueryDir, nil)
+	if err != nil {
+		t.Fatalf("loading index: %v", err)
+	}
+	if idx.Len() == 0 {
+		t.Fatal("index is empty")
+	}
+	return idx
+}
+
+func TestQueryPoint(t *testing.T) {
+	idx := loadTestIndex(t)
+
+	alt := query.Altitude{Feet: 100, Ref: query.RefAGL}
+	result := idx.Point(-122.32, 37.62, &alt)
+
+	if !slices.Equal(result.Summary.Classes, []string{"C"}) {
+		t.Errorf("classes = %v, want [C] (Class E floor is 700 AGL)", result.Summary.Classes)
+	}
+	if result.Summary.UASCeilingFt == nil || *result.Summary.UASCeilingFt != 200 {
+		t.Errorf("uas ceiling = %v, want 200", result.Summary.UASCeilingFt)
+	}
+	if result.Summary.AboveUASCeiling {
+		t.Error("100 ft should be below the 200 ft ceiling")
+	}
+
+	alt.Feet = 300
+	result = idx.Point(-122.32, 37.62, &alt)
+	if !result.Summary.AboveUASCeiling {
+		t.Error("300 ft should be above the 200 ft ceiling")
+	}
+}
+
+func TestQueryLineCrossesSUA(t *testing.T) {
+	idx := loadTestIndex(t)
+
+	// Neither endpoint is inside R-2531, but the path crosses it.
+	path := orb.LineString{{-121.6, 37.1}, {-121.0, 37.1}}
+	alt := query.Altitude{Feet: 1000, Ref: query.RefMSL}
+	result := idx.Query(query.Request{Geometry: path, Altitude: &alt})
+
+	if !slices.Contains(result.Summary.SUA, "R-2531") {
+		t.Errorf("sua = %v, want R-2531", result.Summary.SUA)
+	}
+	if !slices.Contains(result.Summary.Classes, "E") {
+		t.Errorf("classes = %v, want E", result.Summary.Classes)
+	}
+}
+
+func TestQueryPolygonFindsAirport(t *testing.T) {
+	idx := loadTestIndex(t)
+
+	area := orb.Polygon{{{-122.4, 37.6}, {-122.3, 37.6}, {-122.3, 37.7}, {-122.4, 37.7}, {-122.4, 37.6}}}
+	result := idx.Query(query.Request{Geometry: area, Datasets: []string{"airports"}})
+
+	if result.Summary.Airports != 1 || len(result.Matches) != 1 {
+		t.Fatalf("got %d matches, want the SFO airport", len(result.Matches))
+	}
+	if result.Matches[0].ID != "A-SFO" {
+		t.Errorf("id = %q, want A-SFO", result.Matches[0].ID)
+	}
+}
+
+func TestQueryHandler(t *testing.T) {
+	srv := httptest.NewServer(query.Handler(loadTestIndex(t)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/query?lat=37.62&lon=-122.32&alt=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	var result query.Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Geometry != "Point" || len(result.Matches) == 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	resp, err = http.Get(srv.URL + "/query?lat=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad lat: status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestQueryHandlerNullGeometry(t *testing.T) {
+	srv := httptest.NewServer(query.Handler(loadTestIndex(t)))
+	defer srv.Close()
+
+	bodies := map[string]string{
+		"feature":    `{"type":"Feature","geometry":null,"properties":{}}`,
+		"collection": `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":null,"properties":{}}]}`,
+		"empty line": `{"type":"LineString","coordinates":[]}`,
+	}
+	for name, body := range bodies {
+		resp, err := http.Post(srv.URL+"/query", "application/geo+json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package r2 is a small S3-API client for Cloudflare R2.
+//
+// It covers what publishing airspace tiles needs: single and multipart PUT
+// (parts uploaded in parallel), GET and HEAD, with every request signed with
+// AWS Signature Version 4 and retried with exponential backoff on network
+// errors, 5xx and 429. Objects carry their SHA-256 in x-amz-meta-sha256 so
+// callers can skip uploads whose content is already stored.
+//
+// Any S3-compatible endpoint works; requests use path-style addressing
+// (endpoint/bucket/key), which R2 and local stand-ins both accept.
+package r2
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Environment variables read by FromEnv.
+const (
+	EnvAccessKeyID     = "R2_ACCESS_KEY_ID"
+	EnvSecretAccessKey = "R2_SECRET_ACCESS_KEY"
+	EnvAccountID       = "CLOUDFLARE_ACCOUNT_ID"
+	EnvEndpoint        = "R2_ENDPOINT" // Overrides the account endpoint (e.g. a local S3)
+)
+
+// MinPartSize is the smallest part S3 accepts (except the last part).
+const MinPartSize = 5 << 20
+
+// ErrNotFound is returned by Get and Head for missing objects.
+var ErrNotFound = errors.New("object not found")
+
+// Client talks to one bucket.
+type Client struct {
+	Endpoint        string // e.g. https://<account>.r2.cloudflarestorage.com
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string // Signing region ("auto" for R2)
+
+	HTTP        *http.Client
+	PartSize    int64         // Files larger than this use multipart upload
+	Concurrency int           // Parts uploaded in parallel
+	Retries     int           // Attempts per request
+	Backoff     time.Duration // Delay before the first retry, doubled each time
+}
+
+// New creates a client with default part size, concurrency and retries.
+func New(endpoint, bucket, accessKeyID, secretAccessKey string) *Client {
+	return &Client{
+		Endpoint:        strings.TrimSuffix(endpoint, "/"),
+		Bucket:          bucket,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Region:          "auto",
+		HTTP:            &http.Client{Timeout: 10 * time.Minute},
+		PartSize:        16 << 20,
+		Concurrency:     4,
+		Retries:         4,
+		Backoff:         500 * time.Millisecond,
+	}
+}
+
+// FromEnv creates a client for bucket from R2_ACCESS_KEY_ID,
+// R2_SECRET_ACCESS_KEY and CLOUDFLARE_ACCOUNT_ID (or R2_ENDPOINT).
+func FromEnv(bucket string) (*Client, error) {
+	accessKey := os.Getenv(EnvAccessKeyID)
+	secret := os.Getenv(EnvSecretAccessKey)
+	if accessKey == "" || secret == "" {
+		return nil, fmt.Errorf("%s and %s must be set (R2 API token with Object Read & Write)", EnvAccessKeyID, EnvSecretAccessKey)
+	}
+
+	endpoint := os.Getenv(EnvEndpoint)
+	if endpoint == "" {
+		account := os.Getenv(EnvAccountID)
+		if account == "" {
+			return nil, fmt.Errorf("%s or %s must be set", EnvAccountID, EnvEndpoint)
+		}
+		endpoint = "https://" + account + ".r2.cloudflarestorage.com"
+	}
+	return New(endpoint, bucket, accessKey, secret), nil
+}
+
+// Object describes a stored object.
+type Object struct {
+	Key    string
+	Size   int64
+	ETag   string
+	SHA256 string // From x-amz-meta-sha256, if the uploader set it
+}
+
+// PutOptions sets headers on uploaded objects.
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+	SHA256       string // Stored as x-amz-meta-sha256
+}
+
+func (o PutOptions) header() http.Header {
+	h := http.Header{}
+	if o.ContentType != "" {
+		h.Set("Content-Type", o.ContentType)
+	}
+	if o.CacheControl != "" {
+		h.Set("Cache-Control", o.CacheControl)
+	}
+	if o.SHA256 != "" {
+		h.Set("X-Amz-Meta-Sha256", o.SHA256)
+	}
+	return h
+}
+
+// Error is an S3 error response.
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("HTTP %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// Head returns an object's size, ETag and stored SHA-256.
+func (c *Client) Head(key string) (*Object, error) {
+	resp, _, err := c.do(http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Key:    key,
+		Size:   resp.ContentLength,
+		ETag:   resp.Header.Get("ETag"),
+		SHA256: resp.Header.Get("X-Amz-Meta-Sha256"),
+	}, nil
+}
+
+// Get returns an object's content.
+func (c *Client) Get(key string) ([]byte, error) {
+	_, body, err := c.do(http.MethodGet, key, nil, nil, nil)
+	return body, err
+}
+
+// Put uploads data as one object.
+func (c *Client) Put(key string, data []byte, opts PutOptions) error {
+	_, _, err := c.do(http.MethodPut, key, nil, opts.header(), data)
+	return err
+}
+
+// PutFile uploads a file, using multipart upload above PartSize.
+func (c *Client) PutFile(key, path string, opts PutOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() <= c.PartSize {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		return c.Put(key, data, opts)
+	}
+	return c.putMultipart(key, f, info.Size(), opts)
+}
+
+// do sends a signed request, retrying transient failures. It returns the
+// response (body already read and closed) and its body.
+func (c *Client) do(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, []byte, error) {
+	delay := c.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, data, err := c.send(method, key, query, header, body)
+		if err == nil {
+			return resp, data, nil
+		}
+
+		if !retryable(err) || attempt >= c.Retries {
+			return nil, nil, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// retryable reports whether a failed request may succeed if sent again:
+// network errors, 5xx and 429. A missing object and other 4xx are final.
+func retryable(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+	var s3err *Error
+	if errors.As(err, &s3err) {
+		return s3err.StatusCode >= 500 || s3err.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func (c *Client) send(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, []byte, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("endpoint: %w", err)
+	}
+	u.Path = "/" + c.Bucket + "/" + key
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	c.sign(req, hashHex(body), time.Now())
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound && (method == http.MethodGet || method == http.MethodHead) {
+		return nil, nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		s3err := &Error{StatusCode: resp.StatusCode}
+		xml.Unmarshal(data, s3err)
+		return nil, nil, s3err
+	}
+	return resp, data, nil
+}
@@ -0,0 +1,113 @@
+package r2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type initiateResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeRequest struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// putMultipart uploads r in PartSize parts, Concurrency at a time. The upload
+// is aborted if any part fails after its retries.
+func (c *Client) putMultipart(key string, r io.ReaderAt, size int64, opts PutOptions) error {
+	partSize := max(c.PartSize, MinPartSize)
+
+	_, body, err := c.do(http.MethodPost, key, url.Values{"uploads": {""}}, opts.header(), nil)
+	if err != nil {
+		return fmt.Errorf("starting multipart upload: %w", err)
+	}
+	var init initiateResult
+	if err := xml.Unmarshal(body, &init); err != nil || init.UploadID == "" {
+		return fmt.Errorf("starting multipart upload: no upload ID in response")
+	}
+
+	numParts := int((size + partSize - 1) / partSize)
+	parts := make([]completedPart, numParts)
+
+	jobs := make(chan int)
+	errs := make(chan error, numParts)
+	var wg sync.WaitGroup
+	for range max(c.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, partSize)
+			for i := range jobs {
+				etag, err := c.uploadPart(key, init.UploadID, i+1, r, int64(i)*partSize, min(partSize, size-int64(i)*partSize), buf)
+				if err != nil {
+					errs <- fmt.Errorf("part %d: %w", i+1, err)
+					continue
+				}
+				parts[i] = completedPart{PartNumber: i + 1, ETag: etag}
+			}
+		}()
+	}
+	for i := range numParts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		c.abort(key, init.UploadID)
+		return err
+	}
+
+	complete, err := xml.Marshal(completeRequest{Parts: parts})
+	if err != nil {
+		return err
+	}
+	query := url.Values{"uploadId": {init.UploadID}}
+	_, body, err = c.do(http.MethodPost, key, query, http.Header{"Content-Type": {"application/xml"}}, complete)
+	if err == nil && strings.Contains(string(body), "<Error>") {
+		// S3 can report a failed completion inside a 200 response
+		s3err := &Error{StatusCode: http.StatusOK}
+		xml.Unmarshal(body, s3err)
+		err = s3err
+	}
+	if err != nil {
+		c.abort(key, init.UploadID)
+		return fmt.Errorf("completing multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) uploadPart(key, uploadID string, number int, r io.ReaderAt, offset, length int64, buf []byte) (string, error) {
+	data := buf[:length]
+	if _, err := r.ReadAt(data, offset); err != nil && err != io.EOF {
+		return "", err
+	}
+	query := url.Values{"partNumber": {fmt.Sprint(number)}, "uploadId": {uploadID}}
+	resp, _, err := c.do(http.MethodPut, key, query, nil, data)
+	if err != nil {
+		return "", err
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("no ETag in response")
+	}
+	return etag, nil
+}
+
+// abort discards an unfinished multipart upload so its parts are not billed.
+func (c *Client) abort(key, uploadID string) {
+	c.do(http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+}
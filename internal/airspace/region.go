@@ -0,0 +1,269 @@
+package airspace
+
+import (
+	_ "embed"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed regions.yaml
+var defaultRegionsYAML []byte
+
+// Region is a geographic area with its own data sources, tiles and manifest.
+type Region struct {
+	Key           string             `yaml:"-"` // Region key (usa, eu, ...), set from the registry map key
+	Name          string             `yaml:"name"`
+	Authority     string             `yaml:"authority"`
+	UpdateCycle   string             `yaml:"update_cycle"`
+	SourceURLs    map[string]string  `yaml:"source_urls"`
+	BBox          []float64          `yaml:"bbox"` // [west, south, east, north]
+	GeoJSONPath   string             `yaml:"geojson_path"`
+	TilesPath     string             `yaml:"tiles_path"`
+	DataPath      string             `yaml:"data_path"`
+	DefaultLayers []string           `yaml:"default_layers"`
+	DatasetOrder  []string           `yaml:"dataset_order"` // Default processing order
+	Datasets      map[string]Dataset `yaml:"datasets"`
+}
+
+// RegionRegistry is the parsed regions config file.
+type RegionRegistry struct {
+	Default      string                `yaml:"default"`
+	TileProfiles map[string]TileConfig `yaml:"tile_profiles"` // Named tile settings datasets build on
+	Regions      map[string]Region     `yaml:"regions"`
+}
+
+// Regions is the active region registry. It starts as the embedded
+// regions.yaml and can be replaced with UseRegionsFile.
+var Regions = mustParseRegions(defaultRegionsYAML)
+
+// ParseRegions parses and validates a regions config.
+func ParseRegions(data []byte) (*RegionRegistry, error) {
+	var reg RegionRegistry
+	if err := yaml.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("parsing regions: %w", err)
+	}
+	if len(reg.Regions) == 0 {
+		return nil, fmt.Errorf("no regions defined")
+	}
+	if _, ok := reg.Regions[reg.Default]; !ok {
+		return nil, fmt.Errorf("default region %q not defined", reg.Default)
+	}
+	if err := reg.resolveTileProfiles(data); err != nil {
+		return nil, err
+	}
+
+	for rk, r := range reg.Regions {
+		r.Key = rk
+		for dk, ds := range r.Datasets {
+			ds.Key = dk
+			if ds.GeoJSON == "" || ds.PMTiles == "" || ds.Layer == "" {
+				return nil, fmt.Errorf("region %s dataset %s: geojson, pmtiles and layer are required", rk, dk)
+			}
+			if ds.IsPaginated && ds.PageSize <= 0 {
+				return nil, fmt.Errorf("region %s dataset %s: paginated datasets need page_size", rk, dk)
+			}
+			if ds.Manifest != nil && ds.Manifest.Key == "" {
+				ds.Manifest.Key = dk
+			}
+			r.Datasets[dk] = ds
+		}
+		for _, key := range r.DatasetOrder {
+			if _, ok := r.Datasets[key]; !ok {
+				return nil, fmt.Errorf("region %s: dataset_order references unknown dataset %s", rk, key)
+			}
+		}
+		reg.Regions[rk] = r
+	}
+
+	return &reg, nil
+}
+
+// resolveTileProfiles rebuilds the tile settings of datasets that name a
+// profile: the profile is the base and the dataset's own tile keys are
+// decoded on top, so a dataset only lists what differs.
+func (reg *RegionRegistry) resolveTileProfiles(data []byte) error {
+	var raw struct {
+		Regions map[string]struct {
+			Datasets map[string]struct {
+				Tile yaml.Node `yaml:"tile"`
+			} `yaml:"datasets"`
+		} `yaml:"regions"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("parsing regions: %w", err)
+	}
+
+	for name, p := range reg.TileProfiles {
+		if p.Profile != "" {
+			return fmt.Errorf("tile profile %s: profiles cannot build on other profiles", name)
+		}
+	}
+	for rk, r := range reg.Regions {
+		for dk, ds := range r.Datasets {
+			if ds.Tile.Profile == "" {
+				continue
+			}
+			profile, ok := reg.TileProfiles[ds.Tile.Profile]
+			if !ok {
+				return fmt.Errorf("region %s dataset %s: unknown tile profile %q", rk, dk, ds.Tile.Profile)
+			}
+			cfg := profile
+			cfg.Include = slices.Clone(profile.Include)
+			cfg.Exclude = slices.Clone(profile.Exclude)
+			cfg.Rename = maps.Clone(profile.Rename)
+			node := raw.Regions[rk].Datasets[dk].Tile
+			if err := node.Decode(&cfg); err != nil {
+				return fmt.Errorf("region %s dataset %s tile: %w", rk, dk, err)
+			}
+			ds.Tile = cfg
+			r.Datasets[dk] = ds
+		}
+	}
+	return nil
+}
+
+// LoadRegions reads a regions config file. A base_url that is a relative
+// local path is taken relative to the file, so the config works from any
+// directory.
+func LoadRegions(path string) (*RegionRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := ParseRegions(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reg.Regions {
+		for dk, ds := range r.Datasets {
+			if local, ok := localPath(ds.BaseURL); ok && ds.BaseURL != "" && !filepath.IsAbs(local) {
+				ds.BaseURL = filepath.Join(filepath.Dir(path), local)
+				r.Datasets[dk] = ds
+			}
+		}
+	}
+	return reg, nil
+}
+
+// UseRegionsFile replaces the active registry with the given config file.
+func UseRegionsFile(path string) error {
+	reg, err := LoadRegions(path)
+	if err != nil {
+		return err
+	}
+	Regions = reg
+	return nil
+}
+
+func mustParseRegions(data []byte) *RegionRegistry {
+	reg, err := ParseRegions(data)
+	if err != nil {
+		panic("airspace: embedded regions.yaml: " + err.Error())
+	}
+	return reg
+}
+
+// GetRegion looks up a region in the active registry. "" means the default region.
+func GetRegion(key string) (Region, error) {
+	if key == "" {
+		key = Regions.Default
+	}
+	r, ok := Regions.Regions[key]
+	if !ok {
+		return Region{}, fmt.Errorf("unknown region: %s (valid: %v)", key, RegionKeys())
+	}
+	return r, nil
+}
+
+// DefaultRegion returns the registry's default region.
+func DefaultRegion() Region {
+	return Regions.Regions[Regions.Default]
+}
+
+// RegionKeys returns all region keys, sorted.
+func RegionKeys() []string {
+	keys := make([]string, 0, len(Regions.Regions))
+	for k := range Regions.Regions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Dataset looks up a dataset in the region.
+func (r Region) Dataset(key string) (Dataset, error) {
+	ds, ok := r.Datasets[key]
+	if !ok {
+		return Dataset{}, fmt.Errorf("unknown dataset: %s (region %s)", key, r.Key)
+	}
+	return ds, nil
+}
+
+// AllDatasets returns DatasetOrder followed by any remaining datasets
+// (e.g. obstacles), for commands that need everything.
+func (r Region) AllDatasets() []string {
+	all := slices.Clone(r.DatasetOrder)
+	var extra []string
+	for k := range r.Datasets {
+		if !slices.Contains(all, k) {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	return append(all, extra...)
+}
+
+// DynamicDatasets returns the keys of time-bounded (TFR/NOTAM) datasets, sorted.
+func (r Region) DynamicDatasets() []string {
+	var keys []string
+	for k, ds := range r.Datasets {
+		if ds.Dynamic != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ManifestDatasets returns DatasetOrder followed by dynamic datasets that
+// are refreshed on their own schedule rather than by the AIRAC sync.
+func (r Region) ManifestDatasets() []string {
+	keys := slices.Clone(r.DatasetOrder)
+	for _, k := range r.DynamicDatasets() {
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// GeoJSONDir is where the region's GeoJSON is synced to.
+func (r Region) GeoJSONDir() string {
+	return filepath.Join(DirGeoJSON, r.GeoJSONPath)
+}
+
+// PMTilesDir is where the region's PMTiles are written.
+func (r Region) PMTilesDir() string {
+	return filepath.Join(DirGeoJSON, r.TilesPath)
+}
+
+// DataDir holds the region's sync state (ETags, results, history).
+func (r Region) DataDir() string {
+	return filepath.Join(DirData, r.DataPath)
+}
+
+// ManifestFile is the regional manifest filename (in DirData).
+func (r Region) ManifestFile() string {
+	return fmt.Sprintf("manifest_%s.json", r.Key)
+}
+
+// StyleFile is the region's MapLibre style filename (in DirData).
+func (r Region) StyleFile() string {
+	return fmt.Sprintf("style_%s.json", r.Key)
+}
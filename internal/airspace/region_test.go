@@ -0,0 +1,149 @@
+package airspace_test
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+// sampleRegions is a registry file with a European region built from a
+// local sample, the way a second region is added with -regions.
+const sampleRegions = "testdata/eu/regions.yaml"
+
+func TestRegions(t *testing.T) {
+	if keys := airspace.RegionKeys(); !slices.Equal(keys, []string{"usa"}) {
+		t.Fatalf("embedded regions = %v, want [usa]", keys)
+	}
+	if d := airspace.DefaultRegion(); d.Key != "usa" {
+		t.Errorf("default region = %q, want usa", d.Key)
+	}
+	if _, err := airspace.GetRegion("eu"); err == nil {
+		t.Error("GetRegion(eu) succeeded with the embedded registry, want unknown region")
+	}
+	sample, err := airspace.LoadRegions(sampleRegions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		reg                           *airspace.RegionRegistry
+		key                           string
+		geojson, pmtiles, data        string
+		manifest, style, manifestKeys string
+	}{
+		{airspace.Regions, "usa", "static/airspace", "static/airspace/tiles", "data/airspace", "manifest_usa.json", "style_usa.json", "laanc boundary sua airports navaids tfr"},
+		{sample, "eu", "static/airspace/eu", "static/airspace/tiles/eu", "data/airspace/eu", "manifest_eu.json", "style_eu.json", "boundary"},
+	}
+	for _, tt := range tests {
+		r, ok := tt.reg.Regions[tt.key]
+		if !ok {
+			t.Fatalf("region %s not loaded", tt.key)
+		}
+		if r.Key != tt.key || r.GeoJSONDir() != filepath.FromSlash(tt.geojson) || r.PMTilesDir() != filepath.FromSlash(tt.pmtiles) || r.DataDir() != filepath.FromSlash(tt.data) {
+			t.Errorf("%s: dirs = %s, %s, %s", tt.key, r.GeoJSONDir(), r.PMTilesDir(), r.DataDir())
+		}
+		if r.ManifestFile() != tt.manifest || r.StyleFile() != tt.style {
+			t.Errorf("%s: files = %s, %s", tt.key, r.ManifestFile(), r.StyleFile())
+		}
+		// Manifest keys default to the dataset key
+		var keys []string
+		for _, k := range r.ManifestDatasets() {
+			if ds := r.Datasets[k]; ds.Manifest != nil {
+				keys = append(keys, ds.Manifest.Key)
+			}
+		}
+		if got := strings.Join(keys, " "); got != tt.manifestKeys {
+			t.Errorf("%s: manifest keys = %q, want %q", tt.key, got, tt.manifestKeys)
+		}
+	}
+
+	// Tile profiles are the base for the dataset's own keys
+	usa := airspace.DefaultRegion()
+	if tile := usa.Datasets["tfr"].Tile; tile.MaxZoom != 12 || tile.MinZoom != 0 || !tile.NoFeatureLimit {
+		t.Errorf("tfr tile = %+v, want the complete profile up to z12", tile)
+	}
+
+	// -regions replaces the registry
+	t.Cleanup(func() { airspace.Regions = tests[0].reg })
+	if err := airspace.UseRegionsFile(sampleRegions); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := airspace.GetRegion(""); err != nil || r.Key != "eu" {
+		t.Errorf("default region from %s = %q, %v; want eu", sampleRegions, r.Key, err)
+	}
+}
+
+func TestSyncSampleRegion(t *testing.T) {
+	sample, err := airspace.LoadRegions(sampleRegions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The local base_url is relative to the regions file
+	eu := sample.Regions["eu"]
+	if got, want := eu.Datasets["boundary"].BaseURL, filepath.Join("testdata", "eu", "eu_airspace_sample.geojson"); got != want {
+		t.Errorf("base_url = %s, want %s", got, want)
+	}
+
+	opts := airspace.RegionSyncOptions(eu)
+	opts.OutputDir = t.TempDir()
+	opts.DataDir = t.TempDir()
+	result, err := airspace.Sync(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := result.Datasets["boundary"]; s.Status != "updated" || s.Features != 5 || s.ETag == "" {
+		t.Fatalf("first sync = %+v, want 5 features copied", s)
+	}
+	validateOpts := airspace.DefaultValidateOptions(eu)
+	validateOpts.Dir = opts.OutputDir
+	report, err := airspace.Validate(validateOpts)
+	if err != nil || !report.Passed {
+		t.Errorf("validation: %v, %+v", err, report)
+	}
+
+	result, err = airspace.Sync(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := result.Datasets["boundary"]; s.Status != "unchanged" || result.HasChanges {
+		t.Errorf("second sync = %+v, want unchanged", s)
+	}
+}
+
+func TestParseRegionsErrors(t *testing.T) {
+	const dataset = `
+      boundary:
+        name: Boundary
+        geojson: b.geojson
+        pmtiles: b.pmtiles
+        layer: boundary`
+	region := func(order, datasets string) string {
+		return "default: test\nregions:\n  test:\n    name: Test\n    dataset_order: " + order + "\n    datasets:" + datasets + "\n"
+	}
+	if _, err := airspace.ParseRegions([]byte(region("[boundary]", dataset))); err != nil {
+		t.Fatalf("valid registry: %v", err)
+	}
+
+	tests := []struct {
+		name, yaml, want string
+	}{
+		{"syntax", "regions: [", "parsing regions"},
+		{"no regions", "default: test\n", "no regions defined"},
+		{"unknown default", strings.Replace(region("[boundary]", dataset), "default: test", "default: eu", 1), `default region "eu" not defined`},
+		{"missing geojson", region("[boundary]", strings.Replace(dataset, "geojson: b.geojson", "", 1)), "geojson, pmtiles and layer are required"},
+		{"missing pmtiles", region("[boundary]", strings.Replace(dataset, "pmtiles: b.pmtiles", "", 1)), "geojson, pmtiles and layer are required"},
+		{"missing layer", region("[boundary]", strings.Replace(dataset, "layer: boundary", "", 1)), "geojson, pmtiles and layer are required"},
+		{"bad dataset_order", region("[boundary, sua]", dataset), "dataset_order references unknown dataset sua"},
+		{"paginated without page_size", region("[boundary]", dataset+"\n        paginated: true"), "need page_size"},
+		{"unknown tile profile", region("[boundary]", dataset+"\n        tile: {profile: huge}"), `unknown tile profile "huge"`},
+	}
+	for _, tt := range tests {
+		_, err := airspace.ParseRegions([]byte(tt.yaml))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package airspace
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FileManifestSchema is the JSON Schema of the regional manifest, written
+// next to the manifests so clients can validate what they download.
+const FileManifestSchema = "manifest_region.schema.json"
+
+//go:embed manifest.schema.json
+var manifestSchemaJSON []byte
+
+// ManifestSchema returns the JSON Schema (draft 2020-12) of RegionManifest.
+func ManifestSchema() []byte {
+	return slices.Clone(manifestSchemaJSON)
+}
+
+// SchemaError is one schema violation. Path is a JSON Pointer into the
+// validated document.
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + e.Message
+}
+
+// ValidateManifest checks a regional manifest against ManifestSchema.
+func ValidateManifest(m RegionManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return ValidateManifestJSON(data)
+}
+
+// ValidateManifestJSON checks an encoded regional manifest against
+// ManifestSchema. The error joins every SchemaError found.
+func ValidateManifestJSON(data []byte) error {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	schema, err := parseSchema(manifestSchemaJSON)
+	if err != nil {
+		return fmt.Errorf("manifest schema: %w", err)
+	}
+
+	var errs []error
+	schema.validate(schema, doc, "", &errs)
+	return errors.Join(errs...)
+}
+
+// jsonSchema is the subset of JSON Schema that the manifest schema uses.
+// Unsupported keywords (format, description, ...) are ignored.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+	Type                 json.RawMessage        `json:"type"` // "name" or ["name", ...]
+	Enum                 []any                  `json:"enum"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"` // false or a schema
+	DependentRequired    map[string][]string    `json:"dependentRequired"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	MinLength            *int                   `json:"minLength"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+
+	types      []string
+	pattern    *regexp.Regexp
+	noExtra    bool
+	additional *jsonSchema
+}
+
+func parseSchema(data []byte) (*jsonSchema, error) {
+	var s jsonSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, s.compile()
+}
+
+// compile decodes the polymorphic keywords and patterns of s and its
+// subschemas.
+func (s *jsonSchema) compile() error {
+	if len(s.Type) > 0 {
+		if err := json.Unmarshal(s.Type, &s.types); err != nil {
+			var t string
+			if err := json.Unmarshal(s.Type, &t); err != nil {
+				return fmt.Errorf("invalid type %s", s.Type)
+			}
+			s.types = []string{t}
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	switch a := strings.TrimSpace(string(s.AdditionalProperties)); a {
+	case "", "true":
+	case "false":
+		s.noExtra = true
+	default:
+		s.additional = &jsonSchema{}
+		if err := json.Unmarshal(s.AdditionalProperties, s.additional); err != nil {
+			return err
+		}
+	}
+
+	subs := []*jsonSchema{s.Items, s.additional}
+	for _, m := range []map[string]*jsonSchema{s.Defs, s.Properties} {
+		for _, sub := range m {
+			subs = append(subs, sub)
+		}
+	}
+	for _, sub := range subs {
+		if sub == nil {
+			continue
+		}
+		if err := sub.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate appends v's violations of s to errs. root resolves $ref.
+func (s *jsonSchema) validate(root *jsonSchema, v any, path string, errs *[]error) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		def := root.Defs[name]
+		if !ok || def == nil {
+			fail("unresolvable $ref %q", s.Ref)
+			return
+		}
+		def.validate(root, v, path, errs)
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return hasType(v, t) }) {
+		fail("expected %s, got %s", strings.Join(s.types, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, v) {
+		fail("%v is not one of %v", v, s.Enum)
+	}
+
+	switch v := v.(type) {
+	case string:
+		if s.MinLength != nil && len([]rune(v)) < *s.MinLength {
+			fail("shorter than %d characters", *s.MinLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("%q does not match %s", v, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("%v is less than %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("%v is greater than %v", v, *s.Maximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("%d items, want at least %d", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("%d items, want at most %d", len(v), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(root, item, fmt.Sprintf("%s/%d", path, i), errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		for name, deps := range s.DependentRequired {
+			if _, ok := v[name]; !ok {
+				continue
+			}
+			for _, dep := range deps {
+				if _, ok := v[dep]; !ok {
+					fail("property %q requires %q", name, dep)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub := pointerPath(path, k)
+			switch prop, ok := s.Properties[k]; {
+			case ok:
+				prop.validate(root, v[k], sub, errs)
+			case s.noExtra:
+				fail("unknown property %q", k)
+			case s.additional != nil:
+				s.additional.validate(root, v[k], sub, errs)
+			}
+		}
+	}
+}
+
+// hasType reports whether a decoded JSON value has the schema type t.
+func hasType(v any, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+// pointerPath appends an escaped JSON Pointer token.
+func pointerPath(path, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	return path + "/" + strings.ReplaceAll(token, "/", "~1")
+}
@@ -0,0 +1,445 @@
+// Package serve is a local tile server for a region's PMTiles archives.
+//
+// It lets the fleet airspace demo run offline and lets clients that cannot
+// read PMTiles directly (QGIS, MapLibre native) use our layers:
+//
+//	GET /                          layer index (JSON)
+//	GET /manifest.json             the regional manifest
+//	GET /style.json                MapLibre style of the manifest's layers (TileJSON sources)
+//	GET /pmtiles/{file}            raw archive, with HTTP range requests
+//	GET /{layer}.json              TileJSON 3.0 for a layer
+//	GET /{layer}/{z}/{x}/{y}.mvt   one vector tile
+//
+// Layers are the manifest's layer keys (plus "combined" when the manifest
+// lists a combined archive); without a manifest every *.pmtiles in the
+// directory is served under its file name. Archives are reopened when they
+// change on disk, so tiles can be rebuilt while the server runs; a replaced
+// archive is closed once the requests reading it are done.
+//
+// Every response carries CORS headers and an ETag; conditional requests get
+// 304 Not Modified. Tiles are sent gzip-encoded when the archive stores them
+// that way and the client accepts it, and missing tiles are 204 No Content.
+package serve
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/protomaps/go-pmtiles/pmtiles"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/inspect"
+)
+
+// CombinedLayer is the layer name of the region's combined archive.
+const CombinedLayer = "combined"
+
+// Server serves the PMTiles archives in one directory.
+type Server struct {
+	Dir        string
+	Manifest   *airspace.RegionManifest // nil serves every archive in Dir
+	CORSOrigin string                   // Access-Control-Allow-Origin (default "*")
+
+	layers map[string]*layer
+}
+
+// layer is one served archive, opened lazily and reopened when it changes.
+type layer struct {
+	name     string
+	file     string
+	manifest *airspace.ManifestLayer // nil if not from the manifest
+
+	mu      sync.Mutex
+	archive *openArchive
+	modTime time.Time
+	size    int64
+}
+
+// openArchive is an open archive and the requests reading it. A replaced
+// archive is retired and closed when the last of them releases it.
+type openArchive struct {
+	*inspect.Archive
+	readers int
+	retired bool
+}
+
+// retire closes the archive now, or once its last reader releases it.
+// The layer's lock must be held.
+func (a *openArchive) retire() {
+	a.retired = true
+	if a.readers == 0 {
+		a.Close()
+	}
+}
+
+// New creates a server for the archives in dir. If manifest is nil, every
+// *.pmtiles file in dir is served.
+func New(dir string, manifest *airspace.RegionManifest) (*Server, error) {
+	s := &Server{Dir: dir, Manifest: manifest, CORSOrigin: "*", layers: make(map[string]*layer)}
+
+	if manifest != nil {
+		for key, ml := range manifest.Layers {
+			s.layers[key] = &layer{name: key, file: ml.File, manifest: &ml}
+		}
+		if manifest.Combined != "" {
+			s.layers[CombinedLayer] = &layer{name: CombinedLayer, file: manifest.Combined}
+		}
+		return s, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pmtiles"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .pmtiles files in %s", dir)
+	}
+	for _, f := range files {
+		base := filepath.Base(f)
+		name := strings.TrimSuffix(base, ".pmtiles")
+		s.layers[name] = &layer{name: name, file: base}
+	}
+	return s, nil
+}
+
+// Layers returns the served layer names, sorted.
+func (s *Server) Layers() []string {
+	names := make([]string, 0, len(s.layers))
+	for name := range s.layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close releases all open archives.
+func (s *Server) Close() error {
+	for _, l := range s.layers {
+		l.mu.Lock()
+		if l.archive != nil {
+			l.archive.retire()
+			l.archive = nil
+		}
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler for the server's endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /manifest.json", s.handleManifest)
+	mux.HandleFunc("GET /style.json", s.handleStyle)
+	mux.HandleFunc("GET /pmtiles/{file}", s.handleArchive)
+	mux.HandleFunc("GET /{name}", s.handleTileJSON)
+	mux.HandleFunc("GET /{layer}/{z}/{x}/{y}", s.handleTile)
+
+	return s.cors(mux)
+}
+
+// cors adds CORS headers and answers preflight requests.
+func (s *Server) cors(next http.Handler) http.Handler {
+	origin := s.CORSOrigin
+	if origin == "" {
+		origin = "*"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		h.Set("Access-Control-Allow-Headers", "Range, If-None-Match, If-Modified-Since, If-Range")
+		h.Set("Access-Control-Expose-Headers", "ETag, Content-Range, Content-Length, Content-Encoding, Accept-Ranges")
+		if r.Method == http.MethodOptions {
+			h.Set("Access-Control-Max-Age", "86400")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	base := baseURL(r)
+	type entry struct {
+		Name     string `json:"name"`
+		File     string `json:"file"`
+		TileJSON string `json:"tilejson"`
+		PMTiles  string `json:"pmtiles"`
+	}
+	var entries []entry
+	for _, name := range s.Layers() {
+		l := s.layers[name]
+		entries = append(entries, entry{
+			Name:     name,
+			File:     l.file,
+			TileJSON: base + "/" + name + ".json",
+			PMTiles:  base + "/pmtiles/" + l.file,
+		})
+	}
+	writeJSON(w, r, http.StatusOK, map[string]any{"layers": entries})
+}
+
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	if s.Manifest == nil {
+		http.Error(w, "no manifest loaded", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s.Manifest)
+}
+
+func (s *Server) handleStyle(w http.ResponseWriter, r *http.Request) {
+	if s.Manifest == nil {
+		http.Error(w, "no manifest loaded", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, airspace.MapLibreStyle(*s.Manifest, airspace.StyleOptions{TilesURL: baseURL(r), TileJSON: true}))
+}
+
+// handleArchive serves a whole archive; http.ServeContent handles Range,
+// If-Range and If-None-Match.
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	l := s.layerByFile(r.PathValue("file"))
+	if l == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.Dir, l.file))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.pmtiles")
+	w.Header().Set("ETag", fileETag(info))
+	http.ServeContent(w, r, l.file, info.ModTime(), f)
+}
+
+func (s *Server) handleTileJSON(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("name"), ".json")
+	l := s.layers[name]
+	if !ok || l == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	a, release, err := l.open(s.Dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer release()
+
+	tj := s.tileJSON(l, a, baseURL(r))
+	data, err := json.MarshalIndent(tj, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	serveBytes(w, r, "application/json", contentETag(data), data)
+}
+
+func (s *Server) handleTile(w http.ResponseWriter, r *http.Request) {
+	l := s.layers[r.PathValue("layer")]
+	yStr, ok := strings.CutSuffix(r.PathValue("y"), ".mvt")
+	if l == nil || !ok {
+		http.NotFound(w, r)
+		return
+	}
+	z, errZ := strconv.ParseUint(r.PathValue("z"), 10, 8)
+	x, errX := strconv.ParseUint(r.PathValue("x"), 10, 32)
+	y, errY := strconv.ParseUint(yStr, 10, 32)
+	if errZ != nil || errX != nil || errY != nil || z > 31 || x >= 1<<z || y >= 1<<z {
+		http.Error(w, "invalid tile coordinates", http.StatusBadRequest)
+		return
+	}
+
+	a, release, err := l.open(s.Dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer release()
+	if a.Header.TileType != pmtiles.Mvt {
+		http.Error(w, "layer is not a vector tileset", http.StatusNotFound)
+		return
+	}
+
+	data, err := a.Tile(uint8(z), uint32(x), uint32(y))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Send the stored gzip as-is if the client takes it, otherwise inflate
+	etag := contentETag(data)
+	w.Header().Add("Vary", "Accept-Encoding")
+	if a.Header.TileCompression == pmtiles.Gzip {
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+		} else {
+			if data, err = gunzip(data); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			etag = strings.TrimSuffix(etag, `"`) + `-identity"`
+		}
+	}
+	serveBytes(w, r, "application/vnd.mapbox-vector-tile", etag, data)
+}
+
+// tileJSON builds a TileJSON 3.0 document from the archive header and
+// metadata, described by the manifest when there is one.
+func (s *Server) tileJSON(l *layer, a *inspect.Archive, base string) map[string]any {
+	h := a.Header
+	tj := map[string]any{
+		"tilejson": "3.0.0",
+		"name":     l.name,
+		"scheme":   "xyz",
+		"tiles":    []string{base + "/" + l.name + "/{z}/{x}/{y}.mvt"},
+		"minzoom":  h.MinZoom,
+		"maxzoom":  h.MaxZoom,
+		"bounds":   []float64{fromE7(h.MinLonE7), fromE7(h.MinLatE7), fromE7(h.MaxLonE7), fromE7(h.MaxLatE7)},
+		"center":   []any{fromE7(h.CenterLonE7), fromE7(h.CenterLatE7), h.CenterZoom},
+	}
+	if layers, ok := a.Metadata["vector_layers"]; ok {
+		tj["vector_layers"] = layers
+	}
+	for _, key := range []string{"attribution", "description", "version"} {
+		if v, ok := a.Metadata[key]; ok {
+			tj[key] = v
+		}
+	}
+
+	if m := s.Manifest; m != nil {
+		if l.manifest != nil {
+			tj["description"] = l.manifest.Name
+		} else {
+			tj["description"] = m.Name + " (all layers)"
+		}
+		if m.Source.Authority != "" {
+			tj["attribution"] = m.Source.Authority
+		}
+		tj["version"] = m.Updated
+	}
+	return tj
+}
+
+// layerByFile finds the layer serving an archive file name.
+func (s *Server) layerByFile(file string) *layer {
+	for _, l := range s.layers {
+		if l.file == file {
+			return l
+		}
+	}
+	return nil
+}
+
+// open returns the layer's archive, reopening it if the file has changed.
+// The archive stays open until release is called, even if the file changes
+// again meanwhile.
+func (l *layer) open(dir string) (a *inspect.Archive, release func(), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	path := filepath.Join(dir, l.file)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if l.archive == nil || !info.ModTime().Equal(l.modTime) || info.Size() != l.size {
+		a, err := inspect.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if l.archive != nil {
+			l.archive.retire()
+		}
+		l.archive, l.modTime, l.size = &openArchive{Archive: a}, info.ModTime(), info.Size()
+	}
+
+	cur := l.archive
+	cur.readers++
+	return cur.Archive, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if cur.readers--; cur.readers == 0 && cur.retired {
+			cur.Close()
+		}
+	}, nil
+}
+
+// serveBytes writes an in-memory response with ETag handling.
+func serveBytes(w http.ResponseWriter, r *http.Request, contentType, etag string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	serveBytes(w, r, "application/json", contentETag(data), data)
+}
+
+// baseURL is the scheme and host the client used to reach us.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+		scheme = p
+	}
+	return scheme + "://" + r.Host
+}
+
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gunzip: %w", err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func fromE7(v int32) float64 {
+	return float64(v) / 1e7
+}
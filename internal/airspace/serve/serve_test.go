@@ -0,0 +1,54 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLayerKeepsReplacedArchiveOpen(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("../testdata/mini_airspace_reference.pmtiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "mini.pmtiles")
+	os.WriteFile(path, data, 0644)
+	touch := func(d time.Duration) {
+		t.Helper()
+		modTime := time.Now().Add(d)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := &layer{file: "mini.pmtiles"}
+	old, release, err := l.open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A rebuild replaces the archive while a request is still reading it
+	touch(time.Minute)
+	cur, releaseCur, err := l.open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseCur()
+	if cur == old {
+		t.Fatal("changed archive not reopened")
+	}
+	if _, err := old.Tile(0, 0, 0); err != nil {
+		t.Fatalf("replaced archive closed under its reader: %v", err)
+	}
+
+	// The last reader closes it
+	release()
+	if _, err := old.Tile(0, 0, 0); err == nil {
+		t.Error("replaced archive still open after its last reader")
+	}
+	if _, err := cur.Tile(0, 0, 0); err != nil {
+		t.Errorf("current archive: %v", err)
+	}
+}
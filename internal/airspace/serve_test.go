@@ -0,0 +1,122 @@
+package airspace_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulmach/orb/encoding/mvt"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/serve"
+)
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile(testReference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "mini.pmtiles"), data, 0644)
+
+	manifest := &airspace.RegionManifest{
+		Name:    "Test",
+		Updated: "2026-01-01T00:00:00Z",
+		Layers:  map[string]airspace.ManifestLayer{"mini": {Name: "Mini Airspace", File: "mini.pmtiles"}},
+		Source:  airspace.ManifestSource{Authority: "FAA"},
+	}
+	s, err := serve.New(dir, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	get := func(path string, header map[string]string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		// Use the transport directly so gzip is not negotiated for us
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Range request on the raw archive
+	resp := get("/pmtiles/mini.pmtiles", map[string]string{"Range": "bytes=0-6"})
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "PMTiles" {
+		t.Errorf("range request = %d %q, want 206 \"PMTiles\"", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Access-Control-Allow-Origin") != "*" || resp.Header.Get("ETag") == "" {
+		t.Errorf("missing CORS or ETag headers: %v", resp.Header)
+	}
+
+	// TileJSON from the archive and manifest
+	resp = get("/mini.json", nil)
+	var tj struct {
+		Tiles       []string `json:"tiles"`
+		MinZoom     int      `json:"minzoom"`
+		Description string   `json:"description"`
+		Attribution string   `json:"attribution"`
+		Layers      []any    `json:"vector_layers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tj); err != nil {
+		t.Fatalf("tilejson: %v", err)
+	}
+	if len(tj.Tiles) != 1 || tj.Tiles[0] != srv.URL+"/mini/{z}/{x}/{y}.mvt" {
+		t.Errorf("tiles = %v", tj.Tiles)
+	}
+	if tj.Description != "Mini Airspace" || tj.Attribution != "FAA" || len(tj.Layers) == 0 {
+		t.Errorf("tilejson = %+v", tj)
+	}
+
+	// Decoded tile, then a conditional request for it
+	resp = get("/mini/0/0/0.mvt", nil)
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("tile = %d (encoding %q)", resp.StatusCode, resp.Header.Get("Content-Encoding"))
+	}
+	if layers, err := mvt.Unmarshal(body); err != nil || len(layers) == 0 {
+		t.Errorf("tile does not decode: %v", err)
+	}
+	resp = get("/mini/0/0/0.mvt", map[string]string{"If-None-Match": resp.Header.Get("ETag")})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional tile request = %d, want 304", resp.StatusCode)
+	}
+
+	// Stored gzip passes through when accepted
+	resp = get("/mini/0/0/0.mvt", map[string]string{"Accept-Encoding": "gzip"})
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Errorf("gzip not passed through: %v", resp.Header)
+	}
+
+	// Tiles outside the archive are empty, bad coordinates rejected
+	if resp = get("/mini/14/0/0.mvt", nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("missing tile = %d, want 204", resp.StatusCode)
+	}
+	if resp = get("/mini/1/2/0.mvt", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("out of range tile = %d, want 400", resp.StatusCode)
+	}
+
+	// CORS preflight
+	req, _ := http.NewRequest(http.MethodOptions, srv.URL+"/pmtiles/mini.pmtiles", nil)
+	pre, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre.Body.Close()
+	if pre.StatusCode != http.StatusNoContent || pre.Header.Get("Access-Control-Allow-Headers") == "" {
+		t.Errorf("preflight = %d %v", pre.StatusCode, pre.Header)
+	}
+}
@@ -0,0 +1,200 @@
+package airspace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Render defaults, matching the Hugo airspace template.
+const (
+	styleOpacity = 0.3 // RenderRule.Opacity
+	styleWidth   = 1.0 // RenderRule.Width
+	styleRadius  = 5.0 // RenderRule.Radius
+)
+
+// Style is a MapLibre GL style (spec version 8).
+type Style struct {
+	Version  int                    `json:"version"`
+	Name     string                 `json:"name"`
+	Metadata map[string]any         `json:"metadata,omitempty"`
+	Center   []float64              `json:"center,omitempty"`
+	Zoom     float64                `json:"zoom,omitempty"`
+	Sources  map[string]StyleSource `json:"sources"`
+	Layers   []StyleLayer           `json:"layers"`
+}
+
+// StyleSource is a vector tile source.
+type StyleSource struct {
+	Type        string `json:"type"`
+	URL         string `json:"url"`
+	Attribution string `json:"attribution,omitempty"`
+}
+
+// StyleLayer is one MapLibre style layer.
+type StyleLayer struct {
+	ID          string            `json:"id"`
+	Type        string            `json:"type"` // fill, line or circle
+	Source      string            `json:"source"`
+	SourceLayer string            `json:"source-layer"`
+	MinZoom     int               `json:"minzoom,omitempty"`
+	Filter      []any             `json:"filter,omitempty"`
+	Layout      map[string]string `json:"layout"`
+	Paint       map[string]any    `json:"paint"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// StyleOptions sets where a style's sources load tiles from.
+type StyleOptions struct {
+	// TilesURL is the base URL of the region's tiles dir. Sources are
+	// pmtiles:// URLs of the archives under it (for the PMTiles protocol
+	// plugin), or TileJSON URLs with TileJSON.
+	TilesURL string
+
+	// TileJSON points sources at <TilesURL>/<layer>.json, as served by
+	// "airspace serve", for clients without the PMTiles plugin.
+	TileJSON bool
+
+	// Combined reads every layer from the manifest's combined archive, if
+	// it has one, instead of one archive per layer.
+	Combined bool
+}
+
+// MapLibreStyle builds a style that renders a regional manifest's layers
+// the way the Hugo template does: every render rule is drawn, a rule with
+// filter_prop matches features whose property equals filter_value, and a
+// rule without one matches every feature. Polygons get a fill and an
+// outline layer, lines a line layer and points a circle layer.
+//
+// Layers are ordered polygons, lines, points, each by manifest key, so
+// points draw on top. Each layer's metadata names its manifest layer, so
+// clients can toggle a manifest layer's style layers together. Hidden
+// layers (default_visible false) have visibility none. Layers appear from
+// the manifest's min zoom; MapLibre overzooms beyond the max.
+//
+// The style holds no base map: use it as is for an overlay, or append its
+// sources and layers to a base style.
+func MapLibreStyle(m RegionManifest, opts StyleOptions) Style {
+	style := Style{
+		Version:  8,
+		Name:     m.Name + " Airspace",
+		Metadata: map[string]any{"airspace:region": m.Region, "airspace:manifest_version": m.Version},
+		Sources:  make(map[string]StyleSource),
+		Layers:   []StyleLayer{},
+	}
+	if len(m.BBox) == 4 {
+		style.Center = []float64{(m.BBox[0] + m.BBox[2]) / 2, (m.BBox[1] + m.BBox[3]) / 2}
+		style.Zoom = 3
+	}
+
+	base := strings.TrimSuffix(opts.TilesURL, "/")
+	source := func(key, file string) StyleSource {
+		url := "pmtiles://" + base + "/" + file
+		if opts.TileJSON {
+			url = base + "/" + key + ".json"
+		}
+		return StyleSource{Type: "vector", URL: url, Attribution: m.Source.Authority}
+	}
+	combined := opts.Combined && m.Combined != ""
+	if combined {
+		style.Sources["combined"] = source("combined", m.Combined)
+	}
+
+	keys := make([]string, 0, len(m.Layers))
+	for k := range m.Layers {
+		keys = append(keys, k)
+	}
+	geomOrder := map[string]int{"polygon": 0, "line": 1, "point": 2}
+	sort.Slice(keys, func(i, j int) bool {
+		gi, gj := geomOrder[m.Layers[keys[i]].GeomType], geomOrder[m.Layers[keys[j]].GeomType]
+		if gi != gj {
+			return gi < gj
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		layer := m.Layers[key]
+		src := "combined"
+		if !combined {
+			src = key
+			style.Sources[key] = source(key, layer.File)
+		}
+		style.Layers = append(style.Layers, styleLayers(key, src, layer)...)
+	}
+	return style
+}
+
+// styleLayers returns the style layers for one manifest layer's render rules.
+func styleLayers(key, source string, layer ManifestLayer) []StyleLayer {
+	visibility := "visible"
+	if !layer.DefaultVisible {
+		visibility = "none"
+	}
+	minZoom := 0
+	if len(layer.ZoomRange) > 0 {
+		minZoom = layer.ZoomRange[0]
+	}
+
+	var out []StyleLayer
+	for i, rule := range layer.RenderRules {
+		opacity, width, radius := rule.Opacity, rule.Width, rule.Radius
+		if opacity == 0 {
+			opacity = styleOpacity
+		}
+		if width == 0 {
+			width = styleWidth
+		}
+		if radius == 0 {
+			radius = styleRadius
+		}
+		stroke := rule.Stroke
+		if stroke == "" {
+			stroke = rule.Fill
+		}
+
+		add := func(kind string, paint map[string]any) {
+			l := StyleLayer{
+				ID:          fmt.Sprintf("%s-%d-%s", key, i, kind),
+				Type:        kind,
+				Source:      source,
+				SourceLayer: layer.PMTilesLayer,
+				MinZoom:     minZoom,
+				Layout:      map[string]string{"visibility": visibility},
+				Paint:       paint,
+				Metadata:    map[string]string{"airspace:layer": key},
+			}
+			if rule.FilterProp != "" {
+				l.Filter = []any{"==", []any{"get", rule.FilterProp}, rule.FilterValue}
+			}
+			out = append(out, l)
+		}
+
+		switch layer.GeomType {
+		case "point":
+			add("circle", map[string]any{
+				"circle-color":        rule.Fill,
+				"circle-opacity":      opacity,
+				"circle-radius":       radius,
+				"circle-stroke-color": stroke,
+				"circle-stroke-width": width,
+			})
+		case "line":
+			add("line", map[string]any{
+				"line-color":   stroke,
+				"line-opacity": opacity,
+				"line-width":   width,
+			})
+		default:
+			add("fill", map[string]any{
+				"fill-color":   rule.Fill,
+				"fill-opacity": opacity,
+			})
+			add("line", map[string]any{
+				"line-color": stroke,
+				"line-width": width,
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package airspace_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+func testManifest() airspace.RegionManifest {
+	return airspace.RegionManifest{
+		Region:  "test",
+		Name:    "Test",
+		Version: 1,
+		Updated: "2026-10-17T00:00:00Z",
+		BBox:    []float64{-125, 24, -66, 50},
+		Layers: map[string]airspace.ManifestLayer{
+			"boundary": {
+				Name: "Class Airspace", File: "faa_boundary.pmtiles", PMTilesLayer: "boundary",
+				GeomType: "polygon", ZoomRange: []int{4, 14}, DefaultVisible: true,
+				RenderRules: []airspace.RenderRule{
+					{FilterProp: "CLASS", FilterValue: "B", Fill: "#0066cc", Opacity: 0.15, Width: 1},
+					{Fill: "#666666", Stroke: "#333333"},
+				},
+			},
+			"airports": {
+				Name: "Airports", File: "faa_airports.pmtiles", PMTilesLayer: "airports",
+				GeomType: "point", ZoomRange: []int{0, 10},
+				RenderRules: []airspace.RenderRule{{Fill: "#00ff00", Radius: 4}},
+			},
+		},
+		Source: airspace.ManifestSource{Authority: "FAA", UpdateCycle: "56 days"},
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	m := testManifest()
+	if err := airspace.ValidateManifest(m); err != nil {
+		t.Fatalf("valid manifest: %v", err)
+	}
+
+	layer := m.Layers["boundary"]
+	layer.GeomType = "area"
+	layer.RenderRules = []airspace.RenderRule{{FilterProp: "CLASS", Fill: "blue", Opacity: 2}}
+	m.Layers["boundary"] = layer
+	m.BBox = m.BBox[:2]
+
+	err := airspace.ValidateManifest(m)
+	if err == nil {
+		t.Fatal("invalid manifest passed")
+	}
+	for _, want := range []string{
+		"/bbox: 2 items",
+		"/layers/boundary/geom_type: area is not one of",
+		`/layers/boundary/render_rules/0: property "filter_prop" requires "filter_value"`,
+		"/layers/boundary/render_rules/0/fill:",
+		"/layers/boundary/render_rules/0/opacity: 2 is greater than 1",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("errors missing %q:\n%v", want, err)
+		}
+	}
+
+	if err := airspace.ValidateManifestJSON([]byte(`{"region":"test","extra":1}`)); err == nil || !strings.Contains(err.Error(), `unknown property "extra"`) {
+		t.Errorf("unknown property: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(airspace.ManifestSchema(), &schema); err != nil || schema["$schema"] == nil {
+		t.Errorf("schema = %v, %v", schema["$schema"], err)
+	}
+}
+
+func TestMapLibreStyle(t *testing.T) {
+	style := airspace.MapLibreStyle(testManifest(), airspace.StyleOptions{TilesURL: "https://example.com/airspace/tiles/"})
+	if style.Version != 8 || style.Sources["boundary"].URL != "pmtiles://https://example.com/airspace/tiles/faa_boundary.pmtiles" {
+		t.Fatalf("style = %+v", style)
+	}
+
+	var ids []string
+	for _, l := range style.Layers {
+		ids = append(ids, l.ID)
+	}
+	if got := strings.Join(ids, " "); got != "boundary-0-fill boundary-0-line boundary-1-fill boundary-1-line airports-0-circle" {
+		t.Fatalf("layers = %s", got)
+	}
+
+	fill, outline, fallback, circle := style.Layers[0], style.Layers[1], style.Layers[3], style.Layers[4]
+	if f, _ := json.Marshal(fill.Filter); string(f) != `["==",["get","CLASS"],"B"]` || fill.Paint["fill-opacity"] != 0.15 {
+		t.Errorf("fill = %+v", fill)
+	}
+	if outline.Paint["line-color"] != "#0066cc" || fill.MinZoom != 4 || fill.SourceLayer != "boundary" {
+		t.Errorf("outline = %+v", outline)
+	}
+	if fallback.Filter != nil || fallback.Paint["line-color"] != "#333333" || style.Layers[2].Paint["fill-opacity"] != 0.3 {
+		t.Errorf("fallback = %+v", fallback)
+	}
+	if circle.Layout["visibility"] != "none" || circle.Paint["circle-radius"] != 4.0 || circle.Metadata["airspace:layer"] != "airports" {
+		t.Errorf("circle = %+v", circle)
+	}
+
+	m := testManifest()
+	m.Combined = "faa_airspace_combined.pmtiles"
+	style = airspace.MapLibreStyle(m, airspace.StyleOptions{TilesURL: "http://localhost:8091", TileJSON: true, Combined: true})
+	if len(style.Sources) != 1 || style.Sources["combined"].URL != "http://localhost:8091/combined.json" || style.Layers[0].Source != "combined" {
+		t.Errorf("combined sources = %+v", style.Sources)
+	}
+}
@@ -37,6 +37,13 @@ type DatasetSync struct {
 	Features   int     `json:"features,omitempty"`
 	ETag       string  `json:"etag,omitempty"`
 	Error      string  `json:"error,omitempty"`
+
+	// Changes is the feature-level diff against the previous download
+	// (nil if the dataset was not downloaded or could not be compared).
+	Changes *FeatureChanges `json:"changes,omitempty"`
+
+	// Dynamic is the feed refresh of a time-bounded dataset.
+	Dynamic *DynamicResult `json:"dynamic,omitempty"`
 }
 
 // SyncHistory maintains a rolling log of sync runs.
@@ -51,6 +58,7 @@ type SyncHistory struct {
 
 // SyncOptions configures sync behavior.
 type SyncOptions struct {
+	Region    Region
 	OutputDir string
 	DataDir   string
 	Force     bool
@@ -59,19 +67,27 @@ type SyncOptions struct {
 	Datasets  []string // Which datasets to sync (nil = default set)
 }
 
-// DefaultSyncOptions returns sensible defaults.
+// DefaultSyncOptions returns sensible defaults for the default region.
 func DefaultSyncOptions() SyncOptions {
+	return RegionSyncOptions(DefaultRegion())
+}
+
+// RegionSyncOptions returns sensible defaults for a region.
+func RegionSyncOptions(region Region) SyncOptions {
 	return SyncOptions{
-		OutputDir: DirGeoJSON,
-		DataDir:   DirData,
+		Region:    region,
+		OutputDir: region.GeoJSONDir(),
+		DataDir:   region.DataDir(),
 		Force:     false,
 		SkipLarge: true,
 		Timeout:   5 * time.Minute,
-		Datasets:  DatasetOrder,
+		Datasets:  region.DatasetOrder,
 	}
 }
 
-// Sync downloads FAA data with ETag-based change detection.
+// Sync downloads FAA data with ETag-based change detection. Each downloaded
+// dataset is compared feature by feature with the previous download and the
+// differences are recorded in its DatasetSync.Changes.
 func Sync(opts SyncOptions) (*SyncResult, error) {
 	syncStart := time.Now()
 
@@ -101,8 +117,26 @@ func Sync(opts SyncOptions) (*SyncResult, error) {
 
 	for _, key := range opts.Datasets {
 		dsStart := time.Now()
-		ds := Datasets[key]
+		ds, err := opts.Region.Dataset(key)
+		if err != nil {
+			return nil, err
+		}
 		outPath := filepath.Join(opts.OutputDir, ds.GeoJSON)
+
+		if ds.Dynamic != nil {
+			dsResult := syncDynamic(client, ds, outPath, result.Timestamp)
+			dsResult.DurationMs = time.Since(dsStart).Milliseconds()
+			result.Datasets[key] = dsResult
+			switch dsResult.Status {
+			case "updated":
+				result.Updated++
+				totalBytes += dsResult.SizeBytes
+			case "unchanged":
+				result.Skipped++
+			}
+			continue
+		}
+
 		dsResult := DatasetSync{}
 
 		// Check if we need to download
@@ -136,8 +170,13 @@ func Sync(opts SyncOptions) (*SyncResult, error) {
 			}
 		}
 
+		// Fingerprint the previous download so we can tell which features changed
+		var prevFeatures map[string]featureFingerprint
+		if dsResult.Status != "missing" {
+			prevFeatures, _ = fingerprintFeatures(outPath)
+		}
+
 		// Download the dataset
-		var err error
 		if ds.IsPaginated {
 			err = DownloadPaginated(client, ds, outPath)
 		} else {
@@ -177,6 +216,12 @@ func Sync(opts SyncOptions) (*SyncResult, error) {
 			dsResult.SizeMB = sizeMB
 		}
 
+		// Feature-level diff (a missing previous file counts every feature as added)
+		if currFeatures, err := fingerprintFeatures(outPath); err == nil {
+			dsResult.Features = len(currFeatures)
+			dsResult.Changes = diffFeatures(prevFeatures, currFeatures)
+		}
+
 		dsResult.Status = "updated"
 		dsResult.DurationMs = time.Since(dsStart).Milliseconds()
 		result.Datasets[key] = dsResult
@@ -227,9 +272,19 @@ func Sync(opts SyncOptions) (*SyncResult, error) {
 	return result, nil
 }
 
-// CheckETag does a HEAD request and compares ETag.
+// CheckETag does a HEAD request and compares ETag. For a local file the
+// modification time and size stand in for the ETag.
 // Returns (newETag, needsDownload).
 func CheckETag(client *http.Client, url, oldETag string) (string, bool) {
+	if path, ok := localPath(url); ok {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", true // Download on error
+		}
+		newETag := fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+		return newETag, newETag != oldETag
+	}
+
 	req, err := http.NewRequest("HEAD", url, nil)
 	if err != nil {
 		return "", true // Download on error
@@ -0,0 +1,226 @@
+package airspace_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+const (
+	suaV1 = `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"GLOBAL_ID":"A","OBJECTID":1,"NAME":"R-2501"},"geometry":{"type":"Point","coordinates":[-120,35]}},
+{"type":"Feature","properties":{"GLOBAL_ID":"B","OBJECTID":2,"NAME":"R-2502"},"geometry":{"type":"Point","coordinates":[-121,35]}},
+{"type":"Feature","properties":{"GLOBAL_ID":"C","OBJECTID":3,"NAME":"R-2503"},"geometry":{"type":"Point","coordinates":[-122,35]}},
+{"type":"Feature","properties":{"GLOBAL_ID":"D","OBJECTID":4,"NAME":"R-2504"},"geometry":{"type":"Point","coordinates":[-123,35]}}]}`
+
+	// A unchanged (renumbered only), B moved, C renamed, D removed, E added
+	suaV2 = `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"GLOBAL_ID":"A","OBJECTID":11,"NAME":"R-2501"},"geometry":{"type":"Point","coordinates":[-120,35]}},
+{"type":"Feature","properties":{"GLOBAL_ID":"B","OBJECTID":12,"NAME":"R-2502"},"geometry":{"type":"Point","coordinates":[-121.5,35]}},
+{"type":"Feature","properties":{"GLOBAL_ID":"C","OBJECTID":13,"NAME":"R-2503A"},"geometry":{"type":"Point","coordinates":[-122,35]}},
+{"type":"Feature","properties":{"GLOBAL_ID":"E","OBJECTID":15,"NAME":"R-2505"},"geometry":{"type":"Point","coordinates":[-124,35]}}]}`
+)
+
+func TestSyncFeatureChanges(t *testing.T) {
+	version := "v1"
+	body := map[string]string{"v1": suaV1, "v2": suaV2}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", version)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(body[version]))
+		}
+	}))
+	defer srv.Close()
+
+	region := airspace.Region{
+		Key:          "test",
+		DatasetOrder: []string{"sua"},
+		Datasets: map[string]airspace.Dataset{
+			"sua": {Name: "SUA", GeoJSON: "sua.geojson", BaseURL: srv.URL},
+		},
+	}
+	opts := airspace.RegionSyncOptions(region)
+	opts.OutputDir = t.TempDir()
+	opts.DataDir = t.TempDir()
+
+	// First sync: everything is new
+	result, err := airspace.Sync(opts)
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	first := result.Datasets["sua"]
+	if first.Changes == nil || first.Changes.Added != 4 || first.Features != 4 {
+		t.Fatalf("first sync changes = %+v, features = %d; want 4 added", first.Changes, first.Features)
+	}
+
+	// Second sync: source changed
+	version = "v2"
+	result, err = airspace.Sync(opts)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	c := result.Datasets["sua"].Changes
+	if c == nil {
+		t.Fatal("second sync recorded no feature changes")
+	}
+	if c.Added != 1 || c.Removed != 1 || c.Modified != 2 || c.Unchanged != 1 {
+		t.Errorf("got +%d -%d ~%d =%d, want +1 -1 ~2 =1", c.Added, c.Removed, c.Modified, c.Unchanged)
+	}
+
+	want := map[string]string{
+		"B": airspace.ChangeGeometry,
+		"C": airspace.ChangeAttributes,
+		"D": airspace.ChangeRemoved,
+		"E": airspace.ChangeAdded,
+	}
+	for _, ch := range c.Changes {
+		if want[ch.ID] != ch.Change {
+			t.Errorf("%s (%s): change = %q, want %q", ch.ID, ch.Name, ch.Change, want[ch.ID])
+		}
+		delete(want, ch.ID)
+	}
+	if len(want) > 0 {
+		t.Errorf("changes not reported: %v", want)
+	}
+
+	// Changes are kept in the history
+	history := airspace.LoadSyncHistory(filepath.Join(opts.DataDir, airspace.FileSyncHistory))
+	if len(history.Runs) != 2 || history.Runs[0].Datasets["sua"].Changes == nil {
+		t.Errorf("history does not record feature changes: %+v", history.Runs)
+	}
+
+	// Third sync: ETag unchanged, nothing downloaded or compared
+	result, err = airspace.Sync(opts)
+	if err != nil {
+		t.Fatalf("third sync: %v", err)
+	}
+	if ds := result.Datasets["sua"]; ds.Status != "unchanged" || ds.Changes != nil {
+		t.Errorf("third sync = %+v, want unchanged without changes", ds)
+	}
+}
+
+func TestSyncRenumberedRows(t *testing.T) {
+	// No GLOBAL_ID: the re-export reorders and renumbers every row, and
+	// renames KSFO
+	body := map[string]string{
+		"v1": `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"OBJECTID":1,"IDENT":"KSFO","NAME":"San Francisco"},"geometry":{"type":"Point","coordinates":[-122.37,37.62]}},
+{"type":"Feature","properties":{"OBJECTID":2,"IDENT":"KOAK","NAME":"Oakland"},"geometry":{"type":"Point","coordinates":[-122.22,37.72]}},
+{"type":"Feature","properties":{"OBJECTID":3,"NAME":"Tower"},"geometry":{"type":"Point","coordinates":[-122.4,37.8]}}]}`,
+		"v2": `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"OBJECTID":7,"NAME":"Tower"},"geometry":{"type":"Point","coordinates":[-122.4,37.8]}},
+{"type":"Feature","properties":{"OBJECTID":8,"IDENT":"KOAK","NAME":"Oakland"},"geometry":{"type":"Point","coordinates":[-122.22,37.72]}},
+{"type":"Feature","properties":{"OBJECTID":9,"IDENT":"KSFO","NAME":"San Francisco Intl"},"geometry":{"type":"Point","coordinates":[-122.37,37.62]}}]}`,
+	}
+	version := "v1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", version)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(body[version]))
+		}
+	}))
+	defer srv.Close()
+
+	region := airspace.Region{
+		Key:          "test",
+		DatasetOrder: []string{"airports"},
+		Datasets: map[string]airspace.Dataset{
+			"airports": {Name: "Airports", GeoJSON: "airports.geojson", BaseURL: srv.URL},
+		},
+	}
+	opts := airspace.RegionSyncOptions(region)
+	opts.OutputDir = t.TempDir()
+	opts.DataDir = t.TempDir()
+	if _, err := airspace.Sync(opts); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	version = "v2"
+	result, err := airspace.Sync(opts)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	c := result.Datasets["airports"].Changes
+	if c == nil || c.Added != 0 || c.Removed != 0 || c.Modified != 1 || c.Unchanged != 2 {
+		t.Fatalf("changes = %+v, want KSFO modified only", c)
+	}
+	if ch := c.Changes[0]; ch.ID != "KSFO" || ch.Change != airspace.ChangeAttributes {
+		t.Errorf("change = %+v, want KSFO attributes", ch)
+	}
+}
+
+func TestSyncTruncatesAddedFirst(t *testing.T) {
+	// 60 removed, 30 renamed and 30 added: more than MaxFeatureChanges
+	collection := func(ids []string, name func(string) string) string {
+		var features []string
+		for _, id := range ids {
+			features = append(features, fmt.Sprintf(`{"type":"Feature","properties":{"GLOBAL_ID":%q,"NAME":%q},"geometry":{"type":"Point","coordinates":[0.%s,35]}}`, id, name(id), id[1:]))
+		}
+		return `{"type":"FeatureCollection","features":[` + strings.Join(features, ",\n") + `]}`
+	}
+	var v1, v2 []string
+	for i := range 120 {
+		v1 = append(v1, fmt.Sprintf("F%03d", i))
+		if i >= 60 {
+			v2 = append(v2, fmt.Sprintf("F%03d", i))
+		}
+	}
+	for i := range 30 {
+		v2 = append(v2, fmt.Sprintf("N%03d", i))
+	}
+	body := map[string]string{
+		"v1": collection(v1, func(id string) string { return id }),
+		"v2": collection(v2, func(id string) string {
+			if id < "F090" {
+				return id + " renamed"
+			}
+			return id
+		}),
+	}
+	version := "v1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", version)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(body[version]))
+		}
+	}))
+	defer srv.Close()
+
+	region := airspace.Region{
+		Key:          "test",
+		DatasetOrder: []string{"sua"},
+		Datasets:     map[string]airspace.Dataset{"sua": {Name: "SUA", GeoJSON: "sua.geojson", BaseURL: srv.URL}},
+	}
+	opts := airspace.RegionSyncOptions(region)
+	opts.OutputDir = t.TempDir()
+	opts.DataDir = t.TempDir()
+	if _, err := airspace.Sync(opts); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	version = "v2"
+	result, err := airspace.Sync(opts)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	c := result.Datasets["sua"].Changes
+	if c == nil || c.Removed != 60 || c.Modified != 30 || c.Added != 30 {
+		t.Fatalf("changes = %+v", c)
+	}
+	kinds := make(map[string]int)
+	for _, ch := range c.Changes {
+		kinds[ch.Change]++
+	}
+	if len(c.Changes) != airspace.MaxFeatureChanges || kinds[airspace.ChangeRemoved] != 60 || kinds[airspace.ChangeAttributes] != 30 {
+		t.Errorf("listed %v, want every removed and modified feature", kinds)
+	}
+	if !c.Truncated || !slices.Equal(c.TruncatedKinds, []string{"added"}) {
+		t.Errorf("truncated = %v %v, want added only", c.Truncated, c.TruncatedKinds)
+	}
+}
@@ -0,0 +1,139 @@
+package airspace_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/notam"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/query"
+)
+
+// The feed has an Oakland TFR that ended 2026-10-02, a 3 NM circle around
+// SFO on 2026-10-20 15:00–23:00Z (SFC–3000 MSL) and an open-ended San Jose
+// TFR from 2026-10-15 (SFC–FL180).
+const testTFRFeed = "testdata/tfr/tfr_aixm.xml"
+
+var tfrNow = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+func TestParseTFRFeed(t *testing.T) {
+	f, err := os.Open(testTFRFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rs, err := notam.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 3 {
+		t.Fatalf("parsed %d restrictions, want 3 (TEMPDELTA slice skipped)", len(rs))
+	}
+
+	sfo := rs[1]
+	if sfo.NOTAM != "6/4821" || sfo.Lower.Code != "SFC" || sfo.Upper != (notam.Limit{Value: 3000, UOM: "FT", Code: "MSL"}) {
+		t.Errorf("SFO TFR = %+v", sfo)
+	}
+	circle := sfo.Geometry.(orb.Polygon)
+	if !planar.PolygonContains(circle, orb.Point{-122.375, 37.619}) || planar.PolygonContains(circle, orb.Point{-122.375, 37.68}) {
+		t.Error("SFO circle should contain the airport and not a point 3.6 NM north")
+	}
+	sj := rs[2]
+	if !sj.To.IsZero() || sj.Upper.UOM != "FL" || sj.Geometry.(orb.Polygon)[0].Orientation() != orb.CCW {
+		t.Errorf("San Jose TFR = %+v; want open-ended, FL upper, rewound CCW", sj)
+	}
+
+	current := notam.Current(rs, tfrNow, 72*time.Hour)
+	if len(current) != 2 || current[0].NOTAM != "6/4821" {
+		t.Fatalf("current = %d restrictions, want SFO and San Jose", len(current))
+	}
+	if len(notam.Current(rs, tfrNow, 24*time.Hour)) != 1 {
+		t.Error("a 24h lookahead should defer the SFO TFR")
+	}
+
+	w := notam.Validity(current, tfrNow)
+	if w.Active != 1 || !w.To.IsZero() || !w.NextChange.Equal(sfo.From) {
+		t.Errorf("window = %+v; want 1 active, open-ended, next change at SFO start", w)
+	}
+}
+
+func TestDynamicLayer(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "tfr.geojson")
+	ds := airspace.Dataset{
+		Key:     "tfr",
+		GeoJSON: "tfr.geojson",
+		Dynamic: &airspace.DynamicSource{Format: "aixm", Lookahead: 72 * time.Hour},
+	}
+
+	result, err := airspace.FetchDynamic(http.DefaultClient, ds, testTFRFeed, out, tfrNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Parsed != 3 || result.Expired != 1 || result.Deferred != 0 || result.Written != 2 {
+		t.Errorf("result = %+v; want 3 parsed, 1 expired, 2 written", result)
+	}
+
+	// The manifest window: built at tfrNow, next change when the SFO TFR starts
+	if err := os.Chtimes(out, tfrNow, tfrNow); err != nil {
+		t.Fatal(err)
+	}
+	v, err := airspace.DynamicValidity(out, time.Hour, tfrNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Expires != "2026-10-18T01:00:00Z" || v.From != "2026-10-15T00:00:00Z" || v.To != "" || v.Active != 1 {
+		t.Errorf("validity = %+v", v)
+	}
+	if v, _ := airspace.DynamicValidity(out, 7*24*time.Hour, tfrNow); v.Expires != "2026-10-20T15:00:00Z" {
+		t.Errorf("expires = %s, want the SFO start", v.Expires)
+	}
+
+	// Queries at time T
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc, err := geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := query.New(query.Source{Dataset: "tfr", Features: fc})
+	sfo := orb.Point{-122.375, 37.619}
+	alt := query.Altitude{Feet: 400, Ref: query.RefAGL}
+
+	tests := []struct {
+		at   *time.Time
+		want []string
+	}{
+		{nil, []string{"6/4821 SAN FRANCISCO, CA VIP MOVEMENT"}},
+		{ptr(time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC)), []string{"6/4821 SAN FRANCISCO, CA VIP MOVEMENT"}},
+		{ptr(time.Date(2026, 10, 20, 23, 0, 0, 0, time.UTC)), nil}, // End is exclusive
+		{&tfrNow, nil},
+	}
+	for _, tt := range tests {
+		res := idx.Query(query.Request{Geometry: sfo, Altitude: &alt, At: tt.at})
+		if !slices.Equal(res.Summary.TFRs, tt.want) {
+			t.Errorf("at %v: TFRs = %v, want %v", tt.at, res.Summary.TFRs, tt.want)
+		}
+	}
+
+	// Above the 3000 MSL ceiling the TFR does not apply
+	alt = query.Altitude{Feet: 3500, Ref: query.RefMSL}
+	if res := idx.Query(query.Request{Geometry: sfo, Altitude: &alt}); len(res.Summary.TFRs) != 0 {
+		t.Errorf("3500 MSL: TFRs = %v, want none", res.Summary.TFRs)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -1,15 +1,161 @@
 // Package airspace provides tile generation for FAA airspace data.
 package airspace
 
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Tile limits and point drop rate applied when a TileConfig leaves them
+// unset. These are tippecanoe's defaults, so both tilers agree.
+const (
+	DefaultMaxTileBytes    = 500000 // Compressed bytes per tile
+	DefaultMaxTileFeatures = 200000 // Features per tile
+	DefaultReduceRate      = 2.5    // Point drop rate per zoom below max_zoom
+)
+
 // TileConfig holds settings for tile generation.
 type TileConfig struct {
-	MinZoom         int
-	MaxZoom         int
-	Layer           string // Layer name in the tiles
-	DropDensest     bool   // Drop features in dense tiles
-	NoFeatureLimit  bool   // Don't limit features per tile
-	NoTileSizeLimit bool   // Don't limit tile size
-	ReduceRate      int    // Feature reduction rate (tippecanoe -r flag, 0 = default)
+	Profile         string  `yaml:"profile"` // Named base settings from tile_profiles (see ParseRegions)
+	MinZoom         int     `yaml:"min_zoom"`
+	MaxZoom         int     `yaml:"max_zoom"`
+	Layer           string  `yaml:"-"`                  // Layer name in the tiles (from Dataset.Layer)
+	DropDensest     bool    `yaml:"drop_densest"`       // Over a limit, drop features in the densest areas first
+	NoFeatureLimit  bool    `yaml:"no_feature_limit"`   // Don't limit features per tile
+	NoTileSizeLimit bool    `yaml:"no_tile_size_limit"` // Don't limit tile size
+	ReduceRate      float64 `yaml:"reduce_rate"`        // Point drop rate per zoom (tippecanoe -r; 0 = DefaultReduceRate, 1 = keep all)
+	MemoryBudgetMB  int     `yaml:"memory_budget_mb"`   // gotiler: stream with bounded RAM (0 = load everything in memory)
+
+	MaxTileBytes    int     `yaml:"max_tile_bytes"`    // 0 = DefaultMaxTileBytes
+	MaxTileFeatures int     `yaml:"max_tile_features"` // 0 = DefaultMaxTileFeatures
+	Simplification  float64 `yaml:"simplification"`    // Tolerance in tile units (tippecanoe -S; 0 = built-in per-zoom table, <0 = none)
+
+	// Attribute filtering, applied in this order. Include and Exclude use
+	// the source property names; Rename maps source to tile names.
+	Include []string          `yaml:"include"` // Keep only these properties (empty = all)
+	Exclude []string          `yaml:"exclude"` // Drop these properties
+	Rename  map[string]string `yaml:"rename"`
+}
+
+// TileBytesLimit returns the per-tile byte limit (0 = unlimited).
+func (c TileConfig) TileBytesLimit() int {
+	switch {
+	case c.NoTileSizeLimit:
+		return 0
+	case c.MaxTileBytes > 0:
+		return c.MaxTileBytes
+	}
+	return DefaultMaxTileBytes
+}
+
+// TileFeaturesLimit returns the per-tile feature limit (0 = unlimited).
+func (c TileConfig) TileFeaturesLimit() int {
+	switch {
+	case c.NoFeatureLimit:
+		return 0
+	case c.MaxTileFeatures > 0:
+		return c.MaxTileFeatures
+	}
+	return DefaultMaxTileFeatures
+}
+
+// DropRate returns the effective point drop rate.
+func (c TileConfig) DropRate() float64 {
+	if c.ReduceRate > 0 {
+		return c.ReduceRate
+	}
+	return DefaultReduceRate
+}
+
+// HasAttributeRules reports whether the config filters or renames properties.
+func (c TileConfig) HasAttributeRules() bool {
+	return len(c.Include) > 0 || len(c.Exclude) > 0 || len(c.Rename) > 0
+}
+
+// FilterProperties applies Include, Exclude and Rename. Without rules it
+// returns props itself.
+func (c TileConfig) FilterProperties(props map[string]any) map[string]any {
+	if !c.HasAttributeRules() {
+		return props
+	}
+	out := make(map[string]any, len(props))
+	for k, v := range props {
+		if len(c.Include) > 0 && !slices.Contains(c.Include, k) {
+			continue
+		}
+		if slices.Contains(c.Exclude, k) {
+			continue
+		}
+		if name, ok := c.Rename[k]; ok {
+			k = name
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// WriteFilteredGeoJSON streams a FeatureCollection from inPath to outPath
+// with FilterProperties applied to every feature, for tilers that cannot
+// rename attributes themselves.
+func WriteFilteredGeoJSON(inPath, outPath string, config TileConfig) error {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriterSize(out, 1<<20)
+	w.WriteString(`{"type":"FeatureCollection","features":[`)
+	first := true
+	err = DecodeFeatures(in, func(raw json.RawMessage) error {
+		var feature map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &feature); err != nil {
+			return fmt.Errorf("parsing feature: %w", err)
+		}
+		var props map[string]any
+		if p, ok := feature["properties"]; ok {
+			if err := json.Unmarshal(p, &props); err != nil {
+				return fmt.Errorf("parsing properties: %w", err)
+			}
+		}
+		data, err := json.Marshal(config.FilterProperties(props))
+		if err != nil {
+			return err
+		}
+		feature["properties"] = data
+		if data, err = json.Marshal(feature); err != nil {
+			return err
+		}
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.WriteString("]}\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// LayerInput is one GeoJSON file in a combined multi-layer archive.
+type LayerInput struct {
+	InputPath string
+	Config    TileConfig // Config.Layer names the MVT layer
 }
 
 // Tiler generates PMTiles from GeoJSON.
@@ -18,6 +164,10 @@ type Tiler interface {
 	// Returns the output path and any error.
 	Tile(inputPath, outputPath string, config TileConfig) error
 
+	// TileCombined converts several GeoJSON files into a single PMTiles
+	// archive whose tiles hold one MVT layer per input.
+	TileCombined(inputs []LayerInput, outputPath string) error
+
 	// Name returns the engine name (e.g., "tippecanoe", "go").
 	Name() string
 
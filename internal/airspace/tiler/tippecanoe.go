@@ -3,7 +3,9 @@ package tiler
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/joeblew999/ubuntu-website/internal/airspace"
 )
@@ -29,6 +31,85 @@ func (t *Tippecanoe) Available() bool {
 
 // Tile converts GeoJSON to PMTiles using tippecanoe.
 func (t *Tippecanoe) Tile(inputPath, outputPath string, config airspace.TileConfig) error {
+	inputPath, cleanup, err := filteredInput(inputPath, outputPath, config)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Layer name
+	var args []string
+	if config.Layer != "" {
+		args = append(args, "--layer="+config.Layer)
+	}
+
+	args = append(args, inputPath)
+	return t.run(outputPath, config, args)
+}
+
+// filteredInput applies the config's attribute rules. tippecanoe can keep
+// or drop attributes (-y, -x) but not rename them, so the input is
+// rewritten next to the output instead; cleanup removes the copy.
+func filteredInput(inputPath, outputPath string, config airspace.TileConfig) (string, func(), error) {
+	if !config.HasAttributeRules() {
+		return inputPath, func() {}, nil
+	}
+	f, err := os.CreateTemp(filepath.Dir(outputPath), ".tippecanoe-*.geojson")
+	if err != nil {
+		return "", nil, err
+	}
+	f.Close()
+	cleanup := func() { os.Remove(f.Name()) }
+	if err := airspace.WriteFilteredGeoJSON(inputPath, f.Name(), config); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("filtering attributes: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+// TileCombined converts several GeoJSON files into one multi-layer archive
+// using tippecanoe's named layers (-L name:file). tippecanoe applies one set
+// of options to the whole run, so the zoom range is the union of the inputs'
+// (auto if any input is auto), feature flags are enabled if any input sets
+// them, the tightest tile limits apply and simplification follows the first
+// input. Attribute rules stay per layer.
+func (t *Tippecanoe) TileCombined(inputs []airspace.LayerInput, outputPath string) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no inputs to tile")
+	}
+
+	config := inputs[0].Config
+	var args []string
+	for _, in := range inputs {
+		c := in.Config
+		inputPath, cleanup, err := filteredInput(in.InputPath, outputPath, c)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if config.MinZoom < 0 || c.MinZoom < 0 || config.MaxZoom < 0 || c.MaxZoom < 0 {
+			config.MinZoom, config.MaxZoom = -1, -1
+		} else {
+			config.MinZoom = min(config.MinZoom, c.MinZoom)
+			config.MaxZoom = max(config.MaxZoom, c.MaxZoom)
+		}
+		config.ReduceRate = max(config.ReduceRate, c.ReduceRate)
+		config.DropDensest = config.DropDensest || c.DropDensest
+		config.NoFeatureLimit = config.NoFeatureLimit || c.NoFeatureLimit
+		config.NoTileSizeLimit = config.NoTileSizeLimit || c.NoTileSizeLimit
+		config.MaxTileBytes = tighter(config.MaxTileBytes, c.MaxTileBytes)
+		config.MaxTileFeatures = tighter(config.MaxTileFeatures, c.MaxTileFeatures)
+
+		args = append(args, "-L", c.Layer+":"+inputPath)
+	}
+
+	return t.run(outputPath, config, args)
+}
+
+// run invokes tippecanoe with the shared options for config followed by
+// the input arguments.
+func (t *Tippecanoe) run(outputPath string, config airspace.TileConfig, inputArgs []string) error {
 	if !t.Available() {
 		return fmt.Errorf("tippecanoe not found in PATH")
 	}
@@ -38,11 +119,6 @@ func (t *Tippecanoe) Tile(inputPath, outputPath string, config airspace.TileConf
 		"--force",
 	}
 
-	// Layer name
-	if config.Layer != "" {
-		args = append(args, "--layer="+config.Layer)
-	}
-
 	// Zoom settings
 	if config.MinZoom >= 0 && config.MaxZoom >= 0 {
 		args = append(args, fmt.Sprintf("-Z%d", config.MinZoom))
@@ -53,7 +129,7 @@ func (t *Tippecanoe) Tile(inputPath, outputPath string, config airspace.TileConf
 
 	// Feature handling
 	if config.ReduceRate > 0 {
-		args = append(args, fmt.Sprintf("-r%d", config.ReduceRate))
+		args = append(args, fmt.Sprintf("-r%g", config.ReduceRate))
 	}
 	if config.DropDensest {
 		args = append(args, "--drop-densest-as-needed")
@@ -64,8 +140,22 @@ func (t *Tippecanoe) Tile(inputPath, outputPath string, config airspace.TileConf
 	if config.NoTileSizeLimit {
 		args = append(args, "--no-tile-size-limit")
 	}
+	if config.MaxTileBytes > 0 && !config.NoTileSizeLimit {
+		args = append(args, fmt.Sprintf("-M%d", config.MaxTileBytes))
+	}
+	if config.MaxTileFeatures > 0 && !config.NoFeatureLimit {
+		args = append(args, fmt.Sprintf("-O%d", config.MaxTileFeatures))
+	}
 
-	args = append(args, inputPath)
+	// Simplification
+	switch {
+	case config.Simplification > 0:
+		args = append(args, fmt.Sprintf("-S%g", config.Simplification))
+	case config.Simplification < 0:
+		args = append(args, "--no-line-simplification")
+	}
+
+	args = append(args, inputArgs...)
 
 	cmd := exec.Command("tippecanoe", args...)
 	output, err := cmd.CombinedOutput()
@@ -76,5 +166,13 @@ func (t *Tippecanoe) Tile(inputPath, outputPath string, config airspace.TileConf
 	return nil
 }
 
+// tighter returns the smaller non-zero limit (0 = default).
+func tighter(a, b int) int {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
 // Ensure Tippecanoe implements Tiler.
 var _ airspace.Tiler = (*Tippecanoe)(nil)
@@ -1,14 +1,21 @@
 package airspace_test
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/maptile"
 	"github.com/protomaps/go-pmtiles/pmtiles"
 
 	"github.com/joeblew999/ubuntu-website/internal/airspace"
 	"github.com/joeblew999/ubuntu-website/internal/airspace/gotiler"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/inspect"
 	"github.com/joeblew999/ubuntu-website/internal/airspace/tiler"
 )
 
@@ -108,12 +115,6 @@ func TestGoTilerGeneratesTiles(t *testing.T) {
 func TestGoTilerMatchesReference(t *testing.T) {
 	g := gotiler.New()
 
-	// Read reference file
-	refData, err := os.ReadFile(testReference)
-	if err != nil {
-		t.Fatalf("failed to read reference: %v", err)
-	}
-
 	tmpDir := t.TempDir()
 	goOutput := filepath.Join(tmpDir, "go.pmtiles")
 
@@ -125,63 +126,334 @@ func TestGoTilerMatchesReference(t *testing.T) {
 
 	// Generate with Go
 	if err := g.Tile(testInput, goOutput, config); err != nil {
-		t.Skipf("GoTiler not yet implemented: %v", err)
+		t.Fatalf("GoTiler failed: %v", err)
 	}
 
-	// Read generated file
-	goData, err := os.ReadFile(goOutput)
+	report, err := inspect.Inspect(goOutput, 0)
 	if err != nil {
-		t.Fatalf("failed to read go output: %v", err)
+		t.Fatalf("inspecting go output: %v", err)
+	}
+	if report.Verify != "ok" {
+		t.Errorf("pmtiles verify: %s", report.Verify)
+	}
+	if report.Header.MinZoom != config.MinZoom || report.Header.MaxZoom != config.MaxZoom {
+		t.Errorf("zoom range = %d-%d, want %d-%d", report.Header.MinZoom, report.Header.MaxZoom, config.MinZoom, config.MaxZoom)
+	}
+	if len(report.Layers) != 1 || report.Layers[0].Name != config.Layer {
+		t.Errorf("layers = %+v, want one %q layer", report.Layers, config.Layer)
+	}
+
+	// Exact byte-for-byte match is not required - different tools encode
+	// geometry differently. The same tiles must exist, with the same features.
+	diff, err := inspect.Diff(testReference, goOutput)
+	if err != nil {
+		t.Fatalf("diffing against reference: %v", err)
+	}
+	if diff.Added != 0 || diff.Removed != 0 {
+		t.Errorf("tile set differs from reference: %d added, %d removed", diff.Added, diff.Removed)
+	}
+	for _, z := range diff.Zooms {
+		if z.FeatureDelta != 0 {
+			t.Errorf("zoom %d: %d features, reference has %d", z.Zoom, z.FeaturesB, z.FeaturesA)
+		}
+	}
+}
+
+// TestGoTilerDeterministicAcrossWorkers verifies output does not depend on
+// concurrency: sequential against several parallel runs, in memory and
+// streaming.
+func TestGoTilerDeterministicAcrossWorkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := airspace.TileConfig{
+		MinZoom: 0,
+		MaxZoom: 10,
+		Layer:   "test",
+	}
+
+	for _, budget := range []int{0, 1} {
+		config.MemoryBudgetMB = budget
+
+		seqOutput := filepath.Join(tmpDir, "seq.pmtiles")
+		if err := (&gotiler.GoTiler{Workers: 1}).Tile(testInput, seqOutput, config); err != nil {
+			t.Fatalf("sequential tiling failed: %v", err)
+		}
+		seqData, _ := os.ReadFile(seqOutput)
+
+		for _, workers := range []int{2, 8, 0} {
+			for run := range 3 {
+				parOutput := filepath.Join(tmpDir, "par.pmtiles")
+				if err := (&gotiler.GoTiler{Workers: workers}).Tile(testInput, parOutput, config); err != nil {
+					t.Fatalf("parallel tiling failed: %v", err)
+				}
+				parData, _ := os.ReadFile(parOutput)
+				if !bytes.Equal(seqData, parData) {
+					t.Errorf("budget=%dMB workers=%d run=%d: output differs from sequential (%d vs %d bytes)",
+						budget, workers, run, len(parData), len(seqData))
+				}
+			}
+		}
 	}
+}
+
+// TestGoTilerStreamingMatchesInMemory verifies the memory-bounded streaming
+// mode produces byte-identical output to the default in-memory mode.
+func TestGoTilerStreamingMatchesInMemory(t *testing.T) {
+	g := gotiler.New()
+	tmpDir := t.TempDir()
 
-	// Debug: compare headers
-	t.Logf("Reference header (first 20 bytes): %x", refData[:20])
-	t.Logf("Go output header (first 20 bytes): %x", goData[:20])
+	config := airspace.TileConfig{
+		MinZoom: 0,
+		MaxZoom: 10,
+		Layer:   "test",
+	}
+
+	memOutput := filepath.Join(tmpDir, "mem.pmtiles")
+	if err := g.Tile(testInput, memOutput, config); err != nil {
+		t.Fatalf("in-memory tiling failed: %v", err)
+	}
 
-	// Compare sizes first (quick check)
-	if len(goData) != len(refData) {
-		t.Logf("size mismatch: go=%d bytes, reference=%d bytes", len(goData), len(refData))
+	config.MemoryBudgetMB = 1
+	streamOutput := filepath.Join(tmpDir, "stream.pmtiles")
+	if err := g.Tile(testInput, streamOutput, config); err != nil {
+		t.Fatalf("streaming tiling failed: %v", err)
 	}
 
-	// For now, just verify the file is a valid PMTiles v3
-	if len(goData) < 127 {
-		t.Fatalf("output too small for PMTiles header: %d bytes", len(goData))
+	memData, _ := os.ReadFile(memOutput)
+	streamData, _ := os.ReadFile(streamOutput)
+	if !bytes.Equal(memData, streamData) {
+		t.Errorf("streaming output differs: mem=%d bytes, stream=%d bytes", len(memData), len(streamData))
 	}
 
-	// Check magic number
-	if string(goData[:7]) != "PMTiles" {
-		t.Errorf("invalid magic number: %s", string(goData[:7]))
+	// Spill files must be cleaned up
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 2 {
+		t.Errorf("expected only the two outputs in %s, found %d entries", tmpDir, len(entries))
 	}
+}
+
+// TestGoTilerDeduplicatesTiles verifies identical interior tiles are stored
+// once and every directory entry (root and leaf) resolves inside the tile data.
+func TestGoTilerDeduplicatesTiles(t *testing.T) {
+	g := gotiler.New()
+	tmpDir := t.TempDir()
 
-	// Check version
-	if goData[7] != 3 {
-		t.Errorf("invalid version: %d (expected 3)", goData[7])
+	// One large square: interior tiles at high zoom are fully covered and identical
+	input := filepath.Join(tmpDir, "square.geojson")
+	square := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"CLASS":"E"},` +
+		`"geometry":{"type":"Polygon","coordinates":[[[-100,30],[-90,30],[-90,40],[-100,40],[-100,30]]]}}]}`
+	if err := os.WriteFile(input, []byte(square), 0644); err != nil {
+		t.Fatal(err)
 	}
 
-	t.Log("GoTiler produces valid PMTiles v3 format")
+	output := filepath.Join(tmpDir, "square.pmtiles")
+	config := airspace.TileConfig{MinZoom: 0, MaxZoom: 9, Layer: "test"}
+	if err := g.Tile(input, output, config); err != nil {
+		t.Fatalf("tiling failed: %v", err)
+	}
 
-	// Verify the header can be parsed by go-pmtiles library
-	header, err := pmtiles.DeserializeHeader(goData[:pmtiles.HeaderV3LenBytes])
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := pmtiles.DeserializeHeader(data[:pmtiles.HeaderV3LenBytes])
 	if err != nil {
 		t.Fatalf("failed to deserialize header: %v", err)
 	}
 
-	t.Logf("PMTiles header: minzoom=%d, maxzoom=%d, tiles=%d",
-		header.MinZoom, header.MaxZoom, header.TileEntriesCount)
+	if header.TileContentsCount >= header.AddressedTilesCount {
+		t.Errorf("no deduplication: contents=%d addressed=%d", header.TileContentsCount, header.AddressedTilesCount)
+	}
+	if header.TileEntriesCount >= header.AddressedTilesCount {
+		t.Errorf("no run-length entries: entries=%d addressed=%d", header.TileEntriesCount, header.AddressedTilesCount)
+	}
+	if header.MinLonE7 != -100*1e7 || header.MaxLatE7 != 40*1e7 {
+		t.Errorf("bounds not set: min lon %d, max lat %d", header.MinLonE7, header.MaxLatE7)
+	}
+
+	var addressed uint64
+	fetch := func(offset, length uint64) ([]byte, error) {
+		return data[offset : offset+length], nil
+	}
+	err = pmtiles.IterateEntries(header, fetch, func(e pmtiles.EntryV3) {
+		addressed += uint64(e.RunLength)
+		if e.Offset+uint64(e.Length) > header.TileDataLength {
+			t.Errorf("tile %d points past the tile data section", e.TileID)
+		}
+	})
+	if err != nil {
+		t.Fatalf("iterating directories: %v", err)
+	}
+	if addressed != header.AddressedTilesCount {
+		t.Errorf("directories address %d tiles, header says %d", addressed, header.AddressedTilesCount)
+	}
+}
 
-	if header.MinZoom != uint8(config.MinZoom) {
-		t.Errorf("wrong minzoom: got %d, want %d", header.MinZoom, config.MinZoom)
+// TestGoTilerLeafDirectories tiles a grid of points, one per z13 tile, so
+// the entries don't fit the 16 KiB root directory: they must go to leaf
+// directories, and every tile must still be found through them.
+func TestGoTilerLeafDirectories(t *testing.T) {
+	if testing.Short() {
+		t.Skip("tiles 16,900 points - skipping in short mode")
 	}
-	if header.MaxZoom != uint8(config.MaxZoom) {
-		t.Errorf("wrong maxzoom: got %d, want %d", header.MaxZoom, config.MaxZoom)
+	tmpDir := t.TempDir()
+
+	const n = 130 // 16,900 points, 0.05° apart (z13 tiles are ~0.044° wide)
+	var b strings.Builder
+	b.WriteString(`{"type":"FeatureCollection","features":[`)
+	var points []orb.Point
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			p := orb.Point{-100 + float64(i)*0.05 + 0.01, 30 + float64(j)*0.05 + 0.01}
+			if len(points) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `{"type":"Feature","properties":{"ID":%d},"geometry":{"type":"Point","coordinates":[%f,%f]}}`, len(points), p[0], p[1])
+			points = append(points, p)
+		}
+	}
+	b.WriteString("]}")
+	input := filepath.Join(tmpDir, "grid.geojson")
+	if err := os.WriteFile(input, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(tmpDir, "grid.pmtiles")
+	config := airspace.TileConfig{MinZoom: 13, MaxZoom: 13, Layer: "points", ReduceRate: 1}
+	if err := gotiler.New().Tile(input, output, config); err != nil {
+		t.Fatalf("tiling failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if header.TileEntriesCount == 0 {
-		t.Error("no tiles in output")
+	header, err := pmtiles.DeserializeHeader(data[:pmtiles.HeaderV3LenBytes])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.LeafDirectoryLength == 0 || header.RootLength > 16384-pmtiles.HeaderV3LenBytes {
+		t.Fatalf("root %d bytes, leaves %d bytes; want leaf directories", header.RootLength, header.LeafDirectoryLength)
+	}
+	if header.AddressedTilesCount != n*n || header.TileEntriesCount != n*n || header.TileContentsCount != n*n {
+		t.Errorf("addressed %d, entries %d, contents %d; want %d distinct tiles",
+			header.AddressedTilesCount, header.TileEntriesCount, header.TileContentsCount, n*n)
+	}
+
+	// Every entry is reached through the leaves, one tile each
+	var entries, addressed uint64
+	err = pmtiles.IterateEntries(header, func(offset, length uint64) ([]byte, error) {
+		return data[offset : offset+length], nil
+	}, func(e pmtiles.EntryV3) {
+		entries++
+		addressed += uint64(e.RunLength)
+		if e.RunLength != 1 {
+			t.Errorf("tile %d has run length %d, want 1", e.TileID, e.RunLength)
+		}
+	})
+	if err != nil {
+		t.Fatalf("iterating directories: %v", err)
+	}
+	if entries != header.TileEntriesCount || addressed != header.AddressedTilesCount {
+		t.Errorf("directories have %d entries for %d tiles, header says %d for %d", entries, addressed, header.TileEntriesCount, header.AddressedTilesCount)
+	}
+
+	// Look tiles up as a reader does: root → leaf → tile, which holds its point
+	root := pmtiles.DeserializeEntries(bytes.NewBuffer(data[header.RootOffset:header.RootOffset+header.RootLength]), pmtiles.Gzip)
+	for _, k := range []int{0, 1, n*n/2 + 7, n*n - 1} {
+		tile := maptile.At(points[k], 13)
+		id := pmtiles.ZxyToID(13, tile.X, tile.Y)
+		leaf, ok := pmtiles.FindTile(root, id)
+		if !ok || leaf.RunLength != 0 {
+			t.Fatalf("point %d: no leaf directory for tile %d", k, id)
+		}
+		start := header.LeafDirectoryOffset + leaf.Offset
+		leafEntries := pmtiles.DeserializeEntries(bytes.NewBuffer(data[start:start+uint64(leaf.Length)]), pmtiles.Gzip)
+		e, ok := pmtiles.FindTile(leafEntries, id)
+		if !ok || e.RunLength == 0 {
+			t.Fatalf("point %d: tile %d not in its leaf", k, id)
+		}
+		start = header.TileDataOffset + e.Offset
+		layers, err := mvt.UnmarshalGzipped(data[start : start+uint64(e.Length)])
+		if err != nil {
+			t.Fatalf("point %d: %v", k, err)
+		}
+		if len(layers) != 1 || len(layers[0].Features) != 1 || layers[0].Features[0].Properties["ID"] != float64(k) {
+			t.Errorf("point %d: tile %d has %+v", k, id, layers)
+		}
+	}
+}
+
+// TestGoTilerCombined verifies a combined archive holds one MVT layer per
+// input, each only within its own zoom range, in memory and streaming.
+func TestGoTilerCombined(t *testing.T) {
+	g := gotiler.New()
+	tmpDir := t.TempDir()
+
+	inputs := []airspace.LayerInput{
+		{InputPath: filepath.Join(testQueryDir, "faa_airspace_boundary.geojson"), Config: airspace.TileConfig{MinZoom: 0, MaxZoom: 8, Layer: "boundary"}},
+		{InputPath: filepath.Join(testQueryDir, "faa_special_use_airspace.geojson"), Config: airspace.TileConfig{MinZoom: 0, MaxZoom: 8, Layer: "sua"}},
+		{InputPath: filepath.Join(testQueryDir, "faa_airports.geojson"), Config: airspace.TileConfig{MinZoom: 6, MaxZoom: 8, Layer: "airports"}},
+	}
+
+	// Same file name in both runs: it becomes the archive name in the metadata
+	memOutput := filepath.Join(tmpDir, "combined.pmtiles")
+	if err := g.TileCombined(inputs, memOutput); err != nil {
+		t.Fatalf("combined tiling failed: %v", err)
+	}
+
+	data, err := os.ReadFile(memOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := pmtiles.DeserializeHeader(data[:pmtiles.HeaderV3LenBytes])
+	if err != nil {
+		t.Fatalf("failed to deserialize header: %v", err)
+	}
+	if header.MinZoom != 0 || header.MaxZoom != 8 {
+		t.Errorf("zoom range = %d-%d, want 0-8", header.MinZoom, header.MaxZoom)
+	}
+
+	seen := make(map[string]bool)
+	fetch := func(offset, length uint64) ([]byte, error) {
+		return data[offset : offset+length], nil
+	}
+	err = pmtiles.IterateEntries(header, fetch, func(e pmtiles.EntryV3) {
+		z, _, _ := pmtiles.IDToZxy(e.TileID)
+		start := header.TileDataOffset + e.Offset
+		layers, err := mvt.UnmarshalGzipped(data[start : start+uint64(e.Length)])
+		if err != nil {
+			t.Fatalf("decoding tile %d: %v", e.TileID, err)
+		}
+		for _, l := range layers {
+			seen[l.Name] = true
+			if l.Name == "airports" && z < 6 {
+				t.Errorf("airports layer present at zoom %d, below its minzoom", z)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("iterating directories: %v", err)
+	}
+	for _, in := range inputs {
+		if !seen[in.Config.Layer] {
+			t.Errorf("layer %q missing from combined archive", in.Config.Layer)
+		}
 	}
 
-	// Note: Exact byte-for-byte match is not required - different tools may produce
-	// functionally equivalent but not identical files. What matters is that the
-	// tiles can be read and rendered correctly.
+	// Streaming must match the in-memory archive
+	for i := range inputs {
+		inputs[i].Config.MemoryBudgetMB = 1
+	}
+	streamDir := filepath.Join(tmpDir, "stream")
+	os.Mkdir(streamDir, 0755)
+	streamOutput := filepath.Join(streamDir, "combined.pmtiles")
+	if err := g.TileCombined(inputs, streamOutput); err != nil {
+		t.Fatalf("streaming combined tiling failed: %v", err)
+	}
+	streamData, _ := os.ReadFile(streamOutput)
+	if !bytes.Equal(data, streamData) {
+		t.Errorf("streaming combined output differs: mem=%d bytes, stream=%d bytes", len(data), len(streamData))
+	}
 }
 
 // TestGoTilerRealData tests with real FAA data (if available).
@@ -251,27 +523,131 @@ func TestTippecanoeMatchesReference(t *testing.T) {
 		t.Fatalf("tippecanoe failed: %v", err)
 	}
 
-	// Read both files
-	tipData, err := os.ReadFile(tipOutput)
+	// tippecanoe output may vary slightly between versions, but the tile set
+	// and feature counts should not
+	diff, err := inspect.Diff(testReference, tipOutput)
 	if err != nil {
-		t.Fatalf("failed to read tippecanoe output: %v", err)
+		t.Fatalf("diffing against reference: %v", err)
 	}
-
-	refData, err := os.ReadFile(testReference)
-	if err != nil {
-		t.Fatalf("failed to read reference: %v", err)
+	if diff.Added != 0 || diff.Removed != 0 {
+		t.Errorf("tile set differs from reference: %d added, %d removed", diff.Added, diff.Removed)
+		t.Log("This may be expected if tippecanoe version changed - consider updating reference")
+	}
+	for _, z := range diff.Zooms {
+		if z.FeatureDelta != 0 {
+			t.Errorf("zoom %d: %d features, reference has %d", z.Zoom, z.FeaturesB, z.FeaturesA)
+		}
 	}
+}
 
-	// Compare sizes
-	if len(tipData) != len(refData) {
-		t.Logf("size mismatch: tippecanoe=%d bytes, reference=%d bytes", len(tipData), len(refData))
-		t.Log("This may be expected if tippecanoe version changed - consider updating reference")
+// TestGoTilerTileConfig verifies the Go tiler honours point dropping,
+// per-tile feature limits and attribute rules, in memory and streaming.
+func TestGoTilerTileConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// 500 points in a 0.4° square: one tile per zoom up to 6
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":"FeatureCollection","features":[`)
+	for i := range 500 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"type":"Feature","properties":{"NAME":"P%d","IDENT":"X%d","REMARKS":"r"},"geometry":{"type":"Point","coordinates":[%f,%f]}}`,
+			i, i, -100.1+float64(i%25)*0.016, 35.1+float64(i/25)*0.016)
+	}
+	buf.WriteString(`]}`)
+	input := filepath.Join(tmpDir, "points.geojson")
+	if err := os.WriteFile(input, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tile := func(config airspace.TileConfig) (*inspect.Report, []byte) {
+		t.Helper()
+		config.MinZoom, config.MaxZoom, config.Layer = 0, 6, "points"
+		output := filepath.Join(tmpDir, "points.pmtiles")
+		if err := gotiler.New().Tile(input, output, config); err != nil {
+			t.Fatal(err)
+		}
+		report, err := inspect.Inspect(output, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(output)
+		return report, data
+	}
+	features := func(config airspace.TileConfig) (perZoom []int) {
+		report, _ := tile(config)
+		for _, z := range report.Zooms {
+			perZoom = append(perZoom, z.Features)
+		}
+		return perZoom
+	}
+
+	// Default drop rate thins points below max zoom; rate 1 keeps them all
+	got := features(airspace.TileConfig{})
+	if got[6] != 500 || got[0] >= got[3] || got[3] >= got[6] {
+		t.Errorf("default drop rate: features per zoom = %v, want increasing to 500", got)
+	}
+	if got := features(airspace.TileConfig{ReduceRate: 1}); got[0] != 500 {
+		t.Errorf("reduce_rate 1: features per zoom = %v, want 500 at every zoom", got)
+	}
+
+	// A feature limit drops features until each tile fits
+	limited := airspace.TileConfig{ReduceRate: 1, MaxTileFeatures: 100, DropDensest: true}
+	report, memData := tile(limited)
+	for _, z := range report.Zooms {
+		if z.Features > 100*z.Tiles {
+			t.Errorf("zoom %d: %d features in %d tiles, limit 100 per tile", z.Zoom, z.Features, z.Tiles)
+		}
+	}
+	limited.MemoryBudgetMB = 1
+	if _, streamData := tile(limited); !bytes.Equal(memData, streamData) {
+		t.Error("streaming output differs from in-memory with feature limits")
+	}
+	if got := features(airspace.TileConfig{ReduceRate: 1, MaxTileFeatures: 100, NoFeatureLimit: true}); got[0] != 500 {
+		t.Errorf("no_feature_limit: features per zoom = %v, want 500", got)
+	}
+
+	// Attribute rules apply to tiles and vector_layers metadata
+	report, _ = tile(airspace.TileConfig{Include: []string{"NAME", "IDENT"}, Rename: map[string]string{"IDENT": "id"}})
+	layers, _ := report.Metadata["vector_layers"].([]any)
+	if len(layers) != 1 {
+		t.Fatalf("vector_layers = %v", report.Metadata["vector_layers"])
+	}
+	fields, _ := layers[0].(map[string]any)["fields"].(map[string]any)
+	if _, ok := fields["id"]; !ok || fields["IDENT"] != nil || fields["REMARKS"] != nil || fields["NAME"] == nil {
+		t.Errorf("fields = %v, want NAME and id", fields)
 	}
+}
 
-	// Note: tippecanoe output may vary slightly between runs due to timestamps
-	// For now, just check size is similar (within 10%)
-	sizeDiff := float64(len(tipData)-len(refData)) / float64(len(refData))
-	if sizeDiff > 0.1 || sizeDiff < -0.1 {
-		t.Errorf("size differs by %.1f%% - likely incompatible output", sizeDiff*100)
+// TestTileProfiles verifies datasets build on named profiles and override keys.
+func TestTileProfiles(t *testing.T) {
+	reg, err := airspace.ParseRegions([]byte(`
+default: test
+tile_profiles:
+  complete: {min_zoom: 0, max_zoom: 10, reduce_rate: 1, no_feature_limit: true, exclude: [REMARKS]}
+regions:
+  test:
+    name: Test
+    datasets:
+      a: {geojson: a.geojson, pmtiles: a.pmtiles, layer: a, tile: {profile: complete, max_zoom: 12}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reg.Regions["test"].Datasets["a"].Tile
+	if got.MinZoom != 0 || got.MaxZoom != 12 || got.ReduceRate != 1 || !got.NoFeatureLimit || len(got.Exclude) != 1 {
+		t.Errorf("tile config = %+v", got)
+	}
+
+	_, err = airspace.ParseRegions([]byte(`
+default: test
+regions:
+  test:
+    datasets:
+      a: {geojson: a.geojson, pmtiles: a.pmtiles, layer: a, tile: {profile: missing}}
+`))
+	if err == nil || !strings.Contains(err.Error(), `unknown tile profile "missing"`) {
+		t.Errorf("unknown profile error = %v", err)
 	}
 }
@@ -1,100 +1,392 @@
 package airspace
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"time"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace/r2"
+)
+
+// R2 layout (all keys under R2Prefix):
+//
+//	manifest.json                                 pointer: each region's current version
+//	<tiles_path>/versions.json                    publish log with per-file SHA-256
+//	<tiles_path>/v/<version>/<file>.pmtiles       immutable, uploaded when content changed
+//	<tiles_path>/v/<version>/manifest_<region>.json
+//	<tiles_path>/v/<version>/style_<region>.json  MapLibre style for the version's archives
+//	manifest_region.schema.json                   JSON Schema of the regional manifests
+//
+// A version's regional manifest lists each layer's file relative to
+// <tiles_path>, which may be an object from an earlier version if its content
+// did not change. Clients read manifest.json, so a publish becomes visible
+// only when the pointer flips, and rolling back is flipping it again.
+const R2Prefix = "airspace"
+
+// Cache headers for published objects.
+const (
+	cacheImmutable = "public, max-age=31536000, immutable"
+	cachePointer   = "no-cache"
 )
 
-// UploadToR2 uploads PMTiles and manifests to Cloudflare R2.
-// Reads from the manifest to know what files to upload.
-func UploadToR2() error {
-	manifestPath := filepath.Join(DirData, FileUSAManifest)
+// PublishLog is a region's versions.json in R2.
+type PublishLog struct {
+	Region   string             `json:"region"`
+	Versions []PublishedVersion `json:"versions"` // Newest first
+}
+
+// PublishedVersion is one publish of a region.
+type PublishedVersion struct {
+	Version   string                   `json:"version"`
+	Published time.Time                `json:"published"`
+	Manifest  string                   `json:"manifest"`        // Regional manifest, relative to R2Prefix
+	Style     string                   `json:"style,omitempty"` // MapLibre style, relative to R2Prefix
+	Files     map[string]PublishedFile `json:"files"`           // By local file name
+}
 
-	// Check manifest exists
-	if _, err := os.Stat(manifestPath); err != nil {
-		return fmt.Errorf("manifest not found: %s (run 'airspace manifest' first)", manifestPath)
+// PublishedFile is an archive as stored in R2.
+type PublishedFile struct {
+	Key    string `json:"key"` // Relative to <tiles_path>
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// PublishOptions configures PublishToR2.
+type PublishOptions struct {
+	Region       Region
+	TilesDir     string // Local PMTiles (default region tiles dir)
+	ManifestPath string // Local regional manifest (default data/airspace/manifest_<region>.json)
+	Version      string // Version name (default UTC timestamp)
+}
+
+// RegionPublishOptions returns the default publish options for a region.
+func RegionPublishOptions(region Region) PublishOptions {
+	return PublishOptions{
+		Region:       region,
+		TilesDir:     region.PMTilesDir(),
+		ManifestPath: filepath.Join(DirData, region.ManifestFile()),
 	}
+}
+
+// PublishResult records what a publish did.
+type PublishResult struct {
+	Version string
+	Files   []PublishFileResult
+}
+
+// PublishFileResult is the outcome for one archive.
+type PublishFileResult struct {
+	File   string
+	Key    string
+	Size   int64
+	Status string // "uploaded", "unchanged" (reused from an earlier version), "resumed" (already in this version), "missing"
+}
 
-	// Check wrangler is available
-	if _, err := exec.LookPath("wrangler"); err != nil {
-		return fmt.Errorf("wrangler not found in PATH (npm install -g wrangler)")
+// PublishToR2 publishes a region's PMTiles under a new version prefix and
+// then flips manifest.json to it. Archives whose SHA-256 matches one already
+// published are not uploaded again.
+func PublishToR2(client *r2.Client, opts PublishOptions) (*PublishResult, error) {
+	region := opts.Region
+	manifest, err := LoadRegionManifest(opts.ManifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("manifest not found: %s (run 'airspace manifest' first): %w", opts.ManifestPath, err)
 	}
 
-	fmt.Printf("Uploading airspace data to r2://%s/airspace/\n\n", R2Bucket)
+	version := opts.Version
+	if version == "" {
+		version = time.Now().UTC().Format("20060102T150405Z")
+	}
+	tilesPrefix := R2Prefix + "/" + region.TilesPath + "/"
 
-	// Load manifest
-	manifest, err := loadUSAManifest(manifestPath)
+	log, err := loadPublishLog(client, region)
 	if err != nil {
-		return fmt.Errorf("loading manifest: %w", err)
+		return nil, err
+	}
+	if _, ok := log.find(version); ok {
+		return nil, fmt.Errorf("version %s already published", version)
 	}
 
-	// Upload PMTiles from manifest
-	fmt.Println("=== PMTiles (from manifest) ===")
-	for _, layer := range manifest.Layers {
-		filePath := filepath.Join(DirPMTiles, layer.File)
-		if _, err := os.Stat(filePath); err != nil {
-			fmt.Printf("  SKIP: %s (file not found)\n", layer.File)
+	// Content already in R2, by file name and hash
+	stored := make(map[string]PublishedFile)
+	for _, v := range log.Versions {
+		for name, f := range v.Files {
+			stored[name+"@"+f.SHA256] = f
+		}
+	}
+
+	result := &PublishResult{Version: version}
+	published := PublishedVersion{
+		Version:  version,
+		Manifest: region.TilesPath + "/v/" + version + "/" + region.ManifestFile(),
+		Style:    region.TilesPath + "/v/" + version + "/" + region.StyleFile(),
+		Files:    make(map[string]PublishedFile),
+	}
+
+	// Upload archives that are not stored yet
+	for _, file := range manifestFiles(manifest) {
+		path := filepath.Join(opts.TilesDir, file)
+		info, err := os.Stat(path)
+		if err != nil {
+			result.Files = append(result.Files, PublishFileResult{File: file, Status: "missing"})
 			continue
 		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return nil, err
+		}
 
-		info, _ := os.Stat(filePath)
-		sizeMB := float64(info.Size()) / (1024 * 1024)
-		fmt.Printf("  Uploading: %s (%.1f MB)\n", layer.File, sizeMB)
+		pf := PublishedFile{Key: "v/" + version + "/" + file, SHA256: sum, Size: info.Size()}
+		status := "uploaded"
+		if prev, ok := stored[file+"@"+sum]; ok {
+			pf, status = prev, "unchanged"
+		} else if obj, err := client.Head(tilesPrefix + pf.Key); err == nil && obj.SHA256 == sum {
+			status = "resumed" // Uploaded by an interrupted publish of this version
+		} else {
+			err := client.PutFile(tilesPrefix+pf.Key, path, r2.PutOptions{
+				ContentType:  "application/vnd.pmtiles",
+				CacheControl: cacheImmutable,
+				SHA256:       sum,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("uploading %s: %w", file, err)
+			}
+		}
 
-		r2Key := fmt.Sprintf("%s/airspace/tiles/%s", R2Bucket, layer.File)
-		if err := wranglerPut(r2Key, filePath); err != nil {
-			return fmt.Errorf("uploading %s: %w", layer.File, err)
+		published.Files[file] = pf
+		result.Files = append(result.Files, PublishFileResult{File: file, Key: tilesPrefix + pf.Key, Size: pf.Size, Status: status})
+	}
+
+	// The version's regional manifest points at the stored objects
+	versioned := *manifest
+	versioned.Layers = make(map[string]ManifestLayer, len(manifest.Layers))
+	for key, layer := range manifest.Layers {
+		if pf, ok := published.Files[layer.File]; ok {
+			layer.File = pf.Key
 		}
+		versioned.Layers[key] = layer
+	}
+	if pf, ok := published.Files[manifest.Combined]; ok {
+		versioned.Combined = pf.Key
+	}
+	if err := putJSON(client, R2Prefix+"/"+published.Manifest, versioned, cacheImmutable); err != nil {
+		return nil, fmt.Errorf("uploading regional manifest: %w", err)
+	}
+	style := MapLibreStyle(versioned, StyleOptions{TilesURL: R2PublicURL + "/" + R2Prefix + "/" + region.TilesPath})
+	if err := putJSON(client, R2Prefix+"/"+published.Style, style, cacheImmutable); err != nil {
+		return nil, fmt.Errorf("uploading style: %w", err)
+	}
+	if err := putJSON(client, R2Prefix+"/"+FileManifestSchema, json.RawMessage(manifestSchemaJSON), cachePointer); err != nil {
+		return nil, fmt.Errorf("uploading manifest schema: %w", err)
 	}
 
-	// Upload manifests
-	fmt.Println("\n=== Manifests ===")
-	manifestFiles := []string{FileManifest, FileUSAManifest}
-	for _, name := range manifestFiles {
-		filePath := filepath.Join(DirData, name)
-		if _, err := os.Stat(filePath); err != nil {
-			continue
+	published.Published = time.Now().UTC()
+	log.Versions = append([]PublishedVersion{published}, log.Versions...)
+	if err := putJSON(client, tilesPrefix+"versions.json", log, cachePointer); err != nil {
+		return nil, fmt.Errorf("uploading publish log: %w", err)
+	}
+
+	// Flip the pointer last
+	if err := setCurrentVersion(client, region, published); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RollbackR2 points manifest.json back at an earlier published version of a
+// region. An empty version means the one published before the current one.
+// It returns the version now current.
+func RollbackR2(client *r2.Client, region Region, version string) (string, error) {
+	log, err := loadPublishLog(client, region)
+	if err != nil {
+		return "", err
+	}
+
+	if version == "" {
+		current, err := CurrentR2Version(client, region)
+		if err != nil {
+			return "", err
 		}
-		fmt.Printf("  Uploading: %s\n", name)
-		r2Key := fmt.Sprintf("%s/airspace/%s", R2Bucket, name)
-		if err := wranglerPut(r2Key, filePath); err != nil {
-			return fmt.Errorf("uploading %s: %w", name, err)
+		i, ok := log.find(current)
+		if !ok || i+1 >= len(log.Versions) {
+			return "", fmt.Errorf("no version published before %q", current)
 		}
+		version = log.Versions[i+1].Version
 	}
 
-	fmt.Printf("\nDone. Files available at: %s/airspace/\n", R2PublicURL)
+	i, ok := log.find(version)
+	if !ok {
+		return "", fmt.Errorf("version %s not in publish log", version)
+	}
+	target := log.Versions[i]
+	if _, err := client.Head(R2Prefix + "/" + target.Manifest); err != nil {
+		return "", fmt.Errorf("version %s: %w", version, err)
+	}
 
-	// Print endpoints
-	fmt.Println("\nEndpoints:")
-	for _, layer := range manifest.Layers {
-		fmt.Printf("  %s/airspace/tiles/%s\n", R2PublicURL, layer.File)
+	if err := setCurrentVersion(client, region, target); err != nil {
+		return "", err
 	}
+	return version, nil
+}
+
+// R2Versions returns a region's publish log from R2.
+func R2Versions(client *r2.Client, region Region) (*PublishLog, error) {
+	return loadPublishLog(client, region)
+}
 
+// CurrentR2Version returns the version manifest.json points at for a region.
+func CurrentR2Version(client *r2.Client, region Region) (string, error) {
+	global, err := loadR2Pointer(client)
+	if err != nil {
+		return "", err
+	}
+	return global.Regions[region.Key].Version, nil
+}
+
+// setCurrentVersion rewrites manifest.json with the region pointing at v,
+// leaving other regions as they are.
+func setCurrentVersion(client *r2.Client, region Region, v PublishedVersion) error {
+	global, err := loadR2Pointer(client)
+	if err != nil {
+		return err
+	}
+
+	entry := global.Regions[region.Key]
+	entry.Name = region.Name
+	entry.BBox = region.BBox
+	entry.TilesPath = region.TilesPath
+	entry.DefaultLayers = region.DefaultLayers
+	entry.ManifestFile = v.Manifest
+	entry.Style = v.Style
+	entry.Version = v.Version
+	global.Regions[region.Key] = entry
+	global.Updated = time.Now().UTC().Format(time.RFC3339)
+
+	if err := putJSON(client, R2Prefix+"/"+FileManifest, global, cachePointer); err != nil {
+		return fmt.Errorf("updating %s: %w", FileManifest, err)
+	}
 	return nil
 }
 
-// TestR2Endpoints tests that all R2 endpoints are accessible.
-func TestR2Endpoints() error {
-	manifestPath := filepath.Join(DirData, FileUSAManifest)
+// loadR2Pointer reads manifest.json from R2, or starts a new one.
+func loadR2Pointer(client *r2.Client) (ManifestGlobal, error) {
+	var global ManifestGlobal
+	data, err := client.Get(R2Prefix + "/" + FileManifest)
+	switch {
+	case errors.Is(err, r2.ErrNotFound):
+		global = ManifestGlobal{Version: 1}
+	case err != nil:
+		return global, fmt.Errorf("reading %s: %w", FileManifest, err)
+	default:
+		if err := json.Unmarshal(data, &global); err != nil {
+			return global, fmt.Errorf("parsing %s: %w", FileManifest, err)
+		}
+	}
+	if global.Regions == nil {
+		global.Regions = make(map[string]ManifestRegion)
+	}
+	global.Notes = map[string]string{
+		"bbox_format":   "[west, south, east, north]",
+		"tiles_path":    "Relative to /airspace/ in R2",
+		"manifest_file": "Relative to /airspace/; the region's current version (layer files are relative to tiles_path)",
+	}
+	return global, nil
+}
+
+func loadPublishLog(client *r2.Client, region Region) (*PublishLog, error) {
+	log := &PublishLog{Region: region.Key}
+	data, err := client.Get(R2Prefix + "/" + region.TilesPath + "/versions.json")
+	if errors.Is(err, r2.ErrNotFound) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading publish log: %w", err)
+	}
+	if err := json.Unmarshal(data, log); err != nil {
+		return nil, fmt.Errorf("parsing publish log: %w", err)
+	}
+	return log, nil
+}
+
+// find returns the index of a version in the log.
+func (l *PublishLog) find(version string) (int, bool) {
+	for i, v := range l.Versions {
+		if v.Version == version {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// manifestFiles lists the archives a manifest references.
+func manifestFiles(manifest *RegionManifest) []string {
+	var files []string
+	for _, layer := range manifest.Layers {
+		files = append(files, layer.File)
+	}
+	sort.Strings(files)
+	if manifest.Combined != "" {
+		files = append(files, manifest.Combined)
+	}
+	return files
+}
 
-	manifest, err := loadUSAManifest(manifestPath)
+func putJSON(client *r2.Client, key string, v any, cacheControl string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		return fmt.Errorf("loading manifest: %w", err)
+		return err
 	}
+	return client.Put(key, data, r2.PutOptions{ContentType: "application/json", CacheControl: cacheControl})
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// TestR2Endpoints checks that the region's published version is reachable
+// through the public URL: manifest.json, its regional manifest and every
+// layer archive.
+func TestR2Endpoints(region Region) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := R2PublicURL + "/" + R2Prefix
 
 	fmt.Println("Testing R2 endpoints...")
 	fmt.Println()
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	allOK := true
+	var global ManifestGlobal
+	if err := getPublicJSON(client, base+"/"+FileManifest, &global); err != nil {
+		return err
+	}
+	entry, ok := global.Regions[region.Key]
+	if !ok || entry.Version == "" {
+		return fmt.Errorf("region %s has no published version in %s", region.Key, FileManifest)
+	}
+	fmt.Printf("  Current version: %s\n", entry.Version)
 
+	var manifest RegionManifest
+	if err := getPublicJSON(client, base+"/"+entry.ManifestFile, &manifest); err != nil {
+		return err
+	}
+
+	allOK := true
 	for _, layer := range manifest.Layers {
-		url := fmt.Sprintf("%s/airspace/tiles/%s", R2PublicURL, layer.File)
+		url := fmt.Sprintf("%s/%s/%s", base, entry.TilesPath, layer.File)
 		status, size := testURL(client, url)
 
 		if status == 200 {
@@ -113,11 +405,19 @@ func TestR2Endpoints() error {
 	return fmt.Errorf("some endpoints failed")
 }
 
-func wranglerPut(r2Key, filePath string) error {
-	cmd := exec.Command("wrangler", "r2", "object", "put", r2Key, "--file", filePath, "--remote")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func getPublicJSON(client *http.Client, url string, v any) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", url, err)
+	}
+	return nil
 }
 
 func testURL(client *http.Client, url string) (int, string) {
@@ -145,13 +445,14 @@ func testURL(client *http.Client, url string) (int, string) {
 	return resp.StatusCode, size
 }
 
-func loadUSAManifest(path string) (*ManifestUSA, error) {
+// LoadRegionManifest reads a regional manifest (manifest_<region>.json).
+func LoadRegionManifest(path string) (*RegionManifest, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var manifest ManifestUSA
+	var manifest RegionManifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
 		return nil, err
 	}
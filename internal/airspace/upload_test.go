@@ -0,0 +1,264 @@
+package airspace_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+	"github.com/joeblew999/ubuntu-website/internal/airspace/r2"
+)
+
+// fakeS3 is an in-memory S3 stand-in: objects, multipart uploads, and a
+// number of 500s to return for the first attempts at a given part.
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	meta      map[string]string // key -> x-amz-meta-sha256
+	uploads   map[string]map[int][]byte
+	puts      map[string]int // Completed uploads per key
+	failParts map[int]int
+	nextID    int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects:   make(map[string][]byte),
+		meta:      make(map[string]string),
+		uploads:   make(map[string]map[int][]byte),
+		puts:      make(map[string]int),
+		failParts: make(map[int]int),
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	q := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		s.nextID++
+		id := fmt.Sprintf("upload-%d", s.nextID)
+		s.uploads[id] = make(map[int][]byte)
+		s.meta[key] = r.Header.Get("X-Amz-Meta-Sha256")
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+
+	case r.Method == http.MethodPut && q.Has("partNumber"):
+		var n int
+		fmt.Sscan(q.Get("partNumber"), &n)
+		if s.failParts[n] > 0 {
+			s.failParts[n]--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.uploads[q.Get("uploadId")][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, n))
+
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		var req struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		xml.Unmarshal(body, &req)
+		parts := s.uploads[q.Get("uploadId")]
+		var data []byte
+		for _, p := range req.Parts {
+			data = append(data, parts[p.PartNumber]...)
+		}
+		s.objects[key] = data
+		s.puts[key]++
+		delete(s.uploads, q.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+		s.meta[key] = r.Header.Get("X-Amz-Meta-Sha256")
+		s.puts[key]++
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Amz-Meta-Sha256", s.meta[key])
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	case r.Method == http.MethodDelete:
+		delete(s.uploads, q.Get("uploadId"))
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestR2NotRetried(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[int]int{} // Status -> requests
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusNotFound
+		if strings.HasSuffix(r.URL.Path, "/denied") {
+			status = http.StatusForbidden
+		}
+		mu.Lock()
+		requests[status]++
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	client := r2.New(srv.URL, "bucket", "test", "secret")
+	if _, err := client.Head("missing"); !errors.Is(err, r2.ErrNotFound) {
+		t.Errorf("HEAD missing: %v, want ErrNotFound", err)
+	}
+	if _, err := client.Get("missing"); !errors.Is(err, r2.ErrNotFound) {
+		t.Errorf("GET missing: %v, want ErrNotFound", err)
+	}
+	if err := client.Put("denied", []byte("x"), r2.PutOptions{}); err == nil {
+		t.Error("PUT denied: no error")
+	}
+	if requests[http.StatusNotFound] != 2 || requests[http.StatusForbidden] != 1 {
+		t.Errorf("requests = %v, want one per call", requests)
+	}
+}
+
+func TestPublishToR2(t *testing.T) {
+	s3 := newFakeS3()
+	s3.failParts[2] = 2 // Retried
+	srv := httptest.NewServer(s3)
+	defer srv.Close()
+
+	client := r2.New(srv.URL, "bucket", "test", "secret")
+	client.PartSize = r2.MinPartSize
+	client.Backoff = time.Millisecond
+
+	// Local tiles: one small archive, one large enough for three parts
+	dir := t.TempDir()
+	small := []byte("small archive v1")
+	large := bytes.Repeat([]byte("0123456789abcdef"), (2*r2.MinPartSize+1024)/16)
+	os.WriteFile(filepath.Join(dir, "small.pmtiles"), small, 0644)
+	os.WriteFile(filepath.Join(dir, "large.pmtiles"), large, 0644)
+
+	manifestPath := filepath.Join(dir, "manifest_test.json")
+	data, _ := json.Marshal(airspace.RegionManifest{
+		Region: "test",
+		Layers: map[string]airspace.ManifestLayer{
+			"small": {Name: "Small", File: "small.pmtiles"},
+			"large": {Name: "Large", File: "large.pmtiles"},
+		},
+	})
+	os.WriteFile(manifestPath, data, 0644)
+
+	region := airspace.Region{Key: "test", Name: "Test", TilesPath: "tiles"}
+	opts := airspace.PublishOptions{Region: region, TilesDir: dir, ManifestPath: manifestPath, Version: "v1"}
+
+	// First publish uploads everything, large in parts
+	result, err := airspace.PublishToR2(client, opts)
+	if err != nil {
+		t.Fatalf("publish v1: %v", err)
+	}
+	if got := statuses(result); got != "large=uploaded small=uploaded" {
+		t.Errorf("v1 statuses = %s", got)
+	}
+	if !bytes.Equal(s3.objects["airspace/tiles/v/v1/large.pmtiles"], large) {
+		t.Error("multipart object does not match the local file")
+	}
+	assertPointer(t, client, region, "v1", "large", "v/v1/large.pmtiles")
+
+	// Second publish only uploads what changed
+	os.WriteFile(filepath.Join(dir, "small.pmtiles"), []byte("small archive v2"), 0644)
+	opts.Version = "v2"
+	if result, err = airspace.PublishToR2(client, opts); err != nil {
+		t.Fatalf("publish v2: %v", err)
+	}
+	if got := statuses(result); got != "large=unchanged small=uploaded" {
+		t.Errorf("v2 statuses = %s", got)
+	}
+	if s3.puts["airspace/tiles/v/v2/large.pmtiles"] != 0 {
+		t.Error("unchanged archive uploaded again")
+	}
+	assertPointer(t, client, region, "v2", "large", "v/v1/large.pmtiles")
+	assertPointer(t, client, region, "v2", "small", "v/v2/small.pmtiles")
+
+	// Published versions are immutable
+	if _, err := airspace.PublishToR2(client, opts); err == nil {
+		t.Error("republishing v2 should fail")
+	}
+
+	// Rollback flips the pointer to the previous version
+	v, err := airspace.RollbackR2(client, region, "")
+	if err != nil || v != "v1" {
+		t.Fatalf("rollback = %q, %v; want v1", v, err)
+	}
+	assertPointer(t, client, region, "v1", "small", "v/v1/small.pmtiles")
+	if _, err := airspace.RollbackR2(client, region, ""); err == nil {
+		t.Error("rollback past the first version should fail")
+	}
+}
+
+func statuses(r *airspace.PublishResult) string {
+	var s []string
+	for _, f := range r.Files {
+		s = append(s, strings.TrimSuffix(f.File, ".pmtiles")+"="+f.Status)
+	}
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
+
+// assertPointer follows manifest.json to the region's manifest and checks a layer's file.
+func assertPointer(t *testing.T, client *r2.Client, region airspace.Region, version, layer, file string) {
+	t.Helper()
+	data, err := client.Get("airspace/manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var global airspace.ManifestGlobal
+	json.Unmarshal(data, &global)
+	entry := global.Regions[region.Key]
+	if entry.Version != version {
+		t.Fatalf("manifest.json version = %q, want %q", entry.Version, version)
+	}
+
+	data, err = client.Get("airspace/" + entry.ManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m airspace.RegionManifest
+	json.Unmarshal(data, &m)
+	if got := m.Layers[layer].File; got != file {
+		t.Errorf("%s: %s file = %q, want %q", version, layer, got, file)
+	}
+	if _, err := client.Head("airspace/" + entry.TilesPath + "/" + file); err != nil {
+		t.Errorf("%s: %s not stored: %v", version, file, err)
+	}
+}
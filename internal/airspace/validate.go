@@ -0,0 +1,579 @@
+package airspace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Validation issue codes.
+const (
+	IssueNoGeometry       = "no_geometry"         // Feature has no (parseable) geometry
+	IssueCoordinates      = "invalid_coordinates" // Longitude/latitude out of range
+	IssueGeometryType     = "geometry_type"       // Not a type the dataset allows
+	IssueUnclosedRing     = "unclosed_ring"       // First and last ring positions differ
+	IssueShortRing        = "short_ring"          // Ring with fewer than 4 positions (or line with fewer than 2)
+	IssueSelfIntersection = "self_intersection"   // Ring edges cross
+	IssueWinding          = "winding"             // Not RFC 7946 winding (outer CCW, holes CW)
+	IssueDuplicatePoints  = "duplicate_points"    // Repeated consecutive positions
+	IssueMissingProperty  = "missing_property"    // Required property absent or empty
+)
+
+// Issue severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidateOptions configures Validate.
+type ValidateOptions struct {
+	Region       Region
+	Dir          string   // GeoJSON directory ("" = region GeoJSON dir)
+	Datasets     []string // nil = region DatasetOrder
+	Repair       bool     // Fix what can be fixed and rewrite the GeoJSON
+	MaxErrorRate float64  // Invalid feature fraction that fails a dataset (0 = any invalid feature, <0 = never fail)
+}
+
+// DefaultValidateOptions returns the validation settings for a region,
+// failing a dataset above DefaultMaxErrorRate.
+func DefaultValidateOptions(region Region) ValidateOptions {
+	return ValidateOptions{Region: region, MaxErrorRate: DefaultMaxErrorRate}
+}
+
+// ValidationReport is the outcome of validating a region's GeoJSON.
+type ValidationReport struct {
+	Timestamp    time.Time                     `json:"timestamp"`
+	Region       string                        `json:"region"`
+	Repair       bool                          `json:"repair"`
+	MaxErrorRate float64                       `json:"max_error_rate"`
+	Passed       bool                          `json:"passed"`
+	Features     int                           `json:"features"`
+	Invalid      int                           `json:"invalid"`
+	Warnings     int                           `json:"warnings"`
+	Repaired     int                           `json:"repaired"`
+	Dropped      int                           `json:"dropped"`
+	Datasets     map[string]*DatasetValidation `json:"datasets"`
+}
+
+// DatasetValidation is the validation result of one GeoJSON file. Counts
+// are complete; Issues lists at most MaxValidationIssues entries.
+type DatasetValidation struct {
+	File      string            `json:"file"`
+	Features  int               `json:"features"`
+	Invalid   int               `json:"invalid"` // Features with unrepaired errors
+	Warnings  int               `json:"warnings"`
+	Repaired  int               `json:"repaired"` // Features changed by repair
+	Dropped   int               `json:"dropped"`  // Features removed by repair
+	ErrorRate float64           `json:"error_rate"`
+	Passed    bool              `json:"passed"`
+	Counts    map[string]int    `json:"counts,omitempty"` // Issues per code
+	Issues    []ValidationIssue `json:"issues,omitempty"`
+	Truncated bool              `json:"truncated,omitempty"` // More issues than listed
+}
+
+// ValidationIssue is one problem found in a feature.
+type ValidationIssue struct {
+	Feature  int    `json:"feature"` // Index in the file
+	ID       string `json:"id,omitempty"`
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// Validate checks every dataset GeoJSON of a region, optionally repairing it.
+// Missing files are skipped. The report passes when no dataset's invalid
+// feature rate exceeds MaxErrorRate.
+func Validate(opts ValidateOptions) (*ValidationReport, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = opts.Region.GeoJSONDir()
+	}
+	keys := opts.Datasets
+	if keys == nil {
+		keys = opts.Region.DatasetOrder
+	}
+	maxRate := opts.MaxErrorRate
+
+	report := &ValidationReport{
+		Timestamp:    time.Now().UTC(),
+		Region:       opts.Region.Key,
+		Repair:       opts.Repair,
+		MaxErrorRate: maxRate,
+		Passed:       true,
+		Datasets:     make(map[string]*DatasetValidation),
+	}
+
+	for _, key := range keys {
+		ds, err := opts.Region.Dataset(key)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, ds.GeoJSON)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		v, err := ValidateFile(path, ds.Validate, opts.Repair)
+		if err != nil {
+			return nil, fmt.Errorf("validating %s: %w", key, err)
+		}
+		v.Passed = maxRate < 0 || v.ErrorRate <= maxRate
+		report.Datasets[key] = v
+
+		report.Passed = report.Passed && v.Passed
+		report.Features += v.Features
+		report.Invalid += v.Invalid
+		report.Warnings += v.Warnings
+		report.Repaired += v.Repaired
+		report.Dropped += v.Dropped
+	}
+
+	return report, nil
+}
+
+// ValidateFile checks each feature of a GeoJSON file against rules. With
+// repair, fixable issues are corrected (rings closed and rewound, repeated
+// positions removed, degenerate holes removed), features that cannot be
+// tiled at all are dropped, and the file is rewritten if anything changed.
+// Untouched features keep their original JSON.
+func ValidateFile(path string, rules ValidationRules, repair bool) (*DatasetValidation, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	v := &DatasetValidation{File: filepath.Base(path), Counts: make(map[string]int)}
+
+	var out *os.File
+	var w *bufio.Writer
+	tmpPath := path + ".validate"
+	if repair {
+		if out, err = os.Create(tmpPath); err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmpPath)
+		defer out.Close()
+		w = bufio.NewWriterSize(out, 1<<20)
+		w.WriteString(`{"type":"FeatureCollection","features":[`)
+	}
+
+	written := 0
+	err = DecodeFeatures(in, func(raw json.RawMessage) error {
+		index := v.Features
+		v.Features++
+
+		fv := validateFeature(raw, rules, repair)
+		for _, issue := range fv.issues {
+			issue.Feature = index
+			issue.ID = fv.id
+			v.Counts[issue.Code]++
+			if len(v.Issues) < MaxValidationIssues {
+				v.Issues = append(v.Issues, issue)
+			} else {
+				v.Truncated = true
+			}
+		}
+		if fv.invalid {
+			v.Invalid++
+		}
+		if fv.warning {
+			v.Warnings++
+		}
+
+		switch {
+		case fv.drop:
+			v.Dropped++
+			return nil
+		case fv.repaired != nil:
+			v.Repaired++
+			raw = fv.repaired
+		}
+		if w != nil {
+			if written > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString("\n")
+			w.Write(raw)
+			written++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Features > 0 {
+		v.ErrorRate = float64(v.Invalid) / float64(v.Features)
+	}
+
+	if repair && v.Repaired+v.Dropped > 0 {
+		w.WriteString("\n]}\n")
+		if err := w.Flush(); err != nil {
+			return nil, err
+		}
+		if err := out.Close(); err != nil {
+			return nil, err
+		}
+		in.Close()
+		if err := os.Rename(tmpPath, path); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// featureValidation is the outcome for one feature.
+type featureValidation struct {
+	id       string
+	issues   []ValidationIssue
+	invalid  bool            // Unrepaired error
+	warning  bool            // Unrepaired warning
+	drop     bool            // Removed by repair
+	repaired json.RawMessage // Rewritten feature, if repair changed it
+}
+
+func (fv *featureValidation) add(code, severity string, repaired bool, format string, args ...any) {
+	fv.issues = append(fv.issues, ValidationIssue{
+		Code: code, Severity: severity, Message: fmt.Sprintf(format, args...), Repaired: repaired,
+	})
+	switch {
+	case repaired:
+	case severity == SeverityError:
+		fv.invalid = true
+	default:
+		fv.warning = true
+	}
+}
+
+func validateFeature(raw json.RawMessage, rules ValidationRules, repair bool) *featureValidation {
+	fv := &featureValidation{}
+
+	f, err := geojson.UnmarshalFeature(raw)
+	if err != nil || f.Geometry == nil {
+		var props struct {
+			Properties map[string]any `json:"properties"`
+		}
+		json.Unmarshal(raw, &props)
+		fv.id = FeatureID(props.Properties)
+		msg := "feature has no geometry"
+		if err != nil {
+			msg = err.Error()
+		}
+		fv.add(IssueNoGeometry, SeverityError, repair, "%s", msg)
+		fv.drop = repair
+		return fv
+	}
+	fv.id = FeatureID(f.Properties)
+
+	for _, key := range rules.Required {
+		if v, ok := f.Properties[key]; !ok || v == nil || v == "" {
+			fv.add(IssueMissingProperty, SeverityError, false, "missing required property %s", key)
+		}
+	}
+	if len(rules.Geometry) > 0 && !slices.Contains(rules.Geometry, f.Geometry.GeoJSONType()) {
+		fv.add(IssueGeometryType, SeverityError, false, "geometry is %s, want %s", f.Geometry.GeoJSONType(), strings.Join(rules.Geometry, " or "))
+	}
+
+	if bad, ok := invalidCoordinate(f.Geometry); ok {
+		fv.add(IssueCoordinates, SeverityError, repair, "coordinate %v out of range", bad)
+		fv.drop = repair
+		return fv
+	}
+
+	g, changed, empty := checkGeometry(fv, f.Geometry, repair)
+	if empty {
+		fv.drop = repair
+		return fv
+	}
+	if changed {
+		f.Geometry = g
+		if data, err := f.MarshalJSON(); err == nil {
+			fv.repaired = data
+		}
+	}
+	return fv
+}
+
+// invalidCoordinate returns the first position outside lon/lat range.
+func invalidCoordinate(g orb.Geometry) (orb.Point, bool) {
+	var bad orb.Point
+	found := false
+	eachPoint(g, func(p orb.Point) {
+		if found {
+			return
+		}
+		if math.IsNaN(p[0]) || math.IsNaN(p[1]) || p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+			bad, found = p, true
+		}
+	})
+	return bad, found
+}
+
+func eachPoint(g orb.Geometry, fn func(orb.Point)) {
+	switch g := g.(type) {
+	case orb.Point:
+		fn(g)
+	case orb.MultiPoint:
+		for _, p := range g {
+			fn(p)
+		}
+	case orb.LineString:
+		for _, p := range g {
+			fn(p)
+		}
+	case orb.MultiLineString:
+		for _, ls := range g {
+			eachPoint(ls, fn)
+		}
+	case orb.Ring:
+		for _, p := range g {
+			fn(p)
+		}
+	case orb.Polygon:
+		for _, r := range g {
+			eachPoint(r, fn)
+		}
+	case orb.MultiPolygon:
+		for _, p := range g {
+			eachPoint(p, fn)
+		}
+	case orb.Collection:
+		for _, c := range g {
+			eachPoint(c, fn)
+		}
+	}
+}
+
+// checkGeometry validates lines and polygons, returning the repaired
+// geometry, whether repair changed it, and whether nothing usable is left.
+func checkGeometry(fv *featureValidation, g orb.Geometry, repair bool) (orb.Geometry, bool, bool) {
+	switch g := g.(type) {
+	case orb.LineString:
+		ls, changed := dedupe(fv, g, repair)
+		if len(ls) < 2 {
+			fv.add(IssueShortRing, SeverityError, repair, "line has %d positions", len(ls))
+			return ls, changed, true
+		}
+		return ls, changed, false
+	case orb.MultiLineString:
+		var out orb.MultiLineString
+		changed := false
+		for _, ls := range g {
+			fixed, c, empty := checkGeometry(fv, ls, repair)
+			changed = changed || c
+			if empty && repair {
+				changed = true
+				continue
+			}
+			out = append(out, fixed.(orb.LineString))
+		}
+		return out, changed, len(out) == 0
+	case orb.Polygon:
+		return checkPolygon(fv, g, repair)
+	case orb.MultiPolygon:
+		var out orb.MultiPolygon
+		changed := false
+		for _, p := range g {
+			fixed, c, empty := checkPolygon(fv, p, repair)
+			changed = changed || c
+			if empty && repair {
+				changed = true
+				continue
+			}
+			out = append(out, fixed)
+		}
+		return out, changed, len(out) == 0
+	}
+	return g, false, false
+}
+
+func checkPolygon(fv *featureValidation, p orb.Polygon, repair bool) (orb.Polygon, bool, bool) {
+	var out orb.Polygon
+	changed := false
+
+	for i, ring := range p {
+		kind := "outer ring"
+		if i > 0 {
+			kind = fmt.Sprintf("hole %d", i)
+		}
+
+		r, c := dedupe(fv, orb.LineString(ring), repair)
+		ring, changed = orb.Ring(r), changed || c
+
+		if len(ring) > 0 && !ring.Closed() {
+			fv.add(IssueUnclosedRing, SeverityError, repair, "%s is not closed", kind)
+			if repair {
+				ring = append(ring, ring[0])
+				changed = true
+			}
+		}
+
+		if len(ring) < 4 {
+			fv.add(IssueShortRing, SeverityError, repair, "%s has %d positions", kind, len(ring))
+			if i == 0 {
+				return out, changed, true // Nothing to tile without an outer ring
+			}
+			if repair {
+				changed = true
+				continue
+			}
+		}
+
+		if selfIntersects(ring) {
+			fv.add(IssueSelfIntersection, SeverityError, false, "%s crosses itself", kind)
+		}
+
+		want := orb.CCW
+		if i > 0 {
+			want = orb.CW
+		}
+		if o := ring.Orientation(); o != 0 && o != want {
+			fv.add(IssueWinding, SeverityWarning, repair, "%s is wound %s", kind, windingName(o))
+			if repair {
+				ring = slices.Clone(ring)
+				ring.Reverse()
+				changed = true
+			}
+		}
+		out = append(out, ring)
+	}
+	return out, changed, len(out) == 0
+}
+
+func windingName(o orb.Orientation) string {
+	if o == orb.CCW {
+		return "counter-clockwise"
+	}
+	return "clockwise"
+}
+
+// dedupe reports repeated consecutive positions and returns the line
+// without them, so later checks don't see zero-length edges. The bool is
+// true only with repair, when the result replaces the original.
+func dedupe(fv *featureValidation, ls orb.LineString, repair bool) (orb.LineString, bool) {
+	dups := 0
+	for i := 1; i < len(ls); i++ {
+		if ls[i] == ls[i-1] {
+			dups++
+		}
+	}
+	if dups == 0 {
+		return ls, false
+	}
+
+	fv.add(IssueDuplicatePoints, SeverityWarning, repair, "%d repeated positions", dups)
+	out := make(orb.LineString, 0, len(ls)-dups)
+	for i, p := range ls {
+		if i == 0 || p != ls[i-1] {
+			out = append(out, p)
+		}
+	}
+	return out, repair
+}
+
+// selfIntersects reports whether any two non-adjacent edges of a closed ring
+// touch or cross. Edges are swept in longitude order so only edges with
+// overlapping extents are compared.
+func selfIntersects(r orb.Ring) bool {
+	n := len(r) - 1 // Edges of the closed ring
+	if n < 4 {
+		return false
+	}
+
+	type edge struct {
+		i          int
+		minX, maxX float64
+	}
+	edges := make([]edge, n)
+	for i := range n {
+		edges[i] = edge{i, math.Min(r[i][0], r[i+1][0]), math.Max(r[i][0], r[i+1][0])}
+	}
+	sort.Slice(edges, func(a, b int) bool { return edges[a].minX < edges[b].minX })
+
+	for a, e := range edges {
+		for _, f := range edges[a+1:] {
+			if f.minX > e.maxX {
+				break
+			}
+			d := e.i - f.i
+			if d == 1 || d == -1 || d == n-1 || d == 1-n {
+				continue // Adjacent edges share a vertex
+			}
+			if SegmentsIntersect(r[e.i], r[e.i+1], r[f.i], r[f.i+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SegmentsIntersect reports whether segments p1-p2 and q1-q2 touch or cross.
+func SegmentsIntersect(p1, p2, q1, q2 orb.Point) bool {
+	d1 := cross(q1, q2, p1)
+	d2 := cross(q1, q2, p2)
+	d3 := cross(p1, p2, q1)
+	d4 := cross(p1, p2, q2)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+func cross(a, b, c orb.Point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// onSegment reports whether c, collinear with a-b, lies within its extent.
+func onSegment(a, b, c orb.Point) bool {
+	return math.Min(a[0], b[0]) <= c[0] && c[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= c[1] && c[1] <= math.Max(a[1], b[1])
+}
+
+// FailureSummary names the datasets above the error threshold.
+func (r *ValidationReport) FailureSummary() string {
+	var failed []string
+	for _, key := range slices.Sorted(maps.Keys(r.Datasets)) {
+		if v := r.Datasets[key]; !v.Passed {
+			failed = append(failed, fmt.Sprintf("%s %.2f%%", key, v.ErrorRate*100))
+		}
+	}
+	if len(failed) == 0 {
+		return "all datasets passed"
+	}
+	return fmt.Sprintf("invalid features above %.2f%% in %s", r.MaxErrorRate*100, strings.Join(failed, ", "))
+}
+
+// SaveValidationReport saves a validation report.
+func SaveValidationReport(path string, report ValidationReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadValidationReport loads the last validation report (zero Timestamp if none).
+func LoadValidationReport(path string) ValidationReport {
+	var report ValidationReport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report
+	}
+	json.Unmarshal(data, &report)
+	return report
+}
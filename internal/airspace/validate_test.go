@@ -0,0 +1,173 @@
+package airspace_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+
+	"github.com/joeblew999/ubuntu-website/internal/airspace"
+)
+
+// validateSUA has one feature per problem the validator looks for:
+// clockwise winding, an unclosed ring, repeated positions, a bowtie, no
+// geometry, an out-of-range coordinate and a missing NAME. The last feature
+// is clean.
+const validateSUA = `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"NAME":"CW","TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[0,1],[1,1],[1,0],[0,0]]]}},
+{"type":"Feature","properties":{"NAME":"OPEN","TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}},
+{"type":"Feature","properties":{"NAME":"DUPS","TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,0],[1,1],[0,1],[0,0]]]}},
+{"type":"Feature","properties":{"NAME":"BOWTIE","TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,1],[1,0],[0,1],[0,0]]]}},
+{"type":"Feature","properties":{"NAME":"NULL","TYPE_CODE":"R"},"geometry":null},
+{"type":"Feature","properties":{"NAME":"FAR","TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[200,0],[200,1],[0,0]]]}},
+{"type":"Feature","properties":{"TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}},
+{"type":"Feature","properties":{"NAME":"OK","TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}
+]}`
+
+var validateRules = airspace.ValidationRules{
+	Required: []string{"NAME", "TYPE_CODE"},
+	Geometry: []string{"Polygon", "MultiPolygon"},
+}
+
+func TestValidateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sua.geojson")
+	if err := os.WriteFile(path, []byte(validateSUA), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := airspace.ValidateFile(path, validateRules, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OPEN, BOWTIE, NULL, FAR and the unnamed feature are errors
+	if v.Features != 8 || v.Invalid != 5 || v.Warnings != 2 || v.Repaired != 0 || v.Dropped != 0 {
+		t.Errorf("check: features %d, invalid %d, warnings %d, repaired %d, dropped %d; want 8/5/2/0/0",
+			v.Features, v.Invalid, v.Warnings, v.Repaired, v.Dropped)
+	}
+	for _, code := range []string{airspace.IssueWinding, airspace.IssueUnclosedRing, airspace.IssueDuplicatePoints,
+		airspace.IssueSelfIntersection, airspace.IssueNoGeometry, airspace.IssueCoordinates, airspace.IssueMissingProperty} {
+		if v.Counts[code] != 1 {
+			t.Errorf("check: %s count = %d, want 1", code, v.Counts[code])
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != validateSUA {
+		t.Error("check without repair rewrote the file")
+	}
+
+	v, err = airspace.ValidateFile(path, validateRules, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bowtie and the missing property can't be repaired
+	if v.Invalid != 2 || v.Warnings != 0 || v.Repaired != 3 || v.Dropped != 2 {
+		t.Errorf("repair: invalid %d, warnings %d, repaired %d, dropped %d; want 2/0/3/2",
+			v.Invalid, v.Warnings, v.Repaired, v.Dropped)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc, err := geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 6 {
+		t.Fatalf("repaired file has %d features, want 6", len(fc.Features))
+	}
+	for _, f := range fc.Features[:3] {
+		ring := f.Geometry.(orb.Polygon)[0]
+		if !ring.Closed() || ring.Orientation() != orb.CCW || len(ring) != 5 {
+			t.Errorf("%v: repaired ring %v is not a closed CCW square", f.Properties["NAME"], ring)
+		}
+	}
+
+	v, err = airspace.ValidateFile(path, validateRules, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Invalid != 2 || v.Warnings != 0 {
+		t.Errorf("after repair: invalid %d, warnings %d; want 2/0", v.Invalid, v.Warnings)
+	}
+}
+
+func TestValidateThreshold(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sua.geojson"), []byte(validateSUA), 0644); err != nil {
+		t.Fatal(err)
+	}
+	region := airspace.Region{
+		Key:          "test",
+		DatasetOrder: []string{"sua", "boundary"}, // boundary has no file and is skipped
+		Datasets: map[string]airspace.Dataset{
+			"sua":      {GeoJSON: "sua.geojson", Validate: validateRules},
+			"boundary": {GeoJSON: "boundary.geojson"},
+		},
+	}
+
+	tests := []struct {
+		rate float64
+		want bool
+	}{
+		{0, false},
+		{0.7, true},
+		{-1, true},
+	}
+	for _, tt := range tests {
+		report, err := airspace.Validate(airspace.ValidateOptions{Region: region, Dir: dir, MaxErrorRate: tt.rate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Passed != tt.want || len(report.Datasets) != 1 || report.Invalid != 5 {
+			t.Errorf("rate %v: passed = %v, datasets %d, invalid %d; want %v", tt.rate, report.Passed, len(report.Datasets), report.Invalid, tt.want)
+		}
+	}
+}
+
+func TestPipelineFailsOnAnyInvalidFeature(t *testing.T) {
+	t.Chdir(t.TempDir())
+	// 200 features, one without a NAME: 0.5% invalid
+	var features []string
+	for i := range 200 {
+		name := fmt.Sprintf("R-%d", i)
+		if i == 100 {
+			name = ""
+		}
+		features = append(features, fmt.Sprintf(`{"type":"Feature","properties":{"NAME":%q,"TYPE_CODE":"R"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}`, name))
+	}
+	files := map[string]string{
+		"sua.geojson": `{"type":"FeatureCollection","features":[` + strings.Join(features, ",\n") + `]}`,
+		"regions.yaml": `default: test
+regions:
+  test:
+    name: Test
+    dataset_order: [sua]
+    datasets:
+      sua: {name: SUA, geojson: sua.geojson, pmtiles: sua.pmtiles, layer: sua, base_url: sua.geojson, validate: {required: [NAME]}}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	regions := airspace.Regions
+	t.Cleanup(func() { airspace.Regions = regions })
+	if err := airspace.UseRegionsFile("regions.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := airspace.Pipeline(airspace.PipelineOptions{MaxErrorRate: 0}, nil)
+	if err == nil || result.Validation == nil || result.Validation.Invalid != 1 || result.Validation.MaxErrorRate != 0 {
+		t.Fatalf("pipeline at 0: %v, validation %+v; want one invalid feature to fail", err, result.Validation)
+	}
+	// The default rate lets it through
+	report, err := airspace.Validate(airspace.DefaultValidateOptions(airspace.DefaultRegion()))
+	if err != nil || !report.Passed || report.Invalid != 1 {
+		t.Errorf("default rate: %v, passed = %v, invalid %d; want 1 tolerated", err, report.Passed, report.Invalid)
+	}
+}
@@ -0,0 +1,355 @@
+package autotranslate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bounoable/deepl"
+	"golang.org/x/time/rate"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
+)
+
+// APIError is an error response from a provider, kept so that a Chain can
+// tell a busy provider from one whose quota or credit is used up.
+type APIError struct {
+	Service    string        // e.g. "Claude API", "claude CLI"
+	StatusCode int           // HTTP status, 0 for the CLI
+	Message    string        // Response body or CLI output
+	RetryAfter time.Duration // From the Retry-After header, if any
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s error: %s", e.Service, e.Message)
+	}
+	return fmt.Sprintf("%s error (status %d): %s", e.Service, e.StatusCode, e.Message)
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(h http.Header) time.Duration {
+	if s, err := strconv.Atoi(h.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
+// Claude returns 529 when overloaded; DeepL 456 when the quota is used up
+const (
+	statusOverloaded    = 529
+	statusQuotaExceeded = 456
+)
+
+// IsRetryable reports whether err is temporary: rate limited, overloaded,
+// a server error or a timeout.
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	var deeplErr deepl.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.StatusCode == 0 {
+			return strings.Contains(strings.ToLower(apiErr.Message), "overloaded")
+		}
+		return retryableStatus(apiErr.StatusCode)
+	case errors.As(err, &deeplErr):
+		return retryableStatus(deeplErr.Code)
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, statusOverloaded:
+		return true
+	}
+	return false
+}
+
+// IsQuotaExceeded reports whether err means the provider can't be used for
+// the rest of the run: its quota, credit or usage limit is used up.
+func IsQuotaExceeded(err error) bool {
+	var apiErr *APIError
+	var deeplErr deepl.Error
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.StatusCode == http.StatusPaymentRequired || apiErr.StatusCode == statusQuotaExceeded {
+			return true
+		}
+		msg := strings.ToLower(apiErr.Message)
+		return strings.Contains(msg, "credit balance") || strings.Contains(msg, "usage limit")
+	case errors.As(err, &deeplErr):
+		return deeplErr.Code == statusQuotaExceeded
+	}
+	return false
+}
+
+// ChainOptions configures a Chain.
+type ChainOptions struct {
+	RateLimits map[string]float64 // Requests per minute, by provider name
+	MaxRetries int                // Retries of a retryable error before falling back
+	Backoff    time.Duration      // First retry delay, doubled for each retry
+	MaxBackoff time.Duration
+	Ledger     *Ledger   // Usage and budget (may be nil)
+	Log        io.Writer // Retries and fallbacks are reported here (may be nil)
+}
+
+// DefaultRateLimits are requests per minute: below the DeepL API and
+// Claude tier 1 limits, and a pace the Claude CLI session can keep.
+var DefaultRateLimits = map[string]float64{
+	"deepl":      120,
+	"claude":     50,
+	"claude-cli": 20,
+}
+
+// Chain is a Provider that tries providers in order. Each is rate
+// limited; retryable errors are retried with exponential backoff. A
+// provider that is still unavailable after its retries falls back to the
+// next for that call, and one whose quota is used up for the rest of the
+// run. With a ledger, a call that would exceed the budget goes to the next
+// provider, or fails with ErrBudgetExceeded.
+type Chain struct {
+	providers []Provider
+	limiters  map[string]*rate.Limiter
+	exhausted map[string]bool
+	opts      ChainOptions
+}
+
+// NewChain creates a chain of providers, tried in order.
+func NewChain(opts ChainOptions, providers ...Provider) *Chain {
+	c := &Chain{
+		providers: providers,
+		limiters:  make(map[string]*rate.Limiter),
+		exhausted: make(map[string]bool),
+		opts:      opts,
+	}
+	for _, p := range providers {
+		if rpm := opts.RateLimits[p.Name()]; rpm > 0 {
+			c.limiters[p.Name()] = rate.NewLimiter(rate.Limit(rpm/60), 1)
+		}
+	}
+	if c.opts.Backoff <= 0 {
+		c.opts.Backoff = 2 * time.Second
+	}
+	if c.opts.MaxBackoff <= 0 {
+		c.opts.MaxBackoff = time.Minute
+	}
+	return c
+}
+
+// Providers returns the chained providers.
+func (c *Chain) Providers() []Provider {
+	return c.providers
+}
+
+// Name returns the name of the provider in use: the first whose quota
+// isn't used up.
+func (c *Chain) Name() string {
+	for _, p := range c.providers {
+		if !c.exhausted[p.Name()] {
+			return p.Name()
+		}
+	}
+	return c.providers[len(c.providers)-1].Name()
+}
+
+// Exhausted reports whether every provider's quota is used up.
+func (c *Chain) Exhausted() bool {
+	return len(c.exhausted) == len(c.providers)
+}
+
+// UseGlossary passes the glossary to the providers that enforce one
+func (c *Chain) UseGlossary(g *translate.Glossary) {
+	for _, p := range c.providers {
+		if gu, ok := p.(GlossaryUser); ok {
+			gu.UseGlossary(g)
+		}
+	}
+}
+
+// Translate translates text with the first provider that can.
+func (c *Chain) Translate(ctx context.Context, text, sourceLang, targetLang string) (string, error) {
+	var result string
+	chars := utf8.RuneCountInString(text)
+	err := c.do(ctx, targetLang, func() int { return chars }, func(p Provider) error {
+		var err error
+		result, err = c.retry(ctx, p, func() (string, error) { return p.Translate(ctx, text, sourceLang, targetLang) })
+		return err
+	})
+	return result, err
+}
+
+// TranslateBatch translates texts with the first provider that can.
+// Only a Claude batch big enough to go in one request is sent as a batch;
+// otherwise texts are translated one request each, so each request is
+// rate limited and retried on its own. Neither a retry nor a fallback
+// re-sends (and re-bills) texts already translated: the next provider
+// only gets the texts left.
+func (c *Chain) TranslateBatch(ctx context.Context, texts []string, sourceLang, targetLang string) ([]string, error) {
+	results := make([]string, len(texts))
+	next := 0 // texts[:next] are translated
+	remaining := func() int {
+		chars := 0
+		for _, t := range texts[next:] {
+			chars += utf8.RuneCountInString(t)
+		}
+		return chars
+	}
+	err := c.do(ctx, targetLang, remaining, func(p Provider) error {
+		if _, ok := p.(*ClaudeProvider); ok && len(texts)-next >= claudeMinBatch {
+			// One request for all texts left
+			_, err := c.retry(ctx, p, func() (string, error) {
+				out, err := p.TranslateBatch(ctx, texts[next:], sourceLang, targetLang)
+				if err == nil {
+					next += copy(results[next:], out)
+				}
+				return "", err
+			})
+			return err
+		}
+		for first := next; next < len(texts); next++ {
+			if next > first {
+				if err := c.wait(ctx, p); err != nil {
+					return err
+				}
+			}
+			out, err := c.retry(ctx, p, func() (string, error) { return p.Translate(ctx, texts[next], sourceLang, targetLang) })
+			if err != nil {
+				return fmt.Errorf("translating text %d: %w", next, err)
+			}
+			results[next] = out
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// do runs call with each usable provider in turn until one succeeds. It
+// waits for the provider's rate limit; call retries its requests. chars
+// is the text left to translate, for the budget check.
+func (c *Chain) do(ctx context.Context, targetLang string, chars func() int, call func(Provider) error) error {
+	var lastErr error
+	for i, p := range c.providers {
+		name := p.Name()
+		if c.exhausted[name] || !p.SupportsLanguage(targetLang) {
+			continue
+		}
+		if err := c.opts.Ledger.CheckBudget(name, c.opts.Ledger.Estimate(name, chars())); err != nil {
+			lastErr = err
+			continue
+		}
+		if err := c.wait(ctx, p); err != nil {
+			return err
+		}
+
+		err := call(p)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		c.opts.Ledger.Failed(name)
+		switch {
+		case ctx.Err() != nil:
+			return err
+		case IsQuotaExceeded(err):
+			c.exhausted[name] = true
+		case !IsRetryable(err):
+			return err
+		}
+		if i < len(c.providers)-1 {
+			c.logf("%s unavailable (%v), falling back", name, err)
+		}
+	}
+	if lastErr == nil {
+		return fmt.Errorf("no provider left for %s", targetLang)
+	}
+	return lastErr
+}
+
+// retry calls f until it succeeds, fails for good, or MaxRetries
+// retryable errors have been retried.
+func (c *Chain) retry(ctx context.Context, p Provider, f func() (string, error)) (string, error) {
+	for attempt := 0; ; attempt++ {
+		out, err := f()
+		if err == nil || !IsRetryable(err) || attempt >= c.opts.MaxRetries {
+			return out, err
+		}
+		delay := c.backoff(attempt, err)
+		c.opts.Ledger.Retried(p.Name())
+		c.logf("%s: %v; retrying in %s", p.Name(), err, delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+		if err := c.wait(ctx, p); err != nil {
+			return "", err
+		}
+	}
+}
+
+// backoff is the delay before retry attempt+1: the provider's Retry-After,
+// or Backoff doubled per attempt with up to 25% jitter, at most MaxBackoff.
+func (c *Chain) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, c.opts.MaxBackoff)
+	}
+	d := c.opts.Backoff << attempt
+	d += time.Duration(rand.Int63n(int64(d)/4 + 1))
+	return min(d, c.opts.MaxBackoff)
+}
+
+// wait blocks until the provider's rate limit allows a request.
+func (c *Chain) wait(ctx context.Context, p Provider) error {
+	if l := c.limiters[p.Name()]; l != nil {
+		return l.Wait(ctx)
+	}
+	return nil
+}
+
+func (c *Chain) logf(format string, args ...interface{}) {
+	if c.opts.Log != nil {
+		fmt.Fprintf(c.opts.Log, "⚠ "+format+"\n", args...)
+	}
+}
+
+// SupportedLanguages returns the languages any provider supports
+func (c *Chain) SupportedLanguages() []string {
+	seen := make(map[string]bool)
+	var langs []string
+	for _, p := range c.providers {
+		for _, l := range p.SupportedLanguages() {
+			if !seen[l] {
+				seen[l] = true
+				langs = append(langs, l)
+			}
+		}
+	}
+	return langs
+}
+
+// SupportsLanguage checks if any provider supports a language
+func (c *Chain) SupportsLanguage(langCode string) bool {
+	for _, p := range c.providers {
+		if p.SupportsLanguage(langCode) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package autotranslate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider upper-cases text, after returning errs one per call (a nil
+// error lets that call through).
+type fakeProvider struct {
+	name   string
+	errs   []error
+	calls  int
+	ledger *Ledger
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Translate(ctx context.Context, text, sourceLang, targetLang string) (string, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return "", err
+		}
+	}
+	f.ledger.Record(f.name, len(text), 0, 0)
+	return strings.ToUpper(text), nil
+}
+
+func (f *fakeProvider) TranslateBatch(ctx context.Context, texts []string, sourceLang, targetLang string) ([]string, error) {
+	return nil, errors.New("not used by Chain")
+}
+
+func (f *fakeProvider) SupportedLanguages() []string      { return []string{"de"} }
+func (f *fakeProvider) SupportsLanguage(lang string) bool { return lang == "de" }
+
+func testChain(l *Ledger, providers ...Provider) *Chain {
+	return NewChain(ChainOptions{MaxRetries: 2, Backoff: time.Millisecond, Ledger: l}, providers...)
+}
+
+func TestChainRetriesOverloaded(t *testing.T) {
+	l := NewLedger("")
+	p := &fakeProvider{name: "claude", ledger: l, errs: []error{&APIError{Service: "Claude API", StatusCode: 529, Message: "overloaded"}}}
+
+	got, err := testChain(l, p).Translate(context.Background(), "hello", "en", "de")
+	if err != nil || got != "HELLO" {
+		t.Fatalf("Translate = %q, %v", got, err)
+	}
+	if e := l.Run().Providers["claude"]; p.calls != 2 || e.Retries != 1 || e.Calls != 1 || e.Failures != 0 {
+		t.Errorf("calls = %d, ledger = %+v; want one retry", p.calls, e)
+	}
+
+	// Retries run out: the error is returned, not retried forever
+	p.errs = []error{&APIError{StatusCode: 503}, &APIError{StatusCode: 503}, &APIError{StatusCode: 503}}
+	p.calls = 0
+	if _, err := testChain(l, p).Translate(context.Background(), "hello", "en", "de"); !IsRetryable(err) || p.calls != 3 {
+		t.Errorf("after retries: %v, calls = %d, want 3", err, p.calls)
+	}
+}
+
+func TestChainFallsBackOnQuota(t *testing.T) {
+	l := NewLedger("")
+	deepl := &fakeProvider{name: "deepl", ledger: l, errs: []error{fmt.Errorf("DeepL API error: %w", &APIError{StatusCode: statusQuotaExceeded})}}
+	claude := &fakeProvider{name: "claude", ledger: l}
+	c := testChain(l, deepl, claude)
+
+	got, err := c.Translate(context.Background(), "hello", "en", "de")
+	if err != nil || got != "HELLO" {
+		t.Fatalf("Translate = %q, %v", got, err)
+	}
+	if deepl.calls != 1 || claude.calls != 1 || c.Name() != "claude" || c.Exhausted() {
+		t.Errorf("calls = %d/%d, name = %s, exhausted = %v", deepl.calls, claude.calls, c.Name(), c.Exhausted())
+	}
+
+	// An exhausted provider isn't tried again
+	if _, err := c.TranslateBatch(context.Background(), []string{"a", "b"}, "en", "de"); err != nil || deepl.calls != 1 {
+		t.Errorf("batch: %v, deepl calls = %d", err, deepl.calls)
+	}
+
+	claude.errs = []error{&APIError{StatusCode: http.StatusBadRequest, Message: "Your credit balance is too low"}}
+	if _, err := c.Translate(context.Background(), "hello", "en", "de"); !IsQuotaExceeded(err) || !c.Exhausted() {
+		t.Errorf("all out of quota: %v, exhausted = %v", err, c.Exhausted())
+	}
+}
+
+func TestChainBudget(t *testing.T) {
+	l := NewLedger("")
+	l.SetPrice("claude", ClaudePrice)
+	l.StartRun("missing de", 0.01)
+	p := &fakeProvider{name: "claude", ledger: l}
+	free := &fakeProvider{name: "claude-cli", ledger: l}
+
+	// ~$0.0012 for the prompt alone fits; ~$0.06 of text doesn't
+	c := testChain(l, p)
+	if _, err := c.Translate(context.Background(), "hello", "en", "de"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.Translate(context.Background(), strings.Repeat("text ", 2000), "en", "de")
+	if !errors.Is(err, ErrBudgetExceeded) || p.calls != 1 {
+		t.Errorf("over budget: %v, calls = %d", err, p.calls)
+	}
+
+	// A free provider later in the chain takes over
+	if _, err := testChain(l, p, free).Translate(context.Background(), strings.Repeat("text ", 2000), "en", "de"); err != nil || free.calls != 1 {
+		t.Errorf("free fallback: %v, calls = %d", err, free.calls)
+	}
+}
+
+func TestChainBatchFallbackKeepsTranslated(t *testing.T) {
+	l := NewLedger("")
+	down := &APIError{StatusCode: 503}
+	first := &fakeProvider{name: "deepl", ledger: l, errs: []error{nil, down, down, down}}
+	second := &fakeProvider{name: "claude", ledger: l}
+
+	got, err := testChain(l, first, second).TranslateBatch(context.Background(), []string{"one", "two", "three"}, "en", "de")
+	if err != nil || strings.Join(got, " ") != "ONE TWO THREE" {
+		t.Fatalf("TranslateBatch = %q, %v", got, err)
+	}
+	// Text 1 fails after its retries; the fallback gets texts 1 and 2 only
+	if first.calls != 4 || second.calls != 2 {
+		t.Errorf("calls = %d/%d, want 4/2", first.calls, second.calls)
+	}
+	if e := l.Run().Providers["claude"]; e.Calls != 2 || e.Characters != 8 {
+		t.Errorf("claude ledger = %+v, want 2 calls for 8 characters", e)
+	}
+}
+
+// redirect sends every request to srv, whatever its URL.
+type redirect struct{ srv *httptest.Server }
+
+func (r redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(r.srv.URL)
+	req.URL.Scheme, req.URL.Host = u.Scheme, u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestChainClaudeBatchRetriesOneText(t *testing.T) {
+	var prompts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		prompts = append(prompts, string(body))
+		if len(prompts) == 3 {
+			w.WriteHeader(statusOverloaded)
+			io.WriteString(w, `{"error":{"type":"overloaded_error"}}`)
+			return
+		}
+		io.WriteString(w, `{"content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":10,"output_tokens":2}}`)
+	}))
+	defer srv.Close()
+
+	l := NewLedger("")
+	l.SetPrice("claude", ClaudePrice)
+	p, _ := NewClaudeProvider("key")
+	p.httpClient = &http.Client{Transport: redirect{srv}}
+	p.UseLedger(l)
+
+	got, err := testChain(l, p).TranslateBatch(context.Background(), []string{"one", "two", "three"}, "en", "de")
+	if err != nil || len(got) != 3 {
+		t.Fatalf("TranslateBatch = %v, %v", got, err)
+	}
+	// Texts 1 and 2 are sent once; only text 3 is retried
+	if len(prompts) != 4 || !strings.Contains(prompts[2], "three") || !strings.Contains(prompts[3], "three") {
+		t.Errorf("%d requests, want 4 with the last two for text 3", len(prompts))
+	}
+	if e := l.Run().Providers["claude"]; e.Calls != 3 || e.Retries != 1 || e.InputTokens != 30 {
+		t.Errorf("ledger = %+v, want 3 billed calls", e)
+	}
+}
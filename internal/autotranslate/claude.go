@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
 )
 
 const (
@@ -16,6 +19,7 @@ const (
 	claudeModel      = "claude-sonnet-4-20250514"
 	claudeMaxTokens  = 4096
 	claudeAPIVersion = "2023-06-01"
+	claudeMinBatch   = 4 // Fewer texts are sent one request each
 )
 
 // Language name mappings for Claude prompts
@@ -60,6 +64,8 @@ var claudeLangNames = map[string]string{
 type ClaudeProvider struct {
 	apiKey     string
 	httpClient *http.Client
+	glossary   *translate.Glossary
+	ledger     *Ledger
 }
 
 // claudeRequest represents a request to Claude API
@@ -101,6 +107,16 @@ func NewClaudeProvider(apiKey string) (*ClaudeProvider, error) {
 	}, nil
 }
 
+// UseGlossary adds the glossary's terms to translation prompts
+func (p *ClaudeProvider) UseGlossary(g *translate.Glossary) {
+	p.glossary = g
+}
+
+// UseLedger records the tokens of each call
+func (p *ClaudeProvider) UseLedger(l *Ledger) {
+	p.ledger = l
+}
+
 // Name returns the provider name
 func (p *ClaudeProvider) Name() string {
 	return "claude"
@@ -124,10 +140,11 @@ CRITICAL INSTRUCTIONS:
    - Inline code (content between single backticks)
    - Hugo shortcodes (like {{< shortcode >}} and {{%% shortcode %%}})
    - HTML tags
+   - Placeholders like ⟦0⟧ (keep each exactly once, where its words belong)
 4. Maintain the same paragraph structure and line breaks
 
-Text to translate:
-%s`, targetName, text)
+%sText to translate:
+%s`, targetName, glossarySection(p.glossary, text, targetLang), text)
 
 	return p.callAPI(ctx, prompt)
 }
@@ -139,7 +156,7 @@ func (p *ClaudeProvider) TranslateBatch(ctx context.Context, texts []string, sou
 	}
 
 	// For small batches, translate individually
-	if len(texts) <= 3 {
+	if len(texts) < claudeMinBatch {
 		results := make([]string, len(texts))
 		for i, text := range texts {
 			translated, err := p.Translate(ctx, text, sourceLang, targetLang)
@@ -162,8 +179,9 @@ func (p *ClaudeProvider) TranslateBatch(ctx context.Context, texts []string, sou
 	builder.WriteString("CRITICAL INSTRUCTIONS:\n")
 	builder.WriteString("1. Return translations in EXACTLY the same numbered format\n")
 	builder.WriteString("2. Preserve ALL markdown formatting\n")
-	builder.WriteString("3. DO NOT translate URLs, code blocks, shortcodes, or HTML tags\n")
+	builder.WriteString("3. DO NOT translate URLs, code blocks, shortcodes, HTML tags, or placeholders like ⟦0⟧\n")
 	builder.WriteString("4. Return ONLY the numbered translations - no explanations\n\n")
+	builder.WriteString(glossarySection(p.glossary, strings.Join(texts, "\n"), targetLang))
 	builder.WriteString("Texts to translate:\n\n")
 
 	for i, text := range texts {
@@ -267,13 +285,14 @@ func (p *ClaudeProvider) callAPI(ctx context.Context, prompt string) (string, er
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Claude API error (status %d): %s", resp.StatusCode, string(body))
+		return "", &APIError{Service: "Claude API", StatusCode: resp.StatusCode, Message: string(body), RetryAfter: retryAfter(resp.Header)}
 	}
 
 	var claudeResp claudeResponse
 	if err := json.Unmarshal(body, &claudeResp); err != nil {
 		return "", fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	p.ledger.Record(p.Name(), utf8.RuneCountInString(prompt), claudeResp.Usage.InputTokens, claudeResp.Usage.OutputTokens)
 
 	if len(claudeResp.Content) == 0 {
 		return "", fmt.Errorf("no content in Claude response")
@@ -6,11 +6,16 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
 )
 
 // ClaudeCLIProvider implements Provider using the Claude CLI (uses logged-in session)
 type ClaudeCLIProvider struct {
 	cliBinary string
+	glossary  *translate.Glossary
+	ledger    *Ledger
 }
 
 // NewClaudeCLIProvider creates a provider that uses the Claude CLI
@@ -41,6 +46,16 @@ func NewClaudeCLIProvider() (*ClaudeCLIProvider, error) {
 	}, nil
 }
 
+// UseGlossary adds the glossary's terms to translation prompts
+func (p *ClaudeCLIProvider) UseGlossary(g *translate.Glossary) {
+	p.glossary = g
+}
+
+// UseLedger records the characters of each call (the CLI reports no tokens)
+func (p *ClaudeCLIProvider) UseLedger(l *Ledger) {
+	p.ledger = l
+}
+
 // Name returns the provider name
 func (p *ClaudeCLIProvider) Name() string {
 	return "claude-cli"
@@ -69,10 +84,11 @@ CRITICAL INSTRUCTIONS:
    - Inline code (content between single backticks)
    - Hugo shortcodes (like {{< shortcode >}} and {{%% shortcode %%}})
    - HTML tags
+   - Placeholders like ⟦0⟧ (keep each exactly once, where its words belong)
 4. Maintain the same paragraph structure and line breaks
 
-Text to translate:
-%s`, targetName, text)
+%sText to translate:
+%s`, targetName, glossarySection(p.glossary, text, targetLang), text)
 
 	return p.callCLI(ctx, prompt)
 }
@@ -114,10 +130,11 @@ func (p *ClaudeCLIProvider) callCLI(ctx context.Context, prompt string) (string,
 	output, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("claude CLI error: %s", string(exitErr.Stderr))
+			return "", &APIError{Service: "claude CLI", Message: strings.TrimSpace(string(exitErr.Stderr) + " " + string(output))}
 		}
 		return "", fmt.Errorf("failed to run claude CLI: %w", err)
 	}
+	p.ledger.Record(p.Name(), utf8.RuneCountInString(prompt), 0, 0)
 
 	return strings.TrimSpace(string(output)), nil
 }
@@ -6,12 +6,15 @@ package autotranslate
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joeblew999/ubuntu-website/internal/translate"
 )
@@ -23,6 +26,13 @@ type CLIOptions struct {
 	Verbose      bool
 	APIKey       string
 	BundlePath   string
+	MemoryPath   string
+	GlossaryPath string
+	FrontMatter  string
+	RateLimits   string
+	Retries      int
+	Budget       float64
+	LedgerPath   string
 }
 
 // Run is the main entry point for the autotranslate CLI.
@@ -32,11 +42,18 @@ func Run(args []string, version string, stdout, stderr io.Writer) int {
 	fs.SetOutput(stderr)
 
 	opts := &CLIOptions{}
-	fs.StringVar(&opts.ProviderName, "provider", "deepl", "Translation provider (deepl, claude, claude-cli)")
+	fs.StringVar(&opts.ProviderName, "provider", "deepl", "Translation provider (deepl, claude, claude-cli), or a comma-separated fallback chain (e.g. deepl,claude)")
 	fs.BoolVar(&opts.DryRun, "dry-run", false, "Show what would be translated without actually translating")
 	fs.BoolVar(&opts.Verbose, "verbose", false, "Verbose output")
 	fs.StringVar(&opts.APIKey, "api-key", "", "API key (or use DEEPL_API_KEY/CLAUDE_API_KEY env var)")
 	fs.StringVar(&opts.BundlePath, "bundle", "tokibundle", "Path to tokibundle directory for ARB translation")
+	fs.StringVar(&opts.MemoryPath, "memory", translate.DefaultMemoryFile, "Translation memory file (empty to disable)")
+	fs.StringVar(&opts.GlossaryPath, "glossary", translate.DefaultGlossaryFile, "Glossary of terms to keep or translate consistently (empty to disable)")
+	fs.StringVar(&opts.FrontMatter, "front-matter", strings.Join(translate.DefaultFrontMatterKeys, ","), "Front matter key paths to translate, comma-separated (e.g. title,cta.title,features.title)")
+	fs.StringVar(&opts.RateLimits, "rate", "", "Requests per minute by provider, e.g. deepl=60,claude=20 (defaults: deepl=120,claude=50,claude-cli=20)")
+	fs.IntVar(&opts.Retries, "retries", 4, "Retries of an overloaded or rate-limited request before falling back to the next provider")
+	fs.Float64Var(&opts.Budget, "budget", 0, "Stop the run before its estimated cost exceeds this many USD (0 = unlimited)")
+	fs.StringVar(&opts.LedgerPath, "ledger", DefaultLedgerFile, "Cost ledger file, shown by 'status' (empty to disable)")
 
 	fs.Usage = func() {
 		fmt.Fprintf(stderr, "Usage: autotranslate [flags] <command> [args]\n\n")
@@ -52,6 +69,21 @@ func Run(args []string, version string, stdout, stderr io.Writer) int {
 		fmt.Fprintf(stderr, "\nEnvironment:\n")
 		fmt.Fprintf(stderr, "  DEEPL_API_KEY    DeepL API key (free tier ends with :fx)\n")
 		fmt.Fprintf(stderr, "  CLAUDE_API_KEY   Claude API key (uses your subscription)\n")
+		fmt.Fprintf(stderr, "\nTranslation memory:\n")
+		fmt.Fprintf(stderr, "  Paragraphs are looked up in -memory first; only new or changed ones\n")
+		fmt.Fprintf(stderr, "  are sent to the provider. The file is shared with 'translate tm'.\n")
+		fmt.Fprintf(stderr, "\nGlossary:\n")
+		fmt.Fprintf(stderr, "  Terms in -glossary are added to Claude prompts and uploaded to DeepL as a\n")
+		fmt.Fprintf(stderr, "  glossary; every translation is checked against it afterwards.\n")
+		fmt.Fprintf(stderr, "\nFront matter:\n")
+		fmt.Fprintf(stderr, "  YAML (---), TOML (+++) and JSON front matter are supported. Only the values\n")
+		fmt.Fprintf(stderr, "  at -front-matter key paths are translated; a path applies to every item of\n")
+		fmt.Fprintf(stderr, "  a list on the way. Comments, key order and other values are left as written.\n")
+		fmt.Fprintf(stderr, "\nFallback and cost:\n")
+		fmt.Fprintf(stderr, "  Each provider in -provider is rate limited (-rate). Overloaded and rate-limited\n")
+		fmt.Fprintf(stderr, "  requests are retried with backoff (-retries); when a provider's quota or credit\n")
+		fmt.Fprintf(stderr, "  runs out, the next one takes over. Characters, tokens and estimated cost of each\n")
+		fmt.Fprintf(stderr, "  run go to -ledger; a run stops before it would spend more than -budget.\n")
 	}
 
 	if err := fs.Parse(args[1:]); err != nil {
@@ -105,16 +137,144 @@ func Run(args []string, version string, stdout, stderr io.Writer) int {
 }
 
 type cliRunner struct {
-	opts   *CLIOptions
-	stdout io.Writer
-	stderr io.Writer
-	args   []string
+	opts     *CLIOptions
+	stdout   io.Writer
+	stderr   io.Writer
+	args     []string
+	glossary *translate.Glossary
+	chain    *Chain
+	ledger   *Ledger
 }
 
+// getProvider creates the -provider chain, with the -glossary glossary,
+// recording this run in the -ledger ledger
 func (c *cliRunner) getProvider() (Provider, error) {
+	limits, err := parseRateLimits(c.opts.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+	c.ledger = NewLedger("")
+	if c.opts.LedgerPath != "" {
+		if c.ledger, err = LoadLedger(c.opts.LedgerPath); err != nil {
+			return nil, err
+		}
+	}
+	c.ledger.StartRun(strings.Join(c.args, " "), c.opts.Budget)
+
+	var providers []Provider
+	for _, name := range strings.Split(c.opts.ProviderName, ",") {
+		provider, err := c.newProvider(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		switch p := provider.(type) {
+		case *DeepLProvider:
+			if !p.IsFree() {
+				c.ledger.SetPrice(p.Name(), DeepLProPrice)
+			}
+		case *ClaudeProvider:
+			c.ledger.SetPrice(p.Name(), ClaudePrice)
+		}
+		if l, ok := provider.(LedgerUser); ok {
+			l.UseLedger(c.ledger)
+		}
+		providers = append(providers, provider)
+	}
+	c.chain = NewChain(ChainOptions{
+		RateLimits: limits,
+		MaxRetries: c.opts.Retries,
+		Ledger:     c.ledger,
+		Log:        c.stderr,
+	}, providers...)
+
+	if c.opts.GlossaryPath != "" {
+		if c.glossary, err = translate.LoadGlossary(c.opts.GlossaryPath); err != nil {
+			return nil, err
+		}
+		c.chain.UseGlossary(c.glossary)
+	}
+	return c.chain, nil
+}
+
+// parseRateLimits parses -rate ("deepl=60,claude=20") over DefaultRateLimits
+func parseRateLimits(s string) (map[string]float64, error) {
+	limits := make(map[string]float64, len(DefaultRateLimits))
+	for name, rpm := range DefaultRateLimits {
+		limits[name] = rpm
+	}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		rpm, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || rpm < 0 {
+			return nil, fmt.Errorf("invalid -rate %q (want provider=requests-per-minute)", part)
+		}
+		limits[strings.TrimSpace(name)] = rpm
+	}
+	return limits, nil
+}
+
+// saveLedger writes the run's usage to the -ledger file
+func (c *cliRunner) saveLedger() {
+	if err := c.ledger.Save(); err != nil {
+		fmt.Fprintf(c.stderr, "Error saving ledger: %v\n", err)
+	}
+}
+
+// printRunCost reports what this run sent to each provider and its estimated cost
+func (c *cliRunner) printRunCost() {
+	run := c.ledger.Run()
+	if run == nil || len(run.Providers) == 0 {
+		return
+	}
+	for _, name := range run.ProviderNames() {
+		fmt.Fprintf(c.stdout, "%s: %s\n", name, formatLedgerEntry(run.Providers[name]))
+	}
+	if run.Budget > 0 {
+		fmt.Fprintf(c.stdout, "Estimated cost: $%.4f of $%.2f budget\n", run.Cost(), run.Budget)
+	} else {
+		fmt.Fprintf(c.stdout, "Estimated cost: $%.4f\n", run.Cost())
+	}
+}
+
+// formatLedgerEntry formats a provider's use in one run
+func formatLedgerEntry(e *LedgerEntry) string {
+	s := fmt.Sprintf("%d calls, %s chars", e.Calls, formatNumber(e.Characters))
+	if e.InputTokens > 0 || e.OutputTokens > 0 {
+		s += fmt.Sprintf(", %s in / %s out tokens", formatNumber(e.InputTokens), formatNumber(e.OutputTokens))
+	}
+	if e.Retries > 0 || e.Failures > 0 {
+		s += fmt.Sprintf(", %d retries, %d failed", e.Retries, e.Failures)
+	}
+	return s + fmt.Sprintf(", $%.4f", e.Cost)
+}
+
+// deepLProvider returns the DeepL provider in the chain, if any
+func (c *cliRunner) deepLProvider() *DeepLProvider {
+	for _, p := range c.chain.Providers() {
+		if d, ok := p.(*DeepLProvider); ok {
+			return d
+		}
+	}
+	return nil
+}
+
+// checkGlossary reports the glossary terms a translation breaks and
+// returns how many
+func (c *cliRunner) checkGlossary(path, source, translated, targetLang string) int {
+	violations := c.glossary.Check(source, translated, targetLang)
+	for _, v := range violations {
+		fmt.Fprintf(c.stdout, "⚠ Glossary: %s: %s\n", path, v)
+	}
+	return len(violations)
+}
+
+func (c *cliRunner) newProvider(name string) (Provider, error) {
 	key := c.opts.APIKey
 
-	switch c.opts.ProviderName {
+	switch name {
 	case "deepl":
 		if key == "" {
 			key = os.Getenv("DEEPL_API_KEY")
@@ -138,10 +298,31 @@ func (c *cliRunner) getProvider() (Provider, error) {
 		return NewClaudeCLIProvider()
 
 	default:
-		return nil, fmt.Errorf("unsupported provider: %s (available: deepl, claude, claude-cli)", c.opts.ProviderName)
+		return nil, fmt.Errorf("unsupported provider: %s (available: deepl, claude, claude-cli)", name)
 	}
 }
 
+// newMarkdownTranslator creates a markdown translator using the -memory file
+func (c *cliRunner) newMarkdownTranslator(provider Provider) (*MarkdownTranslator, *translate.Memory, error) {
+	mt := NewMarkdownTranslator(provider)
+	mt.UseFrontMatterKeys(translate.ParseFrontMatterKeys(c.opts.FrontMatter))
+	if c.opts.MemoryPath == "" {
+		return mt, translate.NewMemory(""), nil
+	}
+	memory, err := translate.LoadMemory(c.opts.MemoryPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	mt.UseMemory(memory)
+	return mt, memory, nil
+}
+
+// printMemoryStats reports how many paragraphs came from the translation memory
+func (c *cliRunner) printMemoryStats(stats translate.MemoryStats) {
+	fmt.Fprintf(c.stdout, "Translation memory: %d reused, %d translated (%s chars sent)\n",
+		stats.Reused, stats.Translated, formatNumber(int64(stats.Chars)))
+}
+
 func (c *cliRunner) runFileTranslation(sourcePath, targetLang string) int {
 	ctx := context.Background()
 
@@ -178,8 +359,17 @@ func (c *cliRunner) runFileTranslation(sourcePath, targetLang string) int {
 	}
 
 	// Translate
-	mt := NewMarkdownTranslator(provider)
+	mt, memory, err := c.newMarkdownTranslator(provider)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "Error: %v\n", err)
+		return 1
+	}
 	translated, err := mt.TranslateFile(ctx, string(content), "en", targetLang)
+	c.saveLedger()
+	if saveErr := memory.Save(); saveErr != nil {
+		fmt.Fprintf(c.stderr, "Error saving translation memory: %v\n", saveErr)
+		return 1
+	}
 	if err != nil {
 		fmt.Fprintf(c.stderr, "Error translating: %v\n", err)
 		return 1
@@ -199,6 +389,11 @@ func (c *cliRunner) runFileTranslation(sourcePath, targetLang string) int {
 	}
 
 	fmt.Fprintf(c.stdout, "✓ Translated: %s → %s\n", sourcePath, targetPath)
+	c.printMemoryStats(mt.Stats())
+	c.printRunCost()
+	if c.checkGlossary(targetPath, string(content), translated, targetLang) > 0 {
+		fmt.Fprintf(c.stdout, "Fix the translation, or the glossary, then run 'translate content validate'\n")
+	}
 	return 0
 }
 
@@ -297,7 +492,7 @@ func (c *cliRunner) runMissingTranslation(targetLang string) int {
 	}
 
 	// Get usage before translation
-	deeplProvider, _ := provider.(*DeepLProvider)
+	deeplProvider := c.deepLProvider()
 	var usageBefore *Usage
 	if deeplProvider != nil {
 		usageBefore, _ = deeplProvider.GetUsage(ctx)
@@ -310,9 +505,14 @@ func (c *cliRunner) runMissingTranslation(targetLang string) int {
 	}
 
 	// Translate each file
-	mt := NewMarkdownTranslator(provider)
+	mt, memory, err := c.newMarkdownTranslator(provider)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "Error: %v\n", err)
+		return 1
+	}
 	successCount := 0
 	errorCount := 0
+	glossaryCount := 0
 
 	for i, sourcePath := range missingFiles {
 		content, err := os.ReadFile(sourcePath)
@@ -330,9 +530,27 @@ func (c *cliRunner) runMissingTranslation(targetLang string) int {
 		}
 
 		translated, err := mt.TranslateFile(ctx, string(content), "en", targetLang)
+
+		// Stop before going over budget, or once no provider has quota left
+		if errors.Is(err, ErrBudgetExceeded) {
+			c.ledger.Stop("budget")
+		} else if IsQuotaExceeded(err) && c.chain.Exhausted() {
+			c.ledger.Stop("quota")
+		}
+
+		// Save after every file, so an interrupted run keeps its work
+		c.saveLedger()
+		if saveErr := memory.Save(); saveErr != nil {
+			fmt.Fprintf(c.stderr, "Error saving translation memory: %v\n", saveErr)
+			return 1
+		}
 		if err != nil {
 			fmt.Fprintf(c.stderr, "✗ Error translating %s: %v\n", sourcePath, err)
 			errorCount++
+			if c.ledger.Run().Stopped != "" {
+				fmt.Fprintf(c.stderr, "Stopping: %d files left untranslated\n", len(missingFiles)-i-1)
+				break
+			}
 			continue
 		}
 
@@ -351,6 +569,7 @@ func (c *cliRunner) runMissingTranslation(targetLang string) int {
 		}
 
 		successCount++
+		glossaryCount += c.checkGlossary(targetPath, string(content), translated, targetLang)
 		if !c.opts.Verbose {
 			fmt.Fprintf(c.stdout, "✓ [%d/%d] %s\n", i+1, len(missingFiles), relPath)
 		} else {
@@ -359,6 +578,11 @@ func (c *cliRunner) runMissingTranslation(targetLang string) int {
 	}
 
 	fmt.Fprintf(c.stdout, "\nComplete: %d translated, %d errors\n", successCount, errorCount)
+	c.printMemoryStats(mt.Stats())
+	c.printRunCost()
+	if glossaryCount > 0 {
+		fmt.Fprintf(c.stdout, "Glossary: %d violations (see ⚠ above; 'translate content validate' lists them)\n", glossaryCount)
+	}
 
 	// Show usage after translation
 	if deeplProvider != nil {
@@ -468,13 +692,52 @@ func (c *cliRunner) runStatus() int {
 	}
 	fmt.Fprintln(c.stdout)
 
+	c.printLedger()
+
 	fmt.Fprintln(c.stdout, "========================================")
 	fmt.Fprintf(c.stdout, "Current provider: %s\n", c.opts.ProviderName)
 	fmt.Fprintln(c.stdout, "Use --provider=deepl, --provider=claude, or --provider=claude-cli")
+	fmt.Fprintln(c.stdout, "or a fallback chain such as --provider=deepl,claude")
 	fmt.Fprintln(c.stdout, "========================================")
 	return 0
 }
 
+// printLedger shows the last run and this month's cost from the -ledger file
+func (c *cliRunner) printLedger() {
+	if c.opts.LedgerPath == "" {
+		return
+	}
+	fmt.Fprintln(c.stdout, "--- Ledger ---")
+	ledger, err := LoadLedger(c.opts.LedgerPath)
+	if err != nil {
+		fmt.Fprintf(c.stdout, "Error: %v\n\n", err)
+		return
+	}
+	last := ledger.Last()
+	if last == nil {
+		fmt.Fprintf(c.stdout, "No runs recorded in %s\n\n", c.opts.LedgerPath)
+		return
+	}
+
+	fmt.Fprintf(c.stdout, "Last run: %s (%s, %s)\n", last.Command,
+		last.Started.Local().Format("2006-01-02 15:04"), last.Finished.Sub(last.Started))
+	for _, name := range last.ProviderNames() {
+		fmt.Fprintf(c.stdout, "  %-10s %s\n", name, formatLedgerEntry(last.Providers[name]))
+	}
+	if last.Stopped != "" {
+		fmt.Fprintf(c.stdout, "  Stopped early: %s\n", last.Stopped)
+	}
+	fmt.Fprintf(c.stdout, "  Cost: $%.4f\n", last.Cost())
+
+	now := time.Now().UTC()
+	runs := ledger.Since(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+	var cost float64
+	for _, r := range runs {
+		cost += r.Cost()
+	}
+	fmt.Fprintf(c.stdout, "This month: %d runs, $%.4f\n\n", len(runs), cost)
+}
+
 func (c *cliRunner) runARBTranslation(targetLang string) int {
 	ctx := context.Background()
 
@@ -581,6 +844,7 @@ func (c *cliRunner) runARBTranslation(targetLang string) int {
 				fmt.Fprintf(c.stdout, "\r  Progress: %d/%d messages (%.0f%%)", done, total, float64(done)/float64(total)*100)
 			}
 		})
+	c.saveLedger()
 
 	if err != nil {
 		fmt.Fprintf(c.stderr, "\nError during translation: %v\n", err)
@@ -607,6 +871,7 @@ func (c *cliRunner) runARBTranslation(targetLang string) int {
 	fmt.Fprintf(c.stdout, "✓ Saved to %s\n", targetARBPath)
 	fmt.Fprintf(c.stdout, "  Completeness: %.1f%% (%d/%d)\n",
 		finalStats.CompletenessPerc, finalStats.TranslatedCount, finalStats.TotalMessages)
+	c.printRunCost()
 	fmt.Fprintf(c.stdout, "\nNext step: run 'toki apply -t %s' to apply translations to markdown files\n", targetLang)
 	return 0
 }
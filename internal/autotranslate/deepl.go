@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/bounoable/deepl"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
 )
 
 // DeepL language code mappings to deepl.Language type
@@ -49,9 +52,15 @@ var deeplLangMap = map[string]deepl.Language{
 
 // DeepLProvider implements Provider using the DeepL API
 type DeepLProvider struct {
-	client *deepl.Client
+	client      *deepl.Client
+	glossary    *translate.Glossary
+	glossaryIDs map[string]string // lang code → DeepL glossary ID ("" = none)
+	ledger      *Ledger
 }
 
+// deeplGlossaryPrefix names the glossaries this tool uploads to DeepL
+const deeplGlossaryPrefix = "ubuntu-website"
+
 // API endpoints
 const (
 	DeepLProURL  = "https://api.deepl.com/v2"
@@ -79,7 +88,17 @@ func NewDeepLProvider(apiKey string) (*DeepLProvider, error) {
 		client = deepl.New(apiKey, deepl.BaseURL(DeepLProURL))
 	}
 
-	return &DeepLProvider{client: client}, nil
+	return &DeepLProvider{client: client, glossaryIDs: make(map[string]string)}, nil
+}
+
+// UseLedger records the characters of each call
+func (p *DeepLProvider) UseLedger(l *Ledger) {
+	p.ledger = l
+}
+
+// IsFree reports whether the key is for DeepL API Free
+func (p *DeepLProvider) IsFree() bool {
+	return strings.HasSuffix(p.client.AuthKey(), ":fx")
 }
 
 // Name returns the provider name
@@ -103,6 +122,11 @@ func (p *DeepLProvider) Translate(ctx context.Context, text, sourceLang, targetL
 	// Source language is optional for DeepL (auto-detect)
 	if srcLang, ok := deeplLangMap[strings.ToLower(sourceLang)]; ok {
 		opts = append(opts, deepl.SourceLang(srcLang))
+
+		// Glossaries need a source language
+		if id := p.glossaryID(ctx, strings.ToLower(targetLang), srcLang, tgtLang); id != "" {
+			opts = append(opts, deepl.GlossaryID(id))
+		}
 	}
 
 	// Call DeepL API
@@ -110,10 +134,70 @@ func (p *DeepLProvider) Translate(ctx context.Context, text, sourceLang, targetL
 	if err != nil {
 		return "", fmt.Errorf("DeepL API error: %w", err)
 	}
+	p.ledger.Record(p.Name(), utf8.RuneCountInString(text), 0, 0)
 
 	return translated, nil
 }
 
+// UseGlossary makes translations follow the glossary, uploaded to DeepL
+// as a native glossary per target language
+func (p *DeepLProvider) UseGlossary(g *translate.Glossary) {
+	p.glossary = g
+	p.glossaryIDs = make(map[string]string)
+}
+
+// glossaryID returns the DeepL glossary for a target language, uploading
+// it on first use. DeepL glossaries can't be edited, so each version is
+// named <prefix>-<lang>-<hash> and replaces older versions. Without a
+// glossary (none for the language, or DeepL refused it), translations go
+// ahead and the glossary check afterwards reports any violations.
+func (p *DeepLProvider) glossaryID(ctx context.Context, lang string, srcLang, tgtLang deepl.Language) string {
+	if p.glossary == nil {
+		return ""
+	}
+	if id, ok := p.glossaryIDs[lang]; ok {
+		return id
+	}
+	p.glossaryIDs[lang] = "" // Try once per run
+
+	var entries []deepl.GlossaryEntry
+	for _, e := range p.glossary.Entries(lang) {
+		entries = append(entries, deepl.GlossaryEntry{Source: e.Source, Target: e.Target})
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+
+	existing, err := p.client.ListGlossaries(ctx)
+	if err != nil {
+		return ""
+	}
+	prefix := fmt.Sprintf("%s-%s-", deeplGlossaryPrefix, lang)
+	name := prefix + p.glossary.Hash(lang)
+	for _, g := range existing {
+		if g.Name == name {
+			p.glossaryIDs[lang] = g.GlossaryID
+			return g.GlossaryID
+		}
+	}
+
+	// Glossaries take the base language (ZH, not ZH-HANS)
+	base := func(l deepl.Language) deepl.Language {
+		return deepl.Language(strings.SplitN(string(l), "-", 2)[0])
+	}
+	created, err := p.client.CreateGlossary(ctx, name, base(srcLang), base(tgtLang), entries)
+	if err != nil {
+		return ""
+	}
+	for _, g := range existing {
+		if strings.HasPrefix(g.Name, prefix) {
+			p.client.DeleteGlossary(ctx, g.GlossaryID)
+		}
+	}
+	p.glossaryIDs[lang] = created.GlossaryID
+	return created.GlossaryID
+}
+
 // TranslateBatch translates multiple texts by calling Translate for each.
 // Note: DeepL's Go client doesn't have a native batch API, so we translate sequentially.
 // For better performance with many texts, consider chunking on the caller side.
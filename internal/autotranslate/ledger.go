@@ -0,0 +1,239 @@
+package autotranslate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultLedgerFile keeps what each run sent to each provider and what it
+// cost, relative to the site root.
+const DefaultLedgerFile = "translations/ledger.json"
+
+// ErrBudgetExceeded is returned when every provider that could translate
+// a text would take the run past its -budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Price is what a provider charges, in USD.
+type Price struct {
+	PerChar        float64 // DeepL bills source characters
+	PerInputToken  float64 // Claude bills prompt and reply tokens
+	PerOutputToken float64
+}
+
+// Prices of the paid plans. DeepL Free and the Claude CLI (a subscription)
+// cost nothing per call.
+var (
+	DeepLProPrice = Price{PerChar: 25.0 / 1e6}
+	ClaudePrice   = Price{PerInputToken: 3.0 / 1e6, PerOutputToken: 15.0 / 1e6} // claude-sonnet-4
+)
+
+// Before a Claude call its tokens are estimated from the text: about four
+// characters a token, the instructions around it, and a longer reply.
+const (
+	charsPerToken     = 4
+	promptTokens      = 400
+	replyTokensFactor = 1.5
+)
+
+// Ledger is the history of autotranslate runs: per run and provider, the
+// calls made, characters and tokens sent, and the estimated cost.
+type Ledger struct {
+	Runs []*LedgerRun `json:"runs"`
+
+	path   string
+	run    *LedgerRun
+	prices map[string]Price
+}
+
+// LedgerRun is one autotranslate run.
+type LedgerRun struct {
+	Command   string                  `json:"command"` // e.g. "missing de"
+	Started   time.Time               `json:"started"`
+	Finished  time.Time               `json:"finished"`
+	Budget    float64                 `json:"budget_usd,omitempty"`
+	Stopped   string                  `json:"stopped,omitempty"` // Why the run ended early
+	Providers map[string]*LedgerEntry `json:"providers"`
+}
+
+// LedgerEntry is what a run used of one provider.
+type LedgerEntry struct {
+	Calls        int     `json:"calls"`
+	Retries      int     `json:"retries,omitempty"`
+	Failures     int     `json:"failures,omitempty"` // Calls given up on, by retry or fallback
+	Characters   int64   `json:"characters"`         // Source text sent
+	InputTokens  int64   `json:"input_tokens,omitempty"`
+	OutputTokens int64   `json:"output_tokens,omitempty"`
+	Cost         float64 `json:"cost_usd"` // Estimated from Price
+}
+
+// NewLedger creates an empty ledger saved to path ("" to not save).
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path, prices: make(map[string]Price)}
+}
+
+// LoadLedger reads a ledger, or returns an empty one if the file doesn't exist.
+func LoadLedger(path string) (*Ledger, error) {
+	l := NewLedger(path)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("parsing ledger %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Save writes the ledger, if it has a path and the current run used a provider.
+func (l *Ledger) Save() error {
+	if l == nil || l.path == "" || l.run == nil || len(l.run.Providers) == 0 {
+		return nil
+	}
+	l.run.Finished = time.Now().UTC().Truncate(time.Second)
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, append(data, '\n'), 0644)
+}
+
+// StartRun begins recording a run. A budget of 0 is unlimited.
+func (l *Ledger) StartRun(command string, budget float64) {
+	now := time.Now().UTC().Truncate(time.Second)
+	l.run = &LedgerRun{Command: command, Started: now, Finished: now, Budget: budget, Providers: make(map[string]*LedgerEntry)}
+	l.Runs = append(l.Runs, l.run)
+}
+
+// Run returns the current run, or nil.
+func (l *Ledger) Run() *LedgerRun {
+	if l == nil {
+		return nil
+	}
+	return l.run
+}
+
+// Last returns the most recent run, or nil.
+func (l *Ledger) Last() *LedgerRun {
+	if len(l.Runs) == 0 {
+		return nil
+	}
+	return l.Runs[len(l.Runs)-1]
+}
+
+// SetPrice sets what a provider charges; providers without a price are free.
+func (l *Ledger) SetPrice(provider string, p Price) {
+	l.prices[provider] = p
+}
+
+// Stop records why the run ended early.
+func (l *Ledger) Stop(reason string) {
+	if l.Run() != nil {
+		l.run.Stopped = reason
+	}
+}
+
+func (l *Ledger) entry(provider string) *LedgerEntry {
+	if l.run == nil {
+		l.StartRun("", 0)
+	}
+	e := l.run.Providers[provider]
+	if e == nil {
+		e = &LedgerEntry{}
+		l.run.Providers[provider] = e
+	}
+	return e
+}
+
+// Record adds a provider call: the characters of source text sent and,
+// for providers that report them, the tokens used.
+func (l *Ledger) Record(provider string, chars, inputTokens, outputTokens int) {
+	if l == nil {
+		return
+	}
+	e := l.entry(provider)
+	p := l.prices[provider]
+	e.Calls++
+	e.Characters += int64(chars)
+	e.InputTokens += int64(inputTokens)
+	e.OutputTokens += int64(outputTokens)
+	e.Cost += float64(chars)*p.PerChar + float64(inputTokens)*p.PerInputToken + float64(outputTokens)*p.PerOutputToken
+}
+
+// Retried counts a call that is tried again.
+func (l *Ledger) Retried(provider string) {
+	if l != nil {
+		l.entry(provider).Retries++
+	}
+}
+
+// Failed counts a call given up on.
+func (l *Ledger) Failed(provider string) {
+	if l != nil {
+		l.entry(provider).Failures++
+	}
+}
+
+// Estimate returns what sending chars of source text to a provider is
+// likely to cost.
+func (l *Ledger) Estimate(provider string, chars int) float64 {
+	if l == nil {
+		return 0
+	}
+	p := l.prices[provider]
+	tokens := float64(chars) / charsPerToken
+	return float64(chars)*p.PerChar + (tokens+promptTokens)*p.PerInputToken + tokens*replyTokensFactor*p.PerOutputToken
+}
+
+// CheckBudget returns an error wrapping ErrBudgetExceeded if spending
+// estimate would take the run past its budget.
+func (l *Ledger) CheckBudget(provider string, estimate float64) error {
+	run := l.Run()
+	if run == nil || run.Budget <= 0 || estimate <= 0 {
+		return nil
+	}
+	if spent := run.Cost(); spent+estimate > run.Budget {
+		return fmt.Errorf("%w: %s would cost ~$%.4f, $%.4f of $%.2f spent", ErrBudgetExceeded, provider, estimate, spent, run.Budget)
+	}
+	return nil
+}
+
+// Cost returns the run's estimated cost.
+func (r *LedgerRun) Cost() float64 {
+	var total float64
+	for _, e := range r.Providers {
+		total += e.Cost
+	}
+	return total
+}
+
+// ProviderNames returns the run's providers, sorted.
+func (r *LedgerRun) ProviderNames() []string {
+	names := make([]string, 0, len(r.Providers))
+	for name := range r.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Since returns the runs started at or after t.
+func (l *Ledger) Since(t time.Time) []*LedgerRun {
+	var runs []*LedgerRun
+	for _, r := range l.Runs {
+		if !r.Started.Before(t) {
+			runs = append(runs, r)
+		}
+	}
+	return runs
+}
@@ -0,0 +1,63 @@
+package autotranslate
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestLedgerRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := NewLedger(path)
+	l.SetPrice("deepl", DeepLProPrice)
+	l.SetPrice("claude", ClaudePrice)
+
+	// Nothing recorded, nothing written
+	l.StartRun("missing de", 5)
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := LoadLedger(path); len(loaded.Runs) != 0 {
+		t.Fatalf("empty run saved: %+v", loaded.Runs)
+	}
+
+	if e := l.Estimate("deepl", 1000); math.Abs(e-0.025) > 1e-9 {
+		t.Errorf("deepl estimate = %f, want 0.025", e)
+	}
+	if e := l.Estimate("claude", 4000); e <= 0 {
+		t.Errorf("claude estimate = %f", e)
+	}
+	if e := l.Estimate("claude-cli", 4000); e != 0 {
+		t.Errorf("claude-cli estimate = %f, want 0 (no price)", e)
+	}
+
+	l.Record("deepl", 40000, 0, 0)
+	l.Record("claude", 2000, 1000, 1000000)
+	l.Retried("claude")
+	l.Stop("budget")
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := loaded.Last()
+	if run == nil || run.Command != "missing de" || run.Budget != 5 || run.Stopped != "budget" {
+		t.Fatalf("loaded run = %+v", run)
+	}
+	if e := run.Providers["claude"]; e.Calls != 1 || e.Retries != 1 || e.OutputTokens != 1000000 {
+		t.Errorf("claude entry = %+v", e)
+	}
+	// 40k DeepL chars = $1; 1k input + 1M output Claude tokens = $15.003
+	if math.Abs(run.Cost()-16.003) > 1e-9 {
+		t.Errorf("cost = %f, want 16.003", run.Cost())
+	}
+	if err := loaded.CheckBudget("claude", 0.01); err != nil {
+		t.Errorf("loaded ledger has no current run, got %v", err)
+	}
+	if len(loaded.Since(run.Started)) != 1 || len(loaded.Since(run.Started.Add(1))) != 0 {
+		t.Error("Since does not select by start time")
+	}
+}
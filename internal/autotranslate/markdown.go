@@ -3,262 +3,202 @@ package autotranslate
 import (
 	"context"
 	"fmt"
-	"regexp"
 	"strings"
+
+	"github.com/yuin/goldmark/parser"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
 )
 
-// Placeholder markers for content that shouldn't be translated
+// Paragraphs sent in one provider call are separated by segmentMarker;
+// a call holds at most maxBatchChars of English text.
 const (
-	placeholderPrefix = "[[NOTRANSLATE_"
-	placeholderSuffix = "]]"
+	segmentMarker = "[[SEGMENT]]"
+	maxBatchChars = 6000
 )
 
 // MarkdownTranslator handles translation of Hugo markdown files
 // while preserving front matter, shortcodes, code blocks, etc.
+// Front matter (YAML, TOML or JSON) has the values at its key paths
+// translated in place; bodies are parsed with goldmark and only their
+// text is translated. Paragraphs already in the translation memory are
+// not sent to the provider.
 type MarkdownTranslator struct {
-	provider Provider
+	provider    Provider
+	memory      *translate.Memory
+	frontMatter []string
+	stats       translate.MemoryStats
 }
 
 // NewMarkdownTranslator creates a new markdown translator
 func NewMarkdownTranslator(provider Provider) *MarkdownTranslator {
-	return &MarkdownTranslator{provider: provider}
+	return &MarkdownTranslator{
+		provider:    provider,
+		memory:      translate.NewMemory(""),
+		frontMatter: translate.DefaultFrontMatterKeys,
+	}
+}
+
+// UseMemory shares a translation memory between files and runs
+func (t *MarkdownTranslator) UseMemory(m *translate.Memory) {
+	t.memory = m
+}
+
+// UseFrontMatterKeys sets the front matter key paths that are translated
+func (t *MarkdownTranslator) UseFrontMatterKeys(keys []string) {
+	t.frontMatter = keys
+}
+
+// Stats returns the memory hits and provider use of all files translated so far
+func (t *MarkdownTranslator) Stats() translate.MemoryStats {
+	return t.stats
 }
 
 // TranslateFile translates a Hugo markdown file content
 func (t *MarkdownTranslator) TranslateFile(ctx context.Context, content, sourceLang, targetLang string) (string, error) {
 	// Split front matter from body
-	frontMatter, body, err := splitFrontMatter(content)
+	frontMatter, body, err := translate.SplitFrontMatter(strings.TrimLeft(content, " \t\r\n"))
 	if err != nil {
 		return "", err
 	}
+	body = strings.TrimSpace(body)
 
-	// Translate front matter (only specific fields)
-	translatedFrontMatter, err := t.translateFrontMatter(ctx, frontMatter, sourceLang, targetLang)
-	if err != nil {
+	// Translate front matter (only the configured key paths)
+	if err := t.translateFrontMatter(ctx, frontMatter, sourceLang, targetLang); err != nil {
 		return "", fmt.Errorf("translating front matter: %w", err)
 	}
 
-	// Translate body with placeholder protection
+	// Translate body text, keeping its markdown
 	translatedBody, err := t.translateBody(ctx, body, sourceLang, targetLang)
 	if err != nil {
 		return "", fmt.Errorf("translating body: %w", err)
 	}
 
-	// Reassemble
-	return assembleFrontMatter(translatedFrontMatter, translatedBody), nil
-}
-
-// splitFrontMatter separates YAML front matter from markdown body
-func splitFrontMatter(content string) (frontMatter, body string, err error) {
-	content = strings.TrimSpace(content)
-
-	// Check for front matter delimiter
-	if !strings.HasPrefix(content, "---") {
-		return "", content, nil
+	// Code, links, HTML and shortcodes must all have come through
+	if err := translate.CheckProtected(body, translatedBody); err != nil {
+		return "", fmt.Errorf("translated body: %w", err)
 	}
 
-	// Find closing delimiter
-	rest := content[3:]
-	idx := strings.Index(rest, "\n---")
-	if idx == -1 {
-		return "", content, nil
+	// Reassemble
+	if frontMatter == nil {
+		return translatedBody, nil
 	}
-
-	frontMatter = strings.TrimSpace(rest[:idx])
-	body = strings.TrimSpace(rest[idx+4:])
-
-	return frontMatter, body, nil
-}
-
-// assembleFrontMatter combines front matter and body
-func assembleFrontMatter(frontMatter, body string) string {
-	if frontMatter == "" {
-		return body
+	if translatedBody == "" {
+		return frontMatter.Raw, nil
 	}
-	return fmt.Sprintf("---\n%s\n---\n\n%s\n", frontMatter, body)
+	return frontMatter.Raw + "\n" + translatedBody + "\n", nil
 }
 
-// Fields in front matter that should be translated
-var translatableFrontMatterFields = map[string]bool{
-	"title":       true,
-	"meta_title":  true,
-	"description": true,
-	"excerpt":     true,
-	"summary":     true,
-}
-
-// Fields that should never be translated
-var preserveFrontMatterFields = map[string]bool{
-	"date":       true,
-	"draft":      true,
-	"image":      true,
-	"images":     true,
-	"author":     true,
-	"authors":    true,
-	"slug":       true,
-	"url":        true,
-	"aliases":    true,
-	"weight":     true,
-	"categories": true,
-	"tags":       true,
-	"layout":     true,
-	"type":       true,
-}
-
-// translateFrontMatter translates specific fields in YAML front matter
-func (t *MarkdownTranslator) translateFrontMatter(ctx context.Context, frontMatter, sourceLang, targetLang string) (string, error) {
-	if frontMatter == "" {
-		return "", nil
+// translateFrontMatter translates the string values at the front matter
+// key paths, replacing them where they are written
+func (t *MarkdownTranslator) translateFrontMatter(ctx context.Context, frontMatter *translate.FrontMatter, sourceLang, targetLang string) error {
+	if frontMatter == nil {
+		return nil
 	}
-
-	lines := strings.Split(frontMatter, "\n")
-	var result []string
-	var textsToTranslate []string
-	var lineIndices []int // Which lines need translation
-
-	for i, line := range lines {
-		// Skip empty lines and comments
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			result = append(result, line)
-			continue
-		}
-
-		// Parse key: value
-		colonIdx := strings.Index(line, ":")
-		if colonIdx == -1 {
-			result = append(result, line)
-			continue
-		}
-
-		key := strings.TrimSpace(line[:colonIdx])
-		value := strings.TrimSpace(line[colonIdx+1:])
-
-		// Check if this field should be translated
-		if translatableFrontMatterFields[key] && value != "" {
-			// Remove quotes if present
-			cleanValue := strings.Trim(value, "\"'")
-			if cleanValue != "" {
-				textsToTranslate = append(textsToTranslate, cleanValue)
-				lineIndices = append(lineIndices, i)
-			}
-		}
-		result = append(result, line)
+	all, err := frontMatter.Strings(t.frontMatter)
+	if err != nil {
+		return err
 	}
-
-	// Batch translate all fields
-	if len(textsToTranslate) > 0 {
-		translations, err := t.provider.TranslateBatch(ctx, textsToTranslate, sourceLang, targetLang)
-		if err != nil {
-			return "", err
+	var strs []translate.FrontMatterString
+	for _, s := range all {
+		if strings.TrimSpace(s.Value) != "" {
+			strs = append(strs, s)
 		}
+	}
+	if len(strs) == 0 {
+		return nil
+	}
 
-		// Replace translated values
-		for i, lineIdx := range lineIndices {
-			line := result[lineIdx]
-			colonIdx := strings.Index(line, ":")
-			key := line[:colonIdx]
-			indent := ""
-			for _, ch := range line {
-				if ch == ' ' || ch == '\t' {
-					indent += string(ch)
-				} else {
-					break
-				}
-			}
-			// Escape quotes in translated text
-			escaped := strings.ReplaceAll(translations[i], "\"", "\\\"")
-			result[lineIdx] = fmt.Sprintf("%s%s: \"%s\"", indent, strings.TrimSpace(key), escaped)
-		}
+	// Batch translate all values
+	texts := make([]string, len(strs))
+	for i, s := range strs {
+		texts[i] = s.Value
+	}
+	translations, stats, err := t.memory.TranslateSegments(texts, targetLang, t.provider.Name(), func(texts []string) ([]string, error) {
+		return t.provider.TranslateBatch(ctx, texts, sourceLang, targetLang)
+	})
+	t.stats.Add(stats)
+	if err != nil {
+		return err
 	}
 
-	return strings.Join(result, "\n"), nil
+	values := make(map[string]string, len(strs))
+	for i, s := range strs {
+		values[s.Path] = translations[i]
+	}
+	return frontMatter.Set(t.frontMatter, values)
 }
 
-// Patterns for content that should not be translated
-var (
-	// Hugo shortcodes: {{< shortcode >}} or {{% shortcode %}}
-	shortcodePattern = regexp.MustCompile(`\{\{[<%].*?[%>]\}\}`)
-
-	// Code blocks: ```lang ... ```
-	codeBlockPattern = regexp.MustCompile("(?s)```[a-z]*\\n.*?```")
-
-	// Inline code: `code`
-	inlineCodePattern = regexp.MustCompile("`[^`]+`")
-
-	// URLs: [text](url) - protect the URL part
-	urlPattern = regexp.MustCompile(`\]\([^)]+\)`)
-
-	// HTML tags
-	htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
-
-	// Image references: ![alt](path)
-	imagePattern = regexp.MustCompile(`!\[[^\]]*\]\([^)]+\)`)
-
-	// Reference links: [text][ref]
-	refLinkPattern = regexp.MustCompile(`\[[^\]]+\]\[[^\]]*\]`)
-
-	// Link definitions: [ref]: url
-	linkDefPattern = regexp.MustCompile(`(?m)^\[[^\]]+\]:\s+.*$`)
-)
-
-// translateBody translates markdown body while preserving special content
+// translateBody translates markdown body while preserving special content.
+// Only paragraphs missing from the translation memory reach the provider.
 func (t *MarkdownTranslator) translateBody(ctx context.Context, body, sourceLang, targetLang string) (string, error) {
 	if body == "" {
 		return "", nil
 	}
 
-	// Store protected content
-	protected := make(map[string]string)
-	counter := 0
+	// Paragraphs are parsed one by one; reference links need the whole body's definitions
+	refs := translate.LinkReferences(body)
+	translated, stats, err := t.memory.TranslateBody(body, targetLang, t.provider.Name(), func(segments []string) ([]string, error) {
+		return t.translateSegments(ctx, segments, refs, sourceLang, targetLang)
+	})
+	t.stats.Add(stats)
+	return translated, err
+}
 
-	// Helper to create placeholder
-	placeholder := func(content string) string {
-		key := fmt.Sprintf("%s%d%s", placeholderPrefix, counter, placeholderSuffix)
-		protected[key] = content
-		counter++
-		return key
+// translateSegments translates the text of paragraphs, keeping their
+// markdown (see protectMarkdown). Texts go in calls of up to
+// maxBatchChars, joined by segmentMarker; if a reply loses a marker, that
+// call's texts are translated with TranslateBatch instead. A paragraph
+// whose placeholders don't all come back fails the file.
+func (t *MarkdownTranslator) translateSegments(ctx context.Context, segments []string, refs []parser.Reference, sourceLang, targetLang string) ([]string, error) {
+	protected := make([]*protectedText, len(segments))
+	var texts []string
+	for i, s := range segments {
+		protected[i] = protectMarkdown(s, refs)
+		texts = append(texts, protected[i].texts()...)
 	}
 
-	// Protect content in order (code blocks first as they may contain other patterns)
-	processed := body
-
-	// 1. Code blocks (must be first - can contain anything)
-	processed = codeBlockPattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// 2. Shortcodes
-	processed = shortcodePattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// 3. Images (before URLs to avoid partial matches)
-	processed = imagePattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// 4. Link definitions
-	processed = linkDefPattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// 5. Reference links
-	processed = refLinkPattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// 6. URLs in markdown links - protect just the URL part
-	processed = urlPattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// 7. Inline code
-	processed = inlineCodePattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// 8. HTML tags
-	processed = htmlTagPattern.ReplaceAllStringFunc(processed, placeholder)
-
-	// Translate the processed text
-	translated, err := t.provider.Translate(ctx, processed, sourceLang, targetLang)
-	if err != nil {
-		return "", err
+	var translated []string
+	for start := 0; start < len(texts); {
+		end, size := start, 0
+		for end < len(texts) && (end == start || size+len(texts[end]) <= maxBatchChars) {
+			size += len(texts[end])
+			end++
+		}
+		batch := texts[start:end]
+		start = end
+
+		var out []string
+		if len(batch) > 1 {
+			reply, err := t.provider.Translate(ctx, strings.Join(batch, "\n\n"+segmentMarker+"\n\n"), sourceLang, targetLang)
+			if err != nil {
+				return nil, err
+			}
+			if parts := strings.Split(reply, segmentMarker); len(parts) == len(batch) {
+				for _, p := range parts {
+					out = append(out, strings.Trim(p, "\r\n"))
+				}
+			}
+		}
+		if out == nil {
+			var err error
+			if out, err = t.provider.TranslateBatch(ctx, batch, sourceLang, targetLang); err != nil {
+				return nil, err
+			}
+		}
+		translated = append(translated, out...)
 	}
 
-	// Restore protected content
-	for key, original := range protected {
-		translated = strings.ReplaceAll(translated, key, original)
+	results := make([]string, len(segments))
+	for i, p := range protected {
+		n := len(p.texts())
+		var err error
+		if results[i], err = p.restore(translated[:n]); err != nil {
+			return nil, fmt.Errorf("paragraph %q: %w", excerpt(segments[i]), err)
+		}
+		translated = translated[n:]
 	}
-
-	return translated, nil
+	return results, nil
 }
 
 // TranslateResult holds the result of a file translation
@@ -0,0 +1,296 @@
+package autotranslate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
+)
+
+// Placeholders stand for the markdown between translatable text: markup,
+// code spans, link destinations, raw HTML, shortcodes. ⟦n⟧ survives
+// DeepL and Claude better than ASCII brackets, and can't occur in content.
+const (
+	placeholderOpen  = "⟦"
+	placeholderClose = "⟧"
+)
+
+var placeholderPattern = regexp.MustCompile(placeholderOpen + `\d+` + placeholderClose)
+
+// protectedText is a paragraph prepared for translation. Only the text
+// nodes of its goldmark AST are translated, as one text with
+// placeholders for the markdown between them; link and image titles are
+// translated separately. Restoring puts the source bytes back around the
+// translated text, so everything but text is kept byte for byte.
+type protectedText struct {
+	head   []piece   // Markdown before the first text node
+	text   string    // Text nodes, with placeholders for the markdown between them
+	glue   [][]piece // Placeholder n → markdown it stands for
+	tail   []piece   // Markdown after the last text node
+	titles []title   // Link and image titles
+}
+
+// piece is source markdown, or a reference to a translated title.
+type piece struct {
+	lit   string
+	title int // Index in titles, or -1
+}
+
+type title struct {
+	text  string
+	delim byte // Closing delimiter: " ' or )
+}
+
+// span is a byte range of the source.
+type span struct {
+	start, stop int
+	title       bool
+	delim       byte
+}
+
+// protectMarkdown parses a paragraph and prepares its text nodes for
+// translation. refs are the link definitions of the whole body.
+func protectMarkdown(source string, refs []parser.Reference) *protectedText {
+	src := []byte(source)
+	var spans []span
+	cursor := 0
+	ast.Walk(translate.ParseMarkdownAST(src, refs), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.CodeSpan, *ast.RawHTML, *ast.AutoLink:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering && n.Segment.Stop > n.Segment.Start {
+				spans = append(spans, span{start: n.Segment.Start, stop: n.Segment.Stop})
+				cursor = n.Segment.Stop
+			}
+		case *ast.Link:
+			if !entering {
+				spans = appendTitle(spans, src, n.Title, cursor)
+			}
+		case *ast.Image:
+			if !entering {
+				spans = appendTitle(spans, src, n.Title, cursor)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Shortcodes are text to goldmark: cut them out of the text nodes
+	spans = subtract(spans, translate.ShortcodePattern.FindAllStringIndex(source, -1))
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return buildProtected(source, spans)
+}
+
+// appendTitle adds the source range of a link title, searched for after
+// the link text. Titles with escapes aren't found and stay untranslated.
+func appendTitle(spans []span, src, t []byte, from int) []span {
+	if len(t) == 0 {
+		return spans
+	}
+	i := strings.Index(string(src[from:]), string(t))
+	if i < 0 {
+		return spans
+	}
+	start := from + i
+	delim := byte('"')
+	if start > 0 {
+		switch src[start-1] {
+		case '\'':
+			delim = '\''
+		case '(':
+			delim = ')'
+		}
+	}
+	return append(spans, span{start: start, stop: start + len(t), title: true, delim: delim})
+}
+
+// subtract removes the ranges in cut from spans, splitting spans as needed.
+func subtract(spans []span, cut [][]int) []span {
+	for _, c := range cut {
+		var out []span
+		for _, s := range spans {
+			if s.stop <= c[0] || s.start >= c[1] {
+				out = append(out, s)
+				continue
+			}
+			if s.start < c[0] {
+				out = append(out, span{start: s.start, stop: c[0], title: s.title, delim: s.delim})
+			}
+			if s.stop > c[1] {
+				out = append(out, span{start: c[1], stop: s.stop, title: s.title, delim: s.delim})
+			}
+		}
+		spans = out
+	}
+	return spans
+}
+
+// Block markers before a paragraph's first text (headings, list items,
+// quotes) stay outside the text sent to the provider.
+var blockPrefixPattern = regexp.MustCompile(`^(\s*(>|#{1,6}|[-*+]( \[[ xX]\])?|\d{1,9}[.)]|:)?[ \t\n])*`)
+
+func buildProtected(source string, spans []span) *protectedText {
+	p := &protectedText{}
+	var text strings.Builder
+	glue := func(pieces []piece) {
+		fmt.Fprintf(&text, "%s%d%s", placeholderOpen, len(p.glue), placeholderClose)
+		p.glue = append(p.glue, pieces)
+	}
+
+	var cur []piece
+	pos, flow := 0, 0
+	for _, s := range spans {
+		if s.start < pos {
+			continue // Overlaps a text node (title found in the wrong place)
+		}
+		if s.start > pos {
+			cur = append(cur, piece{lit: source[pos:s.start], title: -1})
+		}
+		pos = s.stop
+
+		if s.title {
+			cur = append(cur, piece{title: len(p.titles)})
+			p.titles = append(p.titles, title{text: source[s.start:s.stop], delim: s.delim})
+			continue
+		}
+		switch {
+		case flow == 0 && isLiteral(cur):
+			// Inline markup before the first text, like ** or [, moves with the text
+			head := render(cur, nil)
+			prefix := blockPrefixPattern.FindString(head)
+			p.head = []piece{{lit: prefix, title: -1}}
+			if head != prefix {
+				glue([]piece{{lit: head[len(prefix):], title: -1}})
+			}
+		case flow == 0:
+			p.head = cur
+		case isSpace(cur):
+			text.WriteString(render(cur, nil)) // Line breaks and spaces between text nodes stay in the text
+		default:
+			glue(cur)
+		}
+		cur = nil
+		flow++
+		text.WriteString(source[s.start:s.stop])
+	}
+	if pos < len(source) {
+		cur = append(cur, piece{lit: source[pos:], title: -1})
+	}
+	switch {
+	case flow == 0:
+		p.head = cur
+	case isLiteral(cur):
+		tail := render(cur, nil)
+		markup := strings.TrimRight(tail, " \t\n")
+		if markup != "" {
+			glue([]piece{{lit: markup, title: -1}})
+		}
+		p.tail = []piece{{lit: tail[len(markup):], title: -1}}
+	default:
+		p.tail = cur
+	}
+	p.text = text.String()
+	return p
+}
+
+func isLiteral(pieces []piece) bool {
+	for _, p := range pieces {
+		if p.title >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isSpace(pieces []piece) bool {
+	for _, p := range pieces {
+		if p.title >= 0 || strings.TrimSpace(p.lit) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasText reports whether s has anything to translate besides placeholders.
+func hasText(s string) bool {
+	return strings.IndexFunc(placeholderPattern.ReplaceAllString(s, ""), unicode.IsLetter) >= 0
+}
+
+// texts returns what to send to the provider: the text, if it has words,
+// then the titles.
+func (p *protectedText) texts() []string {
+	var texts []string
+	if hasText(p.text) {
+		texts = append(texts, p.text)
+	}
+	for _, t := range p.titles {
+		texts = append(texts, t.text)
+	}
+	return texts
+}
+
+// restore rebuilds the paragraph from the translations of texts(). Every
+// placeholder must come back exactly once.
+func (p *protectedText) restore(translated []string) (string, error) {
+	text := p.text
+	if hasText(p.text) {
+		text, translated = translated[0], translated[1:]
+	}
+
+	titles := make([]string, len(p.titles))
+	for i, t := range p.titles {
+		titles[i] = escapeTitle(translated[i], t.delim)
+	}
+
+	for i, g := range p.glue {
+		ph := fmt.Sprintf("%s%d%s", placeholderOpen, i, placeholderClose)
+		if n := strings.Count(text, ph); n != 1 {
+			return "", fmt.Errorf("placeholder %s for %q appears %d times in the translation", ph, render(g, nil), n)
+		}
+		text = strings.Replace(text, ph, render(g, titles), 1)
+	}
+	if stray := placeholderPattern.FindString(text); stray != "" {
+		return "", fmt.Errorf("translation has unknown placeholder %s", stray)
+	}
+	return render(p.head, titles) + text + render(p.tail, titles), nil
+}
+
+// render joins pieces, filling in the translated titles. With nil titles
+// the title pieces are left out.
+func render(pieces []piece, titles []string) string {
+	var b strings.Builder
+	for _, p := range pieces {
+		if p.title >= 0 {
+			if p.title < len(titles) {
+				b.WriteString(titles[p.title])
+			}
+			continue
+		}
+		b.WriteString(p.lit)
+	}
+	return b.String()
+}
+
+// escapeTitle escapes a title's closing delimiter, and for (titles) also
+// the opening one.
+func escapeTitle(s string, delim byte) string {
+	if delim == ')' {
+		s = strings.ReplaceAll(s, "(", `\(`)
+	}
+	return strings.ReplaceAll(s, string(delim), `\`+string(delim))
+}
+
+// excerpt returns the start of a paragraph, for error messages.
+func excerpt(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 40 {
+		return string(r[:40]) + "..."
+	}
+	return s
+}
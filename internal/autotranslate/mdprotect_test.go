@@ -0,0 +1,101 @@
+package autotranslate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
+)
+
+// upper "translates" by upper-casing everything but placeholders.
+func upper(texts []string) []string {
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = strings.ToUpper(t)
+	}
+	return out
+}
+
+func TestProtectMarkdownRoundTrip(t *testing.T) {
+	refs := translate.LinkReferences("[ref]: https://example.com/Docs \"Ref title\"\n")
+	tests := []struct {
+		name, src, want string
+	}{
+		{"heading", "## Getting *started* with `go run`", "## GETTING *STARTED* WITH `go run`"},
+		{"list", "- First **bold** item\n- Second item", "- FIRST **BOLD** ITEM\n- SECOND ITEM"},
+		{"ordered list", "1. Open [the app](https://example.com/App)\n2. Done", "1. OPEN [THE APP](https://example.com/App)\n2. DONE"},
+		{"table", "| Name | Value |\n|------|-------|\n| one | `two` |", "| NAME | VALUE |\n|------|-------|\n| ONE | `two` |"},
+		{"link title", `See [the docs](/docs/Intro "Read this") first`, `SEE [THE DOCS](/docs/Intro "READ THIS") FIRST`},
+		{"image title", `![A photo](/img/Photo.png 'Photo title')`, `![A PHOTO](/img/Photo.png 'PHOTO TITLE')`},
+		{"inline shortcode", `Press {{< kbd "Ctrl+C" >}} to copy`, `PRESS {{< kbd "Ctrl+C" >}} TO COPY`},
+		{"multi-line shortcode", "See {{< figure src=\"a.png\"\n  caption=\"Caption\" >}} below", "SEE {{< figure src=\"a.png\"\n  caption=\"Caption\" >}} BELOW"},
+		{"reference link", "Read the [docs][ref] and [ref] now", "READ THE [DOCS][ref] AND [REF] NOW"},
+		{"html", `Click <a href="/Go">here</a> now`, `CLICK <a href="/Go">HERE</a> NOW`},
+		{"quote", "> Quoted *text*\n> more", "> QUOTED *TEXT*\n> MORE"},
+		{"code only", "```go\nfmt.Println(\"Hi\")\n```", "```go\nfmt.Println(\"Hi\")\n```"},
+	}
+
+	for _, tt := range tests {
+		p := protectMarkdown(tt.src, refs)
+		got, err := p.restore(p.texts())
+		if err != nil || got != tt.src {
+			t.Errorf("%s: identity = %q, %v", tt.name, got, err)
+			continue
+		}
+		got, err = p.restore(upper(p.texts()))
+		if err != nil || got != tt.want {
+			t.Errorf("%s: translated = %q, %v\n want %q (texts %q)", tt.name, got, err, tt.want, p.texts())
+		}
+		if err := translate.CheckProtected(tt.src, got); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestProtectMarkdownTitleEscaping(t *testing.T) {
+	p := protectMarkdown(`A [link](/x "Title") and ![img](/y.png (Other))`, nil)
+	texts := p.texts()
+	if len(texts) != 3 || texts[1] != "Title" || texts[2] != "Other" {
+		t.Fatalf("texts = %q", texts)
+	}
+	got, err := p.restore([]string{texts[0], `Say "hi"`, "(a) b"})
+	want := `A [link](/x "Say \"hi\"") and ![img](/y.png (\(a\) b))`
+	if err != nil || got != want {
+		t.Errorf("restore = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestProtectMarkdownPlaceholderErrors(t *testing.T) {
+	p := protectMarkdown("Some **bold** and `code` text", nil)
+	text := p.texts()[0]
+	ph := placeholderOpen + "0" + placeholderClose
+	if !strings.Contains(text, ph) {
+		t.Fatalf("text = %q, want placeholders", text)
+	}
+
+	tests := map[string]string{
+		"lost":       strings.Replace(text, ph, "", 1),
+		"duplicated": strings.Replace(text, ph, ph+ph, 1),
+		"stray":      text + " " + placeholderOpen + "9" + placeholderClose,
+	}
+	for name, translated := range tests {
+		if got, err := p.restore([]string{translated}); err == nil {
+			t.Errorf("%s placeholder: restored %q, want error", name, got)
+		}
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	spans := []span{{start: 0, stop: 10}, {start: 12, stop: 20}}
+	got := subtract(spans, [][]int{{3, 5}, {8, 14}})
+	want := []span{{start: 0, stop: 3}, {start: 5, stop: 8}, {start: 14, stop: 20}}
+	if len(got) != len(want) {
+		t.Fatalf("subtract = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("subtract = %+v, want %+v", got, want)
+			break
+		}
+	}
+}
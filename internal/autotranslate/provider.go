@@ -5,6 +5,8 @@ package autotranslate
 import (
 	"context"
 	"fmt"
+
+	"github.com/joeblew999/ubuntu-website/internal/translate"
 )
 
 // Provider defines the interface for translation services.
@@ -28,6 +30,27 @@ type Provider interface {
 	SupportsLanguage(langCode string) bool
 }
 
+// GlossaryUser is implemented by providers that enforce a glossary:
+// Claude in its prompts, DeepL as a native glossary.
+type GlossaryUser interface {
+	UseGlossary(g *translate.Glossary)
+}
+
+// LedgerUser is implemented by providers that record the characters and
+// tokens of each call in the run's ledger.
+type LedgerUser interface {
+	UseLedger(l *Ledger)
+}
+
+// glossarySection returns the prompt section for the glossary terms in
+// text, followed by a blank line, or "" if there are none
+func glossarySection(g *translate.Glossary, text, lang string) string {
+	if p := g.Prompt(text, lang); p != "" {
+		return p + "\n"
+	}
+	return ""
+}
+
 // ProviderConfig holds common configuration for providers
 type ProviderConfig struct {
 	APIKey string
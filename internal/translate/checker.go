@@ -164,6 +164,45 @@ func (c *Checker) CheckStale() StaleResult {
 	return result
 }
 
+// CheckContent checks every translated page against its English source:
+// the glossary terms in the source must be rendered as the glossary says.
+func (c *Checker) CheckContent() ContentValidateResult {
+	var result ContentValidateResult
+	glossary, err := LoadGlossary(c.config.GlossaryFile)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	englishFiles := c.getEnglishFiles()
+	sort.Strings(englishFiles)
+	for _, enFile := range englishFiles {
+		source, err := os.ReadFile(enFile)
+		if err != nil {
+			continue
+		}
+		for _, lang := range c.config.TargetLangs {
+			langFile := c.config.GetTargetPath(enFile, lang.Code)
+			translated, err := os.ReadFile(langFile)
+			if err != nil {
+				continue // File doesn't exist
+			}
+			result.Checked++
+			for _, v := range glossary.Check(string(source), string(translated), lang.Code) {
+				result.Issues = append(result.Issues, ContentIssue{
+					TargetPath: langFile,
+					SourcePath: enFile,
+					LangCode:   lang.Code,
+					Check:      "glossary",
+					Message:    v.String(),
+				})
+			}
+		}
+	}
+
+	return result
+}
+
 // CheckOrphans computes files in target languages with no English source.
 func (c *Checker) CheckOrphans() OrphansResult {
 	result := OrphansResult{
@@ -15,6 +15,12 @@ const (
 	claudeAPIURL = "https://api.anthropic.com/v1/messages"
 	claudeModel  = "claude-sonnet-4-20250514"
 	maxTokens    = 4096
+
+	// segmentMarker separates the paragraphs of a TranslateSegments call
+	segmentMarker = "<!-- segment -->"
+	// maxSegmentChars bounds the English text of one call, so the
+	// translation fits in maxTokens
+	maxSegmentChars = 6000
 )
 
 // ClaudeClient handles communication with Claude API or CLI
@@ -22,6 +28,7 @@ type ClaudeClient struct {
 	apiKey     string
 	httpClient *http.Client
 	useCLI     bool // true if using claude CLI instead of API
+	glossary   *Glossary
 }
 
 // ClaudeRequest represents a request to Claude API
@@ -73,6 +80,19 @@ func NewClaudeClient(apiKey string) (*ClaudeClient, error) {
 	}, nil
 }
 
+// UseGlossary adds the glossary's terms to translation prompts
+func (c *ClaudeClient) UseGlossary(g *Glossary) {
+	c.glossary = g
+}
+
+// Name returns the provider name recorded in the translation memory
+func (c *ClaudeClient) Name() string {
+	if c.useCLI {
+		return "claude-cli"
+	}
+	return "claude"
+}
+
 // Translate translates text to the target language
 func (c *ClaudeClient) Translate(text, targetLang, targetLangName string) (string, error) {
 	prompt := fmt.Sprintf("Translate the following Hugo markdown content from English to %s (%s).\n\n"+
@@ -86,10 +106,12 @@ func (c *ClaudeClient) Translate(text, targetLang, targetLangName string) (strin
 		"   - YAML/TOML front matter field names\n"+
 		"3. Preserve all markdown formatting (headers, lists, bold, italic, etc.)\n"+
 		"4. Maintain the same structure and paragraph breaks\n"+
-		"5. Keep the tone and style appropriate for the content type\n\n"+
+		"5. Keep the tone and style appropriate for the content type\n"+
+		"6. Keep every "+segmentMarker+" line exactly as it is\n\n"+
+		"%s"+
 		"Content to translate:\n\n%s\n\n"+
 		"Please provide ONLY the translated text, with no explanations or additional commentary.",
-		targetLangName, targetLang, text)
+		targetLangName, targetLang, glossaryPrompt(c.glossary, text, targetLang), text)
 
 	if c.useCLI {
 		return c.callCLI(prompt)
@@ -97,6 +119,53 @@ func (c *ClaudeClient) Translate(text, targetLang, targetLangName string) (strin
 	return c.callAPI(prompt)
 }
 
+// glossaryPrompt returns the prompt section for the glossary terms in
+// text, followed by a blank line, or "" if there are none
+func glossaryPrompt(g *Glossary, text, lang string) string {
+	if p := g.Prompt(text, lang); p != "" {
+		return p + "\n"
+	}
+	return ""
+}
+
+// TranslateSegments translates paragraphs to the target language. The
+// paragraphs are sent in as few calls as fit maxSegmentChars, separated by
+// segmentMarker; if a reply loses a marker, that call's paragraphs are
+// translated one by one.
+func (c *ClaudeClient) TranslateSegments(segments []string, targetLang, targetLangName string) ([]string, error) {
+	var results []string
+	for start := 0; start < len(segments); {
+		end, size := start, 0
+		for end < len(segments) && (end == start || size+len(segments[end]) <= maxSegmentChars) {
+			size += len(segments[end])
+			end++
+		}
+		batch := segments[start:end]
+		start = end
+
+		if len(batch) > 1 {
+			translated, err := c.Translate(strings.Join(batch, "\n\n"+segmentMarker+"\n\n"), targetLang, targetLangName)
+			if err != nil {
+				return nil, err
+			}
+			if parts := strings.Split(translated, segmentMarker); len(parts) == len(batch) {
+				for _, p := range parts {
+					results = append(results, strings.Trim(p, "\r\n"))
+				}
+				continue
+			}
+		}
+		for _, text := range batch {
+			translated, err := c.Translate(text, targetLang, targetLangName)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, strings.Trim(translated, "\r\n"))
+		}
+	}
+	return results, nil
+}
+
 // TranslateI18n translates i18n data
 func (c *ClaudeClient) TranslateI18n(data map[string]string, targetLang, targetLangName string) (map[string]string, error) {
 	// Convert map to JSON for easier handling
@@ -112,9 +181,10 @@ func (c *ClaudeClient) TranslateI18n(data map[string]string, targetLang, targetL
 		"3. Preserve the JSON structure exactly\n"+
 		"4. Keep placeholders like {{.Name}} unchanged\n"+
 		"5. Maintain appropriate context for UI strings\n\n"+
+		"%s"+
 		"i18n data to translate:\n\n%s\n\n"+
 		"Please provide ONLY the translated JSON, with no explanations or additional commentary.",
-		targetLangName, targetLang, string(jsonData))
+		targetLangName, targetLang, glossaryPrompt(c.glossary, string(jsonData), targetLang), string(jsonData))
 
 	var translated string
 	if c.useCLI {
@@ -19,6 +19,7 @@ type CLIOptions struct {
 	GithubIssue bool
 	Force       bool
 	Version     bool
+	FrontMatter string
 }
 
 // Run is the main entry point for the translate CLI.
@@ -32,6 +33,7 @@ func Run(args []string, version string, stdout, stderr io.Writer, stdin io.Reade
 	fs.BoolVar(&opts.GithubIssue, "github-issue", false, "Output markdown for GitHub Issue")
 	fs.BoolVar(&opts.Force, "force", false, "Skip confirmation prompts (for CI)")
 	fs.BoolVar(&opts.Version, "version", false, "Print version and exit")
+	fs.StringVar(&opts.FrontMatter, "front-matter", strings.Join(DefaultFrontMatterKeys, ","), "Front matter key paths to translate, comma-separated")
 
 	if err := fs.Parse(args[1:]); err != nil {
 		return 1
@@ -53,6 +55,7 @@ func Run(args []string, version string, stdout, stderr io.Writer, stdin io.Reade
 		fmt.Fprintf(stderr, "Error: %v\n", err)
 		return 1
 	}
+	checker.GetConfig().FrontMatter = ParseFrontMatterKeys(opts.FrontMatter)
 
 	namespace := fs.Arg(0)
 	subCmd := fs.Arg(1)
@@ -75,9 +78,15 @@ func Run(args []string, version string, stdout, stderr io.Writer, stdin io.Reade
 		return ctx.runMenuCommand(subCmd)
 	case "lang":
 		return ctx.runLangCommand(subCmd)
+	case "tm":
+		return ctx.runMemoryCommand(subCmd)
+	case "export":
+		return ctx.runExport(fs.Args()[1:])
+	case "import":
+		return ctx.runImport(fs.Args()[1:])
 	default:
 		fmt.Fprintf(stderr, "Unknown namespace: %s\n", namespace)
-		fmt.Fprintf(stderr, "Available: content, menu, lang\n")
+		fmt.Fprintf(stderr, "Available: content, menu, lang, tm, export, import\n")
 		printUsage(stderr)
 		return 1
 	}
@@ -122,6 +131,8 @@ func (ctx *cliContext) runContentCommand(subCmd string) int {
 		return ctx.runOrphans()
 	case "stale":
 		return ctx.runStale()
+	case "validate":
+		return ctx.runContentValidate()
 	case "clean":
 		return ctx.runClean()
 	case "":
@@ -183,6 +194,35 @@ func (ctx *cliContext) runLangCommand(subCmd string) int {
 	}
 }
 
+// ============================================================================
+// TM Commands - Translation memory shared with autotranslate
+// ============================================================================
+
+func (ctx *cliContext) runMemoryCommand(subCmd string) int {
+	switch subCmd {
+	case "status":
+		return ctx.runMemoryStatus()
+	case "approve":
+		lang := ctx.fs.Arg(2)
+		if lang == "" {
+			fmt.Fprintln(ctx.stderr, "Error: tm approve requires a language code")
+			fmt.Fprintln(ctx.stderr, "Usage: translate tm approve <code> [file...]")
+			return 1
+		}
+		return ctx.runMemoryApprove(lang, ctx.fs.Args()[3:])
+	case "prune":
+		return ctx.runMemoryPrune()
+	case "":
+		fmt.Fprintln(ctx.stderr, "Error: tm requires a subcommand")
+		printMemoryUsage(ctx.stderr)
+		return 1
+	default:
+		fmt.Fprintf(ctx.stderr, "Unknown tm command: %s\n", subCmd)
+		printMemoryUsage(ctx.stderr)
+		return 1
+	}
+}
+
 // ============================================================================
 // Run Functions - Wire checker functions to presenters
 // ============================================================================
@@ -251,6 +291,20 @@ func (ctx *cliContext) runStale() int {
 	return 0
 }
 
+func (ctx *cliContext) runContentValidate() int {
+	result := ctx.checker.CheckContent()
+
+	if ctx.opts.GithubIssue {
+		NewMarkdownPresenterTo(ctx.stdout).ContentValidate(result)
+	} else {
+		NewTerminalPresenterTo(ctx.stdout).ContentValidate(result)
+	}
+	if result.HasIssues() {
+		return 1
+	}
+	return 0
+}
+
 func (ctx *cliContext) runOrphans() int {
 	result := ctx.checker.CheckOrphans()
 
@@ -620,6 +674,93 @@ func (ctx *cliContext) runLangInit() int {
 	return 0
 }
 
+func (ctx *cliContext) runMemoryStatus() int {
+	result := ctx.checker.CheckMemory()
+
+	if ctx.opts.GithubIssue {
+		NewMarkdownPresenterTo(ctx.stdout).MemoryStatus(result)
+	} else {
+		NewTerminalPresenterTo(ctx.stdout).MemoryStatus(result)
+	}
+	if result.Error != nil {
+		return 1
+	}
+	return 0
+}
+
+func (ctx *cliContext) runMemoryApprove(lang string, files []string) int {
+	result := ctx.checker.DoMemoryApprove(lang, files)
+
+	if ctx.opts.GithubIssue {
+		NewMarkdownPresenterTo(ctx.stdout).MemoryApprove(result)
+	} else {
+		NewTerminalPresenterTo(ctx.stdout).MemoryApprove(result)
+	}
+	if result.Error != nil {
+		return 1
+	}
+	return 0
+}
+
+func (ctx *cliContext) runMemoryPrune() int {
+	result := ctx.checker.DoMemoryPrune()
+
+	NewTerminalPresenterTo(ctx.stdout).MemoryPrune(result)
+	if result.Error != nil {
+		return 1
+	}
+	return 0
+}
+
+func (ctx *cliContext) runExport(args []string) int {
+	fs := flag.NewFlagSet("translate export", flag.ContinueOnError)
+	fs.SetOutput(ctx.stderr)
+	fs.Usage = func() { printExchangeUsage(ctx.stderr) }
+	opts := ExportOptions{}
+	fs.StringVar(&opts.LangCode, "lang", "", "Target language code")
+	fs.StringVar(&opts.Format, "format", FormatXLIFF, "Exchange format: xliff or po")
+	fs.StringVar(&opts.Output, "o", "", "Output file (default "+DefaultExchangeDir+"/<code>.xlf or .po)")
+	fs.BoolVar(&opts.All, "all", false, "Export every segment, not only missing or changed ones")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if opts.LangCode == "" {
+		fmt.Fprintln(ctx.stderr, "Error: export requires -lang")
+		printExchangeUsage(ctx.stderr)
+		return 1
+	}
+
+	result := ctx.checker.DoExport(opts)
+	if ctx.opts.GithubIssue {
+		NewMarkdownPresenterTo(ctx.stdout).Export(result)
+	} else {
+		NewTerminalPresenterTo(ctx.stdout).Export(result)
+	}
+	if result.Error != nil {
+		return 1
+	}
+	return 0
+}
+
+func (ctx *cliContext) runImport(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(ctx.stderr, "Error: import requires an exchange file")
+		printExchangeUsage(ctx.stderr)
+		return 1
+	}
+
+	result := ctx.checker.DoImport(args[0])
+	if ctx.opts.GithubIssue {
+		NewMarkdownPresenterTo(ctx.stdout).Import(result)
+	} else {
+		NewTerminalPresenterTo(ctx.stdout).Import(result)
+	}
+	if result.Error != nil {
+		return 1
+	}
+	return 0
+}
+
 // ============================================================================
 // Usage
 // ============================================================================
@@ -634,11 +775,17 @@ Namespaces:
   content   Track English source changes and find translation problems
   menu      Manage navigation menus per language
   lang      Add, remove, and configure languages
+  tm        Translation memory shared with autotranslate
+  export    Write segments to translate as XLIFF 2.0 or PO for a CAT tool
+  import    Write reviewed translations from XLIFF or PO back to content
 
 Flags:
   -github-issue  Output markdown for GitHub Issue (exit 1 if action needed)
   -force         Skip confirmation prompts (for CI)
   -version       Print version and exit
+  -front-matter  Front matter key paths to translate, comma-separated
+                 (default: title, descriptions and the theme's banner,
+                 features and testimonials; e.g. cta.title,features.title)
 
 Run 'translate <namespace>' for namespace-specific help.
 
@@ -648,6 +795,8 @@ Examples:
   translate menu check                  # Validate menu files
   translate lang list                   # Show configured languages
   translate lang add fr Francais french # Add French language
+  translate tm status                   # Show translation memory coverage
+  translate export -lang de -format po  # Segments for a German reviewer
 
 `)
 }
@@ -664,12 +813,14 @@ Commands:
   missing           Show files missing in target languages
   orphans           Show target files with no English source
   stale             Show potentially outdated translations (target < 50%% of source)
+  validate          Check translations against the glossary (exit 1 on violations)
   clean             Delete orphaned files (prompts unless -force)
 
 Examples:
   translate content status
   translate content diff blog/my-post.md
   translate content missing -github-issue
+  translate content validate
   translate content clean -force
 
 `)
@@ -710,3 +861,52 @@ Examples:
 
 `)
 }
+
+func printMemoryUsage(w io.Writer) {
+	fmt.Fprintf(w, `translate tm - Translation memory shared with autotranslate
+
+The memory (%s) holds translated paragraphs by the hash of
+their English text. autotranslate only sends paragraphs missing from it to
+the provider, and never overwrites approved ones.
+
+Commands:
+  status                    Show segments per language, approved and by provider
+  approve <code> [file...]  Approve existing translations (all files if none given)
+  prune                     Remove unapproved segments no longer in any English file
+
+Examples:
+  translate tm status
+  translate tm approve de
+  translate tm approve de blog/my-post.md
+  translate tm prune
+
+`, DefaultMemoryFile)
+}
+
+func printExchangeUsage(w io.Writer) {
+	fmt.Fprintf(w, `translate export / import - Exchange files for human translators
+
+Export writes the front matter fields, paragraphs and i18n strings that
+need translating: missing ones, and ones whose English changed since the
+last translation. Existing and machine translations are prefilled and
+marked for review (fuzzy in PO, state="initial" in XLIFF). Import writes
+the reviewed segments into content/<lang> and i18n/<code>.yaml, and
+approves them in the translation memory.
+
+Usage:
+  translate export -lang <code> [-format xliff|po] [-o file] [-all]
+  translate import <file.xlf|file.po>
+
+Export flags:
+  -lang <code>      Target language
+  -format <name>    xliff (default) or po
+  -o <file>         Output file (default %s/<code>.xlf or .po)
+  -all              Every segment, including approved and unchanged ones
+
+Examples:
+  translate export -lang de
+  translate export -lang de -format po -o de-review.po
+  translate import %s/de.xlf
+
+`, DefaultExchangeDir, DefaultExchangeDir)
+}
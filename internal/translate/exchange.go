@@ -0,0 +1,101 @@
+// Package translator provides translation workflow management.
+//
+// This file contains the exchange files handed to human translators: the
+// segments of content and i18n strings that need translating, written as
+// XLIFF 2.0 or gettext PO for a CAT tool, and read back after review.
+package translate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Exchange formats, by -format name.
+const (
+	FormatXLIFF = "xliff"
+	FormatPO    = "po"
+)
+
+// ExchangeFormats maps each format to its file extension.
+var ExchangeFormats = map[string]string{
+	FormatXLIFF: ".xlf",
+	FormatPO:    ".po",
+}
+
+// Exchange is a set of segments to translate from SourceLang to TargetLang.
+type Exchange struct {
+	SourceLang string
+	TargetLang string
+	Units      []ExchangeUnit
+}
+
+// ExchangeUnit is one segment: a front matter field, a paragraph or an
+// i18n string.
+type ExchangeUnit struct {
+	File     string   // English source, e.g. content/english/blog/post.md or i18n/en.yaml
+	ID       string   // Unique in File: fm-<field>, p-<segment key> or i18n-<key>
+	Source   string   // English text
+	Target   string   // Translation, prefilled for review or reviewed
+	Notes    []string // Context for the translator
+	Reviewed bool     // Target is a translator's (not fuzzy in PO, state past initial in XLIFF)
+}
+
+// Unit ID prefixes
+const (
+	unitFrontMatter = "fm-"
+	unitParagraph   = "p-"
+	unitI18n        = "i18n-"
+)
+
+// Files returns the source files of the units, in order of first use.
+func (x *Exchange) Files() []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, u := range x.Units {
+		if !seen[u.File] {
+			seen[u.File] = true
+			files = append(files, u.File)
+		}
+	}
+	return files
+}
+
+// Write writes the exchange in format.
+func (x *Exchange) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatXLIFF:
+		return writeXLIFF(w, x)
+	case FormatPO:
+		return writePO(w, x)
+	}
+	return fmt.Errorf("unknown format %q (valid: %s, %s)", format, FormatXLIFF, FormatPO)
+}
+
+// ReadExchange reads an XLIFF (.xlf, .xliff) or PO (.po) file.
+func ReadExchange(path string) (*Exchange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var x *Exchange
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlf", ".xliff":
+		x, err = readXLIFF(f)
+	case ".po":
+		x, err = readPO(f)
+	default:
+		return nil, fmt.Errorf("%s: unknown exchange format (want .xlf, .xliff or .po)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if x.TargetLang == "" {
+		return nil, fmt.Errorf("%s has no target language", path)
+	}
+	return x, nil
+}
@@ -0,0 +1,546 @@
+// Package translator provides translation workflow management.
+//
+// This file contains the exchange commands (translate export, translate
+// import): segments that need a human translator go out as XLIFF or PO,
+// and reviewed translations come back into content/<lang> and i18n.
+package translate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultExchangeDir is where exports are written, relative to the site root.
+const DefaultExchangeDir = "translations/exchange"
+
+// ExportOptions selects the segments DoExport writes.
+type ExportOptions struct {
+	LangCode string
+	Format   string // FormatXLIFF or FormatPO
+	Output   string // Exchange file ("" = DefaultExchangeDir/<code>.<ext>)
+	All      bool   // Every segment, not only missing or changed ones
+}
+
+// DoExport writes the segments of English content and i18n strings that
+// need translating into opts.LangCode. A segment is left out when the
+// memory has an approved translation, or when the target already has a
+// translation and the English is unchanged since the checkpoint. Existing
+// and machine translations are prefilled for review.
+func (c *Checker) DoExport(opts ExportOptions) ExportResult {
+	result := ExportResult{LangCode: opts.LangCode, Format: opts.Format, Path: opts.Output}
+	ext, ok := ExchangeFormats[opts.Format]
+	if !ok {
+		result.Error = fmt.Errorf("unknown format %q (valid: %s, %s)", opts.Format, FormatXLIFF, FormatPO)
+		return result
+	}
+	if !c.hasLang(opts.LangCode) {
+		result.Error = fmt.Errorf("language '%s' not configured", opts.LangCode)
+		return result
+	}
+	if result.Path == "" {
+		result.Path = filepath.Join(DefaultExchangeDir, opts.LangCode+ext)
+	}
+
+	m, err := LoadMemory(c.config.MemoryFile)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	glossary, err := LoadGlossary(c.config.GlossaryFile)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	x := &Exchange{SourceLang: c.config.SourceLang, TargetLang: opts.LangCode}
+	add := func(u ExchangeUnit, translated, changed bool) {
+		e, inMemory := m.Lookup(u.Source, opts.LangCode)
+		if !opts.All && (inMemory && e.Approved || translated && !changed) {
+			return
+		}
+		if inMemory {
+			u.Target = e.Text
+		}
+		for _, e := range glossary.Matching(u.Source, opts.LangCode) {
+			if e.Keep {
+				u.Notes = append(u.Notes, fmt.Sprintf("glossary: keep %q in English", e.Source))
+			} else {
+				u.Notes = append(u.Notes, fmt.Sprintf("glossary: %q → %q", e.Source, e.Target))
+			}
+		}
+		x.Units = append(x.Units, u)
+		if u.Target != "" {
+			result.Prefilled++
+		}
+	}
+
+	englishFiles := c.getEnglishFiles()
+	sort.Strings(englishFiles)
+	for _, file := range englishFiles {
+		if err := c.exportContent(file, opts.LangCode, add); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	if err := c.exportI18n(opts.LangCode, add); err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Files = len(x.Files())
+	result.Units = len(x.Units)
+	if err := os.MkdirAll(filepath.Dir(result.Path), 0755); err != nil {
+		result.Error = err
+		return result
+	}
+	var buf bytes.Buffer
+	if err := x.Write(&buf, opts.Format); err != nil {
+		result.Error = err
+		return result
+	}
+	result.Error = os.WriteFile(result.Path, buf.Bytes(), 0644)
+	return result
+}
+
+// exportUnit receives a segment with the target's current translation, if
+// any, and whether its English changed since the checkpoint.
+type exportUnit func(u ExchangeUnit, translated, changed bool)
+
+// exportContent passes the front matter fields and paragraphs of an
+// English page to add.
+func (c *Checker) exportContent(file, langCode string, add exportUnit) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	src, err := ParseMarkdown(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	srcStrings, err := src.Strings(c.config.FrontMatter)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	tgt := &MarkdownDoc{FrontMatter: map[string]interface{}{}}
+	if data, err := os.ReadFile(c.config.GetTargetPath(file, langCode)); err == nil {
+		if tgt, err = ParseMarkdown(data); err != nil {
+			return fmt.Errorf("%s: %w", c.config.GetTargetPath(file, langCode), err)
+		}
+	}
+
+	tgtStrings, err := tgt.Strings(c.config.FrontMatter)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.config.GetTargetPath(file, langCode), err)
+	}
+
+	// English at the checkpoint: values and paragraphs found there are unchanged
+	old := make(map[string]bool)
+	if data := c.atCheckpoint(file); data != nil {
+		if doc, err := ParseMarkdown(data); err == nil {
+			strs, _ := doc.Strings(c.config.FrontMatter)
+			for _, s := range strs {
+				old[unitFrontMatter+s.Path+"\x00"+s.Value] = true
+			}
+			for _, p := range translatable(SplitSegments(doc.Body)) {
+				old[unitParagraph+SegmentKey(p)] = true
+			}
+		}
+	}
+	noCheckpoint := !c.checkpointExists()
+
+	for _, s := range srcStrings {
+		if strings.TrimSpace(s.Value) == "" {
+			continue
+		}
+		target, _ := stringAt(tgtStrings, s.Path)
+		u := ExchangeUnit{File: file, ID: unitFrontMatter + s.Path, Source: s.Value, Target: target, Notes: []string{"front matter: " + s.Path}}
+		add(u, target != "", !noCheckpoint && !old[u.ID+"\x00"+s.Value])
+	}
+
+	// Existing paragraphs line up with the English ones, or can't be used
+	paragraphs := translatable(SplitSegments(src.Body))
+	existing := translatable(SplitSegments(tgt.Body))
+	if len(existing) != len(paragraphs) {
+		existing = nil
+	}
+	seen := make(map[string]bool)
+	for i, p := range paragraphs {
+		id := unitParagraph + SegmentKey(p)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		u := ExchangeUnit{File: file, ID: id, Source: p, Notes: []string{"paragraph (markdown: keep links, code and {{< shortcodes >}})"}}
+		if existing != nil {
+			u.Target = existing[i]
+		}
+		add(u, u.Target != "", !noCheckpoint && !old[id])
+	}
+	return nil
+}
+
+// exportI18n passes the English i18n strings to add.
+func (c *Checker) exportI18n(langCode string, add exportUnit) error {
+	file := c.i18nPath(c.config.SourceLang)
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	keys, values, err := readI18nOrdered(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	targets := map[string]string{}
+	if data, err := os.ReadFile(c.i18nPath(langCode)); err == nil {
+		if targets, err = ParseI18n(data); err != nil {
+			return fmt.Errorf("%s: %w", c.i18nPath(langCode), err)
+		}
+	}
+	old := map[string]string{}
+	if data := c.atCheckpoint(file); data != nil {
+		old, _ = ParseI18n(data)
+	}
+	noCheckpoint := !c.checkpointExists()
+
+	for _, key := range keys {
+		if strings.TrimSpace(values[key]) == "" {
+			continue
+		}
+		u := ExchangeUnit{File: file, ID: unitI18n + key, Source: values[key], Target: targets[key], Notes: []string{"i18n key: " + key}}
+		add(u, u.Target != "", !noCheckpoint && old[key] != values[key])
+	}
+	return nil
+}
+
+// DoImport writes the reviewed translations of an exchange file into the
+// target pages and i18n file, and approves them in the memory. Segments
+// still empty, fuzzy or in state initial are left for later.
+func (c *Checker) DoImport(path string) ImportResult {
+	result := ImportResult{
+		Path:    path,
+		Written: make(map[string]int),
+		Skipped: make(map[string]string),
+	}
+	x, err := ReadExchange(path)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.LangCode = x.TargetLang
+	if !c.hasLang(x.TargetLang) {
+		result.Error = fmt.Errorf("language '%s' not configured", x.TargetLang)
+		return result
+	}
+	m, err := LoadMemory(c.config.MemoryFile)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	byFile := make(map[string]map[string]ExchangeUnit)
+	for _, u := range x.Units {
+		if !u.Reviewed || strings.TrimSpace(u.Target) == "" {
+			result.Pending++
+			continue
+		}
+		m.Approve(u.Source, x.TargetLang, u.Target)
+		if byFile[u.File] == nil {
+			byFile[u.File] = make(map[string]ExchangeUnit)
+		}
+		byFile[u.File][u.ID] = u
+	}
+
+	for _, file := range sortedKeys(byFile) {
+		var target string
+		var n int
+		var err error
+		switch {
+		case filepath.Clean(file) == c.i18nPath(c.config.SourceLang):
+			target = c.i18nPath(x.TargetLang)
+			n, err = c.importI18n(file, target, byFile[file])
+		case strings.HasPrefix(filepath.Clean(file), c.sourcePath()+string(os.PathSeparator)) && strings.HasSuffix(file, ".md"):
+			target = c.config.GetTargetPath(filepath.Clean(file), x.TargetLang)
+			n, err = c.importContent(file, target, x.TargetLang, byFile[file], m)
+		default:
+			result.Skipped[file] = "not an English page or i18n file"
+			continue
+		}
+		if n > 0 {
+			result.Written[target] = n
+			result.Total += n
+		}
+		if err != nil {
+			result.Skipped[target] = err.Error()
+		}
+	}
+
+	if len(byFile) > 0 {
+		result.Error = m.Save()
+	}
+	return result
+}
+
+// importContent writes reviewed units into a target page, creating it from
+// the English page and the memory if needed. It returns how many units
+// were written; an error explains units that were not.
+func (c *Checker) importContent(file, target, langCode string, units map[string]ExchangeUnit, m *Memory) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	src, err := ParseMarkdown(data)
+	if err != nil {
+		return 0, err
+	}
+
+	doc := src
+	exists := false
+	if data, err := os.ReadFile(target); err == nil {
+		if doc, err = ParseMarkdown(data); err != nil {
+			return 0, err
+		}
+		exists = true
+	}
+
+	srcStrings, err := src.Strings(c.config.FrontMatter)
+	if err != nil {
+		return 0, err
+	}
+	tgtStrings, err := doc.Strings(c.config.FrontMatter)
+	if err != nil {
+		return 0, err
+	}
+	n, outdated, missing := 0, 0, 0
+	values := make(map[string]string)
+	for id, u := range units {
+		path, ok := strings.CutPrefix(id, unitFrontMatter)
+		if !ok {
+			continue
+		}
+		if s, _ := stringAt(srcStrings, path); s != u.Source {
+			outdated++
+			continue
+		}
+		if _, ok := stringAt(tgtStrings, path); !ok {
+			missing++
+			continue
+		}
+		values[path] = u.Target
+	}
+	if len(values) > 0 {
+		header := *doc.Header
+		if err := header.Set(c.config.FrontMatter, values); err != nil {
+			return 0, err
+		}
+		doc.Header = &header
+		n += len(values)
+	}
+
+	// Paragraphs replace their counterparts in an existing page; a new page
+	// starts from the English, filled in from the memory
+	srcSegs := SplitSegments(src.Body)
+	segs := append([]Segment(nil), srcSegs...)
+	if exists {
+		segs = SplitSegments(doc.Body)
+	}
+	aligned := len(translatable(segs)) == len(translatable(srcSegs))
+	pending, untranslated := 0, 0
+	for id := range units {
+		if strings.HasPrefix(id, unitParagraph) {
+			pending++
+		}
+	}
+	if aligned {
+		j := 0
+		for i := range segs {
+			if !segs[i].Translate {
+				continue
+			}
+			for !srcSegs[j].Translate {
+				j++
+			}
+			english := srcSegs[j].Text
+			j++
+			if u, ok := units[unitParagraph+SegmentKey(english)]; ok {
+				segs[i].Text = u.Target
+				n++
+				pending--
+			} else if !exists {
+				if e, ok := m.Lookup(english, langCode); ok {
+					segs[i].Text = e.Text
+				} else {
+					untranslated++
+				}
+			}
+		}
+	}
+
+	if n > 0 {
+		output := JoinSegments(segs)
+		if doc.Header != nil {
+			if output != "" {
+				output = "\n" + output
+			}
+			output = doc.Header.Raw + output
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(target, []byte(output), 0644); err != nil {
+			return 0, err
+		}
+	}
+
+	var problems []string
+	if !aligned && pending > 0 {
+		problems = append(problems, fmt.Sprintf("%d paragraphs not written: the page's paragraphs don't line up with the English (they are approved in the memory for autotranslate)", pending))
+	} else if outdated += pending; outdated > 0 {
+		problems = append(problems, fmt.Sprintf("%d segments not written: the English changed since the export", outdated))
+	}
+	if missing > 0 {
+		problems = append(problems, fmt.Sprintf("%d front matter values not written: the page has no such key", missing))
+	}
+	if untranslated > 0 {
+		problems = append(problems, fmt.Sprintf("%d paragraphs left in English", untranslated))
+	}
+	if len(problems) > 0 {
+		return n, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return n, nil
+}
+
+// importI18n writes reviewed units into a target i18n file, keeping its
+// key order and comments.
+func (c *Checker) importI18n(file, target string, units map[string]ExchangeUnit) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	source, err := ParseI18n(data)
+	if err != nil {
+		return 0, err
+	}
+	var doc yaml.Node
+	if data, err := os.ReadFile(target); err == nil {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return 0, fmt.Errorf("parsing %s: %w", target, err)
+		}
+	}
+
+	n, outdated := 0, 0
+	for _, id := range sortedKeys(units) {
+		u := units[id]
+		key := strings.TrimPrefix(id, unitI18n)
+		if source[key] != u.Source {
+			outdated++
+			continue
+		}
+		setYAMLField(&doc, key, u.Target)
+		n++
+	}
+	if n > 0 {
+		output, err := encodeYAML(&doc)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(target, []byte(output), 0644); err != nil {
+			return 0, err
+		}
+	}
+	if outdated > 0 {
+		return n, fmt.Errorf("%d strings not written: the English changed since the export", outdated)
+	}
+	return n, nil
+}
+
+func (c *Checker) hasLang(code string) bool {
+	for _, lang := range c.config.TargetLangs {
+		if lang.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) i18nPath(code string) string {
+	return filepath.Join(c.config.I18nDir, code+".yaml")
+}
+
+// atCheckpoint returns a file's contents at the checkpoint, or nil.
+func (c *Checker) atCheckpoint(file string) []byte {
+	if !c.checkpointExists() {
+		return nil
+	}
+	out, err := exec.Command("git", "show", c.config.CheckpointTag+":"+filepath.ToSlash(file)).Output()
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// readI18nOrdered parses an i18n file, returning its keys in file order.
+func readI18nOrdered(data []byte) ([]string, map[string]string, error) {
+	values, err := ParseI18n(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	if len(doc.Content) > 0 {
+		for i := 0; i+1 < len(doc.Content[0].Content); i += 2 {
+			keys = append(keys, doc.Content[0].Content[i].Value)
+		}
+	}
+	return keys, values, nil
+}
+
+// setYAMLField sets a top-level key of a YAML document to a string,
+// appending the key if it is new.
+func setYAMLField(doc *yaml.Node, key, value string) {
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	m := doc.Content[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v := m.Content[i+1]
+			v.Kind, v.Tag, v.Value = yaml.ScalarNode, "!!str", value
+			if v.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 && !strings.Contains(value, "\n") {
+				v.Style = 0
+			}
+			return
+		}
+	}
+	m.Content = append(m.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+func encodeYAML(doc *yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
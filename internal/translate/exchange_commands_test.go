@@ -0,0 +1,144 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSite writes files relative to the current directory.
+func writeSite(t *testing.T, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readSite(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExportImport(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeSite(t, map[string]string{
+		"content/english/a.md": "---\ntitle: Hello\ndescription: A page\ndate: 2025-01-01\n---\n\nFirst paragraph.\n\nSecond paragraph.\n",
+		"content/english/b.md": "---\ntitle: Other\n---\n\nOne.\n\nTwo.\n",
+		"content/german/b.md":  "---\ntitle: Andere\n---\n\nEins und zwei.\n",
+		"i18n/en.yaml":         "# Menu\nhome: Home\nabout: About us\n",
+		"i18n/de.yaml":         "home: Startseite\n",
+	})
+	c := &Checker{config: &Config{
+		SourceLang:    "en",
+		SourceDir:     "english",
+		ContentDir:    "content",
+		I18nDir:       "i18n",
+		CheckpointTag: "no-such-checkpoint",
+		MemoryFile:    "translations/memory.json",
+		GlossaryFile:  "translations/glossary.yaml",
+		FrontMatter:   DefaultFrontMatterKeys,
+		TargetLangs:   []Language{{Code: "de", Name: "German", DirName: "german"}},
+	}}
+
+	export := c.DoExport(ExportOptions{LangCode: "de", Format: FormatPO, Output: "de.po"})
+	if export.Error != nil {
+		t.Fatal(export.Error)
+	}
+	x, err := ReadExchange("de.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Already translated and no checkpoint to tell it changed: b.md's
+	// title and i18n home stay out
+	ids := make(map[string]*ExchangeUnit)
+	for i, u := range x.Units {
+		ids[filepath.Base(u.File)+":"+u.ID] = &x.Units[i]
+	}
+	for _, id := range []string{"b.md:fm-title", "en.yaml:i18n-home"} {
+		if ids[id] != nil {
+			t.Errorf("%s exported, but is already translated", id)
+		}
+	}
+	if export.Units != 7 || len(x.Files()) != 3 {
+		t.Fatalf("exported %d units from %v, want 7 from 3 files", export.Units, x.Files())
+	}
+
+	// The translator reviews most units; one stays fuzzy, one untouched
+	review := map[string]string{
+		"a.md:fm-title":       "Hallo",
+		"a.md:fm-description": "Eine Seite",
+		"a.md:" + unitParagraph + SegmentKey("First paragraph."): "Erster Absatz.",
+		"b.md:" + unitParagraph + SegmentKey("One."):             "Eins.",
+		"en.yaml:i18n-about": "Über uns",
+	}
+	for id, target := range review {
+		if ids[id] == nil {
+			t.Fatalf("%s not exported (have %v)", id, x.Units)
+		}
+		ids[id].Target, ids[id].Reviewed = target, true
+	}
+	ids["a.md:"+unitParagraph+SegmentKey("Second paragraph.")].Target = "Zweiter Absatz?"
+	f, err := os.Create("de.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Write(f, FormatPO); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// The English description changes before the import
+	writeSite(t, map[string]string{
+		"content/english/a.md": "---\ntitle: Hello\ndescription: A new page\ndate: 2025-01-01\n---\n\nFirst paragraph.\n\nSecond paragraph.\n",
+	})
+
+	result := c.DoImport("de.po")
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if result.Pending != 2 {
+		t.Errorf("pending = %d, want 2 (fuzzy and untranslated)", result.Pending)
+	}
+
+	// New page: reviewed title and paragraph; the fuzzy one stays English
+	if got, want := readSite(t, "content/german/a.md"), "---\ntitle: Hallo\ndescription: A new page\ndate: 2025-01-01\n---\n\nErster Absatz.\n\nSecond paragraph.\n"; got != want {
+		t.Errorf("german/a.md:\n got %q\nwant %q", got, want)
+	}
+	if msg := result.Skipped[filepath.Join("content", "german", "a.md")]; !strings.Contains(msg, "English changed since the export") || !strings.Contains(msg, "1 paragraphs left in English") {
+		t.Errorf("a.md skipped = %q", msg)
+	}
+
+	// Existing page with one paragraph for two: nothing lines up
+	if got := readSite(t, "content/german/b.md"); got != "---\ntitle: Andere\n---\n\nEins und zwei.\n" {
+		t.Errorf("german/b.md changed: %q", got)
+	}
+	if msg := result.Skipped[filepath.Join("content", "german", "b.md")]; !strings.Contains(msg, "don't line up") {
+		t.Errorf("b.md skipped = %q", msg)
+	}
+
+	if got, want := readSite(t, "i18n/de.yaml"), "home: Startseite\nabout: Über uns\n"; got != want {
+		t.Errorf("de.yaml = %q, want %q", got, want)
+	}
+	if result.Total != 3 {
+		t.Errorf("written %v, total %d, want 3", result.Written, result.Total)
+	}
+
+	// Reviewed units are approved in the memory, for autotranslate
+	m, err := LoadMemory("translations/memory.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := m.Lookup("One.", "de"); !ok || !e.Approved || e.Text != "Eins." {
+		t.Errorf("memory for a misaligned paragraph = %+v, %v", e, ok)
+	}
+}
@@ -0,0 +1,163 @@
+package translate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// gettext PO: one entry per ExchangeUnit, with msgctxt "<file>#<id>".
+// Notes are extracted comments (#.), the source file a reference (#:).
+// A prefilled msgstr is marked fuzzy; the translator clears the flag.
+
+func writePO(w io.Writer, x *Exchange) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "# %s → %s translations for review\n", x.SourceLang, x.TargetLang)
+	fmt.Fprintln(b, `msgid ""`)
+	fmt.Fprintln(b, `msgstr ""`)
+	fmt.Fprintf(b, "\"Language: %s\\n\"\n", x.TargetLang)
+	fmt.Fprintf(b, "\"X-Source-Language: %s\\n\"\n", x.SourceLang)
+	fmt.Fprintln(b, `"MIME-Version: 1.0\n"`)
+	fmt.Fprintln(b, `"Content-Type: text/plain; charset=UTF-8\n"`)
+	fmt.Fprintln(b, `"Content-Transfer-Encoding: 8bit\n"`)
+
+	for _, u := range x.Units {
+		fmt.Fprintln(b)
+		for _, note := range u.Notes {
+			fmt.Fprintf(b, "#. %s\n", note)
+		}
+		fmt.Fprintf(b, "#: %s\n", u.File)
+		if u.Target != "" && !u.Reviewed {
+			fmt.Fprintln(b, "#, fuzzy")
+		}
+		writePOString(b, "msgctxt", u.File+"#"+u.ID)
+		writePOString(b, "msgid", u.Source)
+		writePOString(b, "msgstr", u.Target)
+	}
+	return b.Flush()
+}
+
+// writePOString writes a keyword and its string, one line per line of s.
+func writePOString(w io.Writer, keyword, s string) {
+	if !strings.Contains(s, "\n") {
+		fmt.Fprintf(w, "%s %s\n", keyword, poQuote(s))
+		return
+	}
+	fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			fmt.Fprintln(w, poQuote(line))
+		}
+	}
+}
+
+func poQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func readPO(r io.Reader) (*Exchange, error) {
+	x := &Exchange{}
+	var entry struct {
+		notes         []string
+		fuzzy         bool
+		ctxt, id, str *strings.Builder
+	}
+	var current *strings.Builder
+	lineNo := 0
+
+	flush := func() error {
+		defer func() {
+			entry.notes, entry.fuzzy = nil, false
+			entry.ctxt, entry.id, entry.str, current = nil, nil, nil, nil
+		}()
+		if entry.id == nil {
+			return nil
+		}
+		if entry.ctxt == nil {
+			// The header: msgid "" with metadata in msgstr
+			for _, line := range strings.Split(entry.str.String(), "\n") {
+				key, value, _ := strings.Cut(line, ":")
+				switch strings.TrimSpace(key) {
+				case "Language":
+					x.TargetLang = strings.TrimSpace(value)
+				case "X-Source-Language":
+					x.SourceLang = strings.TrimSpace(value)
+				}
+			}
+			return nil
+		}
+		file, id, ok := strings.Cut(entry.ctxt.String(), "#")
+		if !ok {
+			return fmt.Errorf("line %d: msgctxt %q is not <file>#<id>", lineNo, entry.ctxt.String())
+		}
+		u := ExchangeUnit{File: file, ID: id, Source: entry.id.String(), Notes: entry.notes}
+		if entry.str != nil {
+			u.Target = entry.str.String()
+			u.Reviewed = u.Target != "" && !entry.fuzzy
+		}
+		x.Units = append(x.Units, u)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		keyword, rest, _ := strings.Cut(line, " ")
+
+		// An entry ends at a blank line, or where the next one starts
+		startsEntry := strings.HasPrefix(line, "#") || keyword == "msgctxt" || keyword == "msgid"
+		if line == "" || (startsEntry && entry.str != nil) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case line == "", strings.HasPrefix(line, "#~"):
+			// Entry break, or an obsolete entry
+		case strings.HasPrefix(line, "#,"):
+			entry.fuzzy = strings.Contains(line, "fuzzy")
+		case strings.HasPrefix(line, "#."):
+			entry.notes = append(entry.notes, strings.TrimSpace(line[2:]))
+		case strings.HasPrefix(line, "#"):
+			// Reference or translator comment
+		case strings.HasPrefix(line, `"`):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: string outside an entry", lineNo)
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			current.WriteString(s)
+		default:
+			s, err := strconv.Unquote(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			current = &strings.Builder{}
+			current.WriteString(s)
+			switch keyword {
+			case "msgctxt":
+				entry.ctxt = current
+			case "msgid":
+				entry.id = current
+			case "msgstr", "msgstr[0]":
+				entry.str = current
+			}
+			// msgid_plural and msgstr[n] are not used in exchange files
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
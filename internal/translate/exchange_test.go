@@ -0,0 +1,35 @@
+package translate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExchangeRoundTrip(t *testing.T) {
+	x := &Exchange{SourceLang: "en", TargetLang: "de", Units: []ExchangeUnit{
+		{File: "content/english/a.md", ID: "fm-title", Source: `Say "hi" & <go>`, Notes: []string{"front matter: title"}},
+		{File: "content/english/a.md", ID: "p-0123456789abcdef", Source: "- one\n- two\ttabbed", Target: "- eins\n- zwei\ttabbed"},
+		{File: "i18n/en.yaml", ID: "i18n-home", Source: "Home", Target: "Startseite", Reviewed: true},
+	}}
+
+	dir := t.TempDir()
+	for format, ext := range ExchangeFormats {
+		var buf bytes.Buffer
+		if err := x.Write(&buf, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		path := filepath.Join(dir, "de"+ext)
+		os.WriteFile(path, buf.Bytes(), 0644)
+
+		got, err := ReadExchange(path)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, x) {
+			t.Errorf("%s round trip:\n got %+v\nwant %+v\n%s", format, got, x, buf.String())
+		}
+	}
+}
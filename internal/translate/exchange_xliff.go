@@ -0,0 +1,103 @@
+package translate
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// XLIFF 2.0 core: one <file> per source file, one <unit> with a single
+// <segment> per ExchangeUnit. A prefilled target has state="initial";
+// the CAT tool moves it to translated, reviewed or final.
+type xliffDoc struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr,omitempty"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	ID       string      `xml:"id,attr"`
+	Original string      `xml:"original,attr,omitempty"`
+	Space    string      `xml:"xml:space,attr,omitempty"` // Paragraphs keep their line breaks
+	Units    []xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	ID      string       `xml:"id,attr"`
+	Notes   *xliffNotes  `xml:"notes,omitempty"`
+	Segment xliffSegment `xml:"segment"`
+}
+
+type xliffNotes struct {
+	Notes []string `xml:"note"`
+}
+
+type xliffSegment struct {
+	State  string  `xml:"state,attr,omitempty"`
+	Source string  `xml:"source"`
+	Target *string `xml:"target"`
+}
+
+func writeXLIFF(w io.Writer, x *Exchange) error {
+	doc := xliffDoc{Version: "2.0", SrcLang: x.SourceLang, TrgLang: x.TargetLang}
+	for i, file := range x.Files() {
+		f := xliffFile{ID: fmt.Sprintf("f%d", i+1), Original: file, Space: "preserve"}
+		for _, u := range x.Units {
+			if u.File != file {
+				continue
+			}
+			unit := xliffUnit{ID: u.ID, Segment: xliffSegment{Source: u.Source}}
+			if len(u.Notes) > 0 {
+				unit.Notes = &xliffNotes{Notes: u.Notes}
+			}
+			if u.Target != "" {
+				target := u.Target
+				unit.Segment.Target = &target
+				unit.Segment.State = "initial"
+				if u.Reviewed {
+					unit.Segment.State = "translated"
+				}
+			}
+			f.Units = append(f.Units, unit)
+		}
+		doc.Files = append(doc.Files, f)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func readXLIFF(r io.Reader) (*Exchange, error) {
+	var doc xliffDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != "2.0" {
+		return nil, fmt.Errorf("XLIFF version %q, want 2.0", doc.Version)
+	}
+	x := &Exchange{SourceLang: doc.SrcLang, TargetLang: doc.TrgLang}
+	for _, f := range doc.Files {
+		for _, unit := range f.Units {
+			u := ExchangeUnit{File: f.Original, ID: unit.ID, Source: unit.Segment.Source}
+			if unit.Notes != nil {
+				u.Notes = unit.Notes.Notes
+			}
+			if t := unit.Segment.Target; t != nil {
+				u.Target = *t
+				u.Reviewed = unit.Segment.State != "" && unit.Segment.State != "initial"
+			}
+			x.Units = append(x.Units, u)
+		}
+	}
+	return x, nil
+}
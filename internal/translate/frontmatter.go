@@ -0,0 +1,631 @@
+// Package translator provides translation workflow management.
+//
+// This file contains the front matter of content pages, in the three
+// formats Hugo reads: YAML (---), TOML (+++) and JSON ({ ... }). String
+// values are found by key path and replaced where they are written, so
+// comments, key order and formatting survive translation.
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Front matter formats
+const (
+	FrontMatterYAML = "yaml"
+	FrontMatterTOML = "toml"
+	FrontMatterJSON = "json"
+)
+
+// DefaultFrontMatterKeys are the front matter key paths that are translated.
+// Dots separate nested keys, and a path applies to every item of a list on
+// the way: features.title is the title of each feature, and
+// features.bulletpoints each of its bullet points.
+var DefaultFrontMatterKeys = []string{
+	"title", "meta_title", "description", "excerpt", "summary",
+	"banner.title", "banner.content", "banner.button.label", "banner.button2.label",
+	"button.label",
+	"features.title", "features.content", "features.bulletpoints", "features.button.label",
+	"testimonials.name", "testimonials.designation", "testimonials.content",
+}
+
+// ParseFrontMatterKeys parses a comma-separated list of key paths.
+func ParseFrontMatterKeys(s string) []string {
+	var keys []string
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// FrontMatter is a page's front matter as written.
+type FrontMatter struct {
+	Format string // FrontMatterYAML, FrontMatterTOML or FrontMatterJSON
+	Raw    string // Including the delimiters and the line break after them
+}
+
+// FrontMatterString is a string value in the front matter.
+type FrontMatterString struct {
+	Path  string // Key path with list indices, e.g. features.0.title
+	Value string
+
+	start, end int    // Span of the value in Raw
+	style      string // How the value is written (see replacement)
+}
+
+// SplitFrontMatter separates the front matter of a page from its body. A
+// page without front matter returns nil and the whole content.
+func SplitFrontMatter(content string) (*FrontMatter, string, error) {
+	for _, f := range []struct{ format, delim string }{{FrontMatterYAML, "---"}, {FrontMatterTOML, "+++"}} {
+		first, rest, ok := cutLine(content)
+		if !ok || strings.TrimRight(first, " \t\r\n") != f.delim {
+			continue
+		}
+		for rest != "" {
+			var line string
+			line, rest, _ = cutLine(rest)
+			if strings.TrimRight(line, " \t\r\n") == f.delim {
+				end := len(content) - len(rest)
+				return &FrontMatter{Format: f.format, Raw: content[:end]}, content[end:], nil
+			}
+		}
+		return nil, "", fmt.Errorf("front matter has no closing %s", f.delim)
+	}
+
+	if strings.HasPrefix(content, "{") {
+		dec := json.NewDecoder(strings.NewReader(content))
+		var v map[string]interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, "", fmt.Errorf("JSON front matter: %w", err)
+		}
+		end := int(dec.InputOffset())
+		if _, rest, ok := cutLine(content[end:]); ok {
+			end = len(content) - len(rest)
+		} else {
+			end = len(content)
+		}
+		return &FrontMatter{Format: FrontMatterJSON, Raw: content[:end]}, content[end:], nil
+	}
+	return nil, content, nil
+}
+
+// cutLine splits s after its first line break.
+func cutLine(s string) (line, rest string, ok bool) {
+	i := strings.IndexByte(s, '\n')
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i+1], s[i+1:], true
+}
+
+// inner returns the front matter without its delimiters, and its offset in Raw.
+func (fm *FrontMatter) inner() (string, int) {
+	if fm.Format == FrontMatterJSON {
+		return fm.Raw, 0
+	}
+	_, rest, _ := cutLine(fm.Raw)
+	body := strings.TrimSuffix(rest, "\n")
+	if i := strings.LastIndexByte(body, '\n'); i >= 0 {
+		body = body[:i+1]
+	} else {
+		body = ""
+	}
+	return body, len(fm.Raw) - len(rest)
+}
+
+// Decode returns the front matter's values.
+func (fm *FrontMatter) Decode() (map[string]interface{}, error) {
+	text, _ := fm.inner()
+	values := make(map[string]interface{})
+	var err error
+	switch fm.Format {
+	case FrontMatterYAML:
+		err = yaml.Unmarshal([]byte(text), &values)
+	case FrontMatterTOML:
+		err = toml.Unmarshal([]byte(text), &values)
+	case FrontMatterJSON:
+		err = json.Unmarshal([]byte(text), &values)
+	default:
+		err = fmt.Errorf("unknown format %q", fm.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s front matter: %w", fm.Format, err)
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return values, nil
+}
+
+// Strings returns the string values at the key paths, in the order they
+// are written. Empty values are included, so they can be set.
+func (fm *FrontMatter) Strings(keys []string) ([]FrontMatterString, error) {
+	want := make(map[string]bool)
+	for _, k := range keys {
+		want[k] = true
+	}
+	text, offset := fm.inner()
+	var found []FrontMatterString
+	add := func(keys, path []string, value string, start, end int, style string) {
+		if want[strings.Join(keys, ".")] {
+			found = append(found, FrontMatterString{
+				Path: strings.Join(path, "."), Value: value,
+				start: offset + start, end: offset + end, style: style,
+			})
+		}
+	}
+
+	var err error
+	switch fm.Format {
+	case FrontMatterYAML:
+		err = yamlStrings(text, add)
+	case FrontMatterTOML:
+		err = tomlStrings(text, add)
+	case FrontMatterJSON:
+		err = jsonStrings(text, add)
+	default:
+		err = fmt.Errorf("unknown format %q", fm.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s front matter: %w", fm.Format, err)
+	}
+	return found, nil
+}
+
+// Set replaces string values by path (as returned by Strings), leaving
+// the rest of the front matter as written.
+func (fm *FrontMatter) Set(keys []string, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	strs, err := fm.Strings(keys)
+	if err != nil {
+		return err
+	}
+
+	for path := range values {
+		if _, ok := stringAt(strs, path); !ok {
+			return fmt.Errorf("front matter has no %s to translate", path)
+		}
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, s := range strs {
+		if v, ok := values[s.Path]; ok {
+			b.WriteString(fm.Raw[last:s.start])
+			b.WriteString(fm.replacement(s, v))
+			last = s.end
+		}
+	}
+	b.WriteString(fm.Raw[last:])
+
+	// The new values must read back as they were given (block scalars
+	// may add a final line break)
+	updated := &FrontMatter{Format: fm.Format, Raw: b.String()}
+	check, err := updated.Strings(keys)
+	if err != nil {
+		return err
+	}
+	for path, v := range values {
+		got, _ := stringAt(check, path)
+		if strings.TrimSpace(v) != "" && strings.TrimRight(got, "\n") != strings.TrimRight(v, "\n") {
+			return fmt.Errorf("front matter %s: %q could not be written as %s", path, v, fm.Format)
+		}
+	}
+	fm.Raw = updated.Raw
+	return nil
+}
+
+// stringAt returns the value at path.
+func stringAt(strs []FrontMatterString, path string) (string, bool) {
+	for _, s := range strs {
+		if s.Path == path {
+			return s.Value, true
+		}
+	}
+	return "", false
+}
+
+// replacement writes v in place of s, in the same style where possible.
+func (fm *FrontMatter) replacement(s FrontMatterString, v string) string {
+	switch fm.Format {
+	case FrontMatterTOML:
+		if s.style == "'" && !strings.ContainsAny(v, "'\n\r\t") {
+			return "'" + v + "'"
+		}
+		return tomlQuote(v)
+	case FrontMatterJSON:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.Encode(v)
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+
+	switch {
+	case strings.HasPrefix(s.style, "|"), strings.HasPrefix(s.style, ">"):
+		// Block scalar: style is the header line and the indentation
+		header, indent, _ := strings.Cut(s.style, "\n")
+		sep := "\n"
+		if header[0] == '>' {
+			sep = "\n\n" // Folded: a single line break would become a space
+		}
+		var b strings.Builder
+		b.WriteString(header)
+		for i, line := range strings.Split(strings.TrimRight(v, "\n"), "\n") {
+			if i > 0 {
+				b.WriteString(sep[1:])
+			}
+			b.WriteString("\n")
+			if line != "" {
+				b.WriteString(indent + line)
+			}
+		}
+		return b.String()
+	case s.style == "'" && !strings.ContainsAny(v, "\n\r\t"):
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case s.style == "" && !strings.ContainsAny(v, "\n\r\t#"),
+		s.style == "flow" && !strings.ContainsAny(v, "\n\r\t#,[]{}"):
+		out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+		if err == nil && strings.Count(string(out), "\n") == 1 {
+			return strings.TrimSuffix(string(out), "\n")
+		}
+	}
+	return yamlQuote(v)
+}
+
+// yamlQuote writes a YAML double-quoted string on one line.
+func yamlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlQuote writes a TOML basic string.
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// foundString receives a string value: its keys without list indices,
+// its full path, and where and how it is written.
+type foundString func(keys, path []string, value string, start, end int, style string)
+
+func extend(s []string, v string) []string {
+	return append(append([]string(nil), s...), v)
+}
+
+// yamlStrings walks a YAML document. yaml.v3 gives where each value starts
+// (line and column in runes); where it ends depends on its style.
+func yamlStrings(text string, add foundString) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return err
+	}
+	var lineStarts []int
+	for i := 0; i < len(text); {
+		lineStarts = append(lineStarts, i)
+		if j := strings.IndexByte(text[i:], '\n'); j >= 0 {
+			i += j + 1
+		} else {
+			break
+		}
+	}
+
+	// flow is set inside [ ] and { }, where plain values can't hold , [ ] { }
+	var walk func(n *yaml.Node, keys, path []string, flow bool) error
+	walk = func(n *yaml.Node, keys, path []string, flow bool) error {
+		flow = flow || n.Style&yaml.FlowStyle != 0
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				if err := walk(c, keys, path, flow); err != nil {
+					return err
+				}
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := n.Content[i].Value
+				if err := walk(n.Content[i+1], extend(keys, k), extend(path, k), flow); err != nil {
+					return err
+				}
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				if err := walk(c, keys, extend(path, strconv.Itoa(i)), flow); err != nil {
+					return err
+				}
+			}
+		case yaml.ScalarNode:
+			if n.ShortTag() != "!!str" || n.Line < 1 || n.Line > len(lineStarts) {
+				return nil
+			}
+			start := lineStarts[n.Line-1]
+			for col := 1; col < n.Column && start < len(text); col++ {
+				_, size := utf8.DecodeRuneInString(text[start:])
+				start += size
+			}
+			if strings.HasPrefix(text[start:], "!") || strings.HasPrefix(text[start:], "&") {
+				return nil // Tagged or anchored: left as written
+			}
+			end, style, err := yamlScalarEnd(text, start, n)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.Line, err)
+			}
+			if style == "" && flow {
+				style = "flow"
+			}
+			add(keys, path, n.Value, start, end, style)
+		}
+		return nil
+	}
+	return walk(&doc, nil, nil, false)
+}
+
+// yamlScalarEnd finds the end of the scalar n starting at start, and
+// returns the style to write a replacement in.
+func yamlScalarEnd(text string, start int, n *yaml.Node) (int, string, error) {
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, `"`, nil
+			}
+		}
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(text); i++ {
+			if text[i] == '\'' {
+				if i+1 < len(text) && text[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1, "'", nil
+			}
+		}
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		// The header, then lines indented at least as much as the first
+		header, rest, _ := cutLine(text[start:])
+		header = strings.TrimRight(strings.SplitN(header, " #", 2)[0], " \t\r\n")
+		end, indent := start+len(header), ""
+		for offset := len(text) - len(rest); rest != ""; offset = len(text) - len(rest) {
+			var line string
+			line, rest, _ = cutLine(rest)
+			content := strings.TrimRight(line, "\r\n")
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+			lineIndent := content[:len(content)-len(strings.TrimLeft(content, " "))]
+			if indent == "" {
+				indent = lineIndent
+			}
+			if indent == "" || len(lineIndent) < len(indent) {
+				break
+			}
+			end = offset + len(content)
+		}
+		if indent == "" {
+			break
+		}
+		return end, header + "\n" + indent, nil
+	default:
+		// Plain: to the end of the line or a comment, continued on the
+		// following lines until the folded text is the value. Inside
+		// [ ] or { } it ends at the next separator.
+		var lines []string
+		rest := text[start:]
+		for rest != "" {
+			var line string
+			line, rest, _ = cutLine(rest)
+			content := strings.TrimRight(line, "\r\n")
+			if i := strings.Index(content, " #"); i >= 0 {
+				content = content[:i]
+			}
+			content = strings.TrimRight(content, " \t")
+			lineStart := len(text) - len(rest) - len(line)
+			if len(lines) == 0 {
+				if i := plainFlowEnd(content); i >= 0 && strings.TrimRight(content[:i], " \t") == n.Value {
+					return lineStart + len(strings.TrimRight(content[:i], " \t")), "", nil
+				}
+			}
+			lines = append(lines, strings.TrimSpace(content))
+			if foldPlain(lines) == n.Value {
+				return lineStart + len(content), "", nil
+			}
+		}
+	}
+	return 0, "", fmt.Errorf("could not find the end of %q", n.Value)
+}
+
+// plainFlowEnd returns where a plain scalar inside [ ] or { } ends, or -1.
+func plainFlowEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ',', ']', '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// foldPlain joins the lines of a multi-line plain scalar.
+func foldPlain(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		switch {
+		case i == 0:
+		case line == "":
+			b.WriteString("\n")
+			continue
+		case lines[i-1] != "":
+			b.WriteString(" ")
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// tomlStrings walks a TOML document's expressions, tracking the current
+// table and the index of each array of tables.
+func tomlStrings(text string, add foundString) error {
+	var p unstable.Parser
+	p.Reset([]byte(text))
+	var tableKeys, tablePath []string
+	arrays := make(map[string]int) // Concrete path of an array of tables → items so far
+
+	header := func(n *unstable.Node) ([]string, []string) {
+		var keys, path []string
+		it := n.Key()
+		for it.Next() {
+			k := string(it.Node().Data)
+			keys, path = append(keys, k), append(path, k)
+			if count, ok := arrays[strings.Join(path, ".")]; ok && !it.IsLast() {
+				path = append(path, strconv.Itoa(count-1))
+			}
+		}
+		return keys, path
+	}
+
+	var value func(n *unstable.Node, keys, path []string)
+	value = func(n *unstable.Node, keys, path []string) {
+		switch n.Kind {
+		case unstable.String:
+			raw := p.Raw(n.Raw)
+			style := `"`
+			if len(raw) > 0 && raw[0] == '\'' {
+				style = "'"
+			}
+			add(keys, path, string(n.Data), int(n.Raw.Offset), int(n.Raw.Offset+n.Raw.Length), style)
+		case unstable.Array:
+			it := n.Children()
+			for i := 0; it.Next(); i++ {
+				value(it.Node(), keys, extend(path, strconv.Itoa(i)))
+			}
+		case unstable.InlineTable:
+			it := n.Children()
+			for it.Next() {
+				keyValue(it.Node(), keys, path, value)
+			}
+		}
+	}
+
+	for p.NextExpression() {
+		e := p.Expression()
+		switch e.Kind {
+		case unstable.Table:
+			tableKeys, tablePath = header(e)
+		case unstable.ArrayTable:
+			tableKeys, tablePath = header(e)
+			name := strings.Join(tablePath, ".")
+			arrays[name]++
+			tablePath = append(tablePath, strconv.Itoa(arrays[name]-1))
+		case unstable.KeyValue:
+			keyValue(e, tableKeys, tablePath, value)
+		}
+	}
+	return p.Error()
+}
+
+func keyValue(n *unstable.Node, keys, path []string, value func(*unstable.Node, []string, []string)) {
+	keys, path = append([]string(nil), keys...), append([]string(nil), path...)
+	it := n.Key()
+	for it.Next() {
+		k := string(it.Node().Data)
+		keys, path = append(keys, k), append(path, k)
+	}
+	value(n.Value(), keys, path)
+}
+
+// jsonStrings walks a JSON document token by token; a value starts after
+// the separators following the previous token.
+func jsonStrings(text string, add foundString) error {
+	dec := json.NewDecoder(strings.NewReader(text))
+	var walk func(keys, path []string) error
+	walk = func(keys, path []string) error {
+		start := int(dec.InputOffset())
+		for start < len(text) && strings.IndexByte(" \t\r\n:,", text[start]) >= 0 {
+			start++
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			switch tok {
+			case '{':
+				for dec.More() {
+					k, err := dec.Token()
+					if err != nil {
+						return err
+					}
+					key, _ := k.(string)
+					if err := walk(extend(keys, key), extend(path, key)); err != nil {
+						return err
+					}
+				}
+			case '[':
+				for i := 0; dec.More(); i++ {
+					if err := walk(keys, extend(path, strconv.Itoa(i))); err != nil {
+						return err
+					}
+				}
+			}
+			_, err := dec.Token() // Closing delimiter
+			return err
+		case string:
+			add(keys, path, tok, start, int(dec.InputOffset()), `"`)
+		}
+		return nil
+	}
+	return walk(nil, nil)
+}
@@ -0,0 +1,116 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFrontMatterSet(t *testing.T) {
+	keys := []string{"title", "cta.title", "features.title", "features.bullets", "summary"}
+	tests := []struct {
+		name, page, want string
+	}{
+		{
+			name: "yaml",
+			page: `---
+# Page
+title: "Hello \"world\""   # shown in the tab
+date: 2025-01-01
+cta: {title: Go, link: /go}
+features:
+  - title: One, two
+    bullets: ['a', b]
+summary: >
+  Folded
+  text
+---
+
+Body
+`,
+			want: `---
+# Page
+title: "HELLO \"WORLD\""   # shown in the tab
+date: 2025-01-01
+cta: {title: GO, link: /go}
+features:
+  - title: ONE, TWO
+    bullets: ['A', B]
+summary: >
+  FOLDED TEXT
+---
+
+Body
+`,
+		},
+		{
+			name: "toml",
+			page: `+++
+title = 'Hello' # comment
+date = 2025-01-01
+[cta]
+title = "Go"
+[[features]]
+title = "One"
+bullets = ["a", 'b']
+[[features]]
+title = "Two"
++++
+Body
+`,
+			want: `+++
+title = 'HELLO' # comment
+date = 2025-01-01
+[cta]
+title = "GO"
+[[features]]
+title = "ONE"
+bullets = ["A", 'B']
+[[features]]
+title = "TWO"
++++
+Body
+`,
+		},
+		{
+			name: "json",
+			page: `{
+  "title": "Hello <b>",
+  "features": [{"title": "One", "bullets": ["a"]}],
+  "weight": 3
+}
+Body
+`,
+			want: `{
+  "title": "HELLO <B>",
+  "features": [{"title": "ONE", "bullets": ["A"]}],
+  "weight": 3
+}
+Body
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		fm, body, err := SplitFrontMatter(tt.page)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if fm.Format != tt.name || fm.Raw+body != tt.page {
+			t.Fatalf("%s: split as %s %q + %q", tt.name, fm.Format, fm.Raw, body)
+		}
+		strs, err := fm.Strings(keys)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		values := make(map[string]string)
+		for _, s := range strs {
+			values[s.Path] = strings.ToUpper(strings.TrimSpace(s.Value))
+		}
+		if err := fm.Set(keys, values); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := fm.Raw + body; got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package translator provides translation workflow management.
+//
+// This file contains the glossary: product names and terms that must be
+// rendered the same way in every translation. Providers get the glossary
+// in their prompt (Claude) or as a native glossary (DeepL), and every
+// translation is checked against it afterwards.
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultGlossaryFile is the glossary, relative to the site root.
+const DefaultGlossaryFile = "translations/glossary.yaml"
+
+// Glossary is the termbase shared by translate and autotranslate.
+//
+//	terms:
+//	  - term: Pixhawk
+//	    keep: true                  # never translated or transliterated
+//	  - term: flight controller
+//	    translations:
+//	      de: Flugsteuerung
+//	      ja: フライトコントローラー
+type Glossary struct {
+	Terms []GlossaryTerm `yaml:"terms"`
+}
+
+// GlossaryTerm is one English term and how it is rendered per language.
+type GlossaryTerm struct {
+	Term         string            `yaml:"term"`
+	Keep         bool              `yaml:"keep,omitempty"`         // Kept in English where no translation is given
+	Translations map[string]string `yaml:"translations,omitempty"` // lang code → required rendering
+	Note         string            `yaml:"note,omitempty"`         // Context for reviewers
+
+	pattern *regexp.Regexp
+}
+
+// GlossaryEntry is a term's required rendering in one language.
+type GlossaryEntry struct {
+	Source string
+	Target string
+	Keep   bool // Target is the English term
+}
+
+// GlossaryViolation is a glossary term whose required rendering is missing
+// from a translation.
+type GlossaryViolation struct {
+	Term string // English term found in the source
+	Want string // Rendering the translation should contain
+}
+
+func (v GlossaryViolation) String() string {
+	if v.Term == v.Want {
+		return fmt.Sprintf("%q must be kept as is", v.Term)
+	}
+	return fmt.Sprintf("%q must be translated as %q", v.Term, v.Want)
+}
+
+// LoadGlossary reads the glossary at path; a missing file is an empty
+// glossary.
+func LoadGlossary(path string) (*Glossary, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Glossary{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var g Glossary
+	if err := yaml.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("parsing glossary %s: %w", path, err)
+	}
+	for i := range g.Terms {
+		t := &g.Terms[i]
+		t.Term = strings.TrimSpace(t.Term)
+		if t.Term == "" {
+			return nil, fmt.Errorf("glossary %s: term %d is empty", path, i+1)
+		}
+		if !t.Keep && len(t.Translations) == 0 {
+			return nil, fmt.Errorf("glossary %s: %q needs keep: true or translations", path, t.Term)
+		}
+	}
+	return &g, nil
+}
+
+// Entries returns the glossary's renderings in lang, sorted by term.
+func (g *Glossary) Entries(lang string) []GlossaryEntry {
+	if g == nil {
+		return nil
+	}
+	var entries []GlossaryEntry
+	for _, t := range g.Terms {
+		if target := t.Translations[lang]; target != "" {
+			entries = append(entries, GlossaryEntry{Source: t.Term, Target: target})
+		} else if t.Keep {
+			entries = append(entries, GlossaryEntry{Source: t.Term, Target: t.Term, Keep: true})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Source < entries[j].Source })
+	return entries
+}
+
+// Hash identifies the glossary's renderings in lang, so a provider can
+// tell whether an uploaded copy is current.
+func (g *Glossary) Hash(lang string) string {
+	h := sha256.New()
+	for _, e := range g.Entries(lang) {
+		fmt.Fprintf(h, "%s\t%s\n", e.Source, e.Target)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Matching returns the entries for lang whose term occurs in text.
+func (g *Glossary) Matching(text, lang string) []GlossaryEntry {
+	if g == nil {
+		return nil
+	}
+	var entries []GlossaryEntry
+	for _, e := range g.Entries(lang) {
+		if g.term(e.Source).MatchString(text) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Prompt returns glossary instructions for the terms in text, for an LLM
+// prompt, or "" if none occur.
+func (g *Glossary) Prompt(text, lang string) string {
+	entries := g.Matching(text, lang)
+	if len(entries) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("GLOSSARY - render these terms exactly as given:\n")
+	for _, e := range entries {
+		if e.Keep {
+			fmt.Fprintf(&b, "- %q: keep in English, do not translate or transliterate\n", e.Source)
+		} else {
+			fmt.Fprintf(&b, "- %q: %q\n", e.Source, e.Target)
+		}
+	}
+	return b.String()
+}
+
+// Check returns the glossary terms in source whose rendering is missing
+// from translated. Code blocks are ignored on both sides.
+func (g *Glossary) Check(source, translated, lang string) []GlossaryViolation {
+	if g == nil || len(g.Terms) == 0 {
+		return nil
+	}
+	src := strings.Join(translatable(SplitSegments(source)), "\n\n")
+	tgt := strings.ToLower(strings.Join(translatable(SplitSegments(translated)), "\n\n"))
+
+	var violations []GlossaryViolation
+	for _, e := range g.Matching(src, lang) {
+		if !strings.Contains(tgt, strings.ToLower(e.Target)) {
+			violations = append(violations, GlossaryViolation{Term: e.Source, Want: e.Target})
+		}
+	}
+	return violations
+}
+
+// term returns the pattern matching an English term as a whole word,
+// ignoring case.
+func (g *Glossary) term(source string) *regexp.Regexp {
+	for i := range g.Terms {
+		t := &g.Terms[i]
+		if t.Term != source {
+			continue
+		}
+		if t.pattern == nil {
+			t.pattern = regexp.MustCompile(`(?i)(^|\W)` + regexp.QuoteMeta(t.Term) + `($|\W)`)
+		}
+		return t.pattern
+	}
+	return regexp.MustCompile(`(?i)(^|\W)` + regexp.QuoteMeta(source) + `($|\W)`)
+}
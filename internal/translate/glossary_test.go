@@ -0,0 +1,41 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlossary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "glossary.yaml")
+	os.WriteFile(path, []byte(`terms:
+  - term: Pixhawk
+    keep: true
+  - term: flight controller
+    translations:
+      de: Flugsteuerung
+`), 0644)
+	g, err := LoadGlossary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := "Use a Pixhawk flight controller.\n\n```\npixhawk-cli --flight controller\n```\n"
+	if v := g.Check(source, "Verwenden Sie eine Pixhawk-Flugsteuerung.\n", "de"); len(v) != 0 {
+		t.Errorf("good translation: %v", v)
+	}
+	v := g.Check(source, "Verwenden Sie einen Pixhoek-Flugregler.\n", "de")
+	if len(v) != 2 || v[1].Want != "Flugsteuerung" || v[0].String() != `"Pixhawk" must be kept as is` {
+		t.Errorf("violations = %v", v)
+	}
+
+	// Only the terms in the text are prompted; ja has no translation for
+	// "flight controller"
+	if p := g.Prompt("Pixhawk only", "ja"); !strings.Contains(p, `"Pixhawk": keep in English`) || strings.Contains(p, "flight") {
+		t.Errorf("prompt = %q", p)
+	}
+	if len(g.Entries("ja")) != 1 || g.Hash("de") == g.Hash("ja") {
+		t.Errorf("ja entries = %v", g.Entries("ja"))
+	}
+}
@@ -10,52 +10,56 @@ import (
 
 // MarkdownDoc represents a parsed markdown document
 type MarkdownDoc struct {
-	FrontMatter    map[string]interface{}
-	Body           string
-	rawFrontMatter string
+	FrontMatter map[string]interface{}
+	Body        string
+	Header      *FrontMatter // Front matter as written (see frontmatter.go); nil if none
 }
 
-// ParseMarkdown parses a markdown file with YAML front matter
+// ParseMarkdown parses a markdown file with YAML, TOML or JSON front matter
 func ParseMarkdown(content []byte) (*MarkdownDoc, error) {
-	contentStr := string(content)
+	header, body, err := SplitFrontMatter(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid front matter format: %w", err)
+	}
 
-	// Check if file has front matter
-	if !strings.HasPrefix(contentStr, "---") {
-		// No front matter, entire content is body
+	// No front matter, entire content is body
+	if header == nil {
 		return &MarkdownDoc{
 			FrontMatter: make(map[string]interface{}),
-			Body:        contentStr,
+			Body:        body,
 		}, nil
 	}
 
-	// Find the end of front matter
-	parts := strings.SplitN(contentStr, "---", 3)
-	if len(parts) < 3 {
-		return nil, fmt.Errorf("invalid front matter format")
-	}
-
-	frontMatterStr := parts[1]
-	body := strings.TrimLeft(parts[2], "\n")
-
-	// Parse YAML front matter
-	var frontMatter map[string]interface{}
-	if err := yaml.Unmarshal([]byte(frontMatterStr), &frontMatter); err != nil {
-		return nil, fmt.Errorf("failed to parse front matter: %w", err)
+	frontMatter, err := header.Decode()
+	if err != nil {
+		return nil, err
 	}
 
 	return &MarkdownDoc{
-		FrontMatter:    frontMatter,
-		Body:           body,
-		rawFrontMatter: frontMatterStr,
+		FrontMatter: frontMatter,
+		Body:        strings.TrimLeft(body, "\n"),
+		Header:      header,
 	}, nil
 }
 
-// Reconstruct rebuilds the markdown file with front matter and body
+// Strings returns the front matter's string values at the key paths
+func (md *MarkdownDoc) Strings(keys []string) ([]FrontMatterString, error) {
+	if md.Header == nil {
+		return nil, nil
+	}
+	return md.Header.Strings(keys)
+}
+
+// Reconstruct rebuilds the markdown file with front matter and body.
+// Front matter is written as it was parsed, with any values set on Header.
 func (md *MarkdownDoc) Reconstruct() (string, error) {
 	var buf bytes.Buffer
 
 	// Write front matter if it exists
-	if len(md.FrontMatter) > 0 {
+	if md.Header != nil {
+		buf.WriteString(md.Header.Raw)
+		buf.WriteString("\n")
+	} else if len(md.FrontMatter) > 0 {
 		buf.WriteString("---\n")
 
 		// Marshal front matter to YAML
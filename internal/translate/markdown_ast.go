@@ -0,0 +1,130 @@
+// Package translator provides translation workflow management.
+//
+// This file parses markdown bodies with goldmark, the renderer Hugo uses,
+// so code, HTML, link destinations and shortcodes can be told apart from
+// the text that is translated, and checked after translation.
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// ShortcodePattern matches Hugo shortcode tags, {{< name >}} and
+// {{% name %}}, including tags that span lines.
+var ShortcodePattern = regexp.MustCompile(`(?s)\{\{[<%].*?[%>]\}\}`)
+
+// markdownParser parses like Hugo's goldmark renderer: GFM (tables,
+// strikethrough, task lists, bare URLs), footnotes and definition lists.
+// Typographer is left out, so every text node keeps its source position.
+var markdownParser = goldmark.New(goldmark.WithExtensions(
+	extension.GFM,
+	extension.Footnote,
+	extension.DefinitionList,
+)).Parser()
+
+// LinkReferences returns the link reference definitions of a markdown
+// body, so its paragraphs can be parsed one at a time.
+func LinkReferences(body string) []parser.Reference {
+	ctx := parser.NewContext()
+	markdownParser.Parse(text.NewReader([]byte(body)), parser.WithContext(ctx))
+	return ctx.References()
+}
+
+// ParseMarkdownAST parses markdown, resolving reference links with refs
+// as well as the definitions in src.
+func ParseMarkdownAST(src []byte, refs []parser.Reference) ast.Node {
+	ctx := parser.NewContext()
+	for _, ref := range refs {
+		ctx.AddReference(ref)
+	}
+	return markdownParser.Parse(text.NewReader(src), parser.WithContext(ctx))
+}
+
+// ProtectedNodes returns the parts of a markdown body that translation
+// must keep as they are: code blocks and spans, raw HTML, link and image
+// destinations, autolinks and Hugo shortcodes.
+func ProtectedNodes(body string) []string {
+	src := []byte(body)
+	nodes := ShortcodePattern.FindAllString(body, -1)
+	ast.Walk(ParseMarkdownAST(src, nil), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			var b strings.Builder
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				b.Write(seg.Value(src))
+			}
+			nodes = append(nodes, b.String())
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			var b strings.Builder
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					b.Write(t.Segment.Value(src))
+				}
+			}
+			nodes = append(nodes, "`"+b.String()+"`")
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			var b strings.Builder
+			for i := 0; i < n.Segments.Len(); i++ {
+				seg := n.Segments.At(i)
+				b.Write(seg.Value(src))
+			}
+			nodes = append(nodes, b.String())
+		case *ast.Link:
+			nodes = append(nodes, string(n.Destination))
+		case *ast.Image:
+			nodes = append(nodes, string(n.Destination))
+		case *ast.AutoLink:
+			nodes = append(nodes, string(n.URL(src)))
+		}
+		return ast.WalkContinue, nil
+	})
+	return nodes
+}
+
+// CheckProtected returns an error naming the protected parts of source
+// (see ProtectedNodes) that are missing from translated.
+func CheckProtected(source, translated string) error {
+	have := make(map[string]int)
+	for _, n := range ProtectedNodes(translated) {
+		have[n]++
+	}
+	var missing []string
+	for _, n := range ProtectedNodes(source) {
+		if have[n] > 0 {
+			have[n]--
+			continue
+		}
+		missing = append(missing, n)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var quoted []string
+	for i, n := range missing {
+		if i == 3 {
+			quoted = append(quoted, fmt.Sprintf("and %d more", len(missing)-3))
+			break
+		}
+		if len(n) > 60 {
+			n = n[:57] + "..."
+		}
+		quoted = append(quoted, fmt.Sprintf("%q", n))
+	}
+	return fmt.Errorf("%d protected parts changed or lost: %s", len(missing), strings.Join(quoted, ", "))
+}
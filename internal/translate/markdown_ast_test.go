@@ -0,0 +1,25 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckProtected(t *testing.T) {
+	source := "See [the docs](/docs/ \"Docs\") and `make build`.\n\n" +
+		"{{< figure src=\"a.png\" >}}\n\n<div class=\"x\">\nHTML block\n</div>\n\n```sh\ngo test\n```\n"
+
+	good := "Siehe [die Doku](/docs/ \"Doku\") und `make build`.\n\n" +
+		"{{< figure src=\"a.png\" >}}\n\n<div class=\"x\">\nHTML block\n</div>\n\n```sh\ngo test\n```\n"
+	if err := CheckProtected(source, good); err != nil {
+		t.Errorf("good translation: %v", err)
+	}
+
+	// A translated link, a lost code span and a mangled shortcode
+	bad := "Siehe [die Doku](/de/docs/) und make build.\n\n" +
+		"{{< Abbildung src=\"a.png\" >}}\n\n<div class=\"x\">\nHTML block\n</div>\n\n```sh\ngo test\n```\n"
+	err := CheckProtected(source, bad)
+	if err == nil || !strings.HasPrefix(err.Error(), "3 protected parts") || !strings.Contains(err.Error(), "`make build`") {
+		t.Errorf("bad translation: %v", err)
+	}
+}
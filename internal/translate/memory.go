@@ -0,0 +1,396 @@
+// Package translator provides translation workflow management.
+//
+// This file contains the translation memory: a store of translated
+// paragraphs shared by the translate and autotranslate tools, so that
+// only new or changed paragraphs of a page are sent to a provider.
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultMemoryFile is the translation memory, relative to the site root.
+// It is committed with the content so every tool and contributor shares it.
+const DefaultMemoryFile = "translations/memory.json"
+
+// MemoryFormat is the memory file layout version.
+const MemoryFormat = 1
+
+// ProviderHuman marks translations approved from hand-edited content.
+const ProviderHuman = "human"
+
+// Memory is a translation memory: English segments by hash, each with its
+// translation per language.
+type Memory struct {
+	Format   int                       `json:"format"`
+	Segments map[string]*MemorySegment `json:"segments"`
+
+	path string
+}
+
+// MemorySegment is one English segment and its translations.
+type MemorySegment struct {
+	Source       string                  `json:"source"`
+	Translations map[string]*MemoryEntry `json:"translations"` // lang code → translation
+}
+
+// MemoryEntry is a segment's translation into one language.
+type MemoryEntry struct {
+	Text     string    `json:"text"`
+	Provider string    `json:"provider"` // deepl, claude, claude-cli, human
+	Updated  time.Time `json:"updated"`
+	Approved bool      `json:"approved,omitempty"` // Reviewed; never overwritten by a provider
+}
+
+// MemoryStats counts how the segments of a document were translated.
+type MemoryStats struct {
+	Reused     int // Found in the memory
+	Translated int // Sent to the provider
+	Copied     int // Code blocks and other segments kept as they are
+	Chars      int // Characters sent to the provider
+}
+
+// Add adds the counts of o to s.
+func (s *MemoryStats) Add(o MemoryStats) {
+	s.Reused += o.Reused
+	s.Translated += o.Translated
+	s.Copied += o.Copied
+	s.Chars += o.Chars
+}
+
+// NewMemory returns an empty memory that saves to path ("" = not saved).
+func NewMemory(path string) *Memory {
+	return &Memory{Format: MemoryFormat, Segments: make(map[string]*MemorySegment), path: path}
+}
+
+// LoadMemory reads the memory at path; a missing file is an empty memory.
+func LoadMemory(path string) (*Memory, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewMemory(path), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := NewMemory(path)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing translation memory %s: %w", path, err)
+	}
+	if m.Format > MemoryFormat {
+		return nil, fmt.Errorf("translation memory %s has format %d, this tool reads up to %d", path, m.Format, MemoryFormat)
+	}
+	if m.Segments == nil {
+		m.Segments = make(map[string]*MemorySegment)
+	}
+	m.Format = MemoryFormat
+	return m, nil
+}
+
+// Path returns the file the memory saves to.
+func (m *Memory) Path() string {
+	return m.path
+}
+
+// Save writes the memory to its file. Keys are sorted, so the file diffs
+// cleanly between runs.
+func (m *Memory) Save() error {
+	if m.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, append(data, '\n'), 0644)
+}
+
+// SegmentKey is the memory key of an English segment: the first 16 hex
+// digits of the SHA-256 of the segment with outer whitespace trimmed.
+func SegmentKey(source string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(source)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Lookup returns the translation of source into lang.
+func (m *Memory) Lookup(source, lang string) (*MemoryEntry, bool) {
+	seg := m.Segments[SegmentKey(source)]
+	if seg == nil {
+		return nil, false
+	}
+	e, ok := seg.Translations[lang]
+	return e, ok
+}
+
+// Put records a provider's translation of source into lang. An approved
+// translation is kept; Put then reports false.
+func (m *Memory) Put(source, lang, text, provider string) bool {
+	e := m.entry(source, lang)
+	if e.Approved {
+		return false
+	}
+	e.Text = text
+	e.Provider = provider
+	e.Updated = time.Now().UTC().Truncate(time.Second)
+	return true
+}
+
+// Approve records a reviewed translation of source into lang. A text that
+// differs from the stored one is attributed to ProviderHuman.
+func (m *Memory) Approve(source, lang, text string) {
+	e := m.entry(source, lang)
+	if e.Text != text || e.Provider == "" {
+		e.Text = text
+		e.Provider = ProviderHuman
+		e.Updated = time.Now().UTC().Truncate(time.Second)
+	}
+	e.Approved = true
+}
+
+func (m *Memory) entry(source, lang string) *MemoryEntry {
+	key := SegmentKey(source)
+	seg := m.Segments[key]
+	if seg == nil {
+		seg = &MemorySegment{Source: strings.TrimSpace(source), Translations: make(map[string]*MemoryEntry)}
+		m.Segments[key] = seg
+	}
+	e := seg.Translations[lang]
+	if e == nil {
+		e = &MemoryEntry{}
+		seg.Translations[lang] = e
+	}
+	return e
+}
+
+// TranslateSegments translates texts into lang. Texts found in the memory
+// are reused; the others are passed to translate in one call, in order and
+// without duplicates, and stored under provider.
+func (m *Memory) TranslateSegments(texts []string, lang, provider string, translate func([]string) ([]string, error)) ([]string, MemoryStats, error) {
+	var stats MemoryStats
+	out := make([]string, len(texts))
+	var missing []string
+	pending := make(map[string][]int) // segment key → indexes in texts
+	for i, text := range texts {
+		if e, ok := m.Lookup(text, lang); ok {
+			out[i] = e.Text
+			stats.Reused++
+			continue
+		}
+		key := SegmentKey(text)
+		if _, ok := pending[key]; !ok {
+			missing = append(missing, text)
+			stats.Chars += len(text)
+		}
+		pending[key] = append(pending[key], i)
+		stats.Translated++
+	}
+	if len(missing) == 0 {
+		return out, stats, nil
+	}
+
+	translated, err := translate(missing)
+	if err != nil {
+		return nil, stats, err
+	}
+	if len(translated) != len(missing) {
+		return nil, stats, fmt.Errorf("provider returned %d translations for %d segments", len(translated), len(missing))
+	}
+	for i, text := range missing {
+		m.Put(text, lang, translated[i], provider)
+		for _, j := range pending[SegmentKey(text)] {
+			out[j] = translated[i]
+		}
+	}
+	return out, stats, nil
+}
+
+// TranslateBody translates a markdown body paragraph by paragraph with
+// TranslateSegments; code blocks and shortcode-only paragraphs are kept.
+func (m *Memory) TranslateBody(body, lang, provider string, translate func([]string) ([]string, error)) (string, MemoryStats, error) {
+	segs := SplitSegments(body)
+	var texts []string
+	for _, s := range segs {
+		if s.Translate {
+			texts = append(texts, s.Text)
+		}
+	}
+	translated, stats, err := m.TranslateSegments(texts, lang, provider, translate)
+	if err != nil {
+		return "", stats, err
+	}
+	i := 0
+	for k := range segs {
+		if !segs[k].Translate {
+			if segs[k].Text != "" {
+				stats.Copied++
+			}
+			continue
+		}
+		segs[k].Text = translated[i]
+		i++
+	}
+	return JoinSegments(segs), stats, nil
+}
+
+// ApproveBody approves the paragraphs of a translated body against its
+// English body. Both must split into the same number of translatable
+// paragraphs; it returns how many were approved.
+func (m *Memory) ApproveBody(source, target, lang string) (int, error) {
+	src, tgt := translatable(SplitSegments(source)), translatable(SplitSegments(target))
+	if len(src) != len(tgt) {
+		return 0, fmt.Errorf("%d English paragraphs, %d translated", len(src), len(tgt))
+	}
+	for i := range src {
+		m.Approve(src[i], lang, tgt[i])
+	}
+	return len(src), nil
+}
+
+func translatable(segs []Segment) []string {
+	var texts []string
+	for _, s := range segs {
+		if s.Translate {
+			texts = append(texts, s.Text)
+		}
+	}
+	return texts
+}
+
+// Prune removes segments that are not in keep (a set of segment keys) and
+// have no approved translation, and returns how many were removed.
+func (m *Memory) Prune(keep map[string]bool) int {
+	n := 0
+	for key, seg := range m.Segments {
+		if keep[key] {
+			continue
+		}
+		approved := false
+		for _, e := range seg.Translations {
+			approved = approved || e.Approved
+		}
+		if !approved {
+			delete(m.Segments, key)
+			n++
+		}
+	}
+	return n
+}
+
+// MemoryLangStats summarises the memory for one language.
+type MemoryLangStats struct {
+	Lang      string
+	Segments  int
+	Approved  int
+	Providers map[string]int // provider → segments
+}
+
+// Stats returns the memory's per-language counts, sorted by language.
+func (m *Memory) Stats() []MemoryLangStats {
+	byLang := make(map[string]*MemoryLangStats)
+	for _, seg := range m.Segments {
+		for lang, e := range seg.Translations {
+			s := byLang[lang]
+			if s == nil {
+				s = &MemoryLangStats{Lang: lang, Providers: make(map[string]int)}
+				byLang[lang] = s
+			}
+			s.Segments++
+			s.Providers[e.Provider]++
+			if e.Approved {
+				s.Approved++
+			}
+		}
+	}
+	stats := make([]MemoryLangStats, 0, len(byLang))
+	for _, s := range byLang {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Lang < stats[j].Lang })
+	return stats
+}
+
+// ============================================================================
+// Segmentation
+// ============================================================================
+
+// Segment is one paragraph of a markdown body.
+type Segment struct {
+	Text      string // The paragraph, without the line break that ends it
+	Sep       string // Line break and blank lines after the paragraph
+	Translate bool   // False for code blocks and paragraphs without text
+}
+
+// SplitSegments splits a markdown body into paragraphs at blank lines.
+// Fenced code blocks are kept whole, blank lines included. Joining the
+// segments with JoinSegments gives back body exactly.
+func SplitSegments(body string) []Segment {
+	var segs []Segment
+	var block, sep strings.Builder
+	fence := ""
+
+	flush := func() {
+		text := block.String()
+		s := Segment{Text: strings.TrimSuffix(text, "\n"), Sep: sep.String()}
+		if strings.HasSuffix(text, "\n") {
+			s.Sep = "\n" + s.Sep
+		}
+		s.Translate = isTranslatable(s.Text)
+		segs = append(segs, s)
+		block.Reset()
+		sep.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" && trimmed == "" {
+			sep.WriteString(line)
+			continue
+		}
+		if sep.Len() > 0 {
+			flush()
+		}
+		block.WriteString(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		}
+	}
+	if block.Len() > 0 || sep.Len() > 0 {
+		flush()
+	}
+	return segs
+}
+
+// JoinSegments reassembles segments split by SplitSegments.
+func JoinSegments(segs []Segment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		b.WriteString(s.Text)
+		b.WriteString(s.Sep)
+	}
+	return b.String()
+}
+
+func isTranslatable(text string) bool {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		return false
+	}
+	// Shortcode-only paragraphs, like {{< figure ... >}} or {{< /notice >}}
+	return strings.IndexFunc(ShortcodePattern.ReplaceAllString(trimmed, ""), unicode.IsLetter) >= 0
+}
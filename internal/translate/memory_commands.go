@@ -0,0 +1,161 @@
+// Package translator provides translation workflow management.
+//
+// This file contains the translation memory commands (translate tm).
+// Status is a query; approve and prune write the memory file.
+package translate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CheckMemory returns translation memory statistics.
+func (c *Checker) CheckMemory() MemoryStatusResult {
+	result := MemoryStatusResult{Path: c.config.MemoryFile}
+	m, err := LoadMemory(c.config.MemoryFile)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.Segments = len(m.Segments)
+	result.Languages = m.Stats()
+	return result
+}
+
+// DoMemoryApprove records the paragraphs of existing translations as
+// approved, so providers never overwrite them. Files are English sources,
+// relative to the source directory or the site root; none means every
+// English file with a translation.
+func (c *Checker) DoMemoryApprove(langCode string, files []string) MemoryApproveResult {
+	result := MemoryApproveResult{
+		Path:     c.config.MemoryFile,
+		LangCode: langCode,
+		Approved: make(map[string]int),
+		Skipped:  make(map[string]string),
+	}
+	configured := false
+	for _, lang := range c.config.TargetLangs {
+		configured = configured || lang.Code == langCode
+	}
+	if !configured {
+		result.Error = fmt.Errorf("language '%s' not configured", langCode)
+		return result
+	}
+
+	m, err := LoadMemory(c.config.MemoryFile)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	explicit := len(files) > 0
+	if !explicit {
+		files = c.getEnglishFiles()
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			file = filepath.Join(c.sourcePath(), file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			result.Skipped[file] = "English file not found"
+			continue
+		}
+		target := c.config.GetTargetPath(file, langCode)
+		n, err := approveFile(m, file, target, langCode, c.config.FrontMatter)
+		switch {
+		case os.IsNotExist(err):
+			if explicit {
+				result.Skipped[target] = "not translated"
+			}
+		case err != nil:
+			result.Skipped[target] = err.Error()
+		default:
+			result.Approved[target] = n
+			result.Total += n
+		}
+	}
+
+	if result.Total > 0 {
+		result.Error = m.Save()
+	}
+	return result
+}
+
+// approveFile approves a translated file's front matter values and
+// paragraphs against its English source.
+func approveFile(m *Memory, source, target, langCode string, keys []string) (int, error) {
+	targetData, err := os.ReadFile(target)
+	if err != nil {
+		return 0, err
+	}
+	sourceData, err := os.ReadFile(source)
+	if err != nil {
+		return 0, err
+	}
+	src, err := ParseMarkdown(sourceData)
+	if err != nil {
+		return 0, err
+	}
+	tgt, err := ParseMarkdown(targetData)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := m.ApproveBody(src.Body, tgt.Body, langCode)
+	if err != nil {
+		return 0, err
+	}
+	srcStrings, err := src.Strings(keys)
+	if err != nil {
+		return n, err
+	}
+	tgtStrings, err := tgt.Strings(keys)
+	if err != nil {
+		return n, err
+	}
+	for _, s := range srcStrings {
+		if t, _ := stringAt(tgtStrings, s.Path); s.Value != "" && t != "" {
+			m.Approve(s.Value, langCode, t)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DoMemoryPrune removes memory segments that are in no English file
+// anymore. Segments with an approved translation are kept.
+func (c *Checker) DoMemoryPrune() MemoryPruneResult {
+	result := MemoryPruneResult{Path: c.config.MemoryFile}
+	m, err := LoadMemory(c.config.MemoryFile)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	keep := make(map[string]bool)
+	for _, file := range c.getEnglishFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		md, err := ParseMarkdown(data)
+		if err != nil {
+			continue
+		}
+		strs, _ := md.Strings(c.config.FrontMatter)
+		for _, s := range strs {
+			keep[SegmentKey(s.Value)] = true
+		}
+		for _, s := range SplitSegments(md.Body) {
+			keep[SegmentKey(s.Text)] = true
+		}
+	}
+
+	result.Removed = m.Prune(keep)
+	result.Remaining = len(m.Segments)
+	if result.Removed > 0 {
+		result.Error = m.Save()
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package translate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitSegments(t *testing.T) {
+	body := "\n# Title\n\nFirst paragraph\nstill first.\n\n\n```go\nfunc main() {\n\n}\n```\n\n{{< figure src=\"a.png\" >}}\n\n---\n\nLast"
+
+	segs := SplitSegments(body)
+	if got := JoinSegments(segs); got != body {
+		t.Fatalf("round trip = %q", got)
+	}
+
+	var translate []string
+	for _, s := range segs {
+		if s.Translate {
+			translate = append(translate, s.Text)
+		}
+	}
+	want := []string{"# Title", "First paragraph\nstill first.", "Last"}
+	if strings.Join(translate, "|") != strings.Join(want, "|") {
+		t.Errorf("translatable = %q, want %q", translate, want)
+	}
+}
+
+func TestMemoryTranslateBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	m := NewMemory(path)
+
+	var sent []string
+	upper := func(segments []string) ([]string, error) {
+		sent = append(sent, segments...)
+		out := make([]string, len(segments))
+		for i, s := range segments {
+			out[i] = strings.ToUpper(s)
+		}
+		return out, nil
+	}
+
+	body := "Hello\n\nWorld\n\n```\ncode\n```\n\nHello\n"
+	got, stats, err := m.TranslateBody(body, "de", "test", upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "HELLO\n\nWORLD\n\n```\ncode\n```\n\nHELLO\n" {
+		t.Errorf("translated = %q", got)
+	}
+	if len(sent) != 2 || stats.Translated != 3 || stats.Copied != 1 {
+		t.Errorf("sent %q, stats %+v", sent, stats)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A changed paragraph is the only one sent again
+	m, err = LoadMemory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Approve("World", "de", "Welt")
+	sent = nil
+	got, stats, err = m.TranslateBody("Hello\n\nWorld\n\nNew text\n", "de", "test", upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "HELLO\n\nWelt\n\nNEW TEXT\n" || len(sent) != 1 || stats.Reused != 2 {
+		t.Errorf("translated = %q, sent %q, stats %+v", got, sent, stats)
+	}
+
+	// Approved translations are kept; unused unapproved ones are pruned
+	if m.Put("World", "de", "WORLD", "test") {
+		t.Error("Put overwrote an approved translation")
+	}
+	if n := m.Prune(map[string]bool{SegmentKey("Hello"): true}); n != 1 {
+		t.Errorf("pruned %d segments, want 1 (New text)", n)
+	}
+}
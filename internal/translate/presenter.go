@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 )
 
 // Presenter defines the interface for formatting command output.
@@ -19,12 +21,14 @@ type Presenter interface {
 	Diff(r DiffResult)
 	Missing(r MissingResult)
 	Stale(r StaleResult)
+	ContentValidate(r ContentValidateResult)
 	Orphans(r OrphansResult)
 	Next(r NextResult)
 	Changed(r ChangedResult)
 	Validate(r ValidateResult)
 	Langs(r LangsResult)
 	MenuCheck(r MenuCheckResult)
+	MemoryStatus(r MemoryStatusResult)
 
 	// Mutation results (commands that modify state)
 	Clean(r CleanResult)
@@ -33,6 +37,10 @@ type Presenter interface {
 	LangAdd(r LangAddResult)
 	LangRemove(r LangRemoveResult)
 	LangInit(r LangInitResult)
+	MemoryApprove(r MemoryApproveResult)
+	MemoryPrune(r MemoryPruneResult)
+	Export(r ExportResult)
+	Import(r ImportResult)
 }
 
 // ============================================================================
@@ -216,6 +224,28 @@ func (p *TerminalPresenter) Stale(r StaleResult) {
 	p.footer()
 }
 
+// ContentValidate formats translated page checks for terminal.
+func (p *TerminalPresenter) ContentValidate(r ContentValidateResult) {
+	p.header("Validating Translated Content")
+
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "Error: %v\n", r.Error)
+		return
+	}
+
+	if len(r.Issues) == 0 {
+		fmt.Fprintf(p.w, "OK: %d translated pages pass all checks\n", r.Checked)
+	} else {
+		for _, issue := range r.Issues {
+			fmt.Fprintf(p.w, "%s: %s: %s\n", strings.ToUpper(issue.Check), issue.TargetPath, issue.Message)
+		}
+		fmt.Fprintln(p.w)
+		fmt.Fprintf(p.w, "Found %d issues in %d translated pages\n", len(r.Issues), r.Checked)
+		fmt.Fprintf(p.w, "Fix the translations, or the glossary (%s)\n", DefaultGlossaryFile)
+	}
+	p.footer()
+}
+
 // Orphans formats orphaned files for terminal.
 func (p *TerminalPresenter) Orphans(r OrphansResult) {
 	p.header("Orphaned Files (exist in target but not in English)")
@@ -548,6 +578,126 @@ func (p *TerminalPresenter) LangInit(r LangInitResult) {
 	fmt.Fprintln(p.w, "  Run 'task translate:missing' to see what needs translating")
 }
 
+// MemoryStatus formats translation memory statistics for terminal.
+func (p *TerminalPresenter) MemoryStatus(r MemoryStatusResult) {
+	p.header("Translation Memory")
+
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "Error: %v\n", r.Error)
+		return
+	}
+
+	fmt.Fprintf(p.w, "File:     %s\n", r.Path)
+	fmt.Fprintf(p.w, "Segments: %d English\n\n", r.Segments)
+	if len(r.Languages) == 0 {
+		fmt.Fprintln(p.w, "No translations yet")
+		fmt.Fprintln(p.w)
+		p.footer()
+		return
+	}
+
+	fmt.Fprintf(p.w, "%-10s %10s %10s  %s\n", "Language", "Segments", "Approved", "Providers")
+	for _, l := range r.Languages {
+		var providers []string
+		for name, n := range l.Providers {
+			providers = append(providers, fmt.Sprintf("%s %d", name, n))
+		}
+		sort.Strings(providers)
+		fmt.Fprintf(p.w, "%-10s %10d %10d  %s\n", l.Lang, l.Segments, l.Approved, strings.Join(providers, ", "))
+	}
+	fmt.Fprintln(p.w)
+	p.footer()
+}
+
+// MemoryApprove formats approved translations for terminal.
+func (p *TerminalPresenter) MemoryApprove(r MemoryApproveResult) {
+	p.header(fmt.Sprintf("Approving translations: %s", r.LangCode))
+
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "Error: %v\n", r.Error)
+		return
+	}
+
+	for _, file := range sortedKeys(r.Approved) {
+		fmt.Fprintf(p.w, "  %s: %d segments\n", file, r.Approved[file])
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(p.w)
+		p.section("Skipped (translate or fix these files)")
+		for _, file := range sortedKeys(r.Skipped) {
+			fmt.Fprintf(p.w, "  %s: %s\n", file, r.Skipped[file])
+		}
+	}
+	fmt.Fprintln(p.w)
+	p.footer()
+	fmt.Fprintf(p.w, "OK: %d segments approved in %s\n", r.Total, r.Path)
+	p.footer()
+}
+
+// MemoryPrune formats translation memory pruning for terminal.
+func (p *TerminalPresenter) MemoryPrune(r MemoryPruneResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "Error: %v\n", r.Error)
+		return
+	}
+	if r.Removed == 0 {
+		fmt.Fprintf(p.w, "OK: No unused segments in %s\n", r.Path)
+		return
+	}
+	fmt.Fprintf(p.w, "OK: Removed %d unused segments from %s (%d remain)\n", r.Removed, r.Path, r.Remaining)
+}
+
+// Export formats an exchange export for terminal.
+func (p *TerminalPresenter) Export(r ExportResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "Error: %v\n", r.Error)
+		return
+	}
+	if r.Units == 0 {
+		fmt.Fprintf(p.w, "OK: Nothing to translate into %s (%s written empty)\n", r.LangCode, r.Path)
+		return
+	}
+	fmt.Fprintf(p.w, "OK: %d segments from %d files written to %s\n", r.Units, r.Files, r.Path)
+	if r.Prefilled > 0 {
+		fmt.Fprintf(p.w, "    %d prefilled with existing or machine translations, marked for review\n", r.Prefilled)
+	}
+	fmt.Fprintf(p.w, "\nImport the reviewed file with: translate import %s\n", r.Path)
+}
+
+// Import formats an exchange import for terminal.
+func (p *TerminalPresenter) Import(r ImportResult) {
+	p.header(fmt.Sprintf("Importing translations: %s", r.LangCode))
+
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "Error: %v\n", r.Error)
+		return
+	}
+
+	for _, file := range sortedKeys(r.Written) {
+		fmt.Fprintf(p.w, "  %s: %d segments\n", file, r.Written[file])
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(p.w)
+		p.section("Not written")
+		for _, file := range sortedKeys(r.Skipped) {
+			fmt.Fprintf(p.w, "  %s: %s\n", file, r.Skipped[file])
+		}
+	}
+	fmt.Fprintln(p.w)
+	p.footer()
+	fmt.Fprintf(p.w, "OK: %d segments written, %d not reviewed yet\n", r.Total, r.Pending)
+	p.footer()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ============================================================================
 // Markdown Presenter - GitHub Issue compatible output
 // ============================================================================
@@ -676,6 +826,25 @@ func (p *MarkdownPresenter) Stale(r StaleResult) {
 	}
 }
 
+// ContentValidate formats translated page checks as markdown.
+func (p *MarkdownPresenter) ContentValidate(r ContentValidateResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "## Content Validation Failed\n\n**Error:** %v\n", r.Error)
+		return
+	}
+	if len(r.Issues) == 0 {
+		return // No output if no issues
+	}
+
+	fmt.Fprintln(p.w, "## Translated Content Issues")
+	fmt.Fprintln(p.w)
+	fmt.Fprintln(p.w, "| File | Check | Issue |")
+	fmt.Fprintln(p.w, "|------|-------|-------|")
+	for _, issue := range r.Issues {
+		fmt.Fprintf(p.w, "| `%s` | %s | %s |\n", issue.TargetPath, issue.Check, issue.Message)
+	}
+}
+
 // Orphans formats orphaned files as markdown.
 func (p *MarkdownPresenter) Orphans(r OrphansResult) {
 	if r.TotalCount == 0 {
@@ -898,3 +1067,68 @@ func (p *MarkdownPresenter) LangInit(r LangInitResult) {
 	fmt.Fprintf(p.w, "## Initialized Language: %s\n\n", r.Code)
 	fmt.Fprintf(p.w, "Created `%s`\n", r.Path)
 }
+
+// MemoryStatus formats translation memory statistics as markdown.
+func (p *MarkdownPresenter) MemoryStatus(r MemoryStatusResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "## Translation Memory\n\n**Error:** %v\n", r.Error)
+		return
+	}
+
+	fmt.Fprintf(p.w, "## Translation Memory\n\n`%s`: %d English segments\n\n", r.Path, r.Segments)
+	fmt.Fprintln(p.w, "| Language | Segments | Approved |")
+	fmt.Fprintln(p.w, "|----------|----------|----------|")
+	for _, l := range r.Languages {
+		fmt.Fprintf(p.w, "| %s | %d | %d |\n", l.Lang, l.Segments, l.Approved)
+	}
+}
+
+// MemoryApprove formats approved translations as markdown.
+func (p *MarkdownPresenter) MemoryApprove(r MemoryApproveResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "## Approval Failed\n\n**Error:** %v\n", r.Error)
+		return
+	}
+
+	fmt.Fprintf(p.w, "## Approved Translations: %s\n\n%d segments approved.\n", r.LangCode, r.Total)
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(p.w, "\n### Skipped")
+		for _, file := range sortedKeys(r.Skipped) {
+			fmt.Fprintf(p.w, "- `%s`: %s\n", file, r.Skipped[file])
+		}
+	}
+}
+
+// MemoryPrune formats translation memory pruning as markdown.
+func (p *MarkdownPresenter) MemoryPrune(r MemoryPruneResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "## Prune Failed\n\n**Error:** %v\n", r.Error)
+		return
+	}
+	fmt.Fprintf(p.w, "## Translation Memory Pruned\n\nRemoved %d unused segments (%d remain).\n", r.Removed, r.Remaining)
+}
+
+// Export formats an exchange export as markdown.
+func (p *MarkdownPresenter) Export(r ExportResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "## Export Failed\n\n**Error:** %v\n", r.Error)
+		return
+	}
+	fmt.Fprintf(p.w, "## Exported for Translation: %s\n\n%d segments from %d files in `%s` (%d prefilled for review).\n", r.LangCode, r.Units, r.Files, r.Path, r.Prefilled)
+}
+
+// Import formats an exchange import as markdown.
+func (p *MarkdownPresenter) Import(r ImportResult) {
+	if r.Error != nil {
+		fmt.Fprintf(p.w, "## Import Failed\n\n**Error:** %v\n", r.Error)
+		return
+	}
+
+	fmt.Fprintf(p.w, "## Imported Translations: %s\n\n%d segments written, %d not reviewed yet.\n", r.LangCode, r.Total, r.Pending)
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(p.w, "\n### Not Written")
+		for _, file := range sortedKeys(r.Skipped) {
+			fmt.Fprintf(p.w, "- `%s`: %s\n", file, r.Skipped[file])
+		}
+	}
+}
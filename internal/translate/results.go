@@ -209,3 +209,80 @@ type LangInitResult struct {
 	FileCount   int    // Number of existing files (if already exists)
 	Error       error  // Any error during init
 }
+
+// ContentIssue is a problem found in a translated page
+type ContentIssue struct {
+	TargetPath string // Translation file
+	SourcePath string // English source file
+	LangCode   string // Language code
+	Check      string // Check that failed (glossary)
+	Message    string // What is wrong
+}
+
+// ContentValidateResult contains translated page checks
+type ContentValidateResult struct {
+	Checked int            // Translated pages checked
+	Issues  []ContentIssue // Problems found, by file
+	Error   error          // Any error loading the glossary
+}
+
+// HasIssues returns true if any translated page failed a check
+func (r ContentValidateResult) HasIssues() bool {
+	return len(r.Issues) > 0 || r.Error != nil
+}
+
+// ============================================================================
+// Translation Memory Results
+// ============================================================================
+
+// MemoryStatusResult contains translation memory statistics
+type MemoryStatusResult struct {
+	Path      string            // Memory file
+	Segments  int               // English segments in the memory
+	Languages []MemoryLangStats // Per-language counts
+	Error     error             // Any error loading the memory
+}
+
+// MemoryApproveResult contains paragraphs approved from translated files
+type MemoryApproveResult struct {
+	Path     string            // Memory file
+	LangCode string            // Language approved
+	Approved map[string]int    // Target file → segments approved
+	Skipped  map[string]string // Target file → why it was skipped
+	Total    int               // Segments approved across all files
+	Error    error             // Any error loading or saving the memory
+}
+
+// MemoryPruneResult contains pruned translation memory data
+type MemoryPruneResult struct {
+	Path      string // Memory file
+	Removed   int    // Segments no longer in any English file
+	Remaining int    // Segments kept
+	Error     error  // Any error loading or saving the memory
+}
+
+// ============================================================================
+// Exchange Results
+// ============================================================================
+
+// ExportResult contains an exchange file written for human translators
+type ExportResult struct {
+	Path      string // Exchange file written
+	LangCode  string // Target language
+	Format    string // xliff or po
+	Files     int    // Source files with segments to translate
+	Units     int    // Segments exported
+	Prefilled int    // Segments with an existing or machine translation to review
+	Error     error  // Any error reading content or writing the file
+}
+
+// ImportResult contains reviewed translations written back to content
+type ImportResult struct {
+	Path     string            // Exchange file read
+	LangCode string            // Target language
+	Written  map[string]int    // Target file → segments written
+	Skipped  map[string]string // File → why segments were not written
+	Pending  int               // Segments not reviewed yet (empty, fuzzy or state initial)
+	Total    int               // Segments written across all files
+	Error    error             // Any error reading the file or saving the memory
+}
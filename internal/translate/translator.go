@@ -22,6 +22,9 @@ type Config struct {
 	ContentDir    string
 	I18nDir       string
 	CheckpointTag string
+	MemoryFile    string   // Translation memory (see memory.go)
+	GlossaryFile  string   // Glossary (see glossary.go)
+	FrontMatter   []string // Front matter key paths to translate (see frontmatter.go)
 }
 
 // DefaultConfig returns the default configuration.
@@ -34,6 +37,9 @@ func DefaultConfig() *Config {
 		ContentDir:    "content",
 		I18nDir:       "i18n",
 		CheckpointTag: "last-translation",
+		MemoryFile:    DefaultMemoryFile,
+		GlossaryFile:  DefaultGlossaryFile,
+		FrontMatter:   DefaultFrontMatterKeys,
 		// Default fallback languages (used when not a Hugo project)
 		TargetLangs: []Language{
 			{Code: "de", Name: "German", DirName: "german"},
@@ -78,10 +84,12 @@ func (c *Config) GetTargetPath(sourcePath, targetLangCode string) string {
 
 // Translator handles translation operations (requires Claude API key)
 type Translator struct {
-	apiKey string
-	config *Config
-	claude *ClaudeClient
-	git    *GitManager
+	apiKey   string
+	config   *Config
+	claude   *ClaudeClient
+	git      *GitManager
+	memory   *Memory
+	glossary *Glossary
 }
 
 // New creates a new Translator instance for automated translation
@@ -104,11 +112,24 @@ func New(apiKey string) (*Translator, error) {
 		return nil, fmt.Errorf("failed to create Git manager: %w", err)
 	}
 
+	memory, err := LoadMemory(config.MemoryFile)
+	if err != nil {
+		return nil, err
+	}
+
+	glossary, err := LoadGlossary(config.GlossaryFile)
+	if err != nil {
+		return nil, err
+	}
+	claude.UseGlossary(glossary)
+
 	return &Translator{
-		apiKey: apiKey,
-		config: config,
-		claude: claude,
-		git:    git,
+		apiKey:   apiKey,
+		config:   config,
+		claude:   claude,
+		git:      git,
+		memory:   memory,
+		glossary: glossary,
 	}, nil
 }
 
@@ -216,11 +237,22 @@ func (t *Translator) translateFiles(files []string, lang Language) error {
 			return fmt.Errorf("failed to parse %s: %w", file, err)
 		}
 
-		// Translate the content (not front matter or code blocks)
-		translatedBody, err := t.claude.Translate(md.Body, lang.Code, lang.Name)
+		// Translate the changed paragraphs (not front matter or code blocks)
+		translatedBody, stats, err := t.memory.TranslateBody(md.Body, lang.Code, t.claude.Name(), func(segments []string) ([]string, error) {
+			return t.claude.TranslateSegments(segments, lang.Code, lang.Name)
+		})
 		if err != nil {
 			return fmt.Errorf("failed to translate %s: %w", file, err)
 		}
+		fmt.Printf("        %d paragraphs from memory, %d translated\n", stats.Reused, stats.Translated)
+		for _, v := range t.glossary.Check(md.Body, translatedBody, lang.Code) {
+			fmt.Printf("        WARNING: glossary: %s\n", v)
+		}
+
+		// Save after every file, so an interrupted run keeps its work
+		if err := t.memory.Save(); err != nil {
+			return fmt.Errorf("failed to save translation memory: %w", err)
+		}
 
 		// Reconstruct markdown with translated content
 		md.Body = translatedBody